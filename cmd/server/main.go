@@ -3,9 +3,33 @@ package main
 import (
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/authz"
+	"github.com/sudarshanmg/gotask/internal/automation"
+	"github.com/sudarshanmg/gotask/internal/calendar"
+	"github.com/sudarshanmg/gotask/internal/collab"
+	"github.com/sudarshanmg/gotask/internal/digest"
+	"github.com/sudarshanmg/gotask/internal/importer"
+	"github.com/sudarshanmg/gotask/internal/inbound"
+	"github.com/sudarshanmg/gotask/internal/mail"
+	"github.com/sudarshanmg/gotask/internal/milestone"
+	"github.com/sudarshanmg/gotask/internal/notification"
+	"github.com/sudarshanmg/gotask/internal/project"
+	"github.com/sudarshanmg/gotask/internal/reminder"
+	"github.com/sudarshanmg/gotask/internal/report"
+	"github.com/sudarshanmg/gotask/internal/scheduler"
+	"github.com/sudarshanmg/gotask/internal/share"
+	"github.com/sudarshanmg/gotask/internal/sprint"
+	"github.com/sudarshanmg/gotask/internal/stats"
+	"github.com/sudarshanmg/gotask/internal/stream"
 	"github.com/sudarshanmg/gotask/internal/task"
+	"github.com/sudarshanmg/gotask/internal/timeentry"
+	"github.com/sudarshanmg/gotask/internal/timeline"
+	"github.com/sudarshanmg/gotask/internal/transfer"
+	"github.com/sudarshanmg/gotask/internal/webhook"
+	"github.com/sudarshanmg/gotask/internal/workspace"
 	"github.com/sudarshanmg/gotask/pkg/config"
 	"github.com/sudarshanmg/gotask/pkg/db"
 
@@ -29,18 +53,146 @@ func main() {
 		log.Println("Successfully connected to the database!")
 	}
 
-	repo := task.NewRepository(db)
-	service := task.NewService(repo)
-	taskHandler := task.NewHandler(service)
+	projectRepo := project.NewRepository(db)
 
 	authRepo := auth.NewRepository(db)
-	authService := auth.NewService(authRepo, cfg.JWTSecret)
+
+	var mailSender mail.Sender = mail.LogSender{}
+	if cfg.SMTPHost != "" {
+		mailSender = &mail.SMTPSender{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.MailFrom,
+		}
+	}
+	mailService := mail.NewService(mail.NewRepository(db), mailSender)
+
+	notificationService := notification.NewService(notification.NewRepository(db))
+	notificationHandler := notification.NewHandler(notificationService)
+
+	webhookService := webhook.NewService(webhook.NewRepository(db))
+	webhookHandler := webhook.NewHandler(webhookService)
+
+	// task events reach SSE clients on every server through LISTEN/NOTIFY
+	eventBroker := stream.NewBroker(1000)
+	if err := stream.Listen(db, cfg.URL, eventBroker, nil); err != nil {
+		log.Fatal(err)
+	}
+	streamHandler := stream.NewHandler(eventBroker, projectRepo)
+
+	// WebSocket clients get the same events plus presence, shared the same way
+	repo := task.NewRepository(db)
+	hub := collab.NewHub(db, eventBroker, projectRepo, repo, authRepo)
+	if err := hub.Listen(cfg.URL, nil); err != nil {
+		log.Fatal(err)
+	}
+	collabHandler := collab.NewHandler(hub)
+
+	// Automation rules act through the task service and listen to its
+	// events, so they join the publishers once the service exists.
+	publishers := task.Publishers{webhookService, stream.NewPublisher(db)}
+	service := task.NewService(repo, projectRepo, authRepo, notificationService, &publishers)
+	automationService := automation.NewService(automation.NewRepository(db), service, projectRepo, notificationService)
+	publishers = append(publishers, automationService)
+	projectService := project.NewService(projectRepo, service)
+	automationHandler := automation.NewHandler(automationService)
+
+	authzRepo := authz.NewRepository(db)
+	policy := authz.NewPolicy(authzRepo, map[string]authz.ScopeFunc{
+		authz.ResourceTask:    service.Actions,
+		authz.ResourceProject: projectService.Actions,
+	})
+	authzHandler := authz.NewHandler(authz.NewService(authzRepo, policy))
+
+	projectHandler := project.NewHandler(projectService, policy)
+	taskHandler := task.NewHandler(service, policy)
+
+	reminderService := reminder.NewService(reminder.NewRepository(db), service,
+		&reminder.InAppNotifier{Notifications: notificationService},
+		&reminder.EmailNotifier{Mail: mailService},
+		reminder.NewWebhookNotifier(),
+	)
+	reminderHandler := reminder.NewHandler(reminderService)
+
+	timeEntryHandler := timeentry.NewHandler(timeentry.NewService(timeentry.NewRepository(db), service))
+	statsHandler := stats.NewHandler(stats.NewService(stats.NewRepository(db)))
+	reportHandler := report.NewHandler(report.NewService(report.NewRepository(db), projectRepo))
+	sprintHandler := sprint.NewHandler(sprint.NewService(sprint.NewRepository(db), projectRepo, service))
+	transferHandler := transfer.NewHandler(transfer.NewService(transfer.NewRepository(db), service), policy)
+	milestoneHandler := milestone.NewHandler(milestone.NewService(milestone.NewRepository(db), projectRepo, service))
+	timelineHandler := timeline.NewHandler(timeline.NewService(timeline.NewRepository(db), projectRepo, service))
+	importerService := importer.NewService(importer.NewRepository(db), service, projectService, policy)
+	importerHandler := importer.NewHandler(importerService, policy)
+	inboundHandler := inbound.NewHandler(inbound.NewService(inbound.NewRepository(db), service, projectService,
+		cfg.InboundDomain), cfg.InboundSecret)
+	calendarHandler := calendar.NewHandler(calendar.NewService(calendar.NewRepository(db), service, projectService), policy)
+
+	workspaceRepo := workspace.NewRepository(db)
+
+	digestService := digest.NewService(digest.NewRepository(db), repo, workspaceRepo, authRepo)
+	digestHandler := digest.NewHandler(digestService)
+
+	// background jobs; safe to run on every replica
+	sched := scheduler.New()
+	sched.Add(&notification.DueSoonJob{Service: notificationService, Window: 24 * time.Hour}, 5*time.Minute)
+	sched.Add(&reminder.Job{Service: reminderService, Batch: 100}, 30*time.Second)
+	sched.Add(&digest.Job{Service: digestService, Batch: 50}, time.Minute)
+	sched.Add(&mail.OutboxJob{Service: mailService, Batch: 50}, 15*time.Second)
+	sched.Add(&webhook.Job{Service: webhookService, Batch: 50}, 10*time.Second)
+	sched.Add(&importer.Job{Service: importerService, Batch: 5}, 5*time.Second)
+	sched.Add(&automation.Job{Service: automationService, Batch: 50}, 5*time.Second)
+	sched.Start()
+	defer sched.Stop()
+
+	authService := auth.NewService(authRepo, cfg.JWTSecret, cfg.AppURL)
 	authHandler := auth.NewHandler(authService)
 	auth.RegisterRoutes(r, authHandler)
 
+	shareHandler := share.NewHandler(share.NewService(share.NewRepository(db), repo, projectRepo, policy))
+	share.RegisterPublicRoutes(r, shareHandler)
+	calendar.RegisterPublicRoutes(r, calendarHandler)
+	inbound.RegisterPublicRoutes(r, inboundHandler)
+
+	workspaceHandler := workspace.NewHandler(workspace.NewService(workspaceRepo))
+
 	r.Group(func(r chi.Router) {
 		r.Use(auth.AuthMiddleware(cfg.JWTSecret))
-		task.RegisterRoutes(r, taskHandler)
+		auth.RegisterProtectedRoutes(r, authHandler)
+		workspace.RegisterRoutes(r, workspaceHandler)
+		digest.RegisterRoutes(r, digestHandler)
+
+		r.Group(func(r chi.Router) {
+			r.Use(workspace.TenancyMiddleware(workspaceRepo))
+			task.RegisterRoutes(r, taskHandler)
+			project.RegisterRoutes(r, projectHandler)
+			authz.RegisterRoutes(r, authzHandler)
+			share.RegisterRoutes(r, shareHandler)
+			notification.RegisterRoutes(r, notificationHandler)
+			reminder.RegisterRoutes(r, reminderHandler)
+			webhook.RegisterRoutes(r, webhookHandler)
+			stream.RegisterRoutes(r, streamHandler)
+			collab.RegisterRoutes(r, collabHandler)
+			timeentry.RegisterRoutes(r, timeEntryHandler)
+			stats.RegisterRoutes(r, statsHandler)
+			report.RegisterRoutes(r, reportHandler)
+			sprint.RegisterRoutes(r, sprintHandler)
+			timeline.RegisterRoutes(r, timelineHandler)
+			milestone.RegisterRoutes(r, milestoneHandler)
+			transfer.RegisterRoutes(r, transferHandler)
+			importer.RegisterRoutes(r, importerHandler)
+			calendar.RegisterRoutes(r, calendarHandler)
+			inbound.RegisterRoutes(r, inboundHandler)
+			automation.RegisterRoutes(r, automationHandler)
+		})
+	})
+
+	// CalDAV clients sign in with an app password instead of a JWT.
+	r.Group(func(r chi.Router) {
+		r.Use(calendarHandler.BasicAuth)
+		r.Use(workspace.TenancyMiddleware(workspaceRepo))
+		calendar.RegisterDAVRoutes(r, calendarHandler)
 	})
 
 	log.Printf("Server is listening on port %s...\n", cfg.Port)
@@ -1,6 +1,19 @@
 package auth
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Caller identifies who is making a request and which workspace it acts in.
+type Caller struct {
+	UserID      int64
+	WorkspaceID int64
+	// RuleChain lists the automation rules, first to last, whose actions
+	// led to the call. It is empty for requests made by the user.
+	RuleChain []int64
+}
 
 func GetUserID(r *http.Request) int64 {
 	if id, ok := r.Context().Value("userID").(int64); ok {
@@ -8,3 +21,30 @@ func GetUserID(r *http.Request) int64 {
 	}
 	return 0
 }
+
+func GetWorkspaceID(r *http.Request) int64 {
+	if id, ok := r.Context().Value("workspaceID").(int64); ok {
+		return id
+	}
+	return 0
+}
+
+func GetCaller(r *http.Request) Caller {
+	return Caller{UserID: GetUserID(r), WorkspaceID: GetWorkspaceID(r)}
+}
+
+// WithCaller returns ctx carrying the caller, for middleware that
+// authenticates requests without a JWT.
+func WithCaller(ctx context.Context, caller Caller) context.Context {
+	ctx = context.WithValue(ctx, "userID", caller.UserID)
+	return context.WithValue(ctx, "workspaceID", caller.WorkspaceID)
+}
+
+// GetTokenExpiry returns when the request's access token expires, for
+// long-lived connections that must end with it.
+func GetTokenExpiry(r *http.Request) time.Time {
+	if t, ok := r.Context().Value("tokenExpiresAt").(time.Time); ok {
+		return t
+	}
+	return time.Time{}
+}
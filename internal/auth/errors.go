@@ -0,0 +1,11 @@
+package auth
+
+import "errors"
+
+var (
+	ErrUserNotFound      = errors.New("user not found")
+	ErrEmailTaken        = errors.New("email address is already in use")
+	ErrNoEmail           = errors.New("you have not set an email address")
+	ErrAlreadyVerified   = errors.New("email address is already verified")
+	ErrInvalidEmailToken = errors.New("link is invalid or has expired")
+)
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/sudarshanmg/gotask/pkg/response"
@@ -24,6 +25,10 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	user, err := h.service.Register(req)
+	if errors.Is(err, ErrEmailTaken) {
+		response.WriteError(w, http.StatusConflict, err.Error())
+		return
+	}
 	if err != nil {
 		response.WriteError(w, http.StatusBadRequest, err.Error())
 		return
@@ -110,3 +115,123 @@ func (h *Handler) LogoutAll(w http.ResponseWriter, r *http.Request) {
 		"message": "logged out successfully",
 	})
 }
+
+func (h *Handler) SwitchWorkspace(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var req SwitchWorkspaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	accessToken, refreshToken, err := h.service.SwitchWorkspace(GetUserID(r), req)
+	if err != nil {
+		response.WriteError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+	})
+}
+
+func (h *Handler) Me(w http.ResponseWriter, r *http.Request) {
+	user, err := h.service.Me(GetUserID(r))
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch user")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, user)
+}
+
+func (h *Handler) UpdateEmail(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var req UpdateEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	user, err := h.service.UpdateEmail(GetUserID(r), req)
+	if errors.Is(err, ErrEmailTaken) {
+		response.WriteError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, user)
+}
+
+func (h *Handler) SendVerification(w http.ResponseWriter, r *http.Request) {
+	err := h.service.SendVerification(GetUserID(r))
+	if errors.Is(err, ErrNoEmail) || errors.Is(err, ErrAlreadyVerified) {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to send verification email")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{"message": "verification email sent"})
+}
+
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var req VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := h.service.VerifyEmail(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{"message": "email address verified"})
+}
+
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := h.service.ForgotPassword(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "if that address belongs to a verified account, a reset link is on its way",
+	})
+}
+
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := h.service.ResetPassword(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{"message": "password has been reset"})
+}
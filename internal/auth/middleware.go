@@ -11,18 +11,37 @@ import (
 	"github.com/sudarshanmg/gotask/pkg/response"
 )
 
+// WebSocketProtocol is the subprotocol browsers use to send the access
+// token on a WebSocket handshake, where they cannot set headers:
+// new WebSocket(url, ["bearer", token]).
+const WebSocketProtocol = "bearer"
+
+// bearerToken returns the access token from the Authorization header, or
+// from the subprotocol list of a WebSocket handshake.
+func bearerToken(r *http.Request) string {
+	if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+		return strings.TrimPrefix(authHeader, "Bearer ")
+	}
+
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		protocols := strings.Split(r.Header.Get("Sec-WebSocket-Protocol"), ",")
+		if len(protocols) == 2 && strings.TrimSpace(protocols[0]) == WebSocketProtocol {
+			return strings.TrimSpace(protocols[1])
+		}
+	}
+	return ""
+}
+
 func AuthMiddleware(secret string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+			tokenStr := bearerToken(r)
+			if tokenStr == "" {
 				response.WriteError(w, http.StatusUnauthorized, "missing or invalid Authorization header")
 				return
 			}
 
-			tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-
-			claims := &jwt.RegisteredClaims{}
+			claims := &Claims{}
 			token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
 				if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 					return nil, fmt.Errorf("unexpected signing method")
@@ -42,6 +61,10 @@ func AuthMiddleware(secret string) func(http.Handler) http.Handler {
 			}
 
 			ctx := context.WithValue(r.Context(), "userID", userID)
+			ctx = context.WithValue(ctx, "workspaceID", claims.WorkspaceID)
+			if claims.ExpiresAt != nil {
+				ctx = context.WithValue(ctx, "tokenExpiresAt", claims.ExpiresAt.Time)
+			}
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -2,18 +2,30 @@ package auth
 
 import (
 	"time"
+
+	"github.com/golang-jwt/jwt/v5"
 )
 
+// Claims are carried in access tokens. WorkspaceID is the tenant every
+// request made with the token acts in.
+type Claims struct {
+	WorkspaceID int64 `json:"wid"`
+	jwt.RegisteredClaims
+}
+
 type User struct {
-	ID           int64     `json:"id"`
-	Username     string    `json:"username"`
-	PasswordHash string    `json:"-"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID            int64     `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email,omitempty"`
+	EmailVerified bool      `json:"email_verified"`
+	PasswordHash  string    `json:"-"`
+	CreatedAt     time.Time `json:"created_at"`
 }
 
 type RegisterRequest struct {
 	Username string `json:"username" validate:"required,min=3,max=32"`
 	Password string `json:"password" validate:"required,min=6,max=64"`
+	Email    string `json:"email,omitempty" validate:"omitempty,email,max=254"`
 }
 
 type LoginRequest struct {
@@ -22,14 +34,53 @@ type LoginRequest struct {
 }
 
 type RefreshToken struct {
-	ID        int64     `json:"id"`
-	UserID    int64     `json:"user_id"`
-	Token     string    `json:"token"`
-	ExpiresAt time.Time `json:"expires_at"`
-	CreatedAt time.Time `json:"created_at"`
-	Revoked   bool      `json:"revoked"`
+	ID          int64     `json:"id"`
+	UserID      int64     `json:"user_id"`
+	WorkspaceID int64     `json:"workspace_id"`
+	Token       string    `json:"token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	Revoked     bool      `json:"revoked"`
 }
 
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
 }
+
+type SwitchWorkspaceRequest struct {
+	WorkspaceID int64 `json:"workspace_id" validate:"required,gt=0"`
+}
+
+type EmailTokenPurpose string
+
+const (
+	PurposeVerifyEmail   EmailTokenPurpose = "verify_email"
+	PurposeResetPassword EmailTokenPurpose = "reset_password"
+)
+
+// EmailToken is a single-use token sent by email. Only its SHA-256 hash is
+// stored.
+type EmailToken struct {
+	UserID    int64
+	Purpose   EmailTokenPurpose
+	TokenHash string
+	Email     string
+	ExpiresAt time.Time
+}
+
+type UpdateEmailRequest struct {
+	Email string `json:"email" validate:"required,email,max=254"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=6,max=64"`
+}
@@ -2,16 +2,54 @@ package auth
 
 import (
 	"database/sql"
+	"errors"
 	"time"
+
+	"github.com/lib/pq"
+	"github.com/sudarshanmg/gotask/internal/mail"
 )
 
+const userColumns = `u.id, u.username, COALESCE(u.email, ''), u.email_verified_at IS NOT NULL, u.password_hash, u.created_at`
+
+func scanUser(row *sql.Row) (*User, error) {
+	user := &User{}
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.EmailVerified, &user.PasswordHash, &user.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// isEmailTaken reports whether err is a violation of the unique index on
+// users' email addresses.
+func isEmailTaken(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "users_email_key"
+}
+
+// AuthRepository stores identities and sessions. Users are global, but every
+// session and user lookup made on behalf of a request is tied to a
+// workspace; FindByUsername is only used by login, before a workspace is
+// known.
 type AuthRepository interface {
-	CreateUser(username string, passwordHash string) (int64, error)
+	CreateUser(username, passwordHash, email string) (int64, error)
+	FindByID(id int64) (*User, error)
 	FindByUsername(username string) (*User, error)
-	SaveRefreshToken(userID int64, token string, expires time.Time) error
+	FindByEmail(email string) (*User, error)
+	FindByUsernameInWorkspace(workspaceID int64, username string) (*User, error)
+	DefaultWorkspace(userID int64) (int64, error)
+	IsWorkspaceMember(workspaceID, userID int64) (bool, error)
+	SaveRefreshToken(userID, workspaceID int64, token string, expires time.Time) error
 	GetRefreshToken(token string) (*RefreshToken, error)
 	RevokeRefreshToken(token string) error
 	RevokeAllRefreshTokens(userID int64) error
+	SetEmail(userID int64, email string) error
+	SaveEmailToken(token EmailToken, msg mail.Message) error
+	VerifyEmail(tokenHash string) error
+	ResetPassword(tokenHash, passwordHash string) error
 }
 
 type PostgresAuthRepository struct {
@@ -22,42 +60,118 @@ func NewRepository(db *sql.DB) AuthRepository {
 	return &PostgresAuthRepository{DB: db}
 }
 
-func (r *PostgresAuthRepository) CreateUser(username string, passwordHash string) (int64, error) {
+// CreateUser inserts the user together with a personal workspace they own
+// and are the "admin" of. The email, if any, starts out unverified.
+func (r *PostgresAuthRepository) CreateUser(username, passwordHash, email string) (int64, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
 	var id int64
-	query := `INSERT INTO users (username, password_hash) VALUES ($1, $2) RETURNING id;`
-	err := r.DB.QueryRow(query, username, passwordHash).Scan(&id)
-	return id, err
+	query := `INSERT INTO users (username, password_hash, email) VALUES ($1, $2, NULLIF($3, '')) RETURNING id;`
+	if err := tx.QueryRow(query, username, passwordHash, email).Scan(&id); err != nil {
+		if isEmailTaken(err) {
+			return 0, ErrEmailTaken
+		}
+		return 0, err
+	}
+
+	var workspaceID int64
+	err = tx.QueryRow(`INSERT INTO workspaces (name, owner_id) VALUES ($1, $2) RETURNING id;`,
+		username+"'s workspace", id).Scan(&workspaceID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`INSERT INTO workspace_members (workspace_id, user_id, role) VALUES ($1, $2, 'owner');`,
+		workspaceID, id)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`INSERT INTO user_roles (workspace_id, user_id, role_id)
+            SELECT $1, $2, id FROM roles WHERE name = 'admin';`, workspaceID, id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+func (r *PostgresAuthRepository) FindByID(id int64) (*User, error) {
+	query := `SELECT ` + userColumns + ` FROM users u WHERE u.id = $1;`
+	return scanUser(r.DB.QueryRow(query, id))
 }
 
 func (r *PostgresAuthRepository) FindByUsername(username string) (*User, error) {
-	query := `SELECT id, username, password_hash, created_at FROM users WHERE username = $1`
+	query := `SELECT ` + userColumns + ` FROM users u WHERE u.username = $1;`
+	return scanUser(r.DB.QueryRow(query, username))
+}
 
-	user := &User{}
-	err := r.DB.QueryRow(query, username).Scan(
-		&user.ID, &user.Username, &user.PasswordHash, &user.CreatedAt,
-	)
-	if err == sql.ErrNoRows {
-		return nil, nil
+func (r *PostgresAuthRepository) FindByEmail(email string) (*User, error) {
+	query := `SELECT ` + userColumns + ` FROM users u WHERE lower(u.email) = lower($1);`
+	return scanUser(r.DB.QueryRow(query, email))
+}
+
+func (r *PostgresAuthRepository) FindByUsernameInWorkspace(workspaceID int64, username string) (*User, error) {
+	query := `SELECT ` + userColumns + `
+            FROM users u
+            JOIN workspace_members m ON m.user_id = u.id
+            WHERE m.workspace_id = $1 AND u.username = $2;`
+	return scanUser(r.DB.QueryRow(query, workspaceID, username))
+}
+
+// DefaultWorkspace returns the workspace the user used most recently, falling
+// back to the one they joined first.
+func (r *PostgresAuthRepository) DefaultWorkspace(userID int64) (int64, error) {
+	query := `SELECT workspace_id FROM workspace_members
+            WHERE user_id = $1
+            ORDER BY last_used_at DESC NULLS LAST, created_at
+            LIMIT 1;`
+
+	var id int64
+	err := r.DB.QueryRow(query, userID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
 	}
-	return user, nil
+	return id, err
+}
+
+// IsWorkspaceMember also records the workspace as the user's most recently
+// used one when they are a member.
+func (r *PostgresAuthRepository) IsWorkspaceMember(workspaceID, userID int64) (bool, error) {
+	query := `UPDATE workspace_members SET last_used_at = NOW()
+            WHERE workspace_id = $1 AND user_id = $2;`
+
+	res, err := r.DB.Exec(query, workspaceID, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
 }
 
-func (r *PostgresAuthRepository) SaveRefreshToken(userID int64, token string, expires time.Time) error {
-	query := `INSERT INTO refresh_tokens (user_id, token, expires_at) VALUES ($1, $2, $3);`
+func (r *PostgresAuthRepository) SaveRefreshToken(userID, workspaceID int64, token string, expires time.Time) error {
+	query := `INSERT INTO refresh_tokens (user_id, workspace_id, token, expires_at) VALUES ($1, $2, $3, $4);`
 
-	_, err := r.DB.Exec(query, userID, token, expires)
+	_, err := r.DB.Exec(query, userID, workspaceID, token, expires)
 	return err
 }
 
 func (r *PostgresAuthRepository) GetRefreshToken(token string) (*RefreshToken, error) {
-	query := `SELECT id, user_id, token, expires_at, created_at, revoked
+	query := `SELECT id, user_id, COALESCE(workspace_id, 0), token, expires_at, created_at, revoked
             FROM refresh_tokens
             WHERE token = $1;
             `
 
 	rt := &RefreshToken{}
 	err := r.DB.QueryRow(query, token).Scan(
-		&rt.ID, &rt.UserID, &rt.Token, &rt.ExpiresAt, &rt.CreatedAt, &rt.Revoked,
+		&rt.ID, &rt.UserID, &rt.WorkspaceID, &rt.Token, &rt.ExpiresAt, &rt.CreatedAt, &rt.Revoked,
 	)
 
 	if err == sql.ErrNoRows {
@@ -72,8 +186,135 @@ func (r *PostgresAuthRepository) RevokeRefreshToken(token string) error {
 	return err
 }
 
+// RevokeAllRefreshTokens signs the user out of every workspace, which is what
+// "log out everywhere" means to them.
 func (r *PostgresAuthRepository) RevokeAllRefreshTokens(userID int64) error {
 	query := `UPDATE refresh_tokens SET revoked = true WHERE user_id = $1;`
 	_, err := r.DB.Exec(query, userID)
 	return err
 }
+
+// SetEmail changes the user's email address and marks it unverified.
+// Outstanding verification links for the old address stop working.
+func (r *PostgresAuthRepository) SetEmail(userID int64, email string) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE users SET email = $2, email_verified_at = NULL WHERE id = $1;`, userID, email)
+	if isEmailTaken(err) {
+		return ErrEmailTaken
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE email_tokens SET used_at = NOW()
+            WHERE user_id = $1 AND purpose = 'verify_email' AND used_at IS NULL;`, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SaveEmailToken stores the token and queues the email carrying it in one
+// transaction.
+func (r *PostgresAuthRepository) SaveEmailToken(token EmailToken, msg mail.Message) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO email_tokens (user_id, purpose, token_hash, email, expires_at) VALUES ($1, $2, $3, $4, $5);`
+	_, err = tx.Exec(query, token.UserID, token.Purpose, token.TokenHash, token.Email, token.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	if err := mail.Enqueue(tx, msg, ""); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// useEmailToken marks a valid, unused token as used and returns it.
+func useEmailToken(tx *sql.Tx, purpose EmailTokenPurpose, tokenHash string) (*EmailToken, error) {
+	query := `UPDATE email_tokens SET used_at = NOW()
+            WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+            RETURNING user_id, email;`
+
+	token := &EmailToken{Purpose: purpose, TokenHash: tokenHash}
+	err := tx.QueryRow(query, tokenHash, purpose).Scan(&token.UserID, &token.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidEmailToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// VerifyEmail consumes a verification token. It fails if the user has
+// changed their address since the token was sent.
+func (r *PostgresAuthRepository) VerifyEmail(tokenHash string) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	token, err := useEmailToken(tx, PurposeVerifyEmail, tokenHash)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(`UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW())
+            WHERE id = $1 AND lower(email) = lower($2);`, token.UserID, token.Email)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrInvalidEmailToken
+	}
+
+	return tx.Commit()
+}
+
+// ResetPassword consumes a reset token, sets the new password and signs the
+// user out everywhere.
+func (r *PostgresAuthRepository) ResetPassword(tokenHash, passwordHash string) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	token, err := useEmailToken(tx, PurposeResetPassword, tokenHash)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE users SET password_hash = $2 WHERE id = $1;`, token.UserID, passwordHash); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE email_tokens SET used_at = NOW()
+            WHERE user_id = $1 AND purpose = 'reset_password' AND used_at IS NULL;`, token.UserID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE refresh_tokens SET revoked = true WHERE user_id = $1;`, token.UserID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -8,4 +8,16 @@ func RegisterRoutes(r chi.Router, h *Handler) {
 	r.Post("/auth/refresh", h.Refresh)
 	r.Post("/auth/logout", h.Logout)
 	r.Post("/auth/logout-all", h.LogoutAll)
+	r.Post("/auth/email/verify", h.VerifyEmail)
+	r.Post("/auth/password/forgot", h.ForgotPassword)
+	r.Post("/auth/password/reset", h.ResetPassword)
+}
+
+// RegisterProtectedRoutes registers the auth routes that need a valid access
+// token; mount it behind AuthMiddleware.
+func RegisterProtectedRoutes(r chi.Router, h *Handler) {
+	r.Post("/auth/switch-workspace", h.SwitchWorkspace)
+	r.Get("/auth/me", h.Me)
+	r.Put("/auth/email", h.UpdateEmail)
+	r.Post("/auth/email/verification", h.SendVerification)
 }
@@ -2,13 +2,18 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"log"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/sudarshanmg/gotask/internal/mail"
 	"github.com/sudarshanmg/gotask/pkg/validation"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -19,18 +24,34 @@ type AuthService interface {
 	Refresh(refreshToken string) (string, error)
 	Logout(refreshToken string) error
 	LogoutAll(userID int64) error
+	SwitchWorkspace(userID int64, req SwitchWorkspaceRequest) (string, string, error)
+	Me(userID int64) (*User, error)
+	UpdateEmail(userID int64, req UpdateEmailRequest) (*User, error)
+	SendVerification(userID int64) error
+	VerifyEmail(req VerifyEmailRequest) error
+	ForgotPassword(req ForgotPasswordRequest) error
+	ResetPassword(req ResetPasswordRequest) error
 }
 
+const (
+	verifyEmailTTL   = 24 * time.Hour
+	resetPasswordTTL = time.Hour
+)
+
 type authService struct {
 	repo      AuthRepository
 	jwtSecret string
+	appURL    string
 	validator *validator.Validate
 }
 
-func NewService(repo AuthRepository, jwtSecret string) AuthService {
+// NewService creates the auth service. appURL is the base of the links put
+// in verification and password reset emails.
+func NewService(repo AuthRepository, jwtSecret, appURL string) AuthService {
 	return &authService{
 		repo:      repo,
 		jwtSecret: jwtSecret,
+		appURL:    strings.TrimRight(appURL, "/"),
 		validator: validator.New(),
 	}
 }
@@ -41,6 +62,11 @@ func generateSecureToken() string {
 	return hex.EncodeToString(bytes)
 }
 
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 func (s *authService) Register(req RegisterRequest) (*User, error) {
 	if err := s.validator.Struct(req); err != nil {
 		return nil, validation.FormatValidationError(err)
@@ -51,15 +77,25 @@ func (s *authService) Register(req RegisterRequest) (*User, error) {
 		return nil, err
 	}
 
-	id, err := s.repo.CreateUser(req.Username, string(hash))
+	id, err := s.repo.CreateUser(req.Username, string(hash), req.Email)
 	if err != nil {
 		return nil, err
 	}
 
-	return &User{
+	user := &User{
 		ID:       id,
 		Username: req.Username,
-	}, nil
+		Email:    req.Email,
+	}
+
+	// The account exists either way; the user can ask for another link.
+	if user.Email != "" {
+		if err := s.sendEmailToken(user, PurposeVerifyEmail); err != nil {
+			log.Printf("failed to send verification email to user %d: %v", id, err)
+		}
+	}
+
+	return user, nil
 }
 
 func (s *authService) Login(req LoginRequest) (string, string, error) {
@@ -77,14 +113,34 @@ func (s *authService) Login(req LoginRequest) (string, string, error) {
 		return "", "", errors.New("invalid credentials")
 	}
 
-	claims := jwt.RegisteredClaims{
-		Subject:   strconv.FormatInt(user.ID, 10),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(15 * time.Minute)),
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
+	workspaceID, err := s.repo.DefaultWorkspace(user.ID)
+	if err != nil {
+		return "", "", err
+	}
+	if workspaceID == 0 {
+		return "", "", errors.New("user does not belong to any workspace")
+	}
+
+	return s.issueTokens(user.ID, workspaceID)
+}
+
+func (s *authService) signAccessToken(userID, workspaceID int64) (string, error) {
+	claims := Claims{
+		WorkspaceID: workspaceID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatInt(userID, 10),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(15 * time.Minute)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	accessToken, err := token.SignedString([]byte(s.jwtSecret))
+	return token.SignedString([]byte(s.jwtSecret))
+}
+
+// issueTokens creates an access and refresh token pair bound to the workspace.
+func (s *authService) issueTokens(userID, workspaceID int64) (string, string, error) {
+	accessToken, err := s.signAccessToken(userID, workspaceID)
 	if err != nil {
 		return "", "", err
 	}
@@ -92,7 +148,7 @@ func (s *authService) Login(req LoginRequest) (string, string, error) {
 	refreshToken := generateSecureToken()
 	refreshExpiry := time.Now().Add(7 * 24 * time.Hour)
 
-	err = s.repo.SaveRefreshToken(user.ID, refreshToken, refreshExpiry)
+	err = s.repo.SaveRefreshToken(userID, workspaceID, refreshToken, refreshExpiry)
 	if err != nil {
 		return "", "", err
 	}
@@ -109,15 +165,18 @@ func (s *authService) Refresh(refreshToken string) (string, error) {
 		return "", errors.New("refresh token expired or revoked")
 	}
 
-	// Create new access token
-	claims := jwt.RegisteredClaims{
-		Subject:   strconv.FormatInt(rt.UserID, 10),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(15 * time.Minute)),
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
+	// The user may have been removed from the workspace since the token was
+	// issued.
+	isMember, err := s.repo.IsWorkspaceMember(rt.WorkspaceID, rt.UserID)
+	if err != nil {
+		return "", err
+	}
+	if !isMember {
+		return "", errors.New("refresh token is no longer valid for this workspace")
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, err := token.SignedString([]byte(s.jwtSecret))
+	// Create new access token
+	signed, err := s.signAccessToken(rt.UserID, rt.WorkspaceID)
 	if err != nil {
 		return "", err
 	}
@@ -132,3 +191,138 @@ func (s *authService) Logout(refreshToken string) error {
 func (s *authService) LogoutAll(userID int64) error {
 	return s.repo.RevokeAllRefreshTokens(userID)
 }
+
+func (s *authService) SwitchWorkspace(userID int64, req SwitchWorkspaceRequest) (string, string, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return "", "", validation.FormatValidationError(err)
+	}
+
+	isMember, err := s.repo.IsWorkspaceMember(req.WorkspaceID, userID)
+	if err != nil {
+		return "", "", err
+	}
+	if !isMember {
+		return "", "", errors.New("you are not a member of this workspace")
+	}
+
+	return s.issueTokens(userID, req.WorkspaceID)
+}
+
+func (s *authService) Me(userID int64) (*User, error) {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// sendEmailToken creates a single-use token for purpose and queues the email
+// that carries it to the user's current address.
+func (s *authService) sendEmailToken(user *User, purpose EmailTokenPurpose) error {
+	token := generateSecureToken()
+
+	template, path, ttl, expiresIn := "verify_email", "/verify-email", verifyEmailTTL, "24 hours"
+	if purpose == PurposeResetPassword {
+		template, path, ttl, expiresIn = "password_reset", "/reset-password", resetPasswordTTL, "1 hour"
+	}
+
+	msg, err := mail.Render(template, map[string]string{
+		"Username":  user.Username,
+		"Email":     user.Email,
+		"URL":       s.appURL + path + "?token=" + url.QueryEscape(token),
+		"ExpiresIn": expiresIn,
+	})
+	if err != nil {
+		return err
+	}
+	msg.To = user.Email
+
+	return s.repo.SaveEmailToken(EmailToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(ttl),
+	}, *msg)
+}
+
+// UpdateEmail sets a new, unverified address and sends a verification link
+// to it.
+func (s *authService) UpdateEmail(userID int64, req UpdateEmailRequest) (*User, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+
+	user, err := s.Me(userID)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(user.Email, req.Email) && user.EmailVerified {
+		return user, nil
+	}
+
+	if err := s.repo.SetEmail(userID, req.Email); err != nil {
+		return nil, err
+	}
+	user.Email = req.Email
+	user.EmailVerified = false
+
+	if err := s.sendEmailToken(user, PurposeVerifyEmail); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *authService) SendVerification(userID int64) error {
+	user, err := s.Me(userID)
+	if err != nil {
+		return err
+	}
+	if user.Email == "" {
+		return ErrNoEmail
+	}
+	if user.EmailVerified {
+		return ErrAlreadyVerified
+	}
+	return s.sendEmailToken(user, PurposeVerifyEmail)
+}
+
+func (s *authService) VerifyEmail(req VerifyEmailRequest) error {
+	if err := s.validator.Struct(req); err != nil {
+		return validation.FormatValidationError(err)
+	}
+	return s.repo.VerifyEmail(hashToken(req.Token))
+}
+
+// ForgotPassword mails a reset link if the address belongs to a user who
+// has verified it. It reports success either way so that it cannot be used
+// to find out which addresses have accounts.
+func (s *authService) ForgotPassword(req ForgotPasswordRequest) error {
+	if err := s.validator.Struct(req); err != nil {
+		return validation.FormatValidationError(err)
+	}
+
+	user, err := s.repo.FindByEmail(req.Email)
+	if err != nil {
+		return err
+	}
+	if user == nil || !user.EmailVerified {
+		return nil
+	}
+	return s.sendEmailToken(user, PurposeResetPassword)
+}
+
+func (s *authService) ResetPassword(req ResetPasswordRequest) error {
+	if err := s.validator.Struct(req); err != nil {
+		return validation.FormatValidationError(err)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return s.repo.ResetPassword(hashToken(req.Token), string(hash))
+}
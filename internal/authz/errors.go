@@ -0,0 +1,10 @@
+package authz
+
+import "errors"
+
+var (
+	ErrInvalidResource = errors.New("invalid resource, expected type or type:id")
+	ErrUnknownRole     = errors.New("unknown role")
+	ErrForbidden       = errors.New("forbidden")
+	ErrUserNotFound    = errors.New("user not found")
+)
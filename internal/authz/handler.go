@@ -0,0 +1,80 @@
+package authz
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/pkg/response"
+)
+
+type Handler struct {
+	service AuthzService
+}
+
+func NewHandler(service AuthzService) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) GetMyPermissions(w http.ResponseWriter, r *http.Request) {
+	perms, err := h.service.Permissions(auth.GetCaller(r), r.URL.Query().Get("resource"))
+	if errors.Is(err, ErrInvalidResource) {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch permissions")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, perms)
+}
+
+func (h *Handler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.service.ListRoles()
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch roles")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, roles)
+}
+
+func (h *Handler) SetUserRoles(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	var req SetRolesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	err = h.service.SetUserRoles(auth.GetCaller(r), userID, req)
+	if errors.Is(err, ErrForbidden) {
+		response.WriteError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	if errors.Is(err, ErrUserNotFound) {
+		response.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if errors.Is(err, ErrUnknownRole) || (err != nil && strings.HasPrefix(err.Error(), "validation failed:")) {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to update roles")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{"message": "roles updated successfully"})
+}
@@ -0,0 +1,37 @@
+package authz
+
+import (
+	"net/http"
+
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/pkg/response"
+)
+
+// Authorize checks the request's user against the policy. When access is
+// denied it writes the error response and returns false, so handlers can
+// simply return.
+func Authorize(w http.ResponseWriter, r *http.Request, policy Policy, action Action, resource Resource) bool {
+	ok, err := policy.Can(subjectOf(auth.GetCaller(r)), action, resource)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to check permissions")
+		return false
+	}
+	if !ok {
+		response.WriteError(w, http.StatusForbidden, ErrForbidden.Error())
+		return false
+	}
+	return true
+}
+
+// Require rejects requests whose user lacks the action on the resource type.
+// It must run after auth.AuthMiddleware.
+func Require(policy Policy, action Action, resourceType string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !Authorize(w, r, policy, action, Resource{Type: resourceType}) {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
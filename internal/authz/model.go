@@ -0,0 +1,86 @@
+package authz
+
+import (
+	"strconv"
+	"strings"
+)
+
+type Action string
+
+const (
+	ActionRead   Action = "read"
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+	ActionManage Action = "manage"
+)
+
+// AllActions is the order in which actions are reported to clients.
+var AllActions = []Action{ActionRead, ActionCreate, ActionUpdate, ActionDelete, ActionManage}
+
+const (
+	ResourceTask    = "task"
+	ResourceProject = "project"
+	ResourceRole    = "role"
+)
+
+// Subject is the party asking for access and the workspace it acts in.
+type Subject struct {
+	UserID      int64
+	WorkspaceID int64
+}
+
+// Resource identifies what is being accessed. An ID of zero refers to the
+// resource type as a whole, e.g. "may this user create tasks at all".
+type Resource struct {
+	Type string
+	ID   int64
+}
+
+func (r Resource) String() string {
+	if r.ID == 0 {
+		return r.Type
+	}
+	return r.Type + ":" + strconv.FormatInt(r.ID, 10)
+}
+
+// ParseResource accepts "task" or "task:42".
+func ParseResource(s string) (Resource, error) {
+	typ, idStr, hasID := strings.Cut(s, ":")
+	if typ == "" {
+		return Resource{}, ErrInvalidResource
+	}
+	if !hasID {
+		return Resource{Type: typ}, nil
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		return Resource{}, ErrInvalidResource
+	}
+	return Resource{Type: typ, ID: id}, nil
+}
+
+type Permission struct {
+	ResourceType string `json:"resource_type"`
+	Action       string `json:"action"`
+}
+
+func (p Permission) matches(resourceType string, action Action) bool {
+	return (p.ResourceType == "*" || p.ResourceType == resourceType) &&
+		(p.Action == "*" || p.Action == string(action))
+}
+
+type Role struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type SetRolesRequest struct {
+	Roles []string `json:"roles" validate:"required,min=1,dive,required"`
+}
+
+type PermissionsResponse struct {
+	Resource string   `json:"resource"`
+	Actions  []Action `json:"actions"`
+}
@@ -0,0 +1,86 @@
+package authz
+
+// Policy answers whether a subject may perform an action on a resource.
+// Handlers consult it before calling into their services.
+type Policy interface {
+	Can(subject Subject, action Action, resource Resource) (bool, error)
+	Permissions(subject Subject, resource Resource) ([]Action, error)
+}
+
+// ScopeFunc returns the actions a user may take on a single resource
+// instance, e.g. derived from project membership. It returns no actions when
+// the instance does not exist or is not visible to the user.
+type ScopeFunc func(subject Subject, id int64) ([]Action, error)
+
+type rolePolicy struct {
+	repo   AuthzRepository
+	scopes map[string]ScopeFunc
+}
+
+// NewPolicy builds a policy from the roles stored in the database. Requests
+// for a specific resource instance are further narrowed by the scope
+// registered for its type; types without a scope use role permissions only.
+func NewPolicy(repo AuthzRepository, scopes map[string]ScopeFunc) Policy {
+	if scopes == nil {
+		scopes = map[string]ScopeFunc{}
+	}
+	return &rolePolicy{repo: repo, scopes: scopes}
+}
+
+func (p *rolePolicy) Can(subject Subject, action Action, resource Resource) (bool, error) {
+	actions, err := p.Permissions(subject, resource)
+	if err != nil {
+		return false, err
+	}
+	for _, a := range actions {
+		if a == action {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (p *rolePolicy) Permissions(subject Subject, resource Resource) ([]Action, error) {
+	if subject.UserID == 0 {
+		return []Action{}, nil
+	}
+
+	perms, err := p.repo.PermissionsForUser(subject.WorkspaceID, subject.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := map[Action]bool{}
+	for _, action := range AllActions {
+		for _, perm := range perms {
+			if perm.matches(resource.Type, action) {
+				allowed[action] = true
+				break
+			}
+		}
+	}
+
+	if scope, ok := p.scopes[resource.Type]; ok && resource.ID != 0 {
+		scoped, err := scope(subject, resource.ID)
+		if err != nil {
+			return nil, err
+		}
+		inScope := map[Action]bool{}
+		for _, a := range scoped {
+			inScope[a] = true
+		}
+		for a := range allowed {
+			if !inScope[a] {
+				delete(allowed, a)
+			}
+		}
+	}
+
+	actions := []Action{}
+	for _, a := range AllActions {
+		if allowed[a] {
+			actions = append(actions, a)
+		}
+	}
+	return actions, nil
+}
@@ -0,0 +1,121 @@
+package authz
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// AuthzRepository reads and writes roles, which users hold per workspace.
+type AuthzRepository interface {
+	PermissionsForUser(workspaceID, userID int64) ([]Permission, error)
+	ListRoles() ([]Role, error)
+	SetUserRoles(workspaceID, userID int64, roles []string) error
+}
+
+type PostgresAuthzRepository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) AuthzRepository {
+	return &PostgresAuthzRepository{DB: db}
+}
+
+func (r *PostgresAuthzRepository) PermissionsForUser(workspaceID, userID int64) ([]Permission, error) {
+	query := `
+          SELECT DISTINCT p.resource_type, p.action
+          FROM user_roles ur
+          JOIN role_permissions p ON p.role_id = ur.role_id
+          WHERE ur.workspace_id = $1 AND ur.user_id = $2;`
+
+	rows, err := r.DB.Query(query, workspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	perms := []Permission{}
+	for rows.Next() {
+		p := Permission{}
+		if err := rows.Scan(&p.ResourceType, &p.Action); err != nil {
+			return nil, err
+		}
+		perms = append(perms, p)
+	}
+	return perms, rows.Err()
+}
+
+func (r *PostgresAuthzRepository) ListRoles() ([]Role, error) {
+	rows, err := r.DB.Query(`SELECT id, name, description FROM roles ORDER BY id;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []Role{}
+	for rows.Next() {
+		role := Role{}
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+// SetUserRoles replaces the user's roles in the workspace. It fails with
+// ErrUserNotFound if the user is not a member of the workspace, and unknown
+// role names abort the change with ErrUnknownRole.
+func (r *PostgresAuthzRepository) SetUserRoles(workspaceID, userID int64, roles []string) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The member row stays locked so the user cannot leave the workspace
+	// while their roles in it are being written.
+	var member int
+	err = tx.QueryRow(`SELECT 1 FROM workspace_members WHERE workspace_id = $1 AND user_id = $2 FOR SHARE;`,
+		workspaceID, userID).Scan(&member)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM user_roles WHERE workspace_id = $1 AND user_id = $2;`, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(`INSERT INTO user_roles (workspace_id, user_id, role_id)
+            SELECT $1, $2, id FROM roles WHERE name = ANY($3);`, workspaceID, userID, pq.Array(roles))
+	if err != nil {
+		return err
+	}
+
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if int(inserted) != len(uniqueStrings(roles)) {
+		return ErrUnknownRole
+	}
+
+	return tx.Commit()
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,9 @@
+package authz
+
+import "github.com/go-chi/chi/v5"
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Get("/me/permissions", h.GetMyPermissions)
+	r.Get("/roles", h.ListRoles)
+	r.Put("/users/{id}/roles", h.SetUserRoles)
+}
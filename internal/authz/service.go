@@ -0,0 +1,62 @@
+package authz
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/pkg/validation"
+)
+
+var validate = validator.New()
+
+type AuthzService interface {
+	Permissions(caller auth.Caller, resource string) (*PermissionsResponse, error)
+	ListRoles() ([]Role, error)
+	SetUserRoles(caller auth.Caller, userID int64, req SetRolesRequest) error
+}
+
+type authzService struct {
+	repo   AuthzRepository
+	policy Policy
+}
+
+func NewService(repo AuthzRepository, policy Policy) AuthzService {
+	return &authzService{repo: repo, policy: policy}
+}
+
+func subjectOf(caller auth.Caller) Subject {
+	return Subject{UserID: caller.UserID, WorkspaceID: caller.WorkspaceID}
+}
+
+func (s *authzService) Permissions(caller auth.Caller, resource string) (*PermissionsResponse, error) {
+	res, err := ParseResource(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	actions, err := s.policy.Permissions(subjectOf(caller), res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PermissionsResponse{Resource: res.String(), Actions: actions}, nil
+}
+
+func (s *authzService) ListRoles() ([]Role, error) {
+	return s.repo.ListRoles()
+}
+
+func (s *authzService) SetUserRoles(caller auth.Caller, userID int64, req SetRolesRequest) error {
+	ok, err := s.policy.Can(subjectOf(caller), ActionManage, Resource{Type: ResourceRole})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrForbidden
+	}
+
+	if err := validate.Struct(req); err != nil {
+		return validation.FormatValidationError(err)
+	}
+
+	return s.repo.SetUserRoles(caller.WorkspaceID, userID, req.Roles)
+}
@@ -0,0 +1,300 @@
+package automation
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sudarshanmg/gotask/internal/task"
+)
+
+// validateAction checks what the action needs for its type; permissions
+// are checked separately.
+func validateAction(a Action) error {
+	var missing string
+	switch a.Type {
+	case ActionSetField:
+		if a.Field == "" {
+			missing = "field"
+		} else if _, err := fieldUpdate(a.Field, a.Value, time.Now()); err != nil {
+			return err
+		}
+	case ActionAddLabel:
+		if strings.TrimSpace(a.Label) == "" {
+			missing = "label"
+		}
+	case ActionAssign:
+		if a.UserID == 0 {
+			missing = "user_id"
+		}
+	case ActionCreateSubtask:
+		if strings.TrimSpace(a.Title) == "" {
+			missing = "title"
+		}
+	case ActionSendWebhook:
+		if a.URL == "" {
+			missing = "url"
+		} else if validate.Var(a.URL, "http_url,max=2000") != nil {
+			return fmt.Errorf("%w: url must be an http or https URL", ErrInvalidAction)
+		}
+	}
+	if missing != "" {
+		return fmt.Errorf("%w: %s needs %s", ErrInvalidAction, a.Type, missing)
+	}
+	return nil
+}
+
+// fieldUpdate turns a set_field action into a task update. Times may be
+// given as now+3d, like in conditions; they are resolved against now.
+func fieldUpdate(field string, value json.RawMessage, now time.Time) (task.UpdateTaskRequest, error) {
+	var req task.UpdateTaskRequest
+	if len(value) == 0 {
+		return req, fmt.Errorf("%w: set_field needs value", ErrInvalidAction)
+	}
+	null := string(value) == "null"
+	invalid := func(want string) error {
+		return fmt.Errorf("%w: %s must be %s", ErrInvalidAction, field, want)
+	}
+
+	switch field {
+	case "title":
+		var title string
+		if json.Unmarshal(value, &title) != nil || strings.TrimSpace(title) == "" ||
+			utf8.RuneCountInString(title) > 100 {
+			return req, invalid("a string of 1 to 100 characters")
+		}
+		req.Title = &title
+	case "description":
+		var description string
+		if json.Unmarshal(value, &description) != nil || utf8.RuneCountInString(description) > 500 {
+			return req, invalid("a string of up to 500 characters")
+		}
+		req.Description = &description
+	case "completed":
+		var completed bool
+		if null || json.Unmarshal(value, &completed) != nil {
+			return req, invalid("true or false")
+		}
+		req.Completed = &completed
+	case "status_id":
+		var id int64
+		if null || json.Unmarshal(value, &id) != nil || id <= 0 {
+			return req, invalid("a status ID")
+		}
+		req.StatusID = &id
+	case "sprint_id", "milestone_id":
+		var id int64
+		if !null && (json.Unmarshal(value, &id) != nil || id <= 0) {
+			return req, invalid("an ID or null")
+		}
+		switch {
+		case field == "sprint_id" && null:
+			req.ClearSprint = true
+		case field == "sprint_id":
+			req.SprintID = &id
+		case null:
+			req.ClearMilestone = true
+		default:
+			req.MilestoneID = &id
+		}
+	case "priority":
+		var priority int
+		if null {
+			req.ClearPriority = true
+		} else if json.Unmarshal(value, &priority) != nil || priority < 1 || priority > 4 {
+			return req, invalid("1 to 4 or null")
+		} else {
+			req.Priority = &priority
+		}
+	case "story_points":
+		var points string
+		if null {
+			req.ClearStoryPoints = true
+		} else if json.Unmarshal(value, &points) != nil || points == "" || len(points) > 5 {
+			return req, invalid("a point value or null")
+		} else {
+			req.StoryPoints = &points
+		}
+	case "estimate_minutes":
+		var minutes int
+		if null {
+			req.ClearEstimate = true
+		} else if json.Unmarshal(value, &minutes) != nil || minutes <= 0 || minutes > 60000 {
+			return req, invalid("1 to 60000 minutes or null")
+		} else {
+			req.EstimateMinutes = &minutes
+		}
+	case "due_at", "start_at":
+		var at *time.Time
+		if !null {
+			var s string
+			if json.Unmarshal(value, &s) != nil {
+				return req, invalid("a date, now+3d or null")
+			}
+			if offset, ok := parseOffset(strings.ToLower(s)); ok {
+				t := now.Add(offset)
+				at = &t
+			} else if t, ok := parseTime(s); ok {
+				at = &t
+			} else {
+				return req, invalid("a date, now+3d or null")
+			}
+		}
+		switch {
+		case field == "due_at" && at == nil:
+			req.ClearDueAt = true
+		case field == "due_at":
+			req.DueAt = at
+		case at == nil:
+			req.ClearStartAt = true
+		default:
+			req.StartAt = at
+		}
+	default:
+		return req, fmt.Errorf("%w: %q cannot be set", ErrInvalidAction, field)
+	}
+	return req, nil
+}
+
+// applyUpdate makes the changes of a set_field update to a copy of a task,
+// for dry runs.
+func applyUpdate(t *task.TaskResponse, req task.UpdateTaskRequest, now time.Time) {
+	if req.Title != nil {
+		t.Title = *req.Title
+	}
+	if req.Description != nil {
+		t.Description = *req.Description
+	}
+	if req.Completed != nil && *req.Completed != t.Completed {
+		t.Completed = *req.Completed
+		t.CompletedAt = nil
+		if t.Completed {
+			t.CompletedAt = &now
+		}
+	}
+	if req.StatusID != nil {
+		t.StatusID = req.StatusID
+	}
+	if req.SprintID != nil || req.ClearSprint {
+		t.SprintID = req.SprintID
+	}
+	if req.MilestoneID != nil || req.ClearMilestone {
+		t.MilestoneID = req.MilestoneID
+	}
+	if req.Priority != nil || req.ClearPriority {
+		t.Priority = req.Priority
+	}
+	if req.StoryPoints != nil || req.ClearStoryPoints {
+		t.StoryPoints = req.StoryPoints
+	}
+	if req.EstimateMinutes != nil || req.ClearEstimate {
+		t.EstimateMinutes = req.EstimateMinutes
+	}
+	if req.DueAt != nil || req.ClearDueAt {
+		t.DueAt = req.DueAt
+	}
+	if req.StartAt != nil || req.ClearStartAt {
+		t.StartAt = req.StartAt
+	}
+}
+
+// expand fills in {{title}} and {{id}}.
+func expand(template string, t *task.TaskResponse) string {
+	return strings.NewReplacer("{{title}}", t.Title, "{{id}}", strconv.FormatInt(t.ID, 10)).Replace(template)
+}
+
+// subtaskTitle expands the action's title, cut to the length tasks allow.
+func subtaskTitle(a Action, t *task.TaskResponse) string {
+	title := []rune(strings.TrimSpace(expand(a.Title, t)))
+	if len(title) > 100 {
+		title = title[:100]
+	}
+	return string(title)
+}
+
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.TrimSpace(label))
+}
+
+// recipients is who a notify action tells about the task.
+func recipients(a Action, t *task.TaskResponse) []int64 {
+	switch {
+	case a.UserID != 0:
+		return []int64{a.UserID}
+	case len(t.AssigneeIDs) > 0:
+		return t.AssigneeIDs
+	default:
+		return []int64{t.CreatedBy}
+	}
+}
+
+func notifyMessage(rule *Rule, a Action, t *task.TaskResponse) string {
+	if a.Message != "" {
+		return expand(a.Message, t)
+	}
+	return fmt.Sprintf("Automation %q ran on %q", rule.Name, t.Title)
+}
+
+// retriggers reports whether the action makes a change that the rule's
+// own trigger would fire on.
+func retriggers(rule *Rule, a Action) bool {
+	switch rule.Trigger.Type {
+	case TriggerStatusChanged:
+		return a.Type == ActionSetField && (a.Field == "status_id" || a.Field == "completed")
+	case TriggerLabelAdded:
+		return a.Type == ActionAddLabel &&
+			(rule.Trigger.Label == "" || normalizeLabel(rule.Trigger.Label) == normalizeLabel(a.Label))
+	case TriggerCreated:
+		return a.Type == ActionCreateSubtask
+	}
+	return false
+}
+
+// simulate works out what the action would do to t, changing t as the
+// action would.
+func simulate(rule *Rule, a Action, t *task.TaskResponse, now time.Time) (string, error) {
+	switch a.Type {
+	case ActionSetField:
+		req, err := fieldUpdate(a.Field, a.Value, now)
+		if err != nil {
+			return "", err
+		}
+		applyUpdate(t, req, now)
+		return fmt.Sprintf("would set %s to %s", a.Field, a.Value), nil
+	case ActionAddLabel:
+		label := normalizeLabel(a.Label)
+		if slices.Contains(t.Labels, label) {
+			return fmt.Sprintf("task already has label %q", label), nil
+		}
+		t.Labels = append(slices.Clone(t.Labels), label)
+		return fmt.Sprintf("would add label %q", label), nil
+	case ActionAssign:
+		if slices.Contains(t.AssigneeIDs, a.UserID) {
+			return fmt.Sprintf("user %d is already assigned", a.UserID), nil
+		}
+		t.AssigneeIDs = append(slices.Clone(t.AssigneeIDs), a.UserID)
+		return fmt.Sprintf("would assign user %d", a.UserID), nil
+	case ActionCreateSubtask:
+		return fmt.Sprintf("would create task %q blocking this one", subtaskTitle(a, t)), nil
+	case ActionSendWebhook:
+		return fmt.Sprintf("would post the task to %s", a.URL), nil
+	default:
+		return fmt.Sprintf("would notify %s: %q", users(recipients(a, t)), notifyMessage(rule, a, t)), nil
+	}
+}
+
+// users lists user IDs for results, e.g. "users 3, 4".
+func users(ids []int64) string {
+	list := make([]string, len(ids))
+	for i, id := range ids {
+		list[i] = strconv.FormatInt(id, 10)
+	}
+	if len(ids) == 1 {
+		return "user " + list[0]
+	}
+	return "users " + strings.Join(list, ", ")
+}
@@ -0,0 +1,588 @@
+package automation
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/sudarshanmg/gotask/internal/task"
+)
+
+// A condition is a filter over the task's fields, named as in task
+// responses, for example
+//
+//	priority <= 2 and labels contains "bug" and not completed
+//	due_at < now+2d or (status_id in (4, 5) and assignee_ids = null)
+//
+// Comparisons are =, !=, <, <=, >, >=, contains and in. Strings compare
+// without regard to case, contains is a substring test on strings and a
+// membership test on lists, and null stands for an unset field or an
+// empty list. Times compare with now, now+3d (units m, h, d and w) or an
+// RFC 3339 date. A field on its own is true when it is set, non-empty, or,
+// for completed, true.
+
+type kind int
+
+const (
+	kindNumber kind = iota
+	kindString
+	kindBool
+	kindTime
+	kindNumbers
+	kindStrings
+)
+
+// field reads a task field. ok is false when the field is unset.
+type field struct {
+	kind kind
+	get  func(t *task.TaskResponse) (v any, ok bool)
+}
+
+func number[T int | int64](p *T) (any, bool) {
+	if p == nil {
+		return nil, false
+	}
+	return float64(*p), true
+}
+
+func moment(p *time.Time) (any, bool) {
+	if p == nil {
+		return nil, false
+	}
+	return *p, true
+}
+
+func numbers(ids []int64) (any, bool) {
+	values := make([]float64, len(ids))
+	for i, id := range ids {
+		values[i] = float64(id)
+	}
+	return values, len(values) > 0
+}
+
+var fields = map[string]field{
+	"id":               {kindNumber, func(t *task.TaskResponse) (any, bool) { return number(&t.ID) }},
+	"title":            {kindString, func(t *task.TaskResponse) (any, bool) { return t.Title, t.Title != "" }},
+	"description":      {kindString, func(t *task.TaskResponse) (any, bool) { return t.Description, t.Description != "" }},
+	"completed":        {kindBool, func(t *task.TaskResponse) (any, bool) { return t.Completed, true }},
+	"project_id":       {kindNumber, func(t *task.TaskResponse) (any, bool) { return number(t.ProjectID) }},
+	"status_id":        {kindNumber, func(t *task.TaskResponse) (any, bool) { return number(t.StatusID) }},
+	"sprint_id":        {kindNumber, func(t *task.TaskResponse) (any, bool) { return number(t.SprintID) }},
+	"milestone_id":     {kindNumber, func(t *task.TaskResponse) (any, bool) { return number(t.MilestoneID) }},
+	"priority":         {kindNumber, func(t *task.TaskResponse) (any, bool) { return number(t.Priority) }},
+	"created_by":       {kindNumber, func(t *task.TaskResponse) (any, bool) { return number(&t.CreatedBy) }},
+	"estimate_minutes": {kindNumber, func(t *task.TaskResponse) (any, bool) { return number(t.EstimateMinutes) }},
+	"tracked_minutes":  {kindNumber, func(t *task.TaskResponse) (any, bool) { return number(&t.TrackedMinutes) }},
+	"story_points": {kindString, func(t *task.TaskResponse) (any, bool) {
+		if t.StoryPoints == nil {
+			return nil, false
+		}
+		return *t.StoryPoints, true
+	}},
+	"start_at":     {kindTime, func(t *task.TaskResponse) (any, bool) { return moment(t.StartAt) }},
+	"due_at":       {kindTime, func(t *task.TaskResponse) (any, bool) { return moment(t.DueAt) }},
+	"completed_at": {kindTime, func(t *task.TaskResponse) (any, bool) { return moment(t.CompletedAt) }},
+	"created_at":   {kindTime, func(t *task.TaskResponse) (any, bool) { return moment(&t.CreatedAt) }},
+	"updated_at":   {kindTime, func(t *task.TaskResponse) (any, bool) { return moment(&t.UpdatedAt) }},
+	"assignee_ids": {kindNumbers, func(t *task.TaskResponse) (any, bool) { return numbers(t.AssigneeIDs) }},
+	"blocked_by":   {kindNumbers, func(t *task.TaskResponse) (any, bool) { return numbers(t.BlockedBy) }},
+	"labels":       {kindStrings, func(t *task.TaskResponse) (any, bool) { return t.Labels, len(t.Labels) > 0 }},
+}
+
+// Condition is a parsed condition. The zero Condition matches every task.
+type Condition struct {
+	root node
+}
+
+// Match reports whether the task satisfies the condition; now is what now
+// in the condition stands for.
+func (c *Condition) Match(t *task.TaskResponse, now time.Time) bool {
+	if c.root == nil {
+		return true
+	}
+	return c.root.eval(t, now)
+}
+
+type node interface {
+	eval(t *task.TaskResponse, now time.Time) bool
+}
+
+type andNode struct{ left, right node }
+type orNode struct{ left, right node }
+type notNode struct{ operand node }
+
+// setNode is a field on its own.
+type setNode struct{ field field }
+
+// compareNode compares a field with one value, or with several for in.
+// Values are float64, string, bool, time.Time, offsets from now as
+// time.Duration, or nil for null.
+type compareNode struct {
+	field  field
+	op     string
+	values []any
+}
+
+func (n andNode) eval(t *task.TaskResponse, now time.Time) bool {
+	return n.left.eval(t, now) && n.right.eval(t, now)
+}
+
+func (n orNode) eval(t *task.TaskResponse, now time.Time) bool {
+	return n.left.eval(t, now) || n.right.eval(t, now)
+}
+
+func (n notNode) eval(t *task.TaskResponse, now time.Time) bool {
+	return !n.operand.eval(t, now)
+}
+
+func (n setNode) eval(t *task.TaskResponse, now time.Time) bool {
+	v, ok := n.field.get(t)
+	if b, isBool := v.(bool); isBool {
+		return b
+	}
+	return ok
+}
+
+func (n compareNode) eval(t *task.TaskResponse, now time.Time) bool {
+	v, ok := n.field.get(t)
+
+	if n.values[0] == nil {
+		// Only = and != take null.
+		return ok == (n.op == "!=")
+	}
+	if !ok {
+		// An unset field differs from every value and is neither less nor
+		// greater than one.
+		return n.op == "!="
+	}
+
+	switch n.op {
+	case "contains":
+		switch v := v.(type) {
+		case string:
+			return strings.Contains(strings.ToLower(v), strings.ToLower(n.values[0].(string)))
+		case []string:
+			return slices.ContainsFunc(v, func(s string) bool { return strings.EqualFold(s, n.values[0].(string)) })
+		case []float64:
+			return slices.Contains(v, n.values[0].(float64))
+		}
+		return false
+	case "in":
+		for _, want := range n.values {
+			if compare(v, want, now) == 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	c := compare(v, n.values[0], now)
+	switch n.op {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+// compare orders a field value against a value of the same kind, which the
+// parser guarantees.
+func compare(v, want any, now time.Time) int {
+	switch v := v.(type) {
+	case float64:
+		w := want.(float64)
+		switch {
+		case v < w:
+			return -1
+		case v > w:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(strings.ToLower(v), strings.ToLower(want.(string)))
+	case bool:
+		if v == want.(bool) {
+			return 0
+		}
+		return 1
+	case time.Time:
+		w, ok := want.(time.Time)
+		if !ok {
+			w = now.Add(want.(time.Duration))
+		}
+		return v.Compare(w)
+	}
+	return 1
+}
+
+// relativeTime matches now, optionally with an offset such as now+3d or
+// now-12h.
+var relativeTime = regexp.MustCompile(`^now(?:([+-])([0-9]{1,4})([mhdw]))?$`)
+
+var units = map[string]time.Duration{
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// parseOffset reads now[+-]N<unit> as an offset from now.
+func parseOffset(s string) (time.Duration, bool) {
+	m := relativeTime.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false
+	}
+	if m[1] == "" {
+		return 0, true
+	}
+	n, _ := strconv.Atoi(m[2])
+	offset := time.Duration(n) * units[m[3]]
+	if m[1] == "-" {
+		offset = -offset
+	}
+	return offset, true
+}
+
+// parseTime reads an RFC 3339 time or a date, which is taken as midnight
+// UTC.
+func parseTime(s string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{tokComma, ",", i})
+			i++
+		case c == '=':
+			tokens = append(tokens, token{tokOp, "=", i})
+			i++
+		case c == '!' || c == '<' || c == '>':
+			op := string(c)
+			if i+1 < len(src) && src[i+1] == '=' {
+				op += "="
+			} else if c == '!' {
+				return nil, fmt.Errorf("%w: expected != at position %d", ErrInvalidCondition, i+1)
+			}
+			tokens = append(tokens, token{tokOp, op, i})
+			i += len(op)
+		case c == '"' || c == '\'':
+			start := i
+			var b strings.Builder
+			i++
+			for ; i < len(src) && src[i] != c; i++ {
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+				}
+				b.WriteByte(src[i])
+			}
+			if i == len(src) {
+				return nil, fmt.Errorf("%w: unterminated string at position %d", ErrInvalidCondition, start+1)
+			}
+			i++
+			tokens = append(tokens, token{tokString, b.String(), start})
+		case c == '-' || c >= '0' && c <= '9':
+			start := i
+			i++
+			for i < len(src) && (src[i] >= '0' && src[i] <= '9' || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, src[start:i], start})
+		case c == '_' || unicode.IsLetter(rune(c)):
+			start := i
+			for i < len(src) && (src[i] == '_' || unicode.IsLetter(rune(src[i])) || src[i] >= '0' && src[i] <= '9') {
+				i++
+			}
+			// now+3d is a single value.
+			if src[start:i] == "now" && i < len(src) && (src[i] == '+' || src[i] == '-') {
+				i++
+				for i < len(src) && (src[i] >= '0' && src[i] <= '9' || unicode.IsLetter(rune(src[i]))) {
+					i++
+				}
+			}
+			tokens = append(tokens, token{tokIdent, src[start:i], start})
+		default:
+			return nil, fmt.Errorf("%w: unexpected %q at position %d", ErrInvalidCondition, c, i+1)
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(src)}), nil
+}
+
+type conditionParser struct {
+	tokens []token
+	pos    int
+}
+
+// ParseCondition parses a condition; an empty one matches every task.
+func ParseCondition(src string) (*Condition, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 1 {
+		return &Condition{}, nil
+	}
+
+	p := &conditionParser{tokens: tokens}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	return &Condition{root: root}, nil
+}
+
+func (p *conditionParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *conditionParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// keyword consumes the next token if it is the given keyword.
+func (p *conditionParser) keyword(word string) bool {
+	if t := p.peek(); t.kind == tokIdent && strings.EqualFold(t.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *conditionParser) errorf(t token, format string, args ...any) error {
+	at := "end of condition"
+	if t.kind != tokEOF {
+		at = fmt.Sprintf("position %d", t.pos+1)
+	}
+	return fmt.Errorf("%w: %s at %s", ErrInvalidCondition, fmt.Sprintf(format, args...), at)
+}
+
+func (p *conditionParser) or() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) and() (node, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) not() (node, error) {
+	if p.keyword("not") {
+		operand, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+	return p.primary()
+}
+
+func (p *conditionParser) primary() (node, error) {
+	t := p.next()
+	if t.kind == tokLParen {
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, p.errorf(closing, "expected )")
+		}
+		return n, nil
+	}
+	if t.kind != tokIdent {
+		return nil, p.errorf(t, "expected a field")
+	}
+	f, ok := fields[strings.ToLower(t.text)]
+	if !ok {
+		return nil, p.errorf(t, "unknown field %q", t.text)
+	}
+
+	op := p.peek()
+	switch {
+	case op.kind == tokOp:
+		p.pos++
+	case op.kind == tokIdent && (strings.EqualFold(op.text, "contains") || strings.EqualFold(op.text, "in")):
+		p.pos++
+		op.text = strings.ToLower(op.text)
+	default:
+		return setNode{f}, nil
+	}
+
+	n := compareNode{field: f, op: op.text}
+	if op.text == "in" {
+		if open := p.next(); open.kind != tokLParen {
+			return nil, p.errorf(open, "expected ( after in")
+		}
+		for {
+			v, err := p.value(f.kind)
+			if err != nil {
+				return nil, err
+			}
+			n.values = append(n.values, v)
+			if sep := p.next(); sep.kind == tokRParen {
+				break
+			} else if sep.kind != tokComma {
+				return nil, p.errorf(sep, "expected , or )")
+			}
+		}
+	} else {
+		v, err := p.value(f.kind)
+		if err != nil {
+			return nil, err
+		}
+		n.values = []any{v}
+	}
+
+	if err := checkOperator(f.kind, n, t.text); err != nil {
+		return nil, p.errorf(op, "%s", err)
+	}
+	return n, nil
+}
+
+// checkOperator rejects comparisons that make no sense for the field.
+func checkOperator(k kind, n compareNode, name string) error {
+	if n.values[0] == nil {
+		if n.op != "=" && n.op != "!=" {
+			return fmt.Errorf("null only works with = and !=")
+		}
+		return nil
+	}
+	switch n.op {
+	case "contains":
+		if k != kindString && k != kindNumbers && k != kindStrings {
+			return fmt.Errorf("%s does not support contains", name)
+		}
+	case "in":
+		if k != kindNumber && k != kindString {
+			return fmt.Errorf("%s does not support in", name)
+		}
+		if slices.Contains(n.values, nil) {
+			return fmt.Errorf("in does not take null")
+		}
+	case "=", "!=":
+		if k == kindNumbers || k == kindStrings {
+			return fmt.Errorf("%s is a list; use contains or compare with null", name)
+		}
+	default:
+		if k != kindNumber && k != kindTime {
+			return fmt.Errorf("%s cannot be compared with %s", name, n.op)
+		}
+	}
+	return nil
+}
+
+// value reads a literal for a field of the given kind.
+func (p *conditionParser) value(k kind) (any, error) {
+	t := p.next()
+	if t.kind == tokIdent && strings.EqualFold(t.text, "null") {
+		return nil, nil
+	}
+
+	switch k {
+	case kindNumber, kindNumbers:
+		if t.kind == tokNumber {
+			if n, err := strconv.ParseFloat(t.text, 64); err == nil {
+				return n, nil
+			}
+		}
+		return nil, p.errorf(t, "expected a number")
+	case kindString, kindStrings:
+		if t.kind == tokString {
+			return t.text, nil
+		}
+		return nil, p.errorf(t, "expected a quoted string")
+	case kindBool:
+		if t.kind == tokIdent && (strings.EqualFold(t.text, "true") || strings.EqualFold(t.text, "false")) {
+			return strings.EqualFold(t.text, "true"), nil
+		}
+		return nil, p.errorf(t, "expected true or false")
+	default:
+		if t.kind == tokIdent {
+			if offset, ok := parseOffset(strings.ToLower(t.text)); ok {
+				return offset, nil
+			}
+		}
+		if t.kind == tokString {
+			if at, ok := parseTime(t.text); ok {
+				return at, nil
+			}
+		}
+		return nil, p.errorf(t, "expected now, now+3d or a quoted RFC 3339 date")
+	}
+}
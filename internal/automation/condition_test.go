@@ -0,0 +1,133 @@
+package automation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sudarshanmg/gotask/internal/task"
+)
+
+func TestConditionMatch(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	due := now.Add(36 * time.Hour)
+	priority := 2
+	projectID := int64(4)
+	points := "M"
+	fixture := task.TaskResponse{
+		ID:          10,
+		Title:       "Fix Login Bug",
+		ProjectID:   &projectID,
+		Priority:    &priority,
+		StoryPoints: &points,
+		AssigneeIDs: []int64{3, 5},
+		BlockedBy:   []int64{},
+		Labels:      []string{"bug", "Backend"},
+		DueAt:       &due,
+		CreatedAt:   now.Add(-48 * time.Hour),
+	}
+
+	tests := []struct {
+		condition string
+		want      bool
+	}{
+		{"", true},
+		{"priority = 2", true},
+		{"priority != 2", false},
+		{"priority < 3", true},
+		{"priority <= 1", false},
+		{"priority > 1.5", true},
+		{"priority >= 3", false},
+		{"priority in (1, 2)", true},
+		{"priority in (3, 4)", false},
+		{"estimate_minutes = null", true},
+		{"estimate_minutes != null", false},
+		{"estimate_minutes < 10", false},
+		{"estimate_minutes > 10", false},
+		{"estimate_minutes != 10", true},
+		{`title = "fix login bug"`, true},
+		{`title contains "LOGIN"`, true},
+		{`title contains "logout"`, false},
+		{`title in ("a", 'Fix Login Bug')`, true},
+		{`story_points = "m"`, true},
+		{`labels contains "backend"`, true},
+		{`labels contains "back"`, false},
+		{"labels != null", true},
+		{"blocked_by = null", true},
+		{"assignee_ids contains 5", true},
+		{"assignee_ids contains 4", false},
+		{"completed", false},
+		{"not completed", true},
+		{"completed = false", true},
+		{"labels", true},
+		{"blocked_by", false},
+		{"due_at < now+2d", true},
+		{"due_at < now+1d", false},
+		{"due_at > now", true},
+		{"created_at < now-1d", true},
+		{"created_at >= now-1w", true},
+		{"due_at <= now+90m", false},
+		{`due_at < "2024-03-12"`, false},
+		{`due_at <= "2024-03-12"`, true},
+		{`due_at > "2024-03-11T23:59:59Z"`, true},
+		{"completed_at = null", true},
+		{"completed_at < now", false},
+		{"priority <= 2 and labels contains 'bug' and not completed", true},
+		{"priority = 1 or assignee_ids contains 3", true},
+		{"priority = 1 or priority = 3 and completed", false},
+		{"(priority = 1 or priority = 2) and not (completed or labels = null)", true},
+		{"not not priority", true},
+		{"PRIORITY = 2 AND Labels CONTAINS 'BUG'", true},
+	}
+	for _, tt := range tests {
+		c, err := ParseCondition(tt.condition)
+		if err != nil {
+			t.Errorf("ParseCondition(%q): %v", tt.condition, err)
+			continue
+		}
+		if got := c.Match(&fixture, now); got != tt.want {
+			t.Errorf("%q matched %v, want %v", tt.condition, got, tt.want)
+		}
+	}
+}
+
+func TestParseConditionErrors(t *testing.T) {
+	tests := []struct {
+		condition string
+		message   string
+	}{
+		{"colour = 'red'", `unknown field "colour"`},
+		{"priority = 'high'", "expected a number"},
+		{"title = 3", "expected a quoted string"},
+		{"completed = yes", "expected true or false"},
+		{"due_at < tomorrow", "expected now, now+3d"},
+		{"due_at < now+3y", "expected now, now+3d"},
+		{"labels = 'bug'", "labels is a list"},
+		{"title < 'b'", "title cannot be compared with <"},
+		{"completed contains true", "completed does not support contains"},
+		{"labels in ('a')", "labels does not support in"},
+		{"priority in (1, null)", "in does not take null"},
+		{"priority > null", "null only works with = and !="},
+		{"priority in 1", "expected ( after in"},
+		{"priority in (1 2)", "expected , or )"},
+		{"(priority = 1", "expected ) at end of condition"},
+		{"priority = 1 priority", `unexpected "priority" at position 14`},
+		{"priority ! 1", "expected != at position 10"},
+		{"title = 'open", "unterminated string at position 9"},
+		{"priority = #1", "unexpected '#' at position 12"},
+		{"= 1", "expected a field"},
+		{"and = 1", `unknown field "and"`},
+		{"priority =", "expected a number at end of condition"},
+	}
+	for _, tt := range tests {
+		_, err := ParseCondition(tt.condition)
+		if !errors.Is(err, ErrInvalidCondition) {
+			t.Errorf("ParseCondition(%q) = %v, want ErrInvalidCondition", tt.condition, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("ParseCondition(%q) = %q, want it to mention %q", tt.condition, err, tt.message)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package automation
+
+import "errors"
+
+var (
+	ErrNotFound         = errors.New("automation rule not found")
+	ErrInvalidID        = errors.New("invalid automation rule ID")
+	ErrForbidden        = errors.New("you do not have permission to manage this rule")
+	ErrInvalidProject   = errors.New("project must be one you can edit tasks in")
+	ErrInvalidCondition = errors.New("invalid condition")
+	ErrInvalidAction    = errors.New("invalid action")
+	ErrInvalidRecipient = errors.New("user must be able to see the rule's tasks")
+	ErrSampleMissing    = errors.New("task_id or task is required")
+	ErrRuleMissing      = errors.New("rule is required")
+)
@@ -0,0 +1,218 @@
+package automation
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/task"
+	"github.com/sudarshanmg/gotask/pkg/response"
+)
+
+type Handler struct {
+	service AutomationService
+}
+
+func NewHandler(service AutomationService) *Handler {
+	return &Handler{service: service}
+}
+
+func ruleID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return 0, false
+	}
+	return id, true
+}
+
+func writeServiceError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidID), errors.Is(err, ErrInvalidProject), errors.Is(err, ErrInvalidCondition),
+		errors.Is(err, ErrInvalidAction), errors.Is(err, ErrInvalidRecipient), errors.Is(err, ErrSampleMissing),
+		errors.Is(err, ErrRuleMissing), errors.Is(err, task.ErrInvalidID),
+		strings.HasPrefix(err.Error(), "validation failed:"):
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrNotFound), errors.Is(err, task.ErrNotFound):
+		response.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrForbidden):
+		response.WriteError(w, http.StatusForbidden, err.Error())
+	default:
+		response.WriteError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+func (h *Handler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var req CreateRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	rule, err := h.service.Create(auth.GetCaller(r), req)
+	if err != nil {
+		writeServiceError(w, err, "failed to create rule")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, rule)
+}
+
+// GetRules lists the caller's rules; ?project={id} keeps one project's.
+func (h *Handler) GetRules(w http.ResponseWriter, r *http.Request) {
+	var projectID *int64
+	if projectStr := r.URL.Query().Get("project"); projectStr != "" {
+		id, err := strconv.ParseInt(projectStr, 10, 64)
+		if err != nil {
+			response.WriteError(w, http.StatusBadRequest, "invalid project")
+			return
+		}
+		projectID = &id
+	}
+
+	rules, err := h.service.GetAll(auth.GetCaller(r), projectID)
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch rules")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, rules)
+}
+
+func (h *Handler) GetRule(w http.ResponseWriter, r *http.Request) {
+	id, ok := ruleID(w, r)
+	if !ok {
+		return
+	}
+
+	rule, err := h.service.GetByID(auth.GetCaller(r), id)
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch rule")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, rule)
+}
+
+func (h *Handler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	id, ok := ruleID(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	rule, err := h.service.Update(auth.GetCaller(r), id, req)
+	if err != nil {
+		writeServiceError(w, err, "failed to update rule")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, rule)
+}
+
+func (h *Handler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	id, ok := ruleID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(auth.GetCaller(r), id); err != nil {
+		writeServiceError(w, err, "failed to delete rule")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{"message": "rule deleted successfully"})
+}
+
+func (h *Handler) ListRuns(w http.ResponseWriter, r *http.Request) {
+	id, ok := ruleID(w, r)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	filter := RunFilter{}
+	switch status := RunStatus(r.URL.Query().Get("status")); status {
+	case "":
+	case RunPending, RunRunning, RunSucceeded, RunSkipped, RunFailed:
+		filter.Status = &status
+	default:
+		response.WriteError(w, http.StatusBadRequest, "status must be pending, running, succeeded, skipped or failed")
+		return
+	}
+
+	runs, total, totalPages, err := h.service.Runs(auth.GetCaller(r), id, page, limit, filter)
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch runs")
+		return
+	}
+
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	w.Header().Set("X-Total-Pages", strconv.Itoa(totalPages))
+
+	response.WriteJSON(w, http.StatusOK, runs)
+}
+
+// DryRun evaluates the rule in the body against a task; DryRunRule does
+// the same for a saved rule.
+func (h *Handler) DryRun(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var req DryRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	result, err := h.service.DryRun(auth.GetCaller(r), req)
+	if err != nil {
+		writeServiceError(w, err, "failed to evaluate rule")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, result)
+}
+
+func (h *Handler) DryRunRule(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	id, ok := ruleID(w, r)
+	if !ok {
+		return
+	}
+
+	var req DryRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	result, err := h.service.DryRunRule(auth.GetCaller(r), id, req)
+	if err != nil {
+		writeServiceError(w, err, "failed to evaluate rule")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, result)
+}
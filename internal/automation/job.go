@@ -0,0 +1,17 @@
+package automation
+
+// Job runs pending automation runs, after queueing those for deadlines
+// that passed. Every replica may run it: runs are claimed with FOR UPDATE
+// SKIP LOCKED and keyed by rule and event, so each runs once.
+type Job struct {
+	Service AutomationService
+	Batch   int
+}
+
+func (j *Job) Name() string {
+	return "automation rules"
+}
+
+func (j *Job) Run() (int, error) {
+	return j.Service.ProcessPending(j.Batch)
+}
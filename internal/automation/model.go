@@ -0,0 +1,157 @@
+package automation
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/sudarshanmg/gotask/internal/task"
+)
+
+type TriggerType string
+
+const (
+	TriggerCreated       TriggerType = "created"
+	TriggerStatusChanged TriggerType = "status_changed"
+	TriggerDuePassed     TriggerType = "due_passed"
+	TriggerLabelAdded    TriggerType = "label_added"
+)
+
+// Trigger is what makes a rule run. Status changes include completing and
+// reopening a task, and a task created with labels counts as having them
+// added.
+type Trigger struct {
+	Type TriggerType `json:"type" validate:"required,oneof=created status_changed due_passed label_added"`
+	// Label limits label_added to one label.
+	Label string `json:"label,omitempty" validate:"omitempty,max=50"`
+}
+
+type ActionType string
+
+const (
+	ActionSetField      ActionType = "set_field"
+	ActionAddLabel      ActionType = "add_label"
+	ActionAssign        ActionType = "assign"
+	ActionCreateSubtask ActionType = "create_subtask"
+	ActionSendWebhook   ActionType = "send_webhook"
+	ActionNotify        ActionType = "notify"
+)
+
+// Action is one step of a rule. Which fields apply depends on Type:
+//
+//   - set_field: Field and Value, where a null Value clears the field
+//   - add_label: Label
+//   - assign: UserID, added to the assignees
+//   - create_subtask: Title, and UserID to assign it
+//   - send_webhook: URL
+//   - notify: Message, and UserID; without one the assignees are notified,
+//     or the creator of an unassigned task
+//
+// Title and Message may use {{title}} and {{id}} for the task's.
+type Action struct {
+	Type    ActionType      `json:"type" validate:"required,oneof=set_field add_label assign create_subtask send_webhook notify"`
+	Field   string          `json:"field,omitempty"`
+	Value   json.RawMessage `json:"value,omitempty"`
+	Label   string          `json:"label,omitempty" validate:"omitempty,max=50"`
+	UserID  int64           `json:"user_id,omitempty" validate:"omitempty,gt=0"`
+	Title   string          `json:"title,omitempty" validate:"omitempty,max=100"`
+	URL     string          `json:"url,omitempty" validate:"omitempty,http_url,max=2000"`
+	Message string          `json:"message,omitempty" validate:"omitempty,max=500"`
+}
+
+// Rule runs its actions, in order, on tasks that trigger it and match its
+// condition. Personal rules (no project) apply to their creator's personal
+// tasks, project rules to the project's tasks; either way the actions are
+// taken as the rule's creator. Secret signs send_webhook requests and is
+// only returned when the rule is created.
+type Rule struct {
+	ID          int64     `json:"id"`
+	WorkspaceID int64     `json:"workspace_id"`
+	CreatedBy   int64     `json:"created_by"`
+	ProjectID   *int64    `json:"project_id"`
+	Name        string    `json:"name"`
+	Trigger     Trigger   `json:"trigger"`
+	Condition   string    `json:"condition"`
+	Actions     []Action  `json:"actions"`
+	Secret      string    `json:"secret,omitempty"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type CreateRuleRequest struct {
+	Name      string   `json:"name" validate:"required,max=100"`
+	ProjectID *int64   `json:"project_id,omitempty" validate:"omitempty,gt=0"`
+	Trigger   Trigger  `json:"trigger"`
+	Condition string   `json:"condition" validate:"max=1000"`
+	Actions   []Action `json:"actions" validate:"required,min=1,max=10,dive"`
+}
+
+type UpdateRuleRequest struct {
+	Name      *string   `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	Trigger   *Trigger  `json:"trigger,omitempty"`
+	Condition *string   `json:"condition,omitempty" validate:"omitempty,max=1000"`
+	Actions   *[]Action `json:"actions,omitempty" validate:"omitempty,min=1,max=10,dive"`
+	Active    *bool     `json:"active,omitempty"`
+}
+
+type RunStatus string
+
+const (
+	RunPending   RunStatus = "pending"
+	RunRunning   RunStatus = "running"
+	RunSucceeded RunStatus = "succeeded"
+	// RunSkipped runs did not match the rule's condition, or found the rule
+	// switched off.
+	RunSkipped RunStatus = "skipped"
+	RunFailed  RunStatus = "failed"
+)
+
+// Run is one firing of a rule for a task. RuleChain lists the rules whose
+// actions caused the event, so a rule never fires on a change it caused
+// itself, directly or through other rules.
+type Run struct {
+	ID          int64          `json:"id"`
+	WorkspaceID int64          `json:"workspace_id"`
+	RuleID      int64          `json:"rule_id"`
+	TaskID      int64          `json:"task_id"`
+	EventID     string         `json:"event_id"`
+	Trigger     TriggerType    `json:"trigger"`
+	RuleChain   []int64        `json:"rule_chain"`
+	Status      RunStatus      `json:"status"`
+	Results     []ActionResult `json:"results"`
+	Error       string         `json:"error"`
+	CreatedAt   time.Time      `json:"created_at"`
+	FinishedAt  *time.Time     `json:"finished_at"`
+	// Task is the task as the event saw it; due_passed runs have none and
+	// look at the task when they run.
+	Task *task.TaskResponse `json:"-"`
+}
+
+// ActionResult is what an action did, or would do in a dry run.
+type ActionResult struct {
+	Type   ActionType `json:"type"`
+	Result string     `json:"result,omitempty"`
+	Error  string     `json:"error,omitempty"`
+}
+
+type RunFilter struct {
+	Status *RunStatus
+}
+
+// DryRunRequest names a saved task, or describes a sample one, to evaluate
+// a rule against. Rule is used by the dry run of an unsaved rule.
+type DryRunRequest struct {
+	Rule   *CreateRuleRequest `json:"rule,omitempty"`
+	TaskID int64              `json:"task_id,omitempty" validate:"omitempty,gt=0"`
+	Task   *task.TaskResponse `json:"task,omitempty"`
+}
+
+// DryRunResult shows what the rule would do to the task. Task is the task
+// after the actions that change it; Warnings point out actions that would
+// trigger the rule again, which loop protection stops.
+type DryRunResult struct {
+	Matched  bool               `json:"matched"`
+	Actions  []ActionResult     `json:"actions"`
+	Task     *task.TaskResponse `json:"task"`
+	Warnings []string           `json:"warnings"`
+}
@@ -0,0 +1,381 @@
+package automation
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sudarshanmg/gotask/internal/task"
+	"github.com/sudarshanmg/gotask/pkg/db"
+)
+
+const (
+	ruleColumns = `id, workspace_id, created_by, project_id, name, trigger, trigger_label, condition, actions,
+                 active, created_at, updated_at`
+
+	runColumns = `id, workspace_id, rule_id, task_id, event_id, trigger, rule_chain, status, results, error,
+                created_at, finished_at`
+)
+
+// AutomationRepository reads and writes rules and runs within a workspace
+// under db.WithTenant. EnqueueDue and Claim are used by the background job
+// and work across workspaces under db.AcrossTenants.
+type AutomationRepository interface {
+	Create(rule *Rule) error
+	FindByID(workspaceID, id int64) (*Rule, error)
+	// FindVisible returns the user's personal rules and the rules of the
+	// projects they belong to, or only those of projectID.
+	FindVisible(workspaceID, userID int64, projectID *int64) ([]Rule, error)
+	Update(rule *Rule) error
+	Delete(workspaceID, id int64) error
+	// Enqueue records a pending run of the event for every active rule in
+	// scope with one of the triggers, except the rules in its chain. A
+	// label_added rule for a single label only runs if that label is among
+	// labels.
+	Enqueue(event task.Event, triggers []TriggerType, labels []string) (int64, error)
+	// EnqueueDue records a run of every active due_passed rule for each
+	// open task in scope whose due date passed within lookback, and after
+	// the rule was created. Runs are keyed by the due date, so a task
+	// fires again only if it gets a new one.
+	EnqueueDue(lookback time.Duration) (int64, error)
+	FindRuns(workspaceID, ruleID int64, filter RunFilter, offset, limit int) ([]Run, error)
+	CountRuns(workspaceID, ruleID int64, filter RunFilter) (int64, error)
+	// Claim marks the oldest pending run as running and returns it with its
+	// rule, or nil when there is none. Runs left running for staleAfter
+	// are failed rather than retried, since some of their actions may
+	// have been taken.
+	Claim(staleAfter time.Duration) (*Run, *Rule, error)
+	// Finish saves the run's outcome.
+	Finish(run *Run) error
+}
+
+type PostgresAutomationRepository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) AutomationRepository {
+	return &PostgresAutomationRepository{DB: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRule(row rowScanner, rule *Rule, extra ...any) error {
+	var label sql.NullString
+	var actions []byte
+	dest := []any{&rule.ID, &rule.WorkspaceID, &rule.CreatedBy, &rule.ProjectID, &rule.Name, &rule.Trigger.Type,
+		&label, &rule.Condition, &actions, &rule.Active, &rule.CreatedAt, &rule.UpdatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	rule.Trigger.Label = label.String
+	return json.Unmarshal(actions, &rule.Actions)
+}
+
+func scanRun(row rowScanner, run *Run, extra ...any) error {
+	var results []byte
+	dest := []any{&run.ID, &run.WorkspaceID, &run.RuleID, &run.TaskID, &run.EventID, &run.Trigger,
+		(*pq.Int64Array)(&run.RuleChain), &run.Status, &results, &run.Error, &run.CreatedAt, &run.FinishedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	if run.RuleChain == nil {
+		run.RuleChain = []int64{}
+	}
+	return json.Unmarshal(results, &run.Results)
+}
+
+// triggerLabel stores an empty label as NULL, which matches any label.
+func triggerLabel(t Trigger) any {
+	if t.Label == "" {
+		return nil
+	}
+	return t.Label
+}
+
+func (r *PostgresAutomationRepository) Create(rule *Rule) error {
+	query := `INSERT INTO automation_rules (workspace_id, created_by, project_id, name, trigger, trigger_label,
+                condition, actions, secret)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+            RETURNING id, active, created_at, updated_at;`
+
+	actions, err := json.Marshal(rule.Actions)
+	if err != nil {
+		return err
+	}
+	return db.WithTenant(r.DB, rule.WorkspaceID, func(tx *sql.Tx) error {
+		return tx.QueryRow(query, rule.WorkspaceID, rule.CreatedBy, rule.ProjectID, rule.Name, rule.Trigger.Type,
+			triggerLabel(rule.Trigger), rule.Condition, actions, rule.Secret).
+			Scan(&rule.ID, &rule.Active, &rule.CreatedAt, &rule.UpdatedAt)
+	})
+}
+
+func (r *PostgresAutomationRepository) FindByID(workspaceID, id int64) (*Rule, error) {
+	query := `SELECT ` + ruleColumns + ` FROM automation_rules WHERE workspace_id = $1 AND id = $2;`
+
+	var found *Rule
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		rule := Rule{}
+		err := scanRule(tx.QueryRow(query, workspaceID, id), &rule)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		found = &rule
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	return found, nil
+}
+
+func (r *PostgresAutomationRepository) FindVisible(workspaceID, userID int64, projectID *int64) ([]Rule, error) {
+	query := `SELECT ` + ruleColumns + ` FROM automation_rules r
+            WHERE r.workspace_id = $1
+              AND ((r.project_id IS NULL AND r.created_by = $2)
+                OR EXISTS (SELECT 1 FROM project_members pm
+                           WHERE pm.project_id = r.project_id AND pm.user_id = $2))
+              AND ($3::int IS NULL OR r.project_id = $3)
+            ORDER BY r.id;`
+
+	rules := []Rule{}
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, workspaceID, userID, projectID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			rule := Rule{}
+			if err := scanRule(rows, &rule); err != nil {
+				return err
+			}
+			rules = append(rules, rule)
+		}
+		return rows.Err()
+	})
+	return rules, err
+}
+
+func (r *PostgresAutomationRepository) Update(rule *Rule) error {
+	query := `UPDATE automation_rules
+            SET name = $3, trigger = $4, trigger_label = $5, condition = $6, actions = $7, active = $8,
+                updated_at = NOW() AT TIME ZONE 'UTC'
+            WHERE workspace_id = $1 AND id = $2
+            RETURNING updated_at;`
+
+	actions, err := json.Marshal(rule.Actions)
+	if err != nil {
+		return err
+	}
+	return db.WithTenant(r.DB, rule.WorkspaceID, func(tx *sql.Tx) error {
+		err := tx.QueryRow(query, rule.WorkspaceID, rule.ID, rule.Name, rule.Trigger.Type, triggerLabel(rule.Trigger),
+			rule.Condition, actions, rule.Active).Scan(&rule.UpdatedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	})
+}
+
+func (r *PostgresAutomationRepository) Delete(workspaceID, id int64) error {
+	query := `DELETE FROM automation_rules WHERE workspace_id = $1 AND id = $2;`
+
+	return db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		res, err := tx.Exec(query, workspaceID, id)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (r *PostgresAutomationRepository) Enqueue(event task.Event, triggers []TriggerType, labels []string) (int64, error) {
+	// Scope mirrors task visibility: personal rules see their creator's
+	// personal tasks, project rules the project's tasks while their
+	// creator is still a member.
+	query := `INSERT INTO automation_runs (workspace_id, rule_id, task_id, event_id, trigger, rule_chain, task)
+            SELECT r.workspace_id, r.id, $2, $3, r.trigger, $4, $5
+            FROM automation_rules r
+            WHERE r.workspace_id = $1 AND r.active AND r.trigger = ANY($6)
+              AND (r.trigger <> 'label_added' OR r.trigger_label IS NULL OR r.trigger_label = ANY($7))
+              AND NOT r.id = ANY($4)
+              AND (($8::bigint IS NULL AND r.project_id IS NULL AND r.created_by = $9)
+                OR (r.project_id = $8 AND EXISTS (SELECT 1 FROM project_members pm
+                                                  WHERE pm.project_id = $8 AND pm.user_id = r.created_by)))
+            ON CONFLICT (rule_id, event_id) DO NOTHING;`
+
+	snapshot, err := json.Marshal(event.Task)
+	if err != nil {
+		return 0, err
+	}
+	names := make([]string, len(triggers))
+	for i, t := range triggers {
+		names[i] = string(t)
+	}
+	chain := event.RuleChain
+	if chain == nil {
+		chain = []int64{}
+	}
+
+	var enqueued int64
+	err = db.WithTenant(r.DB, event.WorkspaceID, func(tx *sql.Tx) error {
+		res, err := tx.Exec(query, event.WorkspaceID, event.Task.ID, event.ID, pq.Array(chain), snapshot,
+			pq.Array(names), pq.Array(labels), event.Task.ProjectID, event.Task.CreatedBy)
+		if err != nil {
+			return err
+		}
+		enqueued, err = res.RowsAffected()
+		return err
+	})
+	return enqueued, err
+}
+
+func (r *PostgresAutomationRepository) EnqueueDue(lookback time.Duration) (int64, error) {
+	query := `INSERT INTO automation_runs (workspace_id, rule_id, task_id, event_id, trigger)
+            SELECT r.workspace_id, r.id, t.id, 'due:' || t.id || ':' || EXTRACT(EPOCH FROM t.due_at)::bigint,
+                   r.trigger
+            FROM automation_rules r
+            JOIN tasks t ON t.workspace_id = r.workspace_id
+             AND ((r.project_id IS NULL AND t.project_id IS NULL AND t.created_by = r.created_by)
+               OR (t.project_id = r.project_id AND EXISTS (SELECT 1 FROM project_members pm
+                                                           WHERE pm.project_id = r.project_id
+                                                             AND pm.user_id = r.created_by)))
+            WHERE r.active AND r.trigger = 'due_passed'
+              AND NOT t.completed
+              AND t.due_at <= NOW() AT TIME ZONE 'UTC'
+              AND t.due_at > GREATEST(r.created_at, (NOW() AT TIME ZONE 'UTC') - $1 * INTERVAL '1 second')
+            ON CONFLICT (rule_id, event_id) DO NOTHING;`
+
+	var enqueued int64
+	err := db.AcrossTenants(r.DB, func(tx *sql.Tx) error {
+		res, err := tx.Exec(query, int64(lookback.Seconds()))
+		if err != nil {
+			return err
+		}
+		enqueued, err = res.RowsAffected()
+		return err
+	})
+	return enqueued, err
+}
+
+func (r *PostgresAutomationRepository) FindRuns(workspaceID, ruleID int64, filter RunFilter, offset, limit int) ([]Run, error) {
+	query := `SELECT ` + runColumns + ` FROM automation_runs
+            WHERE workspace_id = $1 AND rule_id = $2 AND ($3::text IS NULL OR status = $3)
+            ORDER BY id DESC
+            LIMIT $4 OFFSET $5;`
+
+	runs := []Run{}
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, workspaceID, ruleID, filter.Status, limit, offset)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			run := Run{}
+			if err := scanRun(rows, &run); err != nil {
+				return err
+			}
+			runs = append(runs, run)
+		}
+		return rows.Err()
+	})
+	return runs, err
+}
+
+func (r *PostgresAutomationRepository) CountRuns(workspaceID, ruleID int64, filter RunFilter) (int64, error) {
+	query := `SELECT COUNT(*) FROM automation_runs
+            WHERE workspace_id = $1 AND rule_id = $2 AND ($3::text IS NULL OR status = $3);`
+
+	var count int64
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		return tx.QueryRow(query, workspaceID, ruleID, filter.Status).Scan(&count)
+	})
+	return count, err
+}
+
+func (r *PostgresAutomationRepository) Claim(staleAfter time.Duration) (*Run, *Rule, error) {
+	fail := `UPDATE automation_runs
+            SET status = 'failed', error = 'run was interrupted', finished_at = NOW()
+            WHERE status = 'running' AND started_at < NOW() - $1 * INTERVAL '1 second';`
+	claim := `UPDATE automation_runs
+            SET status = 'running', started_at = NOW()
+            WHERE id = (
+              SELECT id FROM automation_runs
+              WHERE status = 'pending'
+              ORDER BY id
+              LIMIT 1
+              FOR UPDATE SKIP LOCKED)
+            RETURNING ` + runColumns + `, task;`
+	ruleQuery := `SELECT ` + ruleColumns + `, secret FROM automation_rules WHERE id = $1;`
+
+	var claimed *Run
+	var found *Rule
+	err := db.AcrossTenants(r.DB, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(fail, staleAfter.Seconds()); err != nil {
+			return err
+		}
+		run := Run{}
+		var snapshot []byte
+		err := scanRun(tx.QueryRow(claim), &run, &snapshot)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if snapshot != nil {
+			if err := json.Unmarshal(snapshot, &run.Task); err != nil {
+				return err
+			}
+		}
+		rule := Rule{}
+		if err := scanRule(tx.QueryRow(ruleQuery, run.RuleID), &rule, &rule.Secret); err != nil {
+			return err
+		}
+		claimed, found = &run, &rule
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return claimed, found, nil
+}
+
+func (r *PostgresAutomationRepository) Finish(run *Run) error {
+	query := `UPDATE automation_runs
+            SET status = $2, results = $3, error = $4, finished_at = NOW()
+            WHERE id = $1
+            RETURNING finished_at;`
+
+	results := run.Results
+	if results == nil {
+		results = []ActionResult{}
+	}
+	raw, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+	return db.WithTenant(r.DB, run.WorkspaceID, func(tx *sql.Tx) error {
+		return tx.QueryRow(query, run.ID, run.Status, raw, run.Error).Scan(&run.FinishedAt)
+	})
+}
@@ -0,0 +1,18 @@
+package automation
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Route("/automations", func(r chi.Router) {
+		r.Get("/", h.GetRules)
+		r.Post("/", h.CreateRule)
+		r.Post("/dry-run", h.DryRun)
+		r.Get("/{id}", h.GetRule)
+		r.Put("/{id}", h.UpdateRule)
+		r.Delete("/{id}", h.DeleteRule)
+		r.Get("/{id}/runs", h.ListRuns)
+		r.Post("/{id}/dry-run", h.DryRunRule)
+	})
+}
@@ -0,0 +1,610 @@
+package automation
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/notification"
+	"github.com/sudarshanmg/gotask/internal/project"
+	"github.com/sudarshanmg/gotask/internal/task"
+	"github.com/sudarshanmg/gotask/internal/webhook"
+	"github.com/sudarshanmg/gotask/pkg/outbound"
+	"github.com/sudarshanmg/gotask/pkg/validation"
+)
+
+var validate = validator.New()
+
+const (
+	// maxChain is how many rules may set each other off in a row. Events
+	// caused by a longer chain start no more runs.
+	maxChain = 5
+	// dueLookback is how far back the due sweep looks for deadlines that
+	// passed, which covers servers being down for a while.
+	dueLookback = 24 * time.Hour
+	// staleAfter is how long a run may take before it is given up on.
+	staleAfter = 10 * time.Minute
+)
+
+// AutomationService manages rules and runs them. It implements
+// task.EventPublisher, queueing a run for every rule an event triggers;
+// ProcessPending runs them in the background.
+type AutomationService interface {
+	Create(caller auth.Caller, req CreateRuleRequest) (*Rule, error)
+	// GetAll lists the rules the caller can see, optionally of one project.
+	GetAll(caller auth.Caller, projectID *int64) ([]Rule, error)
+	GetByID(caller auth.Caller, id int64) (*Rule, error)
+	Update(caller auth.Caller, id int64, req UpdateRuleRequest) (*Rule, error)
+	Delete(caller auth.Caller, id int64) error
+	Runs(caller auth.Caller, id int64, page, limit int, filter RunFilter) ([]Run, int64, int, error)
+	// DryRun evaluates the unsaved rule in req, and DryRunRule a saved one,
+	// against a task without changing anything.
+	DryRun(caller auth.Caller, req DryRunRequest) (*DryRunResult, error)
+	DryRunRule(caller auth.Caller, id int64, req DryRunRequest) (*DryRunResult, error)
+	Publish(event task.Event) error
+	// ProcessPending queues runs for deadlines that passed and then takes
+	// up to limit pending runs, returning how many it took.
+	ProcessPending(limit int) (int, error)
+}
+
+type automationService struct {
+	repo          AutomationRepository
+	tasks         task.TaskService
+	projects      project.ProjectRepository
+	notifications notification.NotificationService
+	client        *http.Client
+}
+
+func NewService(repo AutomationRepository, tasks task.TaskService, projects project.ProjectRepository,
+	notifications notification.NotificationService) AutomationService {
+	return &automationService{repo: repo, tasks: tasks, projects: projects, notifications: notifications,
+		client: outbound.NewClient(10 * time.Second)}
+}
+
+func generateSecret() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(bytes), nil
+}
+
+// role is the caller's role in the rule's project; personal rules have
+// none.
+func (s *automationService) role(caller auth.Caller, rule *Rule) (project.Role, error) {
+	if rule.ProjectID == nil {
+		return "", nil
+	}
+	return s.projects.GetMemberRole(caller.WorkspaceID, *rule.ProjectID, caller.UserID)
+}
+
+// canView reports whether the caller may see the rule: its creator for
+// personal rules, project members for project rules.
+func (s *automationService) canView(caller auth.Caller, rule *Rule) (bool, error) {
+	if rule.ProjectID == nil {
+		return rule.CreatedBy == caller.UserID, nil
+	}
+	role, err := s.role(caller, rule)
+	return role.CanRead(), err
+}
+
+// canManage reports whether the caller may change or delete the rule: its
+// creator, or the owner of its project.
+func (s *automationService) canManage(caller auth.Caller, rule *Rule) (bool, error) {
+	if rule.CreatedBy == caller.UserID {
+		return true, nil
+	}
+	role, err := s.role(caller, rule)
+	return role.CanManage(), err
+}
+
+// canSee reports whether the user can see the tasks the rule applies to,
+// and so may be assigned or notified by it.
+func (s *automationService) canSee(rule *Rule, userID int64) (bool, error) {
+	if rule.ProjectID == nil {
+		return userID == rule.CreatedBy, nil
+	}
+	role, err := s.projects.GetMemberRole(rule.WorkspaceID, *rule.ProjectID, userID)
+	return role.CanRead(), err
+}
+
+// check validates the rule's condition and actions.
+func (s *automationService) check(rule *Rule) error {
+	if _, err := ParseCondition(rule.Condition); err != nil {
+		return err
+	}
+	for _, a := range rule.Actions {
+		if err := validateAction(a); err != nil {
+			return err
+		}
+		if a.UserID == 0 {
+			continue
+		}
+		ok, err := s.canSee(rule, a.UserID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrInvalidRecipient
+		}
+	}
+	return nil
+}
+
+// build makes an unsaved rule from the request. Project rules need a role
+// that may edit the project's tasks.
+func (s *automationService) build(caller auth.Caller, req CreateRuleRequest) (*Rule, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+
+	rule := &Rule{
+		WorkspaceID: caller.WorkspaceID,
+		CreatedBy:   caller.UserID,
+		ProjectID:   req.ProjectID,
+		Name:        req.Name,
+		Trigger:     req.Trigger,
+		Condition:   req.Condition,
+		Actions:     req.Actions,
+		Active:      true,
+	}
+	rule.Trigger.Label = normalizeLabel(rule.Trigger.Label)
+	if rule.ProjectID != nil {
+		role, err := s.role(caller, rule)
+		if err != nil {
+			return nil, err
+		}
+		if !role.CanWrite() {
+			return nil, ErrInvalidProject
+		}
+	}
+	if err := s.check(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// Create saves a rule. The secret that signs its webhooks is only returned
+// here.
+func (s *automationService) Create(caller auth.Caller, req CreateRuleRequest) (*Rule, error) {
+	rule, err := s.build(caller, req)
+	if err != nil {
+		return nil, err
+	}
+	if rule.Secret, err = generateSecret(); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *automationService) GetAll(caller auth.Caller, projectID *int64) ([]Rule, error) {
+	return s.repo.FindVisible(caller.WorkspaceID, caller.UserID, projectID)
+}
+
+func (s *automationService) GetByID(caller auth.Caller, id int64) (*Rule, error) {
+	if id <= 0 {
+		return nil, ErrInvalidID
+	}
+	rule, err := s.repo.FindByID(caller.WorkspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.canView(caller, rule)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return rule, nil
+}
+
+// Update changes a rule. Its actions keep being taken as its creator,
+// whoever edits it.
+func (s *automationService) Update(caller auth.Caller, id int64, req UpdateRuleRequest) (*Rule, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+
+	rule, err := s.GetByID(caller, id)
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := s.canManage(caller, rule); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, ErrForbidden
+	}
+
+	if req.Name != nil {
+		rule.Name = *req.Name
+	}
+	if req.Trigger != nil {
+		if err := validate.Struct(req.Trigger); err != nil {
+			return nil, validation.FormatValidationError(err)
+		}
+		rule.Trigger = *req.Trigger
+		rule.Trigger.Label = normalizeLabel(rule.Trigger.Label)
+	}
+	if req.Condition != nil {
+		rule.Condition = *req.Condition
+	}
+	if req.Actions != nil {
+		rule.Actions = *req.Actions
+	}
+	if req.Active != nil {
+		rule.Active = *req.Active
+	}
+	if err := s.check(rule); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *automationService) Delete(caller auth.Caller, id int64) error {
+	rule, err := s.GetByID(caller, id)
+	if err != nil {
+		return err
+	}
+	if ok, err := s.canManage(caller, rule); err != nil {
+		return err
+	} else if !ok {
+		return ErrForbidden
+	}
+	return s.repo.Delete(caller.WorkspaceID, id)
+}
+
+func (s *automationService) Runs(caller auth.Caller, id int64, page, limit int, filter RunFilter) ([]Run, int64, int, error) {
+	if _, err := s.GetByID(caller, id); err != nil {
+		return nil, 0, 0, err
+	}
+
+	offset := (page - 1) * limit
+	runs, err := s.repo.FindRuns(caller.WorkspaceID, id, filter, offset, limit)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	total, err := s.repo.CountRuns(caller.WorkspaceID, id, filter)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+	return runs, total, totalPages, nil
+}
+
+// Publish queues runs for the rules the event triggers. Changes made by a
+// rule carry its ID in the event's chain, and rules in the chain are not
+// run again, so rules cannot trigger themselves, directly or through each
+// other.
+func (s *automationService) Publish(event task.Event) error {
+	if len(event.RuleChain) >= maxChain {
+		log.Printf("automation: not running rules for task %d, chain %v is too long", event.Task.ID,
+			event.RuleChain)
+		return nil
+	}
+
+	var triggers []TriggerType
+	added := []string{}
+	switch event.Type {
+	case task.EventTaskCreated:
+		triggers = append(triggers, TriggerCreated)
+		added = event.Task.Labels
+	case task.EventTaskUpdated, task.EventTaskCompleted:
+		previous := event.Previous
+		if previous == nil {
+			return nil
+		}
+		if !sameID(previous.StatusID, event.Task.StatusID) || previous.Completed != event.Task.Completed {
+			triggers = append(triggers, TriggerStatusChanged)
+		}
+		for _, label := range event.Task.Labels {
+			if !slices.Contains(previous.Labels, label) {
+				added = append(added, label)
+			}
+		}
+	}
+	if len(added) > 0 {
+		triggers = append(triggers, TriggerLabelAdded)
+	}
+	if len(triggers) == 0 {
+		return nil
+	}
+
+	_, err := s.repo.Enqueue(event, triggers, added)
+	return err
+}
+
+func sameID(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func (s *automationService) ProcessPending(limit int) (int, error) {
+	if _, err := s.repo.EnqueueDue(dueLookback); err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for processed < limit {
+		run, rule, err := s.repo.Claim(staleAfter)
+		if err != nil {
+			return processed, err
+		}
+		if run == nil {
+			break
+		}
+
+		s.execute(run, rule)
+		if err := s.repo.Finish(run); err != nil {
+			return processed, err
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+// execute takes the rule's actions in order, as the rule's creator, and
+// records the outcome in the run. It stops at the first action that fails.
+func (s *automationService) execute(run *Run, rule *Rule) {
+	if !rule.Active {
+		run.Status = RunSkipped
+		run.Error = "rule is switched off"
+		return
+	}
+
+	caller := auth.Caller{
+		UserID:      rule.CreatedBy,
+		WorkspaceID: rule.WorkspaceID,
+		RuleChain:   append(slices.Clone(run.RuleChain), rule.ID),
+	}
+	subject := run.Task
+	if subject == nil {
+		current, err := s.tasks.GetById(caller, run.TaskID)
+		if err != nil {
+			run.Status = RunFailed
+			run.Error = err.Error()
+			return
+		}
+		subject = current
+	}
+
+	condition, err := ParseCondition(rule.Condition)
+	if err != nil {
+		run.Status = RunFailed
+		run.Error = err.Error()
+		return
+	}
+	if !condition.Match(subject, time.Now()) {
+		run.Status = RunSkipped
+		run.Error = "condition did not match"
+		return
+	}
+
+	for i, a := range rule.Actions {
+		result, err := s.act(caller, run, rule, a)
+		outcome := ActionResult{Type: a.Type, Result: result}
+		if err != nil {
+			outcome.Error = err.Error()
+		}
+		run.Results = append(run.Results, outcome)
+		if err != nil {
+			run.Status = RunFailed
+			run.Error = fmt.Sprintf("action %d (%s) failed", i+1, a.Type)
+			return
+		}
+	}
+	run.Status = RunSucceeded
+}
+
+// WebhookPayload is the JSON body of a send_webhook action, signed like
+// webhook deliveries with the rule's secret.
+type WebhookPayload struct {
+	RuleID  int64             `json:"rule_id"`
+	Rule    string            `json:"rule"`
+	RunID   int64             `json:"run_id"`
+	Trigger TriggerType       `json:"trigger"`
+	Task    task.TaskResponse `json:"task"`
+}
+
+// act takes one action on the task as it is now, after earlier actions.
+func (s *automationService) act(caller auth.Caller, run *Run, rule *Rule, a Action) (string, error) {
+	current, err := s.tasks.GetById(caller, run.TaskID)
+	if err != nil {
+		return "", err
+	}
+
+	switch a.Type {
+	case ActionSetField:
+		req, err := fieldUpdate(a.Field, a.Value, time.Now())
+		if err != nil {
+			return "", err
+		}
+		if err := s.tasks.Update(caller, run.TaskID, req); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("set %s to %s", a.Field, a.Value), nil
+
+	case ActionAddLabel:
+		label := normalizeLabel(a.Label)
+		if slices.Contains(current.Labels, label) {
+			return fmt.Sprintf("task already has label %q", label), nil
+		}
+		labels := append(slices.Clone(current.Labels), label)
+		if err := s.tasks.Update(caller, run.TaskID, task.UpdateTaskRequest{Labels: &labels}); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("added label %q", label), nil
+
+	case ActionAssign:
+		if slices.Contains(current.AssigneeIDs, a.UserID) {
+			return fmt.Sprintf("user %d is already assigned", a.UserID), nil
+		}
+		assignees := append(slices.Clone(current.AssigneeIDs), a.UserID)
+		if err := s.tasks.Update(caller, run.TaskID, task.UpdateTaskRequest{AssigneeIDs: &assignees}); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("assigned user %d", a.UserID), nil
+
+	case ActionCreateSubtask:
+		// Tasks have no hierarchy; a subtask is a task of the same project
+		// that blocks its parent.
+		req := task.CreateTaskRequest{Title: subtaskTitle(a, current), ProjectID: current.ProjectID}
+		if a.UserID != 0 {
+			req.AssigneeIDs = []int64{a.UserID}
+		}
+		subtask, err := s.tasks.Create(caller, req)
+		if err != nil {
+			return "", err
+		}
+		blockers := append(slices.Clone(current.BlockedBy), subtask.ID)
+		if err := s.tasks.Update(caller, run.TaskID, task.UpdateTaskRequest{BlockedBy: &blockers}); err != nil {
+			return fmt.Sprintf("created task %d", subtask.ID), err
+		}
+		return fmt.Sprintf("created task %d blocking this one", subtask.ID), nil
+
+	case ActionSendWebhook:
+		return s.sendWebhook(run, rule, a, current)
+
+	default:
+		ids := recipients(a, current)
+		message := notifyMessage(rule, a, current)
+		for _, userID := range ids {
+			taskID := current.ID
+			err := s.notifications.Notify(notification.Notification{
+				WorkspaceID: rule.WorkspaceID,
+				UserID:      userID,
+				Type:        notification.TypeAutomation,
+				TaskID:      &taskID,
+				Message:     message,
+				DedupeKey:   fmt.Sprintf("automation:%d:%d", run.ID, userID),
+			})
+			if err != nil {
+				return "", err
+			}
+		}
+		return "notified " + users(ids), nil
+	}
+}
+
+func (s *automationService) sendWebhook(run *Run, rule *Rule, a Action, current *task.TaskResponse) (string, error) {
+	body, err := json.Marshal(WebhookPayload{
+		RuleID:  rule.ID,
+		Rule:    rule.Name,
+		RunID:   run.ID,
+		Trigger: run.Trigger,
+		Task:    *current,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, a.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gotask-automation")
+	req.Header.Set(webhook.SignatureHeader, webhook.Sign(rule.Secret, time.Now(), body))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return "", fmt.Errorf("endpoint responded with %s", res.Status)
+	}
+	return fmt.Sprintf("posted the task to %s", a.URL), nil
+}
+
+func (s *automationService) DryRun(caller auth.Caller, req DryRunRequest) (*DryRunResult, error) {
+	if req.Rule == nil {
+		return nil, ErrRuleMissing
+	}
+	rule, err := s.build(caller, *req.Rule)
+	if err != nil {
+		return nil, err
+	}
+	return s.dryRun(caller, rule, req)
+}
+
+func (s *automationService) DryRunRule(caller auth.Caller, id int64, req DryRunRequest) (*DryRunResult, error) {
+	rule, err := s.GetByID(caller, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.dryRun(caller, rule, req)
+}
+
+// dryRun evaluates the rule against the task the request names or
+// describes, whatever its trigger, and reports what the actions would do.
+func (s *automationService) dryRun(caller auth.Caller, rule *Rule, req DryRunRequest) (*DryRunResult, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+
+	var sample task.TaskResponse
+	switch {
+	case req.TaskID != 0:
+		t, err := s.tasks.GetById(caller, req.TaskID)
+		if err != nil {
+			return nil, err
+		}
+		sample = *t
+	case req.Task != nil:
+		sample = *req.Task
+		for i, label := range sample.Labels {
+			sample.Labels[i] = normalizeLabel(label)
+		}
+	default:
+		return nil, ErrSampleMissing
+	}
+
+	condition, err := ParseCondition(rule.Condition)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := &DryRunResult{Actions: []ActionResult{}, Task: &sample, Warnings: []string{}}
+	for i, a := range rule.Actions {
+		if retriggers(rule, a) {
+			result.Warnings = append(result.Warnings, fmt.Sprintf(
+				"action %d (%s) would trigger this rule again; rules never run on changes they caused",
+				i+1, a.Type))
+		}
+	}
+
+	result.Matched = condition.Match(&sample, now)
+	if !result.Matched {
+		return result, nil
+	}
+	for _, a := range rule.Actions {
+		outcome := ActionResult{Type: a.Type}
+		if outcome.Result, err = simulate(rule, a, &sample, now); err != nil {
+			outcome.Error = err.Error()
+		}
+		result.Actions = append(result.Actions, outcome)
+	}
+	return result, nil
+}
@@ -0,0 +1,324 @@
+package calendar
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/task"
+	"github.com/sudarshanmg/gotask/pkg/ical"
+	"github.com/sudarshanmg/gotask/pkg/validation"
+)
+
+// generateAppPassword returns 120 random bits as 24 lowercase letters and
+// digits in groups of four, e.g. abcd-efgh-….
+func generateAppPassword() (string, error) {
+	bytes := make([]byte, 15)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	raw := strings.ToLower(base32.StdEncoding.EncodeToString(bytes))
+	groups := make([]string, 0, len(raw)/4)
+	for i := 0; i < len(raw); i += 4 {
+		groups = append(groups, raw[i:i+4])
+	}
+	return strings.Join(groups, "-"), nil
+}
+
+// hashAppPassword ignores dashes, spaces and case, so that passwords can be
+// typed as shown or not.
+func hashAppPassword(password string) string {
+	password = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(password))
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *calendarService) CreateAppPassword(caller auth.Caller, req CreateAppPasswordRequest) (*NewAppPassword, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+	username, err := s.repo.Username(caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := generateAppPassword()
+	if err != nil {
+		return nil, err
+	}
+
+	created := NewAppPassword{
+		AppPassword: AppPassword{WorkspaceID: caller.WorkspaceID, UserID: caller.UserID, Name: req.Name},
+		Username:    username,
+		Password:    password,
+	}
+	if err := s.repo.CreateAppPassword(&created.AppPassword, hashAppPassword(created.Password)); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (s *calendarService) GetAppPasswords(caller auth.Caller) ([]AppPassword, error) {
+	return s.repo.AppPasswords(caller.WorkspaceID, caller.UserID)
+}
+
+func (s *calendarService) DeleteAppPassword(caller auth.Caller, id int64) error {
+	if id <= 0 {
+		return ErrInvalidID
+	}
+	found, err := s.repo.DeleteAppPassword(caller.WorkspaceID, caller.UserID, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrPasswordNotFound
+	}
+	return nil
+}
+
+func (s *calendarService) Authenticate(username, password string) (*auth.Caller, error) {
+	if username == "" || password == "" {
+		return nil, ErrUnauthorized
+	}
+	p, err := s.repo.Authenticate(username, hashAppPassword(password))
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrUnauthorized
+	}
+	return &auth.Caller{UserID: p.UserID, WorkspaceID: p.WorkspaceID}, nil
+}
+
+func (s *calendarService) Collections(caller auth.Caller) ([]Collection, error) {
+	projects, err := s.projects.GetAll(caller)
+	if err != nil {
+		return nil, err
+	}
+
+	collections := []Collection{{Name: "Personal", CanWrite: true}}
+	for _, p := range projects {
+		if !p.Role.CanRead() {
+			continue
+		}
+		id := p.ID
+		collections = append(collections, Collection{ProjectID: &id, Name: p.Name, CanWrite: p.Role.CanWrite()})
+	}
+	return collections, nil
+}
+
+func (s *calendarService) Collection(caller auth.Caller, segment string) (*Collection, error) {
+	if segment != personalCollection {
+		if _, err := strconv.ParseInt(segment, 10, 64); err != nil {
+			return nil, ErrNotFound
+		}
+	}
+	collections, err := s.Collections(caller)
+	if err != nil {
+		return nil, err
+	}
+	for i := range collections {
+		if collections[i].segment() == segment {
+			return &collections[i], nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func collectionQuery(c *Collection) taskQuery {
+	return taskQuery{ProjectID: c.ProjectID, Personal: c.ProjectID == nil}
+}
+
+func (s *calendarService) CollectionTag(caller auth.Caller, c *Collection) (string, error) {
+	return s.repo.Tag(caller.WorkspaceID, caller.UserID, collectionQuery(c))
+}
+
+func (s *calendarService) Objects(caller auth.Caller, c *Collection) ([]CalendarTask, error) {
+	return s.repo.Tasks(caller.WorkspaceID, caller.UserID, collectionQuery(c))
+}
+
+// objectName is the name a task is served under.
+func (t *CalendarTask) objectName() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return fmt.Sprintf("task-%d.ics", t.ID)
+}
+
+// etag changes with every write to the task.
+func (t *CalendarTask) etag() string {
+	return `"` + strconv.FormatInt(t.ChangeSeq, 10) + `"`
+}
+
+func (s *calendarService) Object(caller auth.Caller, c *Collection, name string) (*CalendarTask, error) {
+	if !strings.HasSuffix(name, ".ics") || strings.Contains(name, "/") {
+		return nil, ErrNotFound
+	}
+	id, err := s.repo.ObjectTask(caller.WorkspaceID, name)
+	if err != nil {
+		return nil, err
+	}
+	if id == 0 {
+		if n, err := fmt.Sscanf(name, "task-%d.ics", &id); n != 1 || err != nil {
+			return nil, ErrNotFound
+		}
+	}
+
+	q := collectionQuery(c)
+	q.IDs = []int64{id}
+	tasks, err := s.repo.Tasks(caller.WorkspaceID, caller.UserID, q)
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 || tasks[0].objectName() != name {
+		return nil, ErrNotFound
+	}
+	return &tasks[0], nil
+}
+
+func (s *calendarService) ObjectData(caller auth.Caller, t *CalendarTask) ([]byte, error) {
+	loc, err := s.location(caller.UserID, "")
+	if err != nil {
+		return nil, err
+	}
+	cal := ical.NewComponent("VCALENDAR")
+	cal.Add("VERSION", "2.0", nil)
+	cal.Add("PRODID", prodID, nil)
+	cal.Components = append(cal.Components, todo(caller.WorkspaceID, t, loc))
+
+	var b bytes.Buffer
+	if err := ical.Encode(&b, cal); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// replaceRequest turns a to-do into an update that replaces every field it
+// maps onto, since a PUT sends the whole resource.
+func replaceRequest(req task.ImportTaskRequest) task.UpdateTaskRequest {
+	t := req.Task
+	labels := t.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+	return task.UpdateTaskRequest{
+		Title:         &t.Title,
+		Description:   &t.Description,
+		Completed:     &req.Completed,
+		Labels:        &labels,
+		Priority:      t.Priority,
+		ClearPriority: t.Priority == nil,
+		StartAt:       t.StartAt,
+		ClearStartAt:  t.StartAt == nil,
+		DueAt:         t.DueAt,
+		ClearDueAt:    t.DueAt == nil,
+	}
+}
+
+// checkPreconditions applies If-Match and If-None-Match: * to a resource,
+// which is nil when it does not exist.
+func checkPreconditions(existing *CalendarTask, ifMatch, ifNoneMatch string) error {
+	if ifNoneMatch == "*" && existing != nil {
+		return ErrPreconditionFailed
+	}
+	if ifMatch != "" && (existing == nil || (ifMatch != "*" && ifMatch != existing.etag())) {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
+
+// PutObject creates the task with the to-do's UID as its external ID, so a
+// client retrying a PUT does not make two tasks. Fields the to-do does not
+// have are cleared on update, and an If-Match ETag must still match when the
+// update is written.
+func (s *calendarService) PutObject(caller auth.Caller, c *Collection, name string, body io.Reader,
+	ifMatch, ifNoneMatch string) (bool, error) {
+	if !strings.HasSuffix(name, ".ics") || strings.Contains(name, "/") || len(name) > 200 {
+		return false, ErrInvalidName
+	}
+	cal, err := ical.Decode(body)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	todos := cal.Children("VTODO")
+	if !strings.EqualFold(cal.Name, "VCALENDAR") || len(todos) != 1 || len(cal.Children("VEVENT")) > 0 {
+		return false, ErrUnsupported
+	}
+	uid := todos[0].Get("UID")
+	if uid == nil || strings.TrimSpace(uid.Value) == "" {
+		return false, fmt.Errorf("%w: missing UID", ErrInvalidFile)
+	}
+
+	existing, err := s.Object(caller, c, name)
+	if err != nil && err != ErrNotFound {
+		return false, err
+	}
+	if err := checkPreconditions(existing, ifMatch, ifNoneMatch); err != nil {
+		return false, err
+	}
+	loc, err := s.location(caller.UserID, "")
+	if err != nil {
+		return false, err
+	}
+	req, err := readTodo(todos[0], c.ProjectID, loc)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+
+	if existing != nil {
+		// The update only applies at the version If-Match was checked
+		// against, so two PUTs with the same ETag cannot both succeed.
+		update := replaceRequest(req)
+		if ifMatch != "" && ifMatch != "*" {
+			update.IfChangeSeq = &existing.ChangeSeq
+		}
+		err := s.tasks.Update(caller, existing.ID, update)
+		if errors.Is(err, task.ErrChanged) {
+			return false, ErrPreconditionFailed
+		}
+		return false, err
+	}
+	if id, err := s.repo.ObjectTask(caller.WorkspaceID, name); err != nil {
+		return false, err
+	} else if id != 0 {
+		return false, ErrConflict
+	}
+
+	res, created, err := s.tasks.Import(caller, req, false)
+	if err != nil {
+		return false, err
+	}
+	if !created {
+		// The to-do was put before under another name.
+		if !sameProject(res.ProjectID, c.ProjectID) {
+			return false, ErrConflict
+		}
+		if err := s.tasks.Update(caller, res.ID, replaceRequest(req)); err != nil {
+			return false, err
+		}
+	}
+	return true, s.repo.SaveObject(caller.WorkspaceID, res.ID, name, strings.TrimSpace(uid.Value))
+}
+
+func sameProject(a, b *int64) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+func (s *calendarService) DeleteObject(caller auth.Caller, c *Collection, name, ifMatch string) error {
+	existing, err := s.Object(caller, c, name)
+	if err != nil {
+		return err
+	}
+	if err := checkPreconditions(existing, ifMatch, ""); err != nil {
+		return err
+	}
+	return s.tasks.Delete(caller, existing.ID)
+}
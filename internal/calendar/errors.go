@@ -0,0 +1,21 @@
+package calendar
+
+import "errors"
+
+var (
+	ErrNotFound        = errors.New("calendar feed not found")
+	ErrInvalidFilter   = errors.New("invalid feed filter")
+	ErrInvalidType     = errors.New("type must be event or todo")
+	ErrInvalidTimezone = errors.New("invalid time zone")
+	ErrInvalidFile     = errors.New("invalid iCalendar file")
+	ErrEmptyFile       = errors.New("iCalendar file has no to-dos")
+	ErrTooManyItems    = errors.New("iCalendar file has too many to-dos")
+
+	ErrPasswordNotFound   = errors.New("app password not found")
+	ErrInvalidID          = errors.New("invalid ID format")
+	ErrUnauthorized       = errors.New("invalid username or app password")
+	ErrInvalidName        = errors.New("resource names must end in .ics")
+	ErrUnsupported        = errors.New("calendar resources must hold a single VTODO")
+	ErrPreconditionFailed = errors.New("resource was changed or already exists")
+	ErrConflict           = errors.New("resource name is used in another calendar")
+)
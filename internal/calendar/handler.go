@@ -0,0 +1,213 @@
+package calendar
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/authz"
+	"github.com/sudarshanmg/gotask/pkg/response"
+)
+
+const (
+	// maxImportBytes bounds an uploaded iCalendar file.
+	maxImportBytes = 20 << 20
+	// importMemory is how much of an upload is kept in memory; the rest
+	// goes to a temporary file.
+	importMemory = 4 << 20
+)
+
+type Handler struct {
+	service CalendarService
+	policy  authz.Policy
+}
+
+func NewHandler(service CalendarService, policy authz.Policy) *Handler {
+	return &Handler{service: service, policy: policy}
+}
+
+func writeServiceError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidFilter), errors.Is(err, ErrInvalidType), errors.Is(err, ErrInvalidTimezone),
+		errors.Is(err, ErrInvalidFile), errors.Is(err, ErrEmptyFile),
+		strings.HasPrefix(err.Error(), "validation failed:"):
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrTooManyItems):
+		response.WriteError(w, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, ErrInvalidID):
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrPasswordNotFound):
+		response.WriteError(w, http.StatusNotFound, err.Error())
+	default:
+		response.WriteError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+func (h *Handler) CreateFeed(w http.ResponseWriter, r *http.Request) {
+	feed, err := h.service.CreateFeed(auth.GetCaller(r))
+	if err != nil {
+		writeServiceError(w, err, "failed to create calendar feed")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, feed)
+}
+
+func (h *Handler) GetFeed(w http.ResponseWriter, r *http.Request) {
+	feed, err := h.service.GetFeed(auth.GetCaller(r))
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch calendar feed")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, feed)
+}
+
+func (h *Handler) DeleteFeed(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteFeed(auth.GetCaller(r)); err != nil {
+		writeServiceError(w, err, "failed to delete calendar feed")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseFeedOptions reads ?project={id}, ?label=, ?completed=true|false,
+// ?type=event|todo and ?tz=.
+func parseFeedOptions(r *http.Request) (FeedOptions, error) {
+	q := r.URL.Query()
+	opts := FeedOptions{Type: EntryType(q.Get("type")), Timezone: q.Get("tz")}
+	if v := q.Get("project"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			return opts, ErrInvalidFilter
+		}
+		opts.ProjectID = &id
+	}
+	if v := q.Get("label"); v != "" {
+		opts.Label = &v
+	}
+	if v := q.Get("completed"); v != "" {
+		completed, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, ErrInvalidFilter
+		}
+		opts.Completed = &completed
+	}
+	return opts, nil
+}
+
+// ViewFeed serves the calendar of the token's owner. It is public: the
+// token is the only credential.
+func (h *Handler) ViewFeed(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseFeedOptions(r)
+	if err != nil {
+		writeServiceError(w, err, "")
+		return
+	}
+
+	// The calendar is built in memory so that an error can still be
+	// reported as such.
+	var body bytes.Buffer
+	if err := h.service.Render(chi.URLParam(r, "token"), opts, &body); err != nil {
+		writeServiceError(w, err, "failed to build calendar")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="tasks.ics"`)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body.Bytes())
+}
+
+// Import creates tasks from the VTODOs of an uploaded .ics file in "file".
+// "project_id" adds them to a project, "tz" is the zone of floating times
+// and "dry_run=true" only checks them.
+func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
+	if !authz.Authorize(w, r, h.policy, authz.ActionCreate, authz.Resource{Type: authz.ResourceTask}) {
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	if err := r.ParseMultipartForm(importMemory); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "expected a multipart form with a file")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "file is required")
+		return
+	}
+	defer file.Close()
+
+	opts := ImportOptions{DryRun: r.FormValue("dry_run") == "true", Timezone: r.FormValue("tz")}
+	if v := r.FormValue("project_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			response.WriteError(w, http.StatusBadRequest, "invalid project_id")
+			return
+		}
+		opts.ProjectID = &id
+	}
+
+	report, err := h.service.Import(auth.GetCaller(r), file, opts)
+	if err != nil {
+		writeServiceError(w, err, "failed to import calendar")
+		return
+	}
+
+	status := http.StatusCreated
+	if opts.DryRun {
+		status = http.StatusOK
+	}
+	response.WriteJSON(w, status, report)
+}
+
+// CreateAppPassword returns a new password for CalDAV clients. It is shown
+// only in this response.
+func (h *Handler) CreateAppPassword(w http.ResponseWriter, r *http.Request) {
+	var req CreateAppPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request payload")
+		return
+	}
+
+	created, err := h.service.CreateAppPassword(auth.GetCaller(r), req)
+	if err != nil {
+		writeServiceError(w, err, "failed to create app password")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, created)
+}
+
+func (h *Handler) GetAppPasswords(w http.ResponseWriter, r *http.Request) {
+	passwords, err := h.service.GetAppPasswords(auth.GetCaller(r))
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch app passwords")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, passwords)
+}
+
+func (h *Handler) DeleteAppPassword(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeServiceError(w, ErrInvalidID, "")
+		return
+	}
+
+	if err := h.service.DeleteAppPassword(auth.GetCaller(r), id); err != nil {
+		writeServiceError(w, err, "failed to delete app password")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
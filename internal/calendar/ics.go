@@ -0,0 +1,220 @@
+package calendar
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sudarshanmg/gotask/internal/task"
+	"github.com/sudarshanmg/gotask/pkg/ical"
+)
+
+const prodID = "-//gotask//Tasks//EN"
+
+// uid is a task's stable UID, so that calendar apps update the entry
+// rather than adding another one when the task changes. Tasks created
+// over CalDAV keep the client's UID.
+func (t *CalendarTask) uid(workspaceID int64) string {
+	if t.UID != "" {
+		return t.UID
+	}
+	return fmt.Sprintf("task-%d-%d@gotask", workspaceID, t.ID)
+}
+
+// allDay reports whether the task is due at midnight in loc, and starts at
+// midnight if it has a start: such tasks are shown as whole days.
+func (t *CalendarTask) allDay(loc *time.Location) bool {
+	midnight := func(at time.Time) bool {
+		at = at.In(loc)
+		return at.Hour() == 0 && at.Minute() == 0 && at.Second() == 0
+	}
+	return t.DueAt != nil && midnight(*t.DueAt) && (t.StartAt == nil || midnight(*t.StartAt))
+}
+
+// icalPriority maps priorities 1 to 4 onto iCalendar's 1 (highest), 3, 5
+// (medium) and 9 (lowest).
+func icalPriority(priority int) int {
+	return []int{1, 3, 5, 9}[priority-1]
+}
+
+// taskPriority maps an iCalendar priority back: 1 is 1, 2 to 4 are 2,
+// 5 is 3 and 6 to 9 are 4. 0 is no priority.
+func taskPriority(priority int) *int {
+	var p int
+	switch {
+	case priority == 1:
+		p = 1
+	case priority >= 2 && priority <= 4:
+		p = 2
+	case priority == 5:
+		p = 3
+	case priority >= 6 && priority <= 9:
+		p = 4
+	default:
+		return nil
+	}
+	return &p
+}
+
+// addDate adds a DATE for all-day tasks and a UTC DATE-TIME otherwise.
+func addDate(c *ical.Component, name string, at time.Time, allDay bool, loc *time.Location) {
+	if allDay {
+		c.AddDate(name, at.In(loc))
+		return
+	}
+	c.AddTime(name, at)
+}
+
+func addCommon(c *ical.Component, workspaceID int64, t *CalendarTask) {
+	c.Add("UID", t.uid(workspaceID), nil)
+	c.AddTime("DTSTAMP", t.UpdatedAt)
+	c.AddTime("CREATED", t.CreatedAt)
+	c.AddTime("LAST-MODIFIED", t.UpdatedAt)
+	c.AddText("SUMMARY", t.Title)
+	if t.Description != "" {
+		c.AddText("DESCRIPTION", t.Description)
+	}
+	if len(t.Labels) > 0 {
+		c.Add("CATEGORIES", ical.JoinText(t.Labels), nil)
+	}
+	if t.Priority != nil {
+		c.Add("PRIORITY", strconv.Itoa(icalPriority(*t.Priority)), nil)
+	}
+}
+
+// todo renders the task as a VTODO.
+func todo(workspaceID int64, t *CalendarTask, loc *time.Location) *ical.Component {
+	c := ical.NewComponent("VTODO")
+	addCommon(c, workspaceID, t)
+	allDay := t.allDay(loc)
+	if t.StartAt != nil {
+		addDate(c, "DTSTART", *t.StartAt, allDay, loc)
+	}
+	if t.DueAt != nil {
+		addDate(c, "DUE", *t.DueAt, allDay, loc)
+	}
+	if t.Completed {
+		c.Add("STATUS", "COMPLETED", nil)
+		c.Add("PERCENT-COMPLETE", "100", nil)
+		if t.CompletedAt != nil {
+			c.AddTime("COMPLETED", *t.CompletedAt)
+		}
+	} else {
+		c.Add("STATUS", "NEEDS-ACTION", nil)
+	}
+	return c
+}
+
+// event renders a task with a due date as a VEVENT from its start, if it
+// has one, to its due date. Without a start the event takes no time;
+// all-day events end the day after they are due.
+func event(workspaceID int64, t *CalendarTask, loc *time.Location) *ical.Component {
+	c := ical.NewComponent("VEVENT")
+	addCommon(c, workspaceID, t)
+	due := *t.DueAt
+	start := due
+	if t.StartAt != nil {
+		start = *t.StartAt
+	}
+	if t.allDay(loc) {
+		c.AddDate("DTSTART", start.In(loc))
+		c.AddDate("DTEND", due.In(loc).AddDate(0, 0, 1))
+	} else {
+		c.AddTime("DTSTART", start)
+		if start.Before(due) {
+			c.AddTime("DTEND", due)
+		}
+	}
+	c.Add("TRANSP", "TRANSPARENT", nil)
+	return c
+}
+
+// writeFeed writes the tasks as a calendar. Times are in UTC, which every
+// app converts to its own zone; X-WR-TIMEZONE names the zone whole days
+// were worked out in.
+func writeFeed(w io.Writer, workspaceID int64, tasks []CalendarTask, typ EntryType, loc *time.Location) error {
+	cal := ical.NewComponent("VCALENDAR")
+	cal.Add("VERSION", "2.0", nil)
+	cal.Add("PRODID", prodID, nil)
+	cal.Add("CALSCALE", "GREGORIAN", nil)
+	cal.Add("METHOD", "PUBLISH", nil)
+	cal.AddText("X-WR-CALNAME", "Tasks")
+	cal.AddText("X-WR-TIMEZONE", loc.String())
+	cal.Add("REFRESH-INTERVAL", "PT1H", map[string]string{"VALUE": "DURATION"})
+	cal.Add("X-PUBLISHED-TTL", "PT1H", nil)
+	for i := range tasks {
+		if typ == EntryTodo {
+			cal.Components = append(cal.Components, todo(workspaceID, &tasks[i], loc))
+		} else {
+			cal.Components = append(cal.Components, event(workspaceID, &tasks[i], loc))
+		}
+	}
+	return ical.Encode(w, cal)
+}
+
+// externalID keeps a to-do's UID as the task's external ID, hashing UIDs
+// too long to store.
+func externalID(uid string) string {
+	if uid == "" {
+		return ""
+	}
+	if len(uid) > 190 {
+		sum := sha1.Sum([]byte(uid))
+		uid = hex.EncodeToString(sum[:])
+	}
+	return "ical:" + uid
+}
+
+// readTodo maps a VTODO onto a task import. Dates, and times without a
+// zone, are read in loc.
+func readTodo(c *ical.Component, projectID *int64, loc *time.Location) (task.ImportTaskRequest, error) {
+	req := task.ImportTaskRequest{Task: task.CreateTaskRequest{ProjectID: projectID}}
+	if p := c.Get("UID"); p != nil {
+		req.ExternalID = externalID(strings.TrimSpace(p.Value))
+	}
+	if p := c.Get("SUMMARY"); p != nil {
+		req.Task.Title = strings.TrimSpace(p.Text())
+	}
+	if p := c.Get("DESCRIPTION"); p != nil {
+		req.Task.Description = strings.TrimSpace(p.Text())
+	}
+	for _, name := range []string{"DTSTART", "DUE"} {
+		p := c.Get(name)
+		if p == nil {
+			continue
+		}
+		at, _, err := p.Time(loc)
+		if err != nil {
+			return req, fmt.Errorf("invalid %s", name)
+		}
+		at = at.UTC()
+		if name == "DUE" {
+			req.Task.DueAt = &at
+		} else {
+			req.Task.StartAt = &at
+		}
+	}
+	if p := c.Get("STATUS"); p != nil && strings.EqualFold(p.Value, "COMPLETED") {
+		req.Completed = true
+	}
+	if c.Get("COMPLETED") != nil {
+		req.Completed = true
+	}
+	if p := c.Get("PRIORITY"); p != nil {
+		if priority, err := strconv.Atoi(strings.TrimSpace(p.Value)); err == nil {
+			req.Task.Priority = taskPriority(priority)
+		}
+	}
+	for _, p := range c.All("CATEGORIES") {
+		for _, label := range ical.SplitText(p.Value) {
+			if label = strings.TrimSpace(label); label != "" {
+				req.Task.Labels = append(req.Task.Labels, label)
+			}
+		}
+	}
+	return req, nil
+}
@@ -0,0 +1,85 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sudarshanmg/gotask/pkg/ical"
+)
+
+func decodeTodo(t *testing.T, lines ...string) *ical.Component {
+	t.Helper()
+	src := "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\n" + strings.Join(lines, "\r\n") + "\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+	cal, err := ical.Decode(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cal.Children("VTODO")[0]
+}
+
+func TestReadTodo(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := readTodo(decodeTodo(t,
+		"UID:abc@example.com",
+		`SUMMARY: Call Alice\, then Bob `,
+		"DESCRIPTION:line one\\nline two",
+		"DTSTART;VALUE=DATE:20240301",
+		"DUE;TZID=Europe/London:20240302T090000",
+		"PRIORITY:5",
+		"CATEGORIES:work,calls",
+		"CATEGORIES:urgent",
+		"STATUS:COMPLETED",
+	), nil, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.ExternalID != "ical:abc@example.com" {
+		t.Errorf("ExternalID = %q", req.ExternalID)
+	}
+	if req.Task.Title != "Call Alice, then Bob" || req.Task.Description != "line one\nline two" {
+		t.Errorf("Title = %q, Description = %q", req.Task.Title, req.Task.Description)
+	}
+	if want := time.Date(2024, 3, 1, 5, 0, 0, 0, time.UTC); req.Task.StartAt == nil || !req.Task.StartAt.Equal(want) {
+		t.Errorf("StartAt = %v, want %v", req.Task.StartAt, want)
+	}
+	if want := time.Date(2024, 3, 2, 9, 0, 0, 0, time.UTC); req.Task.DueAt == nil || !req.Task.DueAt.Equal(want) {
+		t.Errorf("DueAt = %v, want %v", req.Task.DueAt, want)
+	}
+	if req.Task.Priority == nil || *req.Task.Priority != 3 {
+		t.Errorf("Priority = %v, want 3", req.Task.Priority)
+	}
+	if got := strings.Join(req.Task.Labels, ","); got != "work,calls,urgent" {
+		t.Errorf("Labels = %q", got)
+	}
+	if !req.Completed {
+		t.Error("Completed = false")
+	}
+
+	if _, err := readTodo(decodeTodo(t, "SUMMARY:x", "DUE:next week"), nil, loc); err == nil {
+		t.Error("invalid DUE was accepted")
+	}
+}
+
+func TestPriorityMapping(t *testing.T) {
+	tests := []struct {
+		ical int
+		want int
+	}{{1, 1}, {2, 2}, {4, 2}, {5, 3}, {6, 4}, {9, 4}, {0, 0}, {10, 0}}
+	for _, tt := range tests {
+		got := taskPriority(tt.ical)
+		if (got == nil) != (tt.want == 0) || (got != nil && *got != tt.want) {
+			t.Errorf("taskPriority(%d) = %v, want %d", tt.ical, got, tt.want)
+		}
+	}
+	for priority := 1; priority <= 4; priority++ {
+		if back := taskPriority(icalPriority(priority)); back == nil || *back != priority {
+			t.Errorf("priority %d does not round trip: %v", priority, back)
+		}
+	}
+}
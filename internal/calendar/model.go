@@ -0,0 +1,156 @@
+package calendar
+
+import (
+	"strconv"
+	"time"
+)
+
+// Feed is a user's secret calendar URL for one workspace.
+type Feed struct {
+	ID          int64
+	WorkspaceID int64
+	UserID      int64
+	Token       string
+	CreatedAt   time.Time
+	LastUsedAt  *time.Time
+}
+
+type FeedResponse struct {
+	// URL is the feed's path; add ?project=, ?label=, ?type= or ?tz= to
+	// narrow or adjust it.
+	URL        string     `json:"url"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// EntryType is the calendar component a task becomes.
+type EntryType string
+
+const (
+	// EntryEvent makes tasks VEVENTs, which every calendar app shows.
+	EntryEvent EntryType = "event"
+	// EntryTodo makes tasks VTODOs, for apps with a task list.
+	EntryTodo EntryType = "todo"
+)
+
+type FeedOptions struct {
+	ProjectID *int64
+	Label     *string
+	Completed *bool
+	Type      EntryType
+	// Timezone decides which tasks are all-day: those due at midnight in
+	// it. Defaults to the user's time zone.
+	Timezone string
+}
+
+// CalendarTask is a task as shown in a feed or a CalDAV collection. Name
+// and UID are those a CalDAV client chose for a task it created, or empty.
+type CalendarTask struct {
+	ID          int64
+	ProjectID   *int64
+	Title       string
+	Description string
+	Completed   bool
+	Labels      []string
+	Priority    *int
+	StartAt     *time.Time
+	DueAt       *time.Time
+	CompletedAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	ChangeSeq   int64
+	Name        string
+	UID         string
+}
+
+// taskQuery selects the tasks a user can read. Personal keeps only their
+// personal tasks; otherwise ProjectID, if set, keeps one project's.
+type taskQuery struct {
+	ProjectID *int64
+	Personal  bool
+	Label     *string
+	Completed *bool
+	// HasDue keeps tasks with a due date.
+	HasDue bool
+	// IDs keeps the given tasks.
+	IDs []int64
+}
+
+type ImportOptions struct {
+	// ProjectID is the project the to-dos are added to; without one they
+	// become personal tasks.
+	ProjectID *int64
+	DryRun    bool
+	// Timezone is used for dates and for times without a zone.
+	Timezone string
+}
+
+type ItemStatus string
+
+const (
+	ItemCreated ItemStatus = "created"
+	// ItemValid is reported instead of ItemCreated on a dry run.
+	ItemValid ItemStatus = "valid"
+	// ItemExisting to-dos were imported before under the same UID.
+	ItemExisting ItemStatus = "existing"
+	ItemInvalid  ItemStatus = "invalid"
+)
+
+// ItemResult reports one VTODO of an upload.
+type ItemResult struct {
+	UID    string     `json:"uid"`
+	Title  string     `json:"title"`
+	Status ItemStatus `json:"status"`
+	TaskID *int64     `json:"task_id,omitempty"`
+	Error  string     `json:"error,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun   bool         `json:"dry_run"`
+	Total    int          `json:"total"`
+	Created  int          `json:"created"`
+	Existing int          `json:"existing"`
+	Invalid  int          `json:"invalid"`
+	Items    []ItemResult `json:"items"`
+}
+
+// AppPassword lets an app such as a CalDAV client sign in as the user, in
+// one workspace, with HTTP Basic auth.
+type AppPassword struct {
+	ID          int64      `json:"id"`
+	WorkspaceID int64      `json:"workspace_id"`
+	UserID      int64      `json:"user_id"`
+	Name        string     `json:"name"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+}
+
+type CreateAppPasswordRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// NewAppPassword is returned once, when the password is created; only its
+// hash is stored.
+type NewAppPassword struct {
+	AppPassword
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Collection is a CalDAV calendar: a project, or the user's personal
+// tasks when ProjectID is nil.
+type Collection struct {
+	ProjectID *int64
+	Name      string
+	CanWrite  bool
+}
+
+// segment is the collection's name in CalDAV paths.
+func (c *Collection) segment() string {
+	if c.ProjectID == nil {
+		return personalCollection
+	}
+	return strconv.FormatInt(*c.ProjectID, 10)
+}
+
+const personalCollection = "personal"
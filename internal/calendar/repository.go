@@ -0,0 +1,267 @@
+package calendar
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/sudarshanmg/gotask/pkg/db"
+)
+
+// CalendarRepository stores feeds and app passwords, which are not
+// tenant-scoped, and reads tasks within a workspace under db.WithTenant.
+type CalendarRepository interface {
+	// Save creates the user's feed, or gives it a new token.
+	Save(feed *Feed) error
+	Find(workspaceID, userID int64) (*Feed, error)
+	Delete(workspaceID, userID int64) error
+	// FindByToken returns the feed and records that it was used.
+	FindByToken(token string) (*Feed, error)
+	// Tasks returns the tasks the user can read that match the query, as
+	// long as they are still a member of the workspace.
+	Tasks(workspaceID, userID int64, q taskQuery) ([]CalendarTask, error)
+	// Tag changes whenever a task matching the query is created, changed
+	// or removed.
+	Tag(workspaceID, userID int64, q taskQuery) (string, error)
+	// ObjectTask returns the task a CalDAV client stored under the name,
+	// or 0.
+	ObjectTask(workspaceID int64, name string) (int64, error)
+	// SaveObject records the name and UID a CalDAV client gave a task.
+	SaveObject(workspaceID, taskID int64, name, uid string) error
+
+	CreateAppPassword(p *AppPassword, hash string) error
+	AppPasswords(workspaceID, userID int64) ([]AppPassword, error)
+	// DeleteAppPassword reports whether the password existed.
+	DeleteAppPassword(workspaceID, userID, id int64) (bool, error)
+	// Authenticate returns the password with the hash of the user with the
+	// username, recording that it was used, or nil.
+	Authenticate(username, hash string) (*AppPassword, error)
+	Username(userID int64) (string, error)
+	// Timezone returns the user's preferred time zone, or "" if they have
+	// not chosen one.
+	Timezone(userID int64) (string, error)
+}
+
+type PostgresCalendarRepository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) CalendarRepository {
+	return &PostgresCalendarRepository{DB: db}
+}
+
+const feedColumns = `id, workspace_id, user_id, token, created_at, last_used_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanFeed(row rowScanner) (*Feed, error) {
+	feed := Feed{}
+	err := row.Scan(&feed.ID, &feed.WorkspaceID, &feed.UserID, &feed.Token, &feed.CreatedAt, &feed.LastUsedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+func (r *PostgresCalendarRepository) Save(feed *Feed) error {
+	query := `INSERT INTO calendar_feeds (workspace_id, user_id, token)
+            VALUES ($1, $2, $3)
+            ON CONFLICT (workspace_id, user_id)
+            DO UPDATE SET token = EXCLUDED.token, created_at = NOW(), last_used_at = NULL
+            RETURNING ` + feedColumns + `;`
+
+	saved, err := scanFeed(r.DB.QueryRow(query, feed.WorkspaceID, feed.UserID, feed.Token))
+	if err != nil {
+		return err
+	}
+	*feed = *saved
+	return nil
+}
+
+func (r *PostgresCalendarRepository) Find(workspaceID, userID int64) (*Feed, error) {
+	query := `SELECT ` + feedColumns + ` FROM calendar_feeds WHERE workspace_id = $1 AND user_id = $2;`
+	return scanFeed(r.DB.QueryRow(query, workspaceID, userID))
+}
+
+func (r *PostgresCalendarRepository) Delete(workspaceID, userID int64) error {
+	_, err := r.DB.Exec(`DELETE FROM calendar_feeds WHERE workspace_id = $1 AND user_id = $2;`,
+		workspaceID, userID)
+	return err
+}
+
+func (r *PostgresCalendarRepository) FindByToken(token string) (*Feed, error) {
+	query := `UPDATE calendar_feeds SET last_used_at = NOW()
+            WHERE token = $1
+            RETURNING ` + feedColumns + `;`
+	return scanFeed(r.DB.QueryRow(query, token))
+}
+
+// taskConditions select the tasks of a taskQuery, with the workspace in
+// $1, the user in $2 and the query from $3.
+const taskConditions = `t.workspace_id = $1
+            AND ((t.project_id IS NULL AND t.created_by = $2)
+              OR t.project_id IN (SELECT project_id FROM project_members WHERE user_id = $2))
+            AND EXISTS (SELECT 1 FROM workspace_members m WHERE m.workspace_id = $1 AND m.user_id = $2)
+            AND ($3::int IS NULL OR t.project_id = $3)
+            AND (NOT $4 OR t.project_id IS NULL)
+            AND ($5::text IS NULL OR $5 = ANY(t.labels))
+            AND ($6::boolean IS NULL OR t.completed = $6)
+            AND (NOT $7 OR t.due_at IS NOT NULL)
+            AND ($8::int[] IS NULL OR t.id = ANY($8))`
+
+func queryArgs(workspaceID, userID int64, q taskQuery) []any {
+	return []any{workspaceID, userID, q.ProjectID, q.Personal, q.Label, q.Completed, q.HasDue, pq.Array(q.IDs)}
+}
+
+func (r *PostgresCalendarRepository) Tasks(workspaceID, userID int64, q taskQuery) ([]CalendarTask, error) {
+	query := `
+          SELECT t.id, t.project_id, t.title, t.description, t.completed, t.labels, t.priority,
+                 t.start_at, t.due_at, t.completed_at, t.created_at, t.updated_at, t.change_seq,
+                 COALESCE(o.name, ''), COALESCE(o.uid, '')
+          FROM tasks t
+          LEFT JOIN caldav_objects o ON o.task_id = t.id
+          WHERE ` + taskConditions + `
+          ORDER BY t.due_at NULLS LAST, t.id;`
+
+	tasks := []CalendarTask{}
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, queryArgs(workspaceID, userID, q)...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			t := CalendarTask{}
+			err := rows.Scan(&t.ID, &t.ProjectID, &t.Title, &t.Description, &t.Completed, pq.Array(&t.Labels),
+				&t.Priority, &t.StartAt, &t.DueAt, &t.CompletedAt, &t.CreatedAt, &t.UpdatedAt, &t.ChangeSeq,
+				&t.Name, &t.UID)
+			if err != nil {
+				return err
+			}
+			tasks = append(tasks, t)
+		}
+		return rows.Err()
+	})
+	return tasks, err
+}
+
+// Tag combines the latest change with the number of tasks, so that tasks
+// deleted or moved away change it too.
+func (r *PostgresCalendarRepository) Tag(workspaceID, userID int64, q taskQuery) (string, error) {
+	query := `SELECT COALESCE(MAX(t.change_seq), 0), COUNT(*) FROM tasks t WHERE ` + taskConditions + `;`
+
+	var seq, count int64
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		return tx.QueryRow(query, queryArgs(workspaceID, userID, q)...).Scan(&seq, &count)
+	})
+	return fmt.Sprintf("%d-%d", seq, count), err
+}
+
+func (r *PostgresCalendarRepository) ObjectTask(workspaceID int64, name string) (int64, error) {
+	query := `SELECT task_id FROM caldav_objects WHERE workspace_id = $1 AND name = $2;`
+
+	var id int64
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		err := tx.QueryRow(query, workspaceID, name).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	})
+	return id, err
+}
+
+func (r *PostgresCalendarRepository) SaveObject(workspaceID, taskID int64, name, uid string) error {
+	query := `INSERT INTO caldav_objects (task_id, workspace_id, name, uid)
+            VALUES ($1, $2, $3, $4)
+            ON CONFLICT (task_id) DO UPDATE SET name = EXCLUDED.name, uid = EXCLUDED.uid;`
+
+	return db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		_, err := tx.Exec(query, taskID, workspaceID, name, uid)
+		return err
+	})
+}
+
+const appPasswordColumns = `id, workspace_id, user_id, name, created_at, last_used_at`
+
+func scanAppPassword(row rowScanner, p *AppPassword) error {
+	return row.Scan(&p.ID, &p.WorkspaceID, &p.UserID, &p.Name, &p.CreatedAt, &p.LastUsedAt)
+}
+
+func (r *PostgresCalendarRepository) CreateAppPassword(p *AppPassword, hash string) error {
+	query := `INSERT INTO app_passwords (workspace_id, user_id, name, password_hash)
+            VALUES ($1, $2, $3, $4)
+            RETURNING ` + appPasswordColumns + `;`
+	return scanAppPassword(r.DB.QueryRow(query, p.WorkspaceID, p.UserID, p.Name, hash), p)
+}
+
+func (r *PostgresCalendarRepository) AppPasswords(workspaceID, userID int64) ([]AppPassword, error) {
+	query := `SELECT ` + appPasswordColumns + ` FROM app_passwords
+            WHERE workspace_id = $1 AND user_id = $2
+            ORDER BY id;`
+
+	rows, err := r.DB.Query(query, workspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	passwords := []AppPassword{}
+	for rows.Next() {
+		p := AppPassword{}
+		if err := scanAppPassword(rows, &p); err != nil {
+			return nil, err
+		}
+		passwords = append(passwords, p)
+	}
+	return passwords, rows.Err()
+}
+
+func (r *PostgresCalendarRepository) DeleteAppPassword(workspaceID, userID, id int64) (bool, error) {
+	res, err := r.DB.Exec(`DELETE FROM app_passwords WHERE workspace_id = $1 AND user_id = $2 AND id = $3;`,
+		workspaceID, userID, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *PostgresCalendarRepository) Authenticate(username, hash string) (*AppPassword, error) {
+	query := `UPDATE app_passwords p SET last_used_at = NOW()
+            FROM users u
+            WHERE u.id = p.user_id AND p.password_hash = $1 AND u.username = $2
+            RETURNING p.id, p.workspace_id, p.user_id, p.name, p.created_at, p.last_used_at;`
+
+	p := AppPassword{}
+	err := scanAppPassword(r.DB.QueryRow(query, hash, username), &p)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *PostgresCalendarRepository) Timezone(userID int64) (string, error) {
+	var tz string
+	err := r.DB.QueryRow(`SELECT timezone FROM digest_settings WHERE user_id = $1;`, userID).Scan(&tz)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return tz, err
+}
+
+func (r *PostgresCalendarRepository) Username(userID int64) (string, error) {
+	var username string
+	err := r.DB.QueryRow(`SELECT username FROM users WHERE id = $1;`, userID).Scan(&username)
+	return username, err
+}
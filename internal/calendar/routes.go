@@ -0,0 +1,34 @@
+package calendar
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Route("/calendar", func(r chi.Router) {
+		r.Get("/feed", h.GetFeed)
+		r.Post("/feed", h.CreateFeed)
+		r.Delete("/feed", h.DeleteFeed)
+		r.Post("/import", h.Import)
+	})
+	r.Route("/app-passwords", func(r chi.Router) {
+		r.Get("/", h.GetAppPasswords)
+		r.Post("/", h.CreateAppPassword)
+		r.Delete("/{id}", h.DeleteAppPassword)
+	})
+}
+
+// RegisterPublicRoutes mounts the calendar feed, which apps fetch without
+// a JWT, and CalDAV discovery; it must stay outside the AuthMiddleware
+// group.
+func RegisterPublicRoutes(r chi.Router, h *Handler) {
+	r.Get("/cal/{token}.ics", h.ViewFeed)
+	r.HandleFunc("/.well-known/caldav", h.WellKnown)
+}
+
+// RegisterDAVRoutes mounts the CalDAV server. It goes in a group using
+// BasicAuth instead of AuthMiddleware.
+func RegisterDAVRoutes(r chi.Router, h *Handler) {
+	r.HandleFunc("/dav", h.DAV)
+	r.HandleFunc("/dav/*", h.DAV)
+}
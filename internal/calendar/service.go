@@ -0,0 +1,249 @@
+package calendar
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/project"
+	"github.com/sudarshanmg/gotask/internal/task"
+	"github.com/sudarshanmg/gotask/pkg/ical"
+)
+
+var validate = validator.New()
+
+// maxImportItems bounds the to-dos of one upload.
+const maxImportItems = 5000
+
+type CalendarService interface {
+	// CreateFeed gives the caller a feed URL, replacing any earlier one.
+	CreateFeed(caller auth.Caller) (*FeedResponse, error)
+	GetFeed(caller auth.Caller) (*FeedResponse, error)
+	DeleteFeed(caller auth.Caller) error
+	// Render writes the feed of the token's owner to w.
+	Render(token string, opts FeedOptions, w io.Writer) error
+	// Import creates a task for each VTODO of an iCalendar file.
+	Import(caller auth.Caller, file io.Reader, opts ImportOptions) (*ImportReport, error)
+
+	CreateAppPassword(caller auth.Caller, req CreateAppPasswordRequest) (*NewAppPassword, error)
+	GetAppPasswords(caller auth.Caller) ([]AppPassword, error)
+	DeleteAppPassword(caller auth.Caller, id int64) error
+	// Authenticate returns the caller an app password signs in as.
+	Authenticate(username, password string) (*auth.Caller, error)
+
+	// Collections lists the calendars the caller can read: their personal
+	// tasks, then each of their projects.
+	Collections(caller auth.Caller) ([]Collection, error)
+	// Collection finds a calendar by its path segment.
+	Collection(caller auth.Caller, segment string) (*Collection, error)
+	CollectionTag(caller auth.Caller, c *Collection) (string, error)
+	Objects(caller auth.Caller, c *Collection) ([]CalendarTask, error)
+	// Object finds a calendar resource by name, or returns ErrNotFound.
+	Object(caller auth.Caller, c *Collection, name string) (*CalendarTask, error)
+	// ObjectData renders a task as a calendar resource.
+	ObjectData(caller auth.Caller, t *CalendarTask) ([]byte, error)
+	// PutObject creates or replaces a task from a calendar resource.
+	// ifMatch and ifNoneMatch are the request's preconditions.
+	PutObject(caller auth.Caller, c *Collection, name string, body io.Reader, ifMatch, ifNoneMatch string) (bool, error)
+	DeleteObject(caller auth.Caller, c *Collection, name, ifMatch string) error
+}
+
+type calendarService struct {
+	repo     CalendarRepository
+	tasks    task.TaskService
+	projects project.ProjectService
+}
+
+func NewService(repo CalendarRepository, tasks task.TaskService, projects project.ProjectService) CalendarService {
+	return &calendarService{repo: repo, tasks: tasks, projects: projects}
+}
+
+// generateFeedToken returns 256 bits of randomness, URL-safe so it can be
+// used directly as a path segment.
+func generateFeedToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+func mapFeedToResponse(f *Feed) *FeedResponse {
+	return &FeedResponse{
+		URL:        "/cal/" + f.Token + ".ics",
+		CreatedAt:  f.CreatedAt,
+		LastUsedAt: f.LastUsedAt,
+	}
+}
+
+func (s *calendarService) CreateFeed(caller auth.Caller) (*FeedResponse, error) {
+	token, err := generateFeedToken()
+	if err != nil {
+		return nil, err
+	}
+
+	feed := Feed{WorkspaceID: caller.WorkspaceID, UserID: caller.UserID, Token: token}
+	if err := s.repo.Save(&feed); err != nil {
+		return nil, err
+	}
+	return mapFeedToResponse(&feed), nil
+}
+
+func (s *calendarService) GetFeed(caller auth.Caller) (*FeedResponse, error) {
+	feed, err := s.repo.Find(caller.WorkspaceID, caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if feed == nil {
+		return nil, ErrNotFound
+	}
+	return mapFeedToResponse(feed), nil
+}
+
+func (s *calendarService) DeleteFeed(caller auth.Caller) error {
+	return s.repo.Delete(caller.WorkspaceID, caller.UserID)
+}
+
+// location resolves tz, falling back to the user's time zone and then UTC.
+func (s *calendarService) location(userID int64, tz string) (*time.Location, error) {
+	if tz == "" {
+		var err error
+		if tz, err = s.repo.Timezone(userID); err != nil {
+			return nil, err
+		}
+	}
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, ErrInvalidTimezone
+	}
+	return loc, nil
+}
+
+func (s *calendarService) Render(token string, opts FeedOptions, w io.Writer) error {
+	if opts.Type == "" {
+		opts.Type = EntryEvent
+	}
+	if opts.Type != EntryEvent && opts.Type != EntryTodo {
+		return ErrInvalidType
+	}
+	if opts.Label != nil {
+		label := strings.ToLower(strings.TrimSpace(*opts.Label))
+		opts.Label = &label
+	}
+
+	feed, err := s.repo.FindByToken(token)
+	if err != nil {
+		return err
+	}
+	if feed == nil {
+		return ErrNotFound
+	}
+	loc, err := s.location(feed.UserID, opts.Timezone)
+	if err != nil {
+		return err
+	}
+	q := taskQuery{ProjectID: opts.ProjectID, Label: opts.Label, Completed: opts.Completed, HasDue: true}
+	tasks, err := s.repo.Tasks(feed.WorkspaceID, feed.UserID, q)
+	if err != nil {
+		return err
+	}
+	return writeFeed(w, feed.WorkspaceID, tasks, opts.Type, loc)
+}
+
+// Import reads the whole file first, so that a file that cannot be read
+// creates no tasks. Each to-do goes through the same checks as POST /tasks;
+// one with a UID imported before is left alone.
+func (s *calendarService) Import(caller auth.Caller, file io.Reader, opts ImportOptions) (*ImportReport, error) {
+	loc, err := s.location(caller.UserID, opts.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	cal, err := ical.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	if !strings.EqualFold(cal.Name, "VCALENDAR") {
+		return nil, fmt.Errorf("%w: expected a VCALENDAR", ErrInvalidFile)
+	}
+	todos := cal.Children("VTODO")
+	if len(todos) == 0 {
+		return nil, ErrEmptyFile
+	}
+	if len(todos) > maxImportItems {
+		return nil, ErrTooManyItems
+	}
+
+	report := &ImportReport{DryRun: opts.DryRun, Items: []ItemResult{}}
+	// seen catches UIDs repeated within a dry run, where the first to-do
+	// is not saved.
+	seen := map[string]bool{}
+	for _, c := range todos {
+		result, err := s.importTodo(caller, c, opts, loc, seen)
+		if err != nil {
+			return nil, err
+		}
+		report.Total++
+		switch result.Status {
+		case ItemCreated, ItemValid:
+			report.Created++
+		case ItemExisting:
+			report.Existing++
+		case ItemInvalid:
+			report.Invalid++
+		}
+		report.Items = append(report.Items, result)
+	}
+	return report, nil
+}
+
+// importTodo imports one to-do. Problems with it are reported in the
+// result; only unexpected errors are returned.
+func (s *calendarService) importTodo(caller auth.Caller, c *ical.Component, opts ImportOptions,
+	loc *time.Location, seen map[string]bool) (ItemResult, error) {
+	req, err := readTodo(c, opts.ProjectID, loc)
+	result := ItemResult{Title: req.Task.Title}
+	if p := c.Get("UID"); p != nil {
+		result.UID = p.Value
+	}
+	if err != nil {
+		result.Status = ItemInvalid
+		result.Error = err.Error()
+		return result, nil
+	}
+	if req.ExternalID != "" && seen[req.ExternalID] {
+		result.Status = ItemExisting
+		return result, nil
+	}
+	seen[req.ExternalID] = true
+
+	res, created, err := s.tasks.Import(caller, req, opts.DryRun)
+	if err != nil {
+		if !task.IsClientError(err) {
+			return result, err
+		}
+		result.Status = ItemInvalid
+		result.Error = err.Error()
+		return result, nil
+	}
+	if res != nil {
+		result.TaskID = &res.ID
+	}
+	switch {
+	case !created:
+		result.Status = ItemExisting
+	case opts.DryRun:
+		result.Status = ItemValid
+	default:
+		result.Status = ItemCreated
+	}
+	return result, nil
+}
@@ -0,0 +1,678 @@
+package calendar
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/task"
+)
+
+// The CalDAV server implements what clients need to sync to-dos: PROPFIND
+// for discovery, REPORT calendar-query and calendar-multiget, and GET, PUT
+// and DELETE of resources. Each calendar is a collection under
+// /dav/calendars/, named "personal" or after the project's ID.
+
+const (
+	davPrefix     = "/dav"
+	principalHref = davPrefix + "/principal/"
+	homeHref      = davPrefix + "/calendars/"
+
+	nsDAV    = "DAV:"
+	nsCalDAV = "urn:ietf:params:xml:ns:caldav"
+	nsCS     = "http://calendarserver.org/ns/"
+
+	// maxDAVBytes bounds request bodies: XML queries and single resources.
+	maxDAVBytes = 1 << 20
+
+	davAllow = "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT"
+)
+
+func init() {
+	chi.RegisterMethod("PROPFIND")
+	chi.RegisterMethod("REPORT")
+}
+
+// xmlNode is any element of a request body.
+type xmlNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Content  string     `xml:",chardata"`
+	Children []xmlNode  `xml:",any"`
+}
+
+func (n *xmlNode) child(space, local string) *xmlNode {
+	for i := range n.Children {
+		if n.Children[i].XMLName.Space == space && n.Children[i].XMLName.Local == local {
+			return &n.Children[i]
+		}
+	}
+	return nil
+}
+
+func (n *xmlNode) children(space, local string) []*xmlNode {
+	var found []*xmlNode
+	for i := range n.Children {
+		if n.Children[i].XMLName.Space == space && n.Children[i].XMLName.Local == local {
+			found = append(found, &n.Children[i])
+		}
+	}
+	return found
+}
+
+func (n *xmlNode) attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// propNames lists the properties a <D:prop> element asks for.
+func (n *xmlNode) propNames() []xml.Name {
+	names := make([]xml.Name, 0, len(n.Children))
+	for _, c := range n.Children {
+		names = append(names, c.XMLName)
+	}
+	return names
+}
+
+func readXML(r *http.Request) (*xmlNode, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, nil
+	}
+	var n xmlNode
+	if err := xml.Unmarshal(body, &n); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+type resourceKind int
+
+const (
+	kindRoot resourceKind = iota
+	kindPrincipal
+	kindHome
+	kindCollection
+	kindObject
+)
+
+type davResource struct {
+	kind       resourceKind
+	href       string
+	collection *Collection
+	task       *CalendarTask
+}
+
+func collectionHref(c *Collection) string {
+	return homeHref + c.segment() + "/"
+}
+
+func objectHref(c *Collection, t *CalendarTask) string {
+	return collectionHref(c) + url.PathEscape(t.objectName())
+}
+
+// allProps answers a PROPFIND without a body or with <D:allprop/>. Those
+// a resource does not have are left out rather than reported missing.
+var allProps = []xml.Name{
+	{Space: nsDAV, Local: "resourcetype"},
+	{Space: nsDAV, Local: "displayname"},
+	{Space: nsDAV, Local: "getetag"},
+	{Space: nsDAV, Local: "getcontenttype"},
+	{Space: nsDAV, Local: "current-user-principal"},
+	{Space: nsCS, Local: "getctag"},
+}
+
+// multistatus builds a 207 Multi-Status body.
+type multistatus struct {
+	h      *Handler
+	caller auth.Caller
+	all    bool
+	buf    bytes.Buffer
+}
+
+func (h *Handler) newMultistatus(caller auth.Caller, all bool) *multistatus {
+	m := &multistatus{h: h, caller: caller, all: all}
+	m.buf.WriteString(xml.Header)
+	m.buf.WriteString(`<D:multistatus xmlns:D="DAV:" xmlns:C="` + nsCalDAV + `" xmlns:CS="` + nsCS + `">`)
+	return m
+}
+
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func hrefXML(href string) string {
+	return "<D:href>" + escapeXML(href) + "</D:href>"
+}
+
+func element(name xml.Name, inner string) string {
+	var prefix string
+	switch name.Space {
+	case nsDAV:
+		prefix = "D:"
+	case nsCalDAV:
+		prefix = "C:"
+	case nsCS:
+		prefix = "CS:"
+	default:
+		return "<X:" + name.Local + ` xmlns:X="` + escapeXML(name.Space) + `">` + inner + "</X:" + name.Local + ">"
+	}
+	if inner == "" {
+		return "<" + prefix + name.Local + "/>"
+	}
+	return "<" + prefix + name.Local + ">" + inner + "</" + prefix + name.Local + ">"
+}
+
+func privileges(canWrite bool) string {
+	names := []string{"read"}
+	if canWrite {
+		names = append(names, "write", "write-content", "bind", "unbind")
+	}
+	var b strings.Builder
+	for _, n := range names {
+		b.WriteString("<D:privilege><D:" + n + "/></D:privilege>")
+	}
+	return b.String()
+}
+
+// prop renders the value of a property of res, or reports that it has
+// none.
+func (m *multistatus) prop(res *davResource, name xml.Name) (string, bool, error) {
+	switch name.Space + " " + name.Local {
+	case nsDAV + " resourcetype":
+		switch res.kind {
+		case kindPrincipal:
+			return "<D:principal/>", true, nil
+		case kindRoot, kindHome:
+			return "<D:collection/>", true, nil
+		case kindCollection:
+			return "<D:collection/><C:calendar/>", true, nil
+		}
+		return "", true, nil
+	case nsDAV + " current-user-principal":
+		return hrefXML(principalHref), true, nil
+	case nsDAV + " principal-URL":
+		if res.kind == kindPrincipal {
+			return hrefXML(principalHref), true, nil
+		}
+	case nsCalDAV + " calendar-home-set":
+		if res.kind == kindRoot || res.kind == kindPrincipal {
+			return hrefXML(homeHref), true, nil
+		}
+	case nsDAV + " displayname":
+		switch res.kind {
+		case kindHome:
+			return "Calendars", true, nil
+		case kindCollection:
+			return escapeXML(res.collection.Name), true, nil
+		}
+	case nsCalDAV + " supported-calendar-component-set":
+		if res.kind == kindCollection {
+			return `<C:comp name="VTODO"/>`, true, nil
+		}
+	case nsDAV + " supported-report-set":
+		if res.kind == kindCollection {
+			return "<D:supported-report><D:report><C:calendar-query/></D:report></D:supported-report>" +
+				"<D:supported-report><D:report><C:calendar-multiget/></D:report></D:supported-report>", true, nil
+		}
+	case nsDAV + " current-user-privilege-set":
+		switch res.kind {
+		case kindCollection, kindObject:
+			return privileges(res.collection.CanWrite), true, nil
+		default:
+			return privileges(false), true, nil
+		}
+	case nsCS + " getctag":
+		if res.kind == kindCollection {
+			tag, err := m.h.service.CollectionTag(m.caller, res.collection)
+			return escapeXML(tag), true, err
+		}
+	case nsDAV + " getetag":
+		if res.kind == kindObject {
+			return escapeXML(res.task.etag()), true, nil
+		}
+	case nsDAV + " getcontenttype":
+		if res.kind == kindObject {
+			return "text/calendar; charset=utf-8; component=VTODO", true, nil
+		}
+	case nsCalDAV + " calendar-data":
+		if res.kind == kindObject {
+			data, err := m.h.service.ObjectData(m.caller, res.task)
+			return escapeXML(string(data)), true, err
+		}
+	}
+	return "", false, nil
+}
+
+func (m *multistatus) add(res *davResource, names []xml.Name) error {
+	var found, missing strings.Builder
+	for _, name := range names {
+		value, ok, err := m.prop(res, name)
+		if err != nil {
+			return err
+		}
+		if ok {
+			found.WriteString(element(name, value))
+		} else if !m.all {
+			missing.WriteString(element(name, ""))
+		}
+	}
+
+	m.buf.WriteString("<D:response>" + hrefXML(res.href))
+	if found.Len() > 0 {
+		m.buf.WriteString("<D:propstat><D:prop>" + found.String() + "</D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat>")
+	}
+	if missing.Len() > 0 {
+		m.buf.WriteString("<D:propstat><D:prop>" + missing.String() + "</D:prop><D:status>HTTP/1.1 404 Not Found</D:status></D:propstat>")
+	}
+	m.buf.WriteString("</D:response>")
+	return nil
+}
+
+func (m *multistatus) notFound(href string) {
+	m.buf.WriteString("<D:response>" + hrefXML(href) + "<D:status>HTTP/1.1 404 Not Found</D:status></D:response>")
+}
+
+func (m *multistatus) write(w http.ResponseWriter) {
+	m.buf.WriteString("</D:multistatus>")
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	w.Write(m.buf.Bytes())
+}
+
+func writeDAVError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, task.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrPreconditionFailed):
+		status = http.StatusPreconditionFailed
+	case errors.Is(err, ErrConflict):
+		status = http.StatusConflict
+	case errors.Is(err, ErrUnsupported), errors.Is(err, task.ErrForbidden):
+		status = http.StatusForbidden
+	case errors.Is(err, ErrInvalidFile), errors.Is(err, ErrInvalidName), task.IsClientError(err):
+		status = http.StatusBadRequest
+	}
+	if status == http.StatusInternalServerError {
+		http.Error(w, "failed to process request", status)
+		return
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// BasicAuth authenticates CalDAV clients with a username and app password.
+func (h *Handler) BasicAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="gotask"`)
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+		caller, err := h.service.Authenticate(username, password)
+		if errors.Is(err, ErrUnauthorized) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="gotask"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, "failed to authenticate", http.StatusInternalServerError)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithCaller(r.Context(), *caller)))
+	})
+}
+
+// WellKnown points clients discovering the server at its root.
+func (h *Handler) WellKnown(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, davPrefix+"/", http.StatusMovedPermanently)
+}
+
+// DAV routes every request under /dav to its resource.
+func (h *Handler) DAV(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("DAV", "1, 3, calendar-access")
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Allow", davAllow)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxDAVBytes)
+
+	caller := auth.GetCaller(r)
+	path := strings.TrimPrefix(r.URL.Path, davPrefix)
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	res := &davResource{}
+	switch {
+	case path == "" || path == "/":
+		res.kind, res.href = kindRoot, davPrefix+"/"
+	case len(parts) == 1 && parts[0] == "principal":
+		res.kind, res.href = kindPrincipal, principalHref
+	case len(parts) == 1 && parts[0] == "calendars":
+		res.kind, res.href = kindHome, homeHref
+	case len(parts) == 2 && parts[0] == "calendars", len(parts) == 3 && parts[0] == "calendars":
+		c, err := h.service.Collection(caller, parts[1])
+		if err != nil {
+			writeDAVError(w, err)
+			return
+		}
+		res.kind, res.href, res.collection = kindCollection, collectionHref(c), c
+		if len(parts) == 3 {
+			h.object(w, r, caller, c, parts[2])
+			return
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case "PROPFIND":
+		h.propfind(w, r, caller, res)
+	case "REPORT":
+		if res.kind != kindCollection {
+			http.Error(w, "reports are only supported on calendars", http.StatusForbidden)
+			return
+		}
+		h.report(w, r, caller, res.collection)
+	default:
+		w.Header().Set("Allow", "OPTIONS, PROPFIND, REPORT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) object(w http.ResponseWriter, r *http.Request, caller auth.Caller, c *Collection, name string) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		t, err := h.service.Object(caller, c, name)
+		if err != nil {
+			writeDAVError(w, err)
+			return
+		}
+		data, err := h.service.ObjectData(caller, t)
+		if err != nil {
+			writeDAVError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("ETag", t.etag())
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case http.MethodPut:
+		if !c.CanWrite {
+			http.Error(w, "calendar is read-only", http.StatusForbidden)
+			return
+		}
+		// No ETag is returned: the stored resource is not byte for byte
+		// what was sent, so clients must fetch it again.
+		created, err := h.service.PutObject(caller, c, name, r.Body, r.Header.Get("If-Match"), r.Header.Get("If-None-Match"))
+		if err != nil {
+			writeDAVError(w, err)
+			return
+		}
+		if created {
+			w.WriteHeader(http.StatusCreated)
+		} else {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	case http.MethodDelete:
+		if !c.CanWrite {
+			http.Error(w, "calendar is read-only", http.StatusForbidden)
+			return
+		}
+		if err := h.service.DeleteObject(caller, c, name, r.Header.Get("If-Match")); err != nil {
+			writeDAVError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case "PROPFIND":
+		t, err := h.service.Object(caller, c, name)
+		if err != nil {
+			writeDAVError(w, err)
+			return
+		}
+		h.propfind(w, r, caller, &davResource{kind: kindObject, href: objectHref(c, t), collection: c, task: t})
+	default:
+		w.Header().Set("Allow", davAllow)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// children lists the members of a collection, for Depth: 1.
+func (h *Handler) children(caller auth.Caller, res *davResource) ([]*davResource, error) {
+	switch res.kind {
+	case kindRoot:
+		return []*davResource{{kind: kindPrincipal, href: principalHref}, {kind: kindHome, href: homeHref}}, nil
+	case kindHome:
+		collections, err := h.service.Collections(caller)
+		if err != nil {
+			return nil, err
+		}
+		children := make([]*davResource, 0, len(collections))
+		for i := range collections {
+			c := &collections[i]
+			children = append(children, &davResource{kind: kindCollection, href: collectionHref(c), collection: c})
+		}
+		return children, nil
+	case kindCollection:
+		tasks, err := h.service.Objects(caller, res.collection)
+		if err != nil {
+			return nil, err
+		}
+		children := make([]*davResource, 0, len(tasks))
+		for i := range tasks {
+			t := &tasks[i]
+			children = append(children, &davResource{kind: kindObject, href: objectHref(res.collection, t),
+				collection: res.collection, task: t})
+		}
+		return children, nil
+	}
+	return nil, nil
+}
+
+// propfind answers with the resource and, unless Depth is 0, its members.
+// Depth: infinity is treated as 1.
+func (h *Handler) propfind(w http.ResponseWriter, r *http.Request, caller auth.Caller, res *davResource) {
+	body, err := readXML(r)
+	if err != nil {
+		http.Error(w, "invalid PROPFIND body", http.StatusBadRequest)
+		return
+	}
+	names, all := allProps, true
+	if body != nil {
+		if prop := body.child(nsDAV, "prop"); prop != nil {
+			names, all = prop.propNames(), false
+		}
+	}
+
+	resources := []*davResource{res}
+	if r.Header.Get("Depth") != "0" {
+		children, err := h.children(caller, res)
+		if err != nil {
+			writeDAVError(w, err)
+			return
+		}
+		resources = append(resources, children...)
+	}
+
+	m := h.newMultistatus(caller, all)
+	for _, res := range resources {
+		if err := m.add(res, names); err != nil {
+			writeDAVError(w, err)
+			return
+		}
+	}
+	m.write(w)
+}
+
+func (h *Handler) report(w http.ResponseWriter, r *http.Request, caller auth.Caller, c *Collection) {
+	body, err := readXML(r)
+	if err != nil || body == nil {
+		http.Error(w, "invalid REPORT body", http.StatusBadRequest)
+		return
+	}
+	names, all := allProps, true
+	if prop := body.child(nsDAV, "prop"); prop != nil {
+		names, all = prop.propNames(), false
+	}
+
+	m := h.newMultistatus(caller, all)
+	switch body.XMLName {
+	case xml.Name{Space: nsCalDAV, Local: "calendar-multiget"}:
+		for _, href := range body.children(nsDAV, "href") {
+			name, ok := hrefName(c, strings.TrimSpace(href.Content))
+			if !ok {
+				m.notFound(href.Content)
+				continue
+			}
+			t, err := h.service.Object(caller, c, name)
+			if errors.Is(err, ErrNotFound) {
+				m.notFound(href.Content)
+				continue
+			}
+			if err != nil {
+				writeDAVError(w, err)
+				return
+			}
+			res := &davResource{kind: kindObject, href: objectHref(c, t), collection: c, task: t}
+			if err := m.add(res, names); err != nil {
+				writeDAVError(w, err)
+				return
+			}
+		}
+	case xml.Name{Space: nsCalDAV, Local: "calendar-query"}:
+		tasks, err := h.service.Objects(caller, c)
+		if err != nil {
+			writeDAVError(w, err)
+			return
+		}
+		filter := body.child(nsCalDAV, "filter")
+		for i := range tasks {
+			t := &tasks[i]
+			if filter != nil && !matchCalendar(filter.child(nsCalDAV, "comp-filter"), t) {
+				continue
+			}
+			res := &davResource{kind: kindObject, href: objectHref(c, t), collection: c, task: t}
+			if err := m.add(res, names); err != nil {
+				writeDAVError(w, err)
+				return
+			}
+		}
+	default:
+		http.Error(w, "unsupported report", http.StatusForbidden)
+		return
+	}
+	m.write(w)
+}
+
+// hrefName returns the resource name of an href inside collection c.
+func hrefName(c *Collection, href string) (string, bool) {
+	if u, err := url.Parse(href); err == nil {
+		href = u.Path
+	}
+	name, ok := strings.CutPrefix(href, collectionHref(c))
+	if !ok || name == "" || strings.Contains(name, "/") {
+		return "", false
+	}
+	return name, true
+}
+
+// matchCalendar applies the VCALENDAR comp-filter of a calendar-query.
+// Only VTODOs are stored, so a filter on any other component matches
+// nothing.
+func matchCalendar(f *xmlNode, t *CalendarTask) bool {
+	if f == nil {
+		return true
+	}
+	if !strings.EqualFold(f.attr("name"), "VCALENDAR") {
+		return false
+	}
+	for _, comp := range f.children(nsCalDAV, "comp-filter") {
+		if !strings.EqualFold(comp.attr("name"), "VTODO") || !matchTodo(comp, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchTodo supports the filters clients send when syncing: is-not-defined,
+// time-range, and prop-filters on COMPLETED and STATUS. Other prop-filters
+// match every to-do, which at worst sends the client more than it asked
+// for.
+func matchTodo(f *xmlNode, t *CalendarTask) bool {
+	if f.child(nsCalDAV, "is-not-defined") != nil {
+		return false
+	}
+	if tr := f.child(nsCalDAV, "time-range"); tr != nil && !matchTimeRange(tr, t) {
+		return false
+	}
+	for _, p := range f.children(nsCalDAV, "prop-filter") {
+		defined := p.child(nsCalDAV, "is-not-defined") == nil
+		switch strings.ToUpper(p.attr("name")) {
+		case "COMPLETED":
+			if t.Completed != defined {
+				return false
+			}
+		case "STATUS":
+			status := "NEEDS-ACTION"
+			if t.Completed {
+				status = "COMPLETED"
+			}
+			if !defined {
+				return false
+			}
+			if m := p.child(nsCalDAV, "text-match"); m != nil {
+				contains := strings.Contains(status, strings.ToUpper(strings.TrimSpace(m.Content)))
+				if contains == (m.attr("negate-condition") == "yes") {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// matchTimeRange treats a to-do as spanning its start and due dates; one
+// without either overlaps every range.
+func matchTimeRange(tr *xmlNode, t *CalendarTask) bool {
+	from, to := t.StartAt, t.DueAt
+	if from == nil {
+		from = to
+	}
+	if to == nil {
+		to = from
+	}
+	if from == nil {
+		return true
+	}
+	if v := tr.attr("start"); v != "" {
+		if start, err := time.Parse("20060102T150405Z", v); err == nil && to.Before(start) {
+			return false
+		}
+	}
+	if v := tr.attr("end"); v != "" {
+		if end, err := time.Parse("20060102T150405Z", v); err == nil && !from.Before(end) {
+			return false
+		}
+	}
+	return true
+}
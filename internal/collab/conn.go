@@ -0,0 +1,336 @@
+package collab
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/stream"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingInterval   = 30 * time.Second
+	maxMessageSize = 4096
+	// sendBuffer is how many messages a slow client may fall behind before
+	// it is disconnected. It then reconnects and refetches its tasks.
+	sendBuffer = 64
+
+	// CloseTokenExpired tells the client to refresh its access token and
+	// reconnect.
+	CloseTokenExpired = 4001
+)
+
+type conn struct {
+	hub       *Hub
+	ws        *websocket.Conn
+	caller    auth.Caller
+	username  string
+	id        string
+	expiresAt time.Time
+
+	send      chan ServerMessage
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu sync.Mutex
+	// projects holds this connection's presence in each project it is
+	// subscribed to, and members whether the user could read each project
+	// when last checked.
+	projects map[int64]Presence
+	members  map[int64]membership
+}
+
+type membership struct {
+	canRead   bool
+	checkedAt time.Time
+}
+
+func newConn(hub *Hub, ws *websocket.Conn, caller auth.Caller, username string, expiresAt time.Time) (*conn, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return &conn{
+		hub:       hub,
+		ws:        ws,
+		caller:    caller,
+		username:  username,
+		id:        hex.EncodeToString(b),
+		expiresAt: expiresAt,
+		send:      make(chan ServerMessage, sendBuffer),
+		done:      make(chan struct{}),
+		projects:  map[int64]Presence{},
+		members:   map[int64]membership{},
+	}, nil
+}
+
+// run serves the connection until either side closes it.
+func (c *conn) run() {
+	c.hub.add(c)
+	sub, _ := c.hub.broker.Subscribe(c.caller.WorkspaceID, 0)
+
+	go c.writeLoop()
+	go c.eventLoop(sub)
+	c.readLoop()
+
+	c.hub.broker.Unsubscribe(sub)
+	c.close(websocket.CloseNormalClosure, "")
+	c.hub.remove(c)
+}
+
+func (c *conn) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		msg := websocket.FormatCloseMessage(code, reason)
+		c.ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(writeWait))
+		c.ws.Close()
+	})
+}
+
+// enqueue never blocks: a client that cannot keep up is disconnected
+// rather than holding up everyone else.
+func (c *conn) enqueue(m ServerMessage) {
+	select {
+	case <-c.done:
+	case c.send <- m:
+	default:
+		c.close(websocket.CloseTryAgainLater, "too slow")
+	}
+}
+
+func (c *conn) subscribed(projectID int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.projects[projectID]
+	return ok
+}
+
+// canRead reports whether the user may still read the project, asking
+// again once the cached answer is older than membershipTTL.
+func (c *conn) canRead(projectID int64) (bool, error) {
+	c.mu.Lock()
+	m, ok := c.members[projectID]
+	c.mu.Unlock()
+	if ok && time.Since(m.checkedAt) < membershipTTL {
+		return m.canRead, nil
+	}
+
+	role, err := c.hub.projects.GetMemberRole(c.caller.WorkspaceID, projectID, c.caller.UserID)
+	if err != nil {
+		return false, err
+	}
+	c.remember(projectID, role.CanRead())
+	return role.CanRead(), nil
+}
+
+func (c *conn) remember(projectID int64, canRead bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.members[projectID] = membership{canRead: canRead, checkedAt: time.Now()}
+}
+
+func (c *conn) presences() []Presence {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	list := make([]Presence, 0, len(c.projects))
+	for _, p := range c.projects {
+		list = append(list, p)
+	}
+	return list
+}
+
+func (c *conn) readLoop() {
+	c.ws.SetReadLimit(maxMessageSize)
+	c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		var msg ClientMessage
+		if err := c.ws.ReadJSON(&msg); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("websocket: %v", err)
+			}
+			return
+		}
+
+		switch msg.Type {
+		case TypeSubscribe:
+			c.subscribe(msg.ProjectID)
+		case TypeUnsubscribe:
+			c.unsubscribe(msg.ProjectID)
+		case TypePresence:
+			c.updatePresence(msg)
+		default:
+			c.enqueue(ServerMessage{Type: TypeError, Error: "unknown message type"})
+		}
+	}
+}
+
+func (c *conn) writeLoop() {
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+
+	var expired <-chan time.Time
+	if !c.expiresAt.IsZero() {
+		timer := time.NewTimer(time.Until(c.expiresAt))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case m := <-c.send:
+			c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.ws.WriteJSON(m); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-ping.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-expired:
+			c.close(CloseTokenExpired, "token expired")
+			return
+		}
+	}
+}
+
+// eventLoop forwards task events for subscribed projects that the caller
+// may see.
+func (c *conn) eventLoop(sub *stream.Subscription) {
+	visible := stream.NewVisibility(c.caller, c.hub.projects)
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case m, ok := <-sub.C:
+			if !ok {
+				c.close(websocket.CloseTryAgainLater, "too slow")
+				return
+			}
+			if m.Reset {
+				c.enqueue(ServerMessage{Type: TypeReset})
+				continue
+			}
+
+			event := m.Event
+			if event.Task.ProjectID == nil || !c.subscribed(*event.Task.ProjectID) {
+				continue
+			}
+			ok, err := visible.CanSee(event)
+			if err != nil {
+				log.Printf("websocket: visibility check failed: %v", err)
+				continue
+			}
+			if ok {
+				c.enqueue(ServerMessage{Type: TypeEvent, ProjectID: *event.Task.ProjectID, Event: &event})
+			}
+		}
+	}
+}
+
+func (c *conn) subscribe(projectID int64) {
+	role, err := c.hub.projects.GetMemberRole(c.caller.WorkspaceID, projectID, c.caller.UserID)
+	if err != nil {
+		log.Printf("websocket: %v", err)
+		c.enqueue(ServerMessage{Type: TypeError, ProjectID: projectID, Error: "internal server error"})
+		return
+	}
+	c.remember(projectID, role.CanRead())
+	if !role.CanRead() {
+		c.enqueue(ServerMessage{Type: TypeError, ProjectID: projectID, Error: "project not found"})
+		return
+	}
+
+	p := Presence{
+		WorkspaceID:  c.caller.WorkspaceID,
+		ProjectID:    projectID,
+		UserID:       c.caller.UserID,
+		Username:     c.username,
+		ConnectionID: c.id,
+		State:        StateViewing,
+	}
+	c.mu.Lock()
+	c.projects[projectID] = p
+	c.mu.Unlock()
+
+	c.enqueue(ServerMessage{Type: TypeSubscribed, ProjectID: projectID, Present: c.hub.snapshot(projectID)})
+	c.hub.publish(p)
+}
+
+func (c *conn) unsubscribe(projectID int64) {
+	c.mu.Lock()
+	p, ok := c.projects[projectID]
+	delete(c.projects, projectID)
+	c.mu.Unlock()
+
+	if ok {
+		p.State = StateLeft
+		p.TaskID = nil
+		c.hub.publish(p)
+	}
+	c.enqueue(ServerMessage{Type: TypeUnsubscribed, ProjectID: projectID})
+}
+
+func (c *conn) updatePresence(msg ClientMessage) {
+	if msg.State != StateViewing && msg.State != StateEditing {
+		c.enqueue(ServerMessage{Type: TypeError, ProjectID: msg.ProjectID, Error: "state must be viewing or editing"})
+		return
+	}
+
+	if !c.subscribed(msg.ProjectID) {
+		c.enqueue(ServerMessage{Type: TypeError, ProjectID: msg.ProjectID, Error: "not subscribed to project"})
+		return
+	}
+	canRead, err := c.canRead(msg.ProjectID)
+	if err != nil {
+		log.Printf("websocket: %v", err)
+		c.enqueue(ServerMessage{Type: TypeError, ProjectID: msg.ProjectID, Error: "internal server error"})
+		return
+	}
+	if !canRead {
+		c.unsubscribe(msg.ProjectID)
+		return
+	}
+	// Presence may only point at a task of the project it is shown in.
+	if msg.TaskID != nil {
+		t, err := c.hub.tasks.FindById(c.caller.WorkspaceID, *msg.TaskID)
+		if err != nil {
+			log.Printf("websocket: %v", err)
+			c.enqueue(ServerMessage{Type: TypeError, ProjectID: msg.ProjectID, Error: "internal server error"})
+			return
+		}
+		if t == nil || t.ProjectID == nil || *t.ProjectID != msg.ProjectID {
+			c.enqueue(ServerMessage{Type: TypeError, ProjectID: msg.ProjectID, Error: "task not found"})
+			return
+		}
+	}
+
+	c.mu.Lock()
+	p, ok := c.projects[msg.ProjectID]
+	if ok {
+		p.State = msg.State
+		p.TaskID = msg.TaskID
+		c.projects[msg.ProjectID] = p
+	}
+	c.mu.Unlock()
+
+	// The connection may have been unsubscribed meanwhile.
+	if ok {
+		c.hub.publish(p)
+	}
+}
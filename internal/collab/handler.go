@@ -0,0 +1,51 @@
+package collab
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/pkg/response"
+)
+
+type Handler struct {
+	hub      *Hub
+	upgrader websocket.Upgrader
+}
+
+func NewHandler(hub *Hub) *Handler {
+	return &Handler{
+		hub: hub,
+		upgrader: websocket.Upgrader{
+			Subprotocols: []string{auth.WebSocketProtocol},
+			// Connections carry the access token explicitly, so there are
+			// no ambient credentials for another origin to ride on.
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+// Connect upgrades the request to the collaboration WebSocket.
+func (h *Handler) Connect(w http.ResponseWriter, r *http.Request) {
+	caller := auth.GetCaller(r)
+	user, err := h.hub.users.FindByID(caller.UserID)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	ws, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied to the client.
+		return
+	}
+
+	c, err := newConn(h.hub, ws, caller, user.Username, auth.GetTokenExpiry(r))
+	if err != nil {
+		log.Printf("websocket: %v", err)
+		ws.Close()
+		return
+	}
+	c.run()
+}
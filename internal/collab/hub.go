@@ -0,0 +1,250 @@
+package collab
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/project"
+	"github.com/sudarshanmg/gotask/internal/stream"
+	"github.com/sudarshanmg/gotask/internal/task"
+)
+
+const (
+	presenceChannel = "presence"
+	// Connections re-announce their presence every presenceRefresh; entries
+	// not refreshed within presenceTTL are dropped, which also cleans up
+	// after servers that went away.
+	presenceRefresh = 30 * time.Second
+	presenceTTL     = 90 * time.Second
+	// membershipTTL is how long a connection trusts a cached project
+	// membership, and so how long a removed member may keep taking part in
+	// presence.
+	membershipTTL = time.Minute
+)
+
+// Hub tracks this server's WebSocket connections and the presence of
+// everyone connected to any server.
+type Hub struct {
+	db       *sql.DB
+	broker   *stream.Broker
+	projects project.ProjectRepository
+	tasks    task.TaskRepository
+	users    auth.AuthRepository
+
+	mu       sync.Mutex
+	conns    map[*conn]struct{}
+	presence map[int64]map[string]Presence
+}
+
+func NewHub(db *sql.DB, broker *stream.Broker, projects project.ProjectRepository, tasks task.TaskRepository,
+	users auth.AuthRepository) *Hub {
+	return &Hub{
+		db:       db,
+		broker:   broker,
+		projects: projects,
+		tasks:    tasks,
+		users:    users,
+		conns:    map[*conn]struct{}{},
+		presence: map[int64]map[string]Presence{},
+	}
+}
+
+// Listen receives presence from every server until stop is closed.
+func (h *Hub) Listen(url string, stop <-chan struct{}) error {
+	listener := pq.NewListener(url, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("presence listener: %v", err)
+		}
+	})
+	if err := listener.Listen(presenceChannel); err != nil {
+		listener.Close()
+		return err
+	}
+
+	go func() {
+		defer listener.Close()
+
+		refresh := time.NewTicker(presenceRefresh)
+		defer refresh.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case n := <-listener.Notify:
+				if n == nil {
+					continue
+				}
+				var p Presence
+				if err := json.Unmarshal([]byte(n.Extra), &p); err != nil {
+					log.Printf("presence listener: bad payload: %v", err)
+					continue
+				}
+				h.apply(p)
+			case <-refresh.C:
+				h.refresh()
+				go listener.Ping()
+			}
+		}
+	}()
+	return nil
+}
+
+func (h *Hub) add(c *conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.conns[c] = struct{}{}
+}
+
+// remove forgets the connection and tells everyone it left.
+func (h *Hub) remove(c *conn) {
+	h.mu.Lock()
+	delete(h.conns, c)
+	h.mu.Unlock()
+
+	for _, p := range c.presences() {
+		p.State = StateLeft
+		p.TaskID = nil
+		h.publish(p)
+	}
+}
+
+func (h *Hub) publish(p Presence) {
+	p.At = time.Now()
+	payload, err := json.Marshal(p)
+	if err != nil {
+		log.Printf("presence: %v", err)
+		return
+	}
+	if _, err := h.db.Exec(`SELECT pg_notify($1, $2);`, presenceChannel, string(payload)); err != nil {
+		log.Printf("presence: failed to publish: %v", err)
+	}
+}
+
+// apply records a presence change and forwards it to local connections
+// subscribed to the project.
+func (h *Hub) apply(p Presence) {
+	h.mu.Lock()
+	entries := h.presence[p.ProjectID]
+	if p.State == StateLeft {
+		delete(entries, p.ConnectionID)
+	} else {
+		if entries == nil {
+			entries = map[string]Presence{}
+			h.presence[p.ProjectID] = entries
+		}
+		previous, known := entries[p.ConnectionID]
+		entries[p.ConnectionID] = p
+
+		// Refreshes that change nothing are not worth a message.
+		if known && previous.State == p.State && equalTask(previous.TaskID, p.TaskID) {
+			h.mu.Unlock()
+			return
+		}
+	}
+	h.mu.Unlock()
+
+	h.deliver(p)
+}
+
+// deliver sends a presence change to the local connections subscribed to
+// its project. Connections whose user can no longer read the project are
+// unsubscribed from it instead.
+func (h *Hub) deliver(p Presence) {
+	h.mu.Lock()
+	targets := h.subscribers(p.WorkspaceID, p.ProjectID)
+	h.mu.Unlock()
+
+	for _, c := range targets {
+		ok, err := c.canRead(p.ProjectID)
+		if err != nil {
+			log.Printf("presence: membership check failed: %v", err)
+			continue
+		}
+		if !ok {
+			c.unsubscribe(p.ProjectID)
+			continue
+		}
+		c.enqueue(ServerMessage{Type: TypePresence, ProjectID: p.ProjectID, Presence: &p})
+	}
+}
+
+// refresh re-announces local presence, for projects the user can still
+// read, and expires entries nobody has refreshed.
+func (h *Hub) refresh() {
+	h.mu.Lock()
+	conns := make([]*conn, 0, len(h.conns))
+	for c := range h.conns {
+		conns = append(conns, c)
+	}
+
+	expired := []Presence{}
+	cutoff := time.Now().Add(-presenceTTL)
+	for projectID, entries := range h.presence {
+		for id, p := range entries {
+			if p.At.Before(cutoff) {
+				delete(entries, id)
+				p.State = StateLeft
+				p.TaskID = nil
+				expired = append(expired, p)
+			}
+		}
+		if len(entries) == 0 {
+			delete(h.presence, projectID)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, c := range conns {
+		for _, p := range c.presences() {
+			ok, err := c.canRead(p.ProjectID)
+			if err != nil {
+				log.Printf("presence: membership check failed: %v", err)
+				continue
+			}
+			if !ok {
+				c.unsubscribe(p.ProjectID)
+				continue
+			}
+			h.publish(p)
+		}
+	}
+	for _, p := range expired {
+		h.deliver(p)
+	}
+}
+
+// snapshot returns everyone currently present in the project.
+func (h *Hub) snapshot(projectID int64) []Presence {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	present := []Presence{}
+	for _, p := range h.presence[projectID] {
+		present = append(present, p)
+	}
+	return present
+}
+
+// subscribers must be called with h.mu held.
+func (h *Hub) subscribers(workspaceID, projectID int64) []*conn {
+	targets := []*conn{}
+	for c := range h.conns {
+		if c.caller.WorkspaceID == workspaceID && c.subscribed(projectID) {
+			targets = append(targets, c)
+		}
+	}
+	return targets
+}
+
+func equalTask(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,61 @@
+package collab
+
+import (
+	"time"
+
+	"github.com/sudarshanmg/gotask/internal/task"
+)
+
+type PresenceState string
+
+const (
+	StateViewing PresenceState = "viewing"
+	StateEditing PresenceState = "editing"
+	StateLeft    PresenceState = "left"
+)
+
+// Presence is what one connection is doing in a project. It is shared
+// between server instances through Postgres NOTIFY.
+type Presence struct {
+	WorkspaceID  int64         `json:"workspace_id"`
+	ProjectID    int64         `json:"project_id"`
+	UserID       int64         `json:"user_id"`
+	Username     string        `json:"username"`
+	ConnectionID string        `json:"connection_id"`
+	TaskID       *int64        `json:"task_id,omitempty"`
+	State        PresenceState `json:"state"`
+	At           time.Time     `json:"at"`
+}
+
+// Client message types.
+const (
+	TypeSubscribe   = "subscribe"
+	TypeUnsubscribe = "unsubscribe"
+	TypePresence    = "presence"
+)
+
+// Server message types, in addition to TypePresence.
+const (
+	TypeSubscribed   = "subscribed"
+	TypeUnsubscribed = "unsubscribed"
+	TypeEvent        = "event"
+	TypeReset        = "reset"
+	TypeError        = "error"
+)
+
+type ClientMessage struct {
+	Type      string        `json:"type"`
+	ProjectID int64         `json:"project_id"`
+	TaskID    *int64        `json:"task_id,omitempty"`
+	State     PresenceState `json:"state,omitempty"`
+}
+
+type ServerMessage struct {
+	Type      string      `json:"type"`
+	ProjectID int64       `json:"project_id,omitempty"`
+	Event     *task.Event `json:"event,omitempty"`
+	Presence  *Presence   `json:"presence,omitempty"`
+	// Present lists who is in the project when a subscription starts.
+	Present []Presence `json:"present,omitempty"`
+	Error   string     `json:"error,omitempty"`
+}
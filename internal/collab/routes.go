@@ -0,0 +1,9 @@
+package collab
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Get("/ws", h.Connect)
+}
@@ -0,0 +1,7 @@
+package digest
+
+import "errors"
+
+var (
+	ErrInvalidTimezone = errors.New("unknown time zone")
+)
@@ -0,0 +1,55 @@
+package digest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/pkg/response"
+)
+
+type Handler struct {
+	service DigestService
+}
+
+func NewHandler(service DigestService) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.service.GetSettings(auth.GetCaller(r))
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch digest settings")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, settings)
+}
+
+func (h *Handler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var req UpdateSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	settings, err := h.service.UpdateSettings(auth.GetCaller(r), req)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, settings)
+}
+
+func (h *Handler) Preview(w http.ResponseWriter, r *http.Request) {
+	digest, err := h.service.Preview(auth.GetCaller(r))
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to build digest")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, digest)
+}
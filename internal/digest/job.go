@@ -0,0 +1,17 @@
+package digest
+
+// Job queues digest emails. Every replica may run it: users are claimed
+// with FOR UPDATE SKIP LOCKED and their digest is queued in the same
+// transaction that records it as sent.
+type Job struct {
+	Service DigestService
+	Batch   int
+}
+
+func (j *Job) Name() string {
+	return "digests"
+}
+
+func (j *Job) Run() (int, error) {
+	return j.Service.ProcessDue(j.Batch)
+}
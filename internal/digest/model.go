@@ -0,0 +1,81 @@
+package digest
+
+import (
+	"time"
+)
+
+type Frequency string
+
+const (
+	FrequencyOff    Frequency = "off"
+	FrequencyDaily  Frequency = "daily"
+	FrequencyWeekly Frequency = "weekly"
+)
+
+// Settings control when a user gets their digest. SendTime is a local
+// "HH:MM" in Timezone; Weekday is the ISO day (1 = Monday) for weekly
+// digests.
+type Settings struct {
+	UserID     int64      `json:"-"`
+	Frequency  Frequency  `json:"frequency"`
+	SendTime   string     `json:"send_time"`
+	Timezone   string     `json:"timezone"`
+	Weekday    int        `json:"weekday"`
+	LastSentAt *time.Time `json:"last_sent_at"`
+}
+
+func DefaultSettings(userID int64) *Settings {
+	return &Settings{
+		UserID:    userID,
+		Frequency: FrequencyDaily,
+		SendTime:  "08:00",
+		Timezone:  "UTC",
+		Weekday:   1,
+	}
+}
+
+type UpdateSettingsRequest struct {
+	Frequency Frequency `json:"frequency" validate:"required,oneof=off daily weekly"`
+	SendTime  string    `json:"send_time" validate:"required,datetime=15:04"`
+	Timezone  string    `json:"timezone" validate:"required,timezone"`
+	Weekday   int       `json:"weekday" validate:"omitempty,min=1,max=7"`
+}
+
+// Recipient is a user whose digest is due.
+type Recipient struct {
+	UserID   int64
+	Username string
+	Email    string
+	Settings Settings
+}
+
+type Item struct {
+	ID    int64      `json:"id"`
+	Title string     `json:"title"`
+	DueAt *time.Time `json:"due_at"`
+}
+
+type Section struct {
+	Title string `json:"title"`
+	Tasks []Item `json:"tasks"`
+	// More counts matching tasks left out of Tasks.
+	More int64 `json:"more"`
+}
+
+type WorkspaceDigest struct {
+	WorkspaceID int64     `json:"workspace_id"`
+	Name        string    `json:"name"`
+	Sections    []Section `json:"sections"`
+}
+
+type Digest struct {
+	Username   string            `json:"username"`
+	Frequency  Frequency         `json:"frequency"`
+	Date       string            `json:"date"`
+	Workspaces []WorkspaceDigest `json:"workspaces"`
+}
+
+// Empty reports whether the digest has nothing to say.
+func (d *Digest) Empty() bool {
+	return len(d.Workspaces) == 0
+}
@@ -0,0 +1,143 @@
+package digest
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/sudarshanmg/gotask/internal/mail"
+)
+
+type DigestRepository interface {
+	GetSettings(userID int64) (*Settings, error)
+	SaveSettings(settings *Settings) error
+	// ProcessDue claims up to limit users whose digest is due and asks
+	// build for each one's email. A nil message means there is nothing to
+	// send. The email is queued in the same transaction that records the
+	// digest as sent.
+	ProcessDue(limit int, build func(r Recipient) (*mail.Message, error)) (int, error)
+}
+
+type PostgresDigestRepository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) DigestRepository {
+	return &PostgresDigestRepository{DB: db}
+}
+
+// GetSettings returns the defaults for users who have never changed them.
+func (r *PostgresDigestRepository) GetSettings(userID int64) (*Settings, error) {
+	query := `SELECT user_id, frequency, to_char(send_time, 'HH24:MI'), timezone, weekday, last_sent_at
+            FROM digest_settings WHERE user_id = $1;`
+
+	s := &Settings{}
+	err := r.DB.QueryRow(query, userID).Scan(&s.UserID, &s.Frequency, &s.SendTime, &s.Timezone, &s.Weekday,
+		&s.LastSentAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultSettings(userID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (r *PostgresDigestRepository) SaveSettings(s *Settings) error {
+	query := `INSERT INTO digest_settings (user_id, frequency, send_time, timezone, weekday)
+            VALUES ($1, $2, $3, $4, $5)
+            ON CONFLICT (user_id) DO UPDATE
+              SET frequency = EXCLUDED.frequency, send_time = EXCLUDED.send_time,
+                  timezone = EXCLUDED.timezone, weekday = EXCLUDED.weekday, updated_at = NOW()
+            RETURNING last_sent_at;`
+
+	return r.DB.QueryRow(query, s.UserID, s.Frequency, s.SendTime, s.Timezone, s.Weekday).Scan(&s.LastSentAt)
+}
+
+func (r *PostgresDigestRepository) ProcessDue(limit int, build func(r Recipient) (*mail.Message, error)) (int, error) {
+	// Users who verified an email and never saved settings get the
+	// defaults.
+	_, err := r.DB.Exec(`INSERT INTO digest_settings (user_id)
+            SELECT u.id FROM users u
+            WHERE u.email_verified_at IS NOT NULL
+              AND NOT EXISTS (SELECT 1 FROM digest_settings s WHERE s.user_id = u.id)
+            ON CONFLICT DO NOTHING;`)
+	if err != nil {
+		return 0, err
+	}
+
+	claim := `SELECT s.user_id, u.username, u.email, s.frequency, to_char(s.send_time, 'HH24:MI'), s.timezone,
+                 s.weekday, s.last_sent_at
+          FROM digest_settings s
+          JOIN users u ON u.id = s.user_id
+          WHERE s.frequency <> 'off'
+            AND u.email IS NOT NULL AND u.email_verified_at IS NOT NULL
+            AND (NOW() AT TIME ZONE s.timezone)::time >= s.send_time
+            AND (s.last_sent_on IS NULL OR s.last_sent_on < (NOW() AT TIME ZONE s.timezone)::date)
+            AND (s.frequency = 'daily' OR EXTRACT(ISODOW FROM NOW() AT TIME ZONE s.timezone) = s.weekday)
+          ORDER BY s.user_id
+          LIMIT $1
+          FOR UPDATE OF s SKIP LOCKED;`
+
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(claim, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	batch := []Recipient{}
+	for rows.Next() {
+		rc := Recipient{}
+		s := &rc.Settings
+		if err := rows.Scan(&rc.UserID, &rc.Username, &rc.Email, &s.Frequency, &s.SendTime, &s.Timezone,
+			&s.Weekday, &s.LastSentAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		s.UserID = rc.UserID
+		batch = append(batch, rc)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, rc := range batch {
+		msg, err := build(rc)
+		if err != nil {
+			// Leave it unsent; the next run tries again.
+			log.Printf("failed to build digest for user %d: %v", rc.UserID, err)
+			continue
+		}
+
+		var localDate string
+		err = tx.QueryRow(`UPDATE digest_settings
+                SET last_sent_on = (NOW() AT TIME ZONE timezone)::date, last_sent_at = NOW()
+                WHERE user_id = $1
+                RETURNING last_sent_on::text;`, rc.UserID).Scan(&localDate)
+		if err != nil {
+			return 0, err
+		}
+
+		if msg == nil {
+			continue
+		}
+		msg.To = rc.Email
+		if err := mail.Enqueue(tx, *msg, fmt.Sprintf("digest:%d:%s", rc.UserID, localDate)); err != nil {
+			return 0, err
+		}
+		sent++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return sent, nil
+}
@@ -0,0 +1,13 @@
+package digest
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Route("/digest", func(r chi.Router) {
+		r.Get("/settings", h.GetSettings)
+		r.Put("/settings", h.UpdateSettings)
+		r.Get("/preview", h.Preview)
+	})
+}
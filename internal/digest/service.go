@@ -0,0 +1,196 @@
+package digest
+
+import (
+	"time"
+	// Time zone data for users' local times, in case the host has none.
+	_ "time/tzdata"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/mail"
+	"github.com/sudarshanmg/gotask/internal/task"
+	"github.com/sudarshanmg/gotask/internal/workspace"
+	"github.com/sudarshanmg/gotask/pkg/validation"
+)
+
+var validate = validator.New()
+
+// sectionLimit is how many tasks each digest section lists.
+const sectionLimit = 20
+
+type DigestService interface {
+	GetSettings(caller auth.Caller) (*Settings, error)
+	UpdateSettings(caller auth.Caller, req UpdateSettingsRequest) (*Settings, error)
+	Preview(caller auth.Caller) (*Digest, error)
+	ProcessDue(limit int) (int, error)
+}
+
+type digestService struct {
+	repo       DigestRepository
+	tasks      task.TaskRepository
+	workspaces workspace.WorkspaceRepository
+	users      auth.AuthRepository
+}
+
+func NewService(repo DigestRepository, tasks task.TaskRepository, workspaces workspace.WorkspaceRepository,
+	users auth.AuthRepository) DigestService {
+	return &digestService{repo: repo, tasks: tasks, workspaces: workspaces, users: users}
+}
+
+func (s *digestService) GetSettings(caller auth.Caller) (*Settings, error) {
+	return s.repo.GetSettings(caller.UserID)
+}
+
+// UpdateSettings changes when the caller gets their digest; frequency "off"
+// opts out.
+func (s *digestService) UpdateSettings(caller auth.Caller, req UpdateSettingsRequest) (*Settings, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+	if req.Weekday == 0 {
+		req.Weekday = 1
+	}
+
+	settings := &Settings{
+		UserID:    caller.UserID,
+		Frequency: req.Frequency,
+		SendTime:  req.SendTime,
+		Timezone:  req.Timezone,
+		Weekday:   req.Weekday,
+	}
+	if err := s.repo.SaveSettings(settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// Preview builds the digest the caller would get if it were sent now.
+func (s *digestService) Preview(caller auth.Caller) (*Digest, error) {
+	settings, err := s.repo.GetSettings(caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if settings.Frequency == FrequencyOff {
+		settings.Frequency = FrequencyDaily
+	}
+
+	user, err := s.users.FindByID(caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	return s.build(Recipient{UserID: user.ID, Username: user.Username, Settings: *settings}, time.Now())
+}
+
+// ProcessDue queues digests for up to limit users whose send time has
+// passed.
+func (s *digestService) ProcessDue(limit int) (int, error) {
+	return s.repo.ProcessDue(limit, func(r Recipient) (*mail.Message, error) {
+		d, err := s.build(r, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		if d.Empty() {
+			return nil, nil
+		}
+		return mail.Render("digest", d)
+	})
+}
+
+type section struct {
+	title  string
+	filter task.TaskFilter
+}
+
+// build assembles the digest of tasks assigned to the recipient in each of
+// their workspaces, using periods in the recipient's local time.
+func (s *digestService) build(r Recipient, now time.Time) (*Digest, error) {
+	loc, err := time.LoadLocation(r.Settings.Timezone)
+	if err != nil {
+		return nil, ErrInvalidTimezone
+	}
+	local := now.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+
+	days := 1
+	dueTitle, completedTitle := "Due today", "Completed yesterday"
+	if r.Settings.Frequency == FrequencyWeekly {
+		days = 7
+		dueTitle, completedTitle = "Due this week", "Completed last week"
+	}
+	periodEnd := today.AddDate(0, 0, days)
+	periodStart := today.AddDate(0, 0, -days)
+
+	assignedSince := periodStart
+	if r.Settings.LastSentAt != nil && r.Settings.LastSentAt.After(assignedSince) {
+		assignedSince = *r.Settings.LastSentAt
+	}
+
+	open, done := false, true
+	sections := []section{
+		{"Overdue", task.TaskFilter{Completed: &open, DueBefore: &today, SortBy: "due_at"}},
+		{dueTitle, task.TaskFilter{Completed: &open, DueAfter: &today, DueBefore: &periodEnd, SortBy: "due_at"}},
+		{completedTitle, task.TaskFilter{Completed: &done, CompletedAfter: &periodStart, CompletedBefore: &today,
+			SortBy: "updated_at"}},
+		{"Newly assigned to you", task.TaskFilter{Completed: &open, AssignedAfter: &assignedSince,
+			SortBy: "created_at"}},
+	}
+
+	workspaces, _, err := s.workspaces.FindForUser(r.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Digest{
+		Username:   r.Username,
+		Frequency:  r.Settings.Frequency,
+		Date:       local.Format("Monday, 2 January 2006"),
+		Workspaces: []WorkspaceDigest{},
+	}
+	for _, ws := range workspaces {
+		wd := WorkspaceDigest{WorkspaceID: ws.ID, Name: ws.Name}
+
+		for _, sec := range sections {
+			filter := sec.filter
+			filter.WorkspaceID = ws.ID
+			filter.ViewerID = r.UserID
+			filter.AssigneeID = &r.UserID
+			filter.Order = "asc"
+
+			tasks, err := s.tasks.FindAll(0, sectionLimit, filter)
+			if err != nil {
+				return nil, err
+			}
+			if len(tasks) == 0 {
+				continue
+			}
+
+			out := Section{Title: sec.title, Tasks: make([]Item, 0, len(tasks))}
+			for _, t := range tasks {
+				item := Item{ID: t.Id, Title: t.Title}
+				if t.DueAt != nil {
+					due := t.DueAt.In(loc)
+					item.DueAt = &due
+				}
+				out.Tasks = append(out.Tasks, item)
+			}
+
+			if len(tasks) == sectionLimit {
+				total, err := s.tasks.Count(filter)
+				if err != nil {
+					return nil, err
+				}
+				out.More = total - int64(len(tasks))
+			}
+			wd.Sections = append(wd.Sections, out)
+		}
+
+		if len(wd.Sections) > 0 {
+			d.Workspaces = append(d.Workspaces, wd)
+		}
+	}
+	return d, nil
+}
@@ -0,0 +1,12 @@
+package importer
+
+import "errors"
+
+var (
+	ErrInvalidSource = errors.New("source must be todoist, trello or todotxt")
+	ErrInvalidFile   = errors.New("invalid import file")
+	ErrEmptyFile     = errors.New("import file has no tasks")
+	ErrTooManyItems  = errors.New("import file has too many tasks")
+	ErrNotFound      = errors.New("import not found")
+	ErrInvalidID     = errors.New("invalid ID format")
+)
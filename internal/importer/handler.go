@@ -0,0 +1,99 @@
+package importer
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/authz"
+	"github.com/sudarshanmg/gotask/pkg/response"
+)
+
+const (
+	// maxImportBytes bounds an uploaded import file.
+	maxImportBytes = 20 << 20
+	// importMemory is how much of an upload is kept in memory; the rest
+	// goes to a temporary file.
+	importMemory = 4 << 20
+)
+
+type Handler struct {
+	service ImporterService
+	policy  authz.Policy
+}
+
+func NewHandler(service ImporterService, policy authz.Policy) *Handler {
+	return &Handler{service: service, policy: policy}
+}
+
+func writeServiceError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidSource), errors.Is(err, ErrInvalidFile), errors.Is(err, ErrEmptyFile),
+		errors.Is(err, ErrInvalidID), strings.HasPrefix(err.Error(), "validation failed:"):
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrTooManyItems):
+		response.WriteError(w, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, ErrNotFound):
+		response.WriteError(w, http.StatusNotFound, err.Error())
+	default:
+		response.WriteError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+// StartImport queues an uploaded file for import: the file in "file" and
+// the app it came from in "source", which is otherwise guessed. The job is
+// returned at once; poll it for progress.
+func (h *Handler) StartImport(w http.ResponseWriter, r *http.Request) {
+	if !authz.Authorize(w, r, h.policy, authz.ActionCreate, authz.Resource{Type: authz.ResourceTask}) {
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	if err := r.ParseMultipartForm(importMemory); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "expected a multipart form with a file")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "file is required")
+		return
+	}
+	defer file.Close()
+
+	job, err := h.service.Start(auth.GetCaller(r), Source(r.FormValue("source")), header.Filename, file)
+	if err != nil {
+		writeServiceError(w, err, "failed to start import")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusAccepted, job)
+}
+
+func (h *Handler) GetImports(w http.ResponseWriter, r *http.Request) {
+	jobs, err := h.service.GetAll(auth.GetCaller(r))
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch imports")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, jobs)
+}
+
+func (h *Handler) GetImport(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeServiceError(w, ErrInvalidID, "")
+		return
+	}
+
+	job, err := h.service.GetByID(auth.GetCaller(r), id)
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch import")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, job)
+}
@@ -0,0 +1,16 @@
+package importer
+
+// Job runs queued imports. Every replica may run it: jobs are claimed with
+// FOR UPDATE SKIP LOCKED, so each import runs on a single server.
+type Job struct {
+	Service ImporterService
+	Batch   int
+}
+
+func (j *Job) Name() string {
+	return "imports"
+}
+
+func (j *Job) Run() (int, error) {
+	return j.Service.ProcessQueued(j.Batch)
+}
@@ -0,0 +1,126 @@
+package importer
+
+import (
+	"time"
+)
+
+// Source is the app an import file was exported from.
+type Source string
+
+const (
+	// SourceTodoist reads a Todoist JSON backup or a project's CSV export.
+	SourceTodoist Source = "todoist"
+	// SourceTrello reads a Trello board's JSON export.
+	SourceTrello Source = "trello"
+	// SourceTodoTxt reads a todo.txt file.
+	SourceTodoTxt Source = "todotxt"
+)
+
+func (s Source) valid() bool {
+	return s == SourceTodoist || s == SourceTrello || s == SourceTodoTxt
+}
+
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobCompleted JobStatus = "completed"
+	JobFailed    JobStatus = "failed"
+)
+
+// ImportJob is an import running in the background. Counts are updated as it
+// goes, so that the caller can poll for progress.
+type ImportJob struct {
+	ID          int64     `json:"id"`
+	WorkspaceID int64     `json:"workspace_id"`
+	UserID      int64     `json:"user_id"`
+	Source      Source    `json:"source"`
+	Filename    string    `json:"filename"`
+	Status      JobStatus `json:"status"`
+	// Total is the number of tasks in the file and Processed the number
+	// handled so far; Percent is Processed as a share of Total.
+	Total     int `json:"total"`
+	Processed int `json:"processed"`
+	Percent   int `json:"percent"`
+	Created   int `json:"created"`
+	Existing  int `json:"existing"`
+	Invalid   int `json:"invalid"`
+	// Skipped counts entries of the file that are not imported, such as
+	// archived Trello cards.
+	Skipped         int    `json:"skipped"`
+	ProjectsCreated int    `json:"projects_created"`
+	Error           string `json:"error,omitempty"`
+	// Results are only filled in once the job has finished, and only when
+	// a single job is fetched.
+	Results    []ItemResult `json:"results,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+	StartedAt  *time.Time   `json:"started_at"`
+	FinishedAt *time.Time   `json:"finished_at"`
+	Attempts   int          `json:"-"`
+}
+
+func (j *ImportJob) setPercent() {
+	j.Percent = 0
+	if j.Total > 0 {
+		j.Percent = j.Processed * 100 / j.Total
+	}
+	if j.Status == JobCompleted {
+		j.Percent = 100
+	}
+}
+
+type ItemStatus string
+
+const (
+	ItemCreated ItemStatus = "created"
+	// ItemExisting tasks were imported before from the same source.
+	ItemExisting ItemStatus = "existing"
+	ItemInvalid  ItemStatus = "invalid"
+)
+
+// ItemResult reports one task of the file; Item counts them from 1 in file
+// order.
+type ItemResult struct {
+	Item       int        `json:"item"`
+	ExternalID string     `json:"external_id"`
+	Title      string     `json:"title"`
+	Status     ItemStatus `json:"status"`
+	TaskID     *int64     `json:"task_id,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	// Warnings note data that could not be kept as it was, e.g. a
+	// shortened description.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// item is a task read from an import file, before it is mapped onto a
+// task. Project is a project name; empty means a personal task.
+type item struct {
+	ExternalID  string
+	Title       string
+	Description string
+	Project     string
+	Labels      []string
+	Priority    *int
+	DueAt       *time.Time
+	Completed   bool
+	Checklists  []checklist
+	Warnings    []string
+}
+
+// checklist becomes a Markdown task list in the task's description.
+type checklist struct {
+	Name  string
+	Items []checkItem
+}
+
+type checkItem struct {
+	Text string
+	Done bool
+}
+
+// parsed is the content of an import file.
+type parsed struct {
+	Items   []item
+	Skipped int
+}
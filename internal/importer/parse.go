@@ -0,0 +1,185 @@
+package importer
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sudarshanmg/gotask/internal/task"
+)
+
+const (
+	// maxItems bounds the tasks of one import.
+	maxItems = 5000
+	// Task field limits; longer values are shortened with a warning rather
+	// than failing the task.
+	maxTitle       = 100
+	maxDescription = 500
+	maxLabel       = 50
+	maxLabels      = 20
+	maxProjectName = 100
+)
+
+// detect guesses the source of a file from its name and content: text
+// files are todo.txt, CSV files Todoist exports, and JSON files Trello
+// boards when they have cards.
+func detect(filename string, data []byte) Source {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".txt":
+		return SourceTodoTxt
+	case ".csv":
+		return SourceTodoist
+	case ".json":
+		var probe struct {
+			Cards json.RawMessage `json:"cards"`
+		}
+		if json.Unmarshal(data, &probe) == nil && probe.Cards != nil {
+			return SourceTrello
+		}
+		return SourceTodoist
+	}
+	return ""
+}
+
+func parse(source Source, filename string, data []byte) (*parsed, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	var p *parsed
+	var err error
+	switch source {
+	case SourceTodoist:
+		p, err = parseTodoist(filename, data)
+	case SourceTrello:
+		p, err = parseTrello(data)
+	case SourceTodoTxt:
+		p, err = parseTodoTxt(data)
+	default:
+		return nil, ErrInvalidSource
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	if len(p.Items) == 0 {
+		return nil, ErrEmptyFile
+	}
+	if len(p.Items) > maxItems {
+		return nil, ErrTooManyItems
+	}
+	return p, nil
+}
+
+// parseDate reads a due date. Dates and times without a zone are taken
+// as UTC.
+func parseDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, true
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// hashID makes an external ID for a source without IDs from the parts that
+// identify an entry. seen counts earlier entries with the same parts, so
+// that identical lines still make separate tasks.
+func hashID(source Source, seen map[string]int, parts ...string) string {
+	sum := sha1.Sum([]byte(strings.Join(parts, "\x00")))
+	id := hex.EncodeToString(sum[:8])
+	seen[id]++
+	if n := seen[id]; n > 1 {
+		id = fmt.Sprintf("%s-%d", id, n)
+	}
+	return string(source) + ":" + id
+}
+
+// shorten cuts s to at most n characters.
+func shorten(s string, n int) (string, bool) {
+	if utf8.RuneCountInString(s) <= n {
+		return s, false
+	}
+	return string([]rune(s)[:n]), true
+}
+
+// description renders the item's description followed by its checklists
+// as Markdown task lists.
+func (it *item) description() string {
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(it.Description))
+	for _, list := range it.Checklists {
+		if len(list.Items) == 0 {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		if list.Name != "" {
+			b.WriteString("**" + list.Name + "**\n")
+		}
+		for i, ci := range list.Items {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			mark := " "
+			if ci.Done {
+				mark = "x"
+			}
+			b.WriteString("- [" + mark + "] " + strings.TrimSpace(ci.Text))
+		}
+	}
+	return b.String()
+}
+
+// request maps the item onto a task import. Values over the task limits
+// are shortened, and each change adds a warning.
+func (it *item) request(projectID *int64) (task.ImportTaskRequest, []string) {
+	warnings := append([]string{}, it.Warnings...)
+
+	title, cut := shorten(strings.TrimSpace(it.Title), maxTitle)
+	if cut {
+		warnings = append(warnings, fmt.Sprintf("title shortened to %d characters", maxTitle))
+	}
+	description, cut := shorten(it.description(), maxDescription)
+	if cut {
+		warnings = append(warnings, fmt.Sprintf("description shortened to %d characters", maxDescription))
+	}
+
+	labels := []string{}
+	seen := map[string]bool{}
+	for _, label := range it.Labels {
+		label, cut := shorten(strings.ToLower(strings.TrimSpace(label)), maxLabel)
+		if label == "" || seen[label] {
+			continue
+		}
+		if cut {
+			warnings = append(warnings, fmt.Sprintf("label %q shortened", label))
+		}
+		seen[label] = true
+		labels = append(labels, label)
+	}
+	if len(labels) > maxLabels {
+		warnings = append(warnings, fmt.Sprintf("only the first %d labels were kept", maxLabels))
+		labels = labels[:maxLabels]
+	}
+
+	return task.ImportTaskRequest{
+		ExternalID: it.ExternalID,
+		Completed:  it.Completed,
+		Task: task.CreateTaskRequest{
+			Title:       title,
+			Description: description,
+			ProjectID:   projectID,
+			Labels:      labels,
+			Priority:    it.Priority,
+			DueAt:       it.DueAt,
+		},
+	}, warnings
+}
@@ -0,0 +1,160 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// intPtr and timePtr make expected values for item fields.
+func intPtr(n int) *int { return &n }
+
+func timePtr(t time.Time) *time.Time { return &t }
+
+func sameInt(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		filename string
+		data     string
+		want     Source
+	}{
+		{"todo.txt", "anything", SourceTodoTxt},
+		{"Work.CSV", "TYPE,CONTENT", SourceTodoist},
+		{"board.json", `{"name": "Board", "cards": []}`, SourceTrello},
+		{"backup.json", `{"items": []}`, SourceTodoist},
+		{"broken.json", `{`, SourceTodoist},
+		{"tasks.xlsx", "", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		if got := detect(tt.filename, []byte(tt.data)); got != tt.want {
+			t.Errorf("detect(%q) = %q, want %q", tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source Source
+		data   string
+		want   error
+	}{
+		{"unknown source", "asana", "x", ErrInvalidSource},
+		{"invalid JSON", SourceTrello, "{", ErrInvalidFile},
+		{"not a board", SourceTrello, `{"name": "x"}`, ErrInvalidFile},
+		{"CSV without CONTENT", SourceTodoist, "TYPE,PRIORITY\ntask,1\n", ErrInvalidFile},
+		{"no tasks", SourceTodoTxt, "\n\n", ErrEmptyFile},
+		{"too many tasks", SourceTodoTxt, strings.Repeat("task\n", maxItems+1), ErrTooManyItems},
+	}
+	for _, tt := range tests {
+		if _, err := parse(tt.source, "file", []byte(tt.data)); !errors.Is(err, tt.want) {
+			t.Errorf("%s: parse error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	p, err := parse(SourceTodoTxt, "todo.txt", []byte("\ufeffBuy milk\n"))
+	if err != nil || p.Items[0].Title != "Buy milk" {
+		t.Errorf("byte order mark not removed: %+v, %v", p, err)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+		ok   bool
+	}{
+		{"2024-03-01", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), true},
+		{" 2024-03-01 ", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), true},
+		{"2024-03-01 14:30", time.Date(2024, 3, 1, 14, 30, 0, 0, time.UTC), true},
+		{"2024-03-01T14:30:00", time.Date(2024, 3, 1, 14, 30, 0, 0, time.UTC), true},
+		{"2024-03-01T14:30:00+02:00", time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC), true},
+		{"2024-03-01T14:30:00.000Z", time.Date(2024, 3, 1, 14, 30, 0, 0, time.UTC), true},
+		{"tomorrow", time.Time{}, false},
+		{"03/01/2024", time.Time{}, false},
+		{"", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseDate(tt.in)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("parseDate(%q) = %v, %v, want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestHashIDKeepsDuplicatesApart(t *testing.T) {
+	seen := map[string]int{}
+	first := hashID(SourceTodoTxt, seen, "Buy milk")
+	second := hashID(SourceTodoTxt, seen, "Buy milk")
+	other := hashID(SourceTodoTxt, seen, "Buy bread")
+
+	if !strings.HasPrefix(first, "todotxt:") {
+		t.Errorf("ID %q has no source prefix", first)
+	}
+	if second != first+"-2" || other == first {
+		t.Errorf("IDs = %q, %q, %q", first, second, other)
+	}
+	if again := hashID(SourceTodoTxt, map[string]int{}, "Buy milk"); again != first {
+		t.Errorf("ID is not stable across files: %q, %q", again, first)
+	}
+}
+
+func TestItemRequest(t *testing.T) {
+	projectID := int64(7)
+	it := item{
+		ExternalID:  "trello:1",
+		Title:       "  " + strings.Repeat("t", maxTitle+5) + "  ",
+		Description: " Notes ",
+		Labels:      []string{"Work", "work ", "", strings.Repeat("l", maxLabel+1)},
+		Priority:    intPtr(2),
+		Completed:   true,
+		Checklists: []checklist{
+			{Name: "Steps", Items: []checkItem{{Text: "one", Done: true}, {Text: " two "}}},
+			{Name: "Empty"},
+		},
+		Warnings: []string{"from the parser"},
+	}
+
+	req, warnings := it.request(&projectID)
+
+	if len([]rune(req.Task.Title)) != maxTitle {
+		t.Errorf("title has %d characters, want %d", len([]rune(req.Task.Title)), maxTitle)
+	}
+	if want := "Notes\n\n**Steps**\n- [x] one\n- [ ] two"; req.Task.Description != want {
+		t.Errorf("Description = %q, want %q", req.Task.Description, want)
+	}
+	if got := strings.Join(req.Task.Labels, ","); got != "work,"+strings.Repeat("l", maxLabel) {
+		t.Errorf("Labels = %q", got)
+	}
+	if req.ExternalID != "trello:1" || !req.Completed || !sameInt(req.Task.Priority, intPtr(2)) ||
+		req.Task.ProjectID == nil || *req.Task.ProjectID != projectID {
+		t.Errorf("request = %+v", req)
+	}
+	if len(warnings) != 3 || warnings[0] != "from the parser" {
+		t.Errorf("warnings = %q", warnings)
+	}
+
+	many := item{Title: "x"}
+	for i := 0; i < maxLabels+3; i++ {
+		many.Labels = append(many.Labels, strings.Repeat("a", i+1))
+	}
+	req, warnings = many.request(nil)
+	if len(req.Task.Labels) != maxLabels || len(warnings) != 1 {
+		t.Errorf("%d labels kept with warnings %q", len(req.Task.Labels), warnings)
+	}
+}
@@ -0,0 +1,190 @@
+package importer
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/sudarshanmg/gotask/pkg/db"
+)
+
+// ImporterRepository reads a user's jobs within a workspace under
+// db.WithTenant. Claim is used by the background job and works across
+// workspaces under db.AcrossTenants.
+type ImporterRepository interface {
+	Create(job *ImportJob, file []byte) error
+	FindByID(workspaceID, userID, id int64) (*ImportJob, error)
+	// FindByUser returns the user's latest jobs, without their results.
+	FindByUser(workspaceID, userID int64, limit int) ([]ImportJob, error)
+	// Claim marks the oldest queued job as running and returns it with its
+	// file, or nil when there is none. Running jobs without a heartbeat for
+	// staleAfter are taken over, or failed after maxAttempts.
+	Claim(staleAfter time.Duration, maxAttempts int) (*ImportJob, []byte, error)
+	// Progress saves the job's counts and heartbeat.
+	Progress(job *ImportJob) error
+	// Finish saves the job's final state and drops its file.
+	Finish(job *ImportJob) error
+}
+
+type PostgresImporterRepository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) ImporterRepository {
+	return &PostgresImporterRepository{DB: db}
+}
+
+const jobColumns = `id, workspace_id, user_id, source, filename, status, attempts, total, processed, created,
+                 existing, invalid, skipped, projects_created, error, created_at, started_at, finished_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanJob(row rowScanner, job *ImportJob, extra ...any) error {
+	dest := []any{&job.ID, &job.WorkspaceID, &job.UserID, &job.Source, &job.Filename, &job.Status,
+		&job.Attempts, &job.Total, &job.Processed, &job.Created, &job.Existing, &job.Invalid, &job.Skipped,
+		&job.ProjectsCreated, &job.Error, &job.CreatedAt, &job.StartedAt, &job.FinishedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	job.setPercent()
+	return nil
+}
+
+func (r *PostgresImporterRepository) Create(job *ImportJob, file []byte) error {
+	query := `INSERT INTO import_jobs (workspace_id, user_id, source, filename, file, total, skipped)
+            VALUES ($1, $2, $3, $4, $5, $6, $7)
+            RETURNING ` + jobColumns + `;`
+
+	return db.WithTenant(r.DB, job.WorkspaceID, func(tx *sql.Tx) error {
+		return scanJob(tx.QueryRow(query, job.WorkspaceID, job.UserID, job.Source, job.Filename, file,
+			job.Total, job.Skipped), job)
+	})
+}
+
+func (r *PostgresImporterRepository) FindByID(workspaceID, userID, id int64) (*ImportJob, error) {
+	query := `SELECT ` + jobColumns + `, results FROM import_jobs
+            WHERE workspace_id = $1 AND user_id = $2 AND id = $3;`
+
+	var found *ImportJob
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		job := ImportJob{}
+		var results []byte
+		err := scanJob(tx.QueryRow(query, workspaceID, userID, id), &job, &results)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(results, &job.Results); err != nil {
+			return err
+		}
+		found = &job
+		return nil
+	})
+	return found, err
+}
+
+func (r *PostgresImporterRepository) FindByUser(workspaceID, userID int64, limit int) ([]ImportJob, error) {
+	query := `SELECT ` + jobColumns + ` FROM import_jobs
+            WHERE workspace_id = $1 AND user_id = $2
+            ORDER BY id DESC
+            LIMIT $3;`
+
+	jobs := []ImportJob{}
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, workspaceID, userID, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			job := ImportJob{}
+			if err := scanJob(rows, &job); err != nil {
+				return err
+			}
+			jobs = append(jobs, job)
+		}
+		return rows.Err()
+	})
+	return jobs, err
+}
+
+func (r *PostgresImporterRepository) Claim(staleAfter time.Duration, maxAttempts int) (*ImportJob, []byte, error) {
+	// Stale jobs that have run out of attempts probably bring their server
+	// down; they fail instead of being taken over again.
+	fail := `UPDATE import_jobs
+            SET status = 'failed', error = 'import was interrupted too many times', finished_at = NOW(),
+                file = NULL
+            WHERE status = 'running' AND heartbeat_at < NOW() - $1 * INTERVAL '1 second' AND attempts >= $2;`
+	claim := `UPDATE import_jobs
+            SET status = 'running', attempts = attempts + 1, heartbeat_at = NOW(),
+                started_at = COALESCE(started_at, NOW())
+            WHERE id = (
+              SELECT id FROM import_jobs
+              WHERE status = 'queued'
+                 OR (status = 'running' AND heartbeat_at < NOW() - $1 * INTERVAL '1 second')
+              ORDER BY created_at, id
+              LIMIT 1
+              FOR UPDATE SKIP LOCKED)
+            RETURNING ` + jobColumns + `, file;`
+
+	var claimed *ImportJob
+	var file []byte
+	err := db.AcrossTenants(r.DB, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(fail, staleAfter.Seconds(), maxAttempts); err != nil {
+			return err
+		}
+		job := ImportJob{}
+		err := scanJob(tx.QueryRow(claim, staleAfter.Seconds()), &job, &file)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		claimed = &job
+		return nil
+	})
+	if err != nil || claimed == nil {
+		return nil, nil, err
+	}
+	return claimed, file, nil
+}
+
+func (r *PostgresImporterRepository) Progress(job *ImportJob) error {
+	query := `UPDATE import_jobs
+            SET processed = $2, created = $3, existing = $4, invalid = $5, projects_created = $6,
+                heartbeat_at = NOW()
+            WHERE id = $1;`
+
+	return db.WithTenant(r.DB, job.WorkspaceID, func(tx *sql.Tx) error {
+		_, err := tx.Exec(query, job.ID, job.Processed, job.Created, job.Existing, job.Invalid, job.ProjectsCreated)
+		return err
+	})
+}
+
+func (r *PostgresImporterRepository) Finish(job *ImportJob) error {
+	query := `UPDATE import_jobs
+            SET status = $2, processed = $3, created = $4, existing = $5, invalid = $6, projects_created = $7,
+                error = $8, results = $9, file = NULL, heartbeat_at = NOW(), finished_at = NOW()
+            WHERE id = $1
+            RETURNING finished_at;`
+
+	results := job.Results
+	if results == nil {
+		results = []ItemResult{}
+	}
+	raw, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+	return db.WithTenant(r.DB, job.WorkspaceID, func(tx *sql.Tx) error {
+		return tx.QueryRow(query, job.ID, job.Status, job.Processed, job.Created, job.Existing, job.Invalid,
+			job.ProjectsCreated, job.Error, raw).Scan(&job.FinishedAt)
+	})
+}
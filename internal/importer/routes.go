@@ -0,0 +1,11 @@
+package importer
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Post("/imports", h.StartImport)
+	r.Get("/imports", h.GetImports)
+	r.Get("/imports/{id}", h.GetImport)
+}
@@ -0,0 +1,256 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/authz"
+	"github.com/sudarshanmg/gotask/internal/project"
+	"github.com/sudarshanmg/gotask/internal/task"
+)
+
+const (
+	// listLimit bounds the jobs listed for a user.
+	listLimit = 50
+	// progressEvery is how many tasks are imported between progress saves.
+	progressEvery = 25
+	// staleAfter is how long a running job may go without a heartbeat
+	// before another server takes it over, at most maxAttempts times.
+	staleAfter  = 5 * time.Minute
+	maxAttempts = 3
+)
+
+type ImporterService interface {
+	// Start reads the file and queues a job to import it. An empty source
+	// is guessed from the file.
+	Start(caller auth.Caller, source Source, filename string, file io.Reader) (*ImportJob, error)
+	GetByID(caller auth.Caller, id int64) (*ImportJob, error)
+	GetAll(caller auth.Caller) ([]ImportJob, error)
+	// ProcessQueued runs up to limit queued jobs to completion, returning
+	// how many ran.
+	ProcessQueued(limit int) (int, error)
+}
+
+type importerService struct {
+	repo     ImporterRepository
+	tasks    task.TaskService
+	projects project.ProjectService
+	policy   authz.Policy
+}
+
+func NewService(repo ImporterRepository, tasks task.TaskService, projects project.ProjectService,
+	policy authz.Policy) ImporterService {
+	return &importerService{repo: repo, tasks: tasks, projects: projects, policy: policy}
+}
+
+func (s *importerService) Start(caller auth.Caller, source Source, filename string, file io.Reader) (*ImportJob, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	if source == "" {
+		source = detect(filename, data)
+	}
+	if !source.valid() {
+		return nil, ErrInvalidSource
+	}
+	// The file is read now so that a bad file is reported at once, and
+	// again by the job.
+	p, err := parse(source, filename, data)
+	if err != nil {
+		return nil, err
+	}
+
+	job := ImportJob{
+		WorkspaceID: caller.WorkspaceID,
+		UserID:      caller.UserID,
+		Source:      source,
+		Filename:    filename,
+		Total:       len(p.Items),
+		Skipped:     p.Skipped,
+	}
+	if err := s.repo.Create(&job, data); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (s *importerService) GetByID(caller auth.Caller, id int64) (*ImportJob, error) {
+	job, err := s.repo.FindByID(caller.WorkspaceID, caller.UserID, id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrNotFound
+	}
+	return job, nil
+}
+
+func (s *importerService) GetAll(caller auth.Caller) ([]ImportJob, error) {
+	return s.repo.FindByUser(caller.WorkspaceID, caller.UserID, listLimit)
+}
+
+func (s *importerService) ProcessQueued(limit int) (int, error) {
+	ran := 0
+	for ran < limit {
+		job, file, err := s.repo.Claim(staleAfter, maxAttempts)
+		if err != nil {
+			return ran, err
+		}
+		if job == nil {
+			break
+		}
+		ran++
+
+		if err := s.run(job, file); err != nil {
+			log.Printf("importer: job %d failed: %v", job.ID, err)
+			job.Status = JobFailed
+			job.Error = "failed to import tasks"
+		} else {
+			job.Status = JobCompleted
+		}
+		if err := s.repo.Finish(job); err != nil {
+			return ran, err
+		}
+	}
+	return ran, nil
+}
+
+// run imports the job's tasks as its user. A job taken over from another
+// server starts again from the top; tasks created before then have
+// external IDs and are reported as existing.
+func (s *importerService) run(job *ImportJob, file []byte) error {
+	p, err := parse(job.Source, job.Filename, file)
+	if err != nil {
+		return err
+	}
+	caller := auth.Caller{UserID: job.UserID, WorkspaceID: job.WorkspaceID}
+	projects, err := s.projectFinder(caller)
+	if err != nil {
+		return err
+	}
+
+	job.Processed, job.Created, job.Existing, job.Invalid, job.ProjectsCreated = 0, 0, 0, 0, 0
+	job.Results = make([]ItemResult, 0, len(p.Items))
+	for i := range p.Items {
+		result, err := s.importItem(caller, &p.Items[i], projects, job)
+		if err != nil {
+			return err
+		}
+		result.Item = i + 1
+		job.Results = append(job.Results, result)
+		job.Processed++
+		switch result.Status {
+		case ItemCreated:
+			job.Created++
+		case ItemExisting:
+			job.Existing++
+		case ItemInvalid:
+			job.Invalid++
+		}
+		if job.Processed%progressEvery == 0 {
+			if err := s.repo.Progress(job); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// projectFinder looks up the caller's projects by name, ignoring case.
+type projectFinder struct {
+	byName map[string]project.ProjectResponse
+	// canCreate is checked the first time a project is missing.
+	canCreate *bool
+}
+
+func (s *importerService) projectFinder(caller auth.Caller) (*projectFinder, error) {
+	all, err := s.projects.GetAll(caller)
+	if err != nil {
+		return nil, err
+	}
+	f := &projectFinder{byName: map[string]project.ProjectResponse{}}
+	for _, p := range all {
+		key := strings.ToLower(strings.TrimSpace(p.Name))
+		if _, ok := f.byName[key]; !ok {
+			f.byName[key] = p
+		}
+	}
+	return f, nil
+}
+
+// project returns the ID of the caller's project with the given name,
+// creating it if there is none. Problems the caller can fix are returned
+// as a message.
+func (s *importerService) project(caller auth.Caller, f *projectFinder, name string, job *ImportJob) (*int64, string, error) {
+	name, _ = shorten(strings.TrimSpace(name), maxProjectName)
+	key := strings.ToLower(name)
+	if p, ok := f.byName[key]; ok {
+		if !p.Role.CanWrite() {
+			return nil, fmt.Sprintf("you cannot add tasks to project %q", p.Name), nil
+		}
+		return &p.ID, "", nil
+	}
+
+	if f.canCreate == nil {
+		subject := authz.Subject{UserID: caller.UserID, WorkspaceID: caller.WorkspaceID}
+		ok, err := s.policy.Can(subject, authz.ActionCreate, authz.Resource{Type: authz.ResourceProject})
+		if err != nil {
+			return nil, "", err
+		}
+		f.canCreate = &ok
+	}
+	if !*f.canCreate {
+		return nil, fmt.Sprintf("you cannot create project %q", name), nil
+	}
+	p, err := s.projects.Create(caller, project.CreateProjectRequest{Name: name})
+	if err != nil {
+		return nil, "", err
+	}
+	f.byName[key] = *p
+	job.ProjectsCreated++
+	return &p.ID, "", nil
+}
+
+// importItem imports one task. Problems with the task are reported in the
+// result; only unexpected errors are returned.
+func (s *importerService) importItem(caller auth.Caller, it *item, projects *projectFinder,
+	job *ImportJob) (ItemResult, error) {
+	result := ItemResult{ExternalID: it.ExternalID, Title: it.Title}
+
+	var projectID *int64
+	if it.Project != "" {
+		id, problem, err := s.project(caller, projects, it.Project, job)
+		if err != nil {
+			return result, err
+		}
+		if problem != "" {
+			result.Status = ItemInvalid
+			result.Error = problem
+			return result, nil
+		}
+		projectID = id
+	}
+
+	req, warnings := it.request(projectID)
+	result.Warnings = warnings
+	res, created, err := s.tasks.Import(caller, req, false)
+	if err != nil {
+		if !task.IsClientError(err) {
+			return result, err
+		}
+		result.Status = ItemInvalid
+		result.Error = err.Error()
+		return result, nil
+	}
+	result.TaskID = &res.ID
+	result.Status = ItemCreated
+	if !created {
+		result.Status = ItemExisting
+	}
+	return result, nil
+}
@@ -0,0 +1,262 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// flexID reads an ID that older Todoist exports write as a number and newer
+// ones as a string.
+type flexID string
+
+func (id *flexID) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*id = ""
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*id = flexID(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("invalid ID %s", data)
+	}
+	*id = flexID(n.String())
+	return nil
+}
+
+type todoistBackup struct {
+	Projects []struct {
+		ID   flexID `json:"id"`
+		Name string `json:"name"`
+	} `json:"projects"`
+	Items []todoistItem `json:"items"`
+	// Tasks is what the REST API calls items.
+	Tasks []todoistItem `json:"tasks"`
+}
+
+type todoistItem struct {
+	ID          flexID   `json:"id"`
+	Content     string   `json:"content"`
+	Description string   `json:"description"`
+	ProjectID   flexID   `json:"project_id"`
+	ParentID    flexID   `json:"parent_id"`
+	Priority    int      `json:"priority"`
+	Labels      []string `json:"labels"`
+	Due         *struct {
+		Date     string `json:"date"`
+		Datetime string `json:"datetime"`
+	} `json:"due"`
+	Checked     bool `json:"checked"`
+	IsCompleted bool `json:"is_completed"`
+	IsDeleted   bool `json:"is_deleted"`
+}
+
+// todoistPriority maps Todoist's API priorities, where 4 is the most
+// urgent and 1 is none, onto task priorities.
+func todoistPriority(p int) *int {
+	if p < 2 || p > 4 {
+		return nil
+	}
+	priority := 5 - p
+	return &priority
+}
+
+func parseTodoist(filename string, data []byte) (*parsed, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return parseTodoistJSON(trimmed)
+	}
+	return parseTodoistCSV(filename, data)
+}
+
+// parseTodoistJSON reads a backup with projects and items. Subtasks become
+// checklist items of their top-level task; sections are not kept.
+func parseTodoistJSON(data []byte) (*parsed, error) {
+	var backup todoistBackup
+	if data[0] == '[' {
+		if err := json.Unmarshal(data, &backup.Tasks); err != nil {
+			return nil, err
+		}
+	} else if err := json.Unmarshal(data, &backup); err != nil {
+		return nil, err
+	}
+	items := append(backup.Items, backup.Tasks...)
+
+	projects := map[flexID]string{}
+	for _, p := range backup.Projects {
+		projects[p.ID] = p.Name
+	}
+	byID := map[flexID]*todoistItem{}
+	for i := range items {
+		byID[items[i].ID] = &items[i]
+	}
+	// root follows parents up to the top-level task.
+	root := func(it *todoistItem) *todoistItem {
+		for depth := 0; it.ParentID != "" && depth < 100; depth++ {
+			parent, ok := byID[it.ParentID]
+			if !ok {
+				break
+			}
+			it = parent
+		}
+		return it
+	}
+
+	p := &parsed{}
+	index := map[flexID]int{}
+	subtasks := map[flexID][]checkItem{}
+	for i := range items {
+		it := &items[i]
+		if it.IsDeleted {
+			p.Skipped++
+			continue
+		}
+		if top := root(it); top != it {
+			subtasks[top.ID] = append(subtasks[top.ID], checkItem{Text: it.Content, Done: it.Checked || it.IsCompleted})
+			continue
+		}
+
+		labels, title := inlineLabels(it.Content)
+		imported := item{
+			ExternalID:  "todoist:" + string(it.ID),
+			Title:       title,
+			Description: it.Description,
+			Project:     projects[it.ProjectID],
+			Labels:      append(it.Labels, labels...),
+			Priority:    todoistPriority(it.Priority),
+			Completed:   it.Checked || it.IsCompleted,
+		}
+		if it.Due != nil {
+			due := it.Due.Datetime
+			if due == "" {
+				due = it.Due.Date
+			}
+			if at, ok := parseDate(due); ok {
+				imported.DueAt = &at
+			} else if due != "" {
+				imported.Warnings = append(imported.Warnings, fmt.Sprintf("due date %q was not understood", due))
+			}
+		}
+		// Inbox tasks are personal tasks.
+		if strings.EqualFold(imported.Project, "Inbox") {
+			imported.Project = ""
+		}
+		index[it.ID] = len(p.Items)
+		p.Items = append(p.Items, imported)
+	}
+	for id, list := range subtasks {
+		if i, ok := index[id]; ok {
+			p.Items[i].Checklists = []checklist{{Items: list}}
+		}
+	}
+	return p, nil
+}
+
+// inlineLabels takes @label words out of a task's content, as Todoist
+// writes labels in CSV exports and some clients in the content itself.
+func inlineLabels(content string) ([]string, string) {
+	labels := []string{}
+	words := []string{}
+	for _, word := range strings.Fields(content) {
+		if len(word) > 1 && word[0] == '@' {
+			labels = append(labels, word[1:])
+			continue
+		}
+		words = append(words, word)
+	}
+	return labels, strings.Join(words, " ")
+}
+
+// parseTodoistCSV reads a project exported as CSV; the project is named
+// after the file. Rows with an INDENT over 1 are subtasks of the task
+// above them. Todoist's CSV priorities run from 1, the most urgent, to 4,
+// which is no priority.
+func parseTodoistCSV(filename string, data []byte) (*parsed, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToUpper(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["CONTENT"]; !ok {
+		return nil, errors.New("missing CONTENT column")
+	}
+	cell := func(row []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	project := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	if filename == "" || strings.EqualFold(project, "Inbox") {
+		project = ""
+	}
+	p := &parsed{}
+	seen := map[string]int{}
+	for line := 2; ; line++ {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		kind := strings.ToLower(cell(row, "TYPE"))
+		content := cell(row, "CONTENT")
+		if kind != "task" || content == "" {
+			if kind != "" && kind != "meta" {
+				p.Skipped++
+			}
+			continue
+		}
+
+		indent, _ := strconv.Atoi(cell(row, "INDENT"))
+		if indent > 1 && len(p.Items) > 0 {
+			parent := &p.Items[len(p.Items)-1]
+			if len(parent.Checklists) == 0 {
+				parent.Checklists = []checklist{{}}
+			}
+			_, text := inlineLabels(content)
+			parent.Checklists[0].Items = append(parent.Checklists[0].Items, checkItem{Text: text})
+			continue
+		}
+
+		labels, title := inlineLabels(content)
+		imported := item{
+			ExternalID:  hashID(SourceTodoist, seen, project, content),
+			Title:       title,
+			Description: cell(row, "DESCRIPTION"),
+			Project:     project,
+			Labels:      labels,
+		}
+		if priority, err := strconv.Atoi(cell(row, "PRIORITY")); err == nil && priority >= 1 && priority <= 3 {
+			imported.Priority = &priority
+		}
+		if due := cell(row, "DATE"); due != "" {
+			if at, ok := parseDate(due); ok {
+				imported.DueAt = &at
+			} else {
+				imported.Warnings = append(imported.Warnings,
+					fmt.Sprintf("line %d: due date %q was not understood", line, due))
+			}
+		}
+		p.Items = append(p.Items, imported)
+	}
+	return p, nil
+}
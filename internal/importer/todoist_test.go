@@ -0,0 +1,124 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTodoistCSV(t *testing.T) {
+	data := "type,Content,PRIORITY,INDENT,description,DATE,DATE_LANG\r\n" +
+		"meta,view_style,,,,,\r\n" +
+		"section,Later,,,,,\r\n" +
+		"task,Call the bank @phone @errands,1,1,About the card,2024-03-01,en\r\n" +
+		"task,Check the statement,4,2,,,en\r\n" +
+		"task,Sub-sub @ignored,4,3,,,en\r\n" +
+		"task,Plan trip,4,1,,next week,en\r\n" +
+		"task,,1,1,,,en\r\n" +
+		"task,Plan trip,2,1,,2024-04-01 09:00,en\r\n" +
+		"task,Short row\r\n"
+
+	p, err := parseTodoistCSV("Personal Errands.csv", []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Skipped != 2 {
+		t.Errorf("Skipped = %d, want 2 (the section and the empty task)", p.Skipped)
+	}
+	if len(p.Items) != 4 {
+		t.Fatalf("got %d items, want 4: %+v", len(p.Items), p.Items)
+	}
+
+	bank := p.Items[0]
+	if bank.Title != "Call the bank" || bank.Description != "About the card" || bank.Project != "Personal Errands" {
+		t.Errorf("first item = %+v", bank)
+	}
+	if strings.Join(bank.Labels, ",") != "phone,errands" {
+		t.Errorf("Labels = %q", bank.Labels)
+	}
+	if !sameInt(bank.Priority, intPtr(1)) || !sameTime(bank.DueAt, timePtr(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))) {
+		t.Errorf("Priority = %v, DueAt = %v", bank.Priority, bank.DueAt)
+	}
+	if len(bank.Checklists) != 1 || len(bank.Checklists[0].Items) != 2 ||
+		bank.Checklists[0].Items[0].Text != "Check the statement" || bank.Checklists[0].Items[1].Text != "Sub-sub" {
+		t.Errorf("Checklists = %+v", bank.Checklists)
+	}
+
+	trip := p.Items[1]
+	if trip.Priority != nil || trip.DueAt != nil || len(trip.Warnings) != 1 || !strings.Contains(trip.Warnings[0], "line 7") {
+		t.Errorf("second item = %+v", trip)
+	}
+	if again := p.Items[2]; again.ExternalID == trip.ExternalID || !sameInt(again.Priority, intPtr(2)) {
+		t.Errorf("duplicate row = %+v", again)
+	}
+	if p.Items[3].Title != "Short row" {
+		t.Errorf("short row = %+v", p.Items[3])
+	}
+
+	inbox, err := parseTodoistCSV("inbox.csv", []byte("TYPE,CONTENT\ntask,x\n"))
+	if err != nil || inbox.Items[0].Project != "" {
+		t.Errorf("Inbox tasks should be personal: %+v, %v", inbox, err)
+	}
+}
+
+func TestParseTodoistJSON(t *testing.T) {
+	data := `{
+	  "projects": [{"id": 1, "name": "Inbox"}, {"id": "2", "name": "Home"}],
+	  "items": [
+	    {"id": 10, "content": "Paint @diy", "project_id": "2", "priority": 4, "labels": ["weekend"],
+	     "due": {"date": "2024-05-01"}},
+	    {"id": 11, "content": "Buy paint", "project_id": "2", "parent_id": 10, "checked": true},
+	    {"id": 12, "content": "Pick a color", "project_id": "2", "parent_id": "11"},
+	    {"id": 13, "content": "Old", "project_id": 1, "is_deleted": true},
+	    {"id": 14, "content": "Reply", "project_id": 1, "priority": 1,
+	     "due": {"date": "2024-05-01", "datetime": "2024-05-01T09:00:00Z"}},
+	    {"id": 15, "content": "Someday", "project_id": 1, "due": {"date": "some day"}, "is_completed": true}
+	  ]
+	}`
+
+	p, err := parseTodoist("backup.json", []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Skipped != 1 || len(p.Items) != 3 {
+		t.Fatalf("Skipped = %d, items = %+v", p.Skipped, p.Items)
+	}
+
+	paint := p.Items[0]
+	if paint.ExternalID != "todoist:10" || paint.Title != "Paint" || paint.Project != "Home" {
+		t.Errorf("first item = %+v", paint)
+	}
+	if strings.Join(paint.Labels, ",") != "weekend,diy" || !sameInt(paint.Priority, intPtr(1)) {
+		t.Errorf("Labels = %q, Priority = %v", paint.Labels, paint.Priority)
+	}
+	if len(paint.Checklists) != 1 || len(paint.Checklists[0].Items) != 2 || !paint.Checklists[0].Items[0].Done {
+		t.Errorf("subtasks = %+v", paint.Checklists)
+	}
+
+	reply := p.Items[1]
+	if reply.Project != "" || reply.Priority != nil ||
+		!sameTime(reply.DueAt, timePtr(time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC))) {
+		t.Errorf("second item = %+v", reply)
+	}
+	if someday := p.Items[2]; !someday.Completed || someday.DueAt != nil || len(someday.Warnings) != 1 {
+		t.Errorf("third item = %+v", someday)
+	}
+
+	// The REST API returns a bare list of tasks.
+	p, err = parseTodoist("tasks.json", []byte(`[{"id": "a1", "content": "Water plants"}]`))
+	if err != nil || len(p.Items) != 1 || p.Items[0].ExternalID != "todoist:a1" {
+		t.Errorf("task list = %+v, %v", p, err)
+	}
+}
+
+func TestTodoistPriority(t *testing.T) {
+	tests := []struct {
+		in   int
+		want *int
+	}{{4, intPtr(1)}, {3, intPtr(2)}, {2, intPtr(3)}, {1, nil}, {0, nil}, {5, nil}}
+	for _, tt := range tests {
+		if got := todoistPriority(tt.in); !sameInt(got, tt.want) {
+			t.Errorf("todoistPriority(%d) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
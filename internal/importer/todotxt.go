@@ -0,0 +1,93 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// todoTxtPriority maps (A) to priority 1, (B) to 2, (C) to 3 and any later
+// letter to 4.
+func todoTxtPriority(letter byte) *int {
+	priority := min(int(letter-'A')+1, 4)
+	return &priority
+}
+
+func isTodoTxtDate(word string) bool {
+	_, ok := parseDate(word)
+	return ok && len(word) == len("2006-01-02")
+}
+
+// parseTodoTxt reads one task per line. The first +project of a line is
+// its project and any others are labels, as are @contexts; due:YYYY-MM-DD
+// sets the due date. Completed lines start with "x", and keep their
+// priority in a pri: tag. Other key:value tags stay in the title.
+func parseTodoTxt(data []byte) (*parsed, error) {
+	p := &parsed{}
+	seen := map[string]int{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		words := strings.Fields(scanner.Text())
+		if len(words) == 0 {
+			continue
+		}
+
+		imported := item{}
+		if words[0] == "x" {
+			imported.Completed = true
+			words = words[1:]
+			// The completion date, then the creation date.
+			for i := 0; i < 2 && len(words) > 0 && isTodoTxtDate(words[0]); i++ {
+				words = words[1:]
+			}
+		}
+		if len(words) > 0 && len(words[0]) == 3 && words[0][0] == '(' && words[0][2] == ')' &&
+			words[0][1] >= 'A' && words[0][1] <= 'Z' {
+			imported.Priority = todoTxtPriority(words[0][1])
+			words = words[1:]
+		}
+		if !imported.Completed && len(words) > 0 && isTodoTxtDate(words[0]) {
+			words = words[1:]
+		}
+		// The task's identity leaves out its completion and priority, which
+		// change over time.
+		identity := []string{}
+		title := []string{}
+		for _, word := range words {
+			if !strings.HasPrefix(word, "pri:") {
+				identity = append(identity, word)
+			}
+			switch {
+			case len(word) > 1 && word[0] == '+':
+				if imported.Project == "" {
+					imported.Project = word[1:]
+				} else {
+					imported.Labels = append(imported.Labels, word[1:])
+				}
+			case len(word) > 1 && word[0] == '@':
+				imported.Labels = append(imported.Labels, word[1:])
+			case strings.HasPrefix(word, "due:") && len(word) > len("due:"):
+				if at, ok := parseDate(word[len("due:"):]); ok {
+					imported.DueAt = &at
+				} else {
+					imported.Warnings = append(imported.Warnings,
+						fmt.Sprintf("line %d: due date %q was not understood", line, word[len("due:"):]))
+					title = append(title, word)
+				}
+			case strings.HasPrefix(word, "pri:") && len(word) == len("pri:A") && word[4] >= 'A' && word[4] <= 'Z':
+				imported.Priority = todoTxtPriority(word[4])
+			default:
+				title = append(title, word)
+			}
+		}
+		imported.Title = strings.Join(title, " ")
+		imported.ExternalID = hashID(SourceTodoTxt, seen, strings.Join(identity, " "))
+		p.Items = append(p.Items, imported)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,71 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTodoTxt(t *testing.T) {
+	tests := []struct {
+		line      string
+		title     string
+		project   string
+		labels    string
+		priority  *int
+		due       *time.Time
+		completed bool
+		warnings  int
+	}{
+		{line: "Buy milk", title: "Buy milk"},
+		{line: "(A) Call Mom +Family @phone", title: "Call Mom", project: "Family", labels: "phone", priority: intPtr(1)},
+		{line: "(C) 2024-01-05 Pay rent +Home +Bills due:2024-02-01", title: "Pay rent", project: "Home",
+			labels: "Bills", priority: intPtr(3), due: timePtr(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))},
+		{line: "(Z) Someday", title: "Someday", priority: intPtr(4)},
+		{line: "x 2024-01-10 2024-01-01 File taxes pri:B", title: "File taxes", priority: intPtr(2), completed: true},
+		{line: "x Done without dates", title: "Done without dates", completed: true},
+		{line: "Meet due:friday url:https://x.test", title: "Meet due:friday url:https://x.test", warnings: 1},
+		{line: "(a) lower case is not a priority", title: "(a) lower case is not a priority"},
+		{line: "+ @ lone markers", title: "+ @ lone markers"},
+	}
+	for _, tt := range tests {
+		p, err := parseTodoTxt([]byte(tt.line))
+		if err != nil {
+			t.Fatalf("%q: %v", tt.line, err)
+		}
+		if len(p.Items) != 1 {
+			t.Fatalf("%q: got %d items", tt.line, len(p.Items))
+		}
+		it := p.Items[0]
+		if it.Title != tt.title || it.Project != tt.project || strings.Join(it.Labels, ",") != tt.labels ||
+			!sameInt(it.Priority, tt.priority) || !sameTime(it.DueAt, tt.due) || it.Completed != tt.completed ||
+			len(it.Warnings) != tt.warnings {
+			t.Errorf("%q = %+v", tt.line, it)
+		}
+	}
+}
+
+func TestParseTodoTxtIDs(t *testing.T) {
+	id := func(data string) []string {
+		p, err := parseTodoTxt([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := []string{}
+		for _, it := range p.Items {
+			ids = append(ids, it.ExternalID)
+		}
+		return ids
+	}
+
+	// Completing a task or changing its priority keeps its ID, so that
+	// importing the file again updates the task.
+	open := id("(A) Water plants")[0]
+	if done := id("x 2024-01-02 Water plants pri:B")[0]; done != open {
+		t.Errorf("completed task ID = %q, want %q", done, open)
+	}
+	// Identical lines make separate tasks.
+	if ids := id("Water plants\n\nWater plants"); len(ids) != 2 || ids[0] != open || ids[1] != open+"-2" {
+		t.Errorf("IDs = %q", ids)
+	}
+}
@@ -0,0 +1,111 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+type trelloBoard struct {
+	Name  string `json:"name"`
+	Lists []struct {
+		ID     string `json:"id"`
+		Name   string `json:"name"`
+		Closed bool   `json:"closed"`
+	} `json:"lists"`
+	Cards []struct {
+		ID          string  `json:"id"`
+		Name        string  `json:"name"`
+		Desc        string  `json:"desc"`
+		IDList      string  `json:"idList"`
+		Closed      bool    `json:"closed"`
+		Due         *string `json:"due"`
+		DueComplete bool    `json:"dueComplete"`
+		Labels      []struct {
+			Name  string `json:"name"`
+			Color string `json:"color"`
+		} `json:"labels"`
+		IDChecklists []string `json:"idChecklists"`
+	} `json:"cards"`
+	Checklists []struct {
+		ID         string  `json:"id"`
+		IDCard     string  `json:"idCard"`
+		Name       string  `json:"name"`
+		Pos        float64 `json:"pos"`
+		CheckItems []struct {
+			Name  string  `json:"name"`
+			State string  `json:"state"`
+			Pos   float64 `json:"pos"`
+		} `json:"checkItems"`
+	} `json:"checklists"`
+}
+
+// parseTrello reads a board export into a project named after the board.
+// Each card's list becomes a label, and unnamed card labels are named by
+// their color. Archived cards and cards in archived lists are skipped; a
+// card whose due date is marked complete is completed.
+func parseTrello(data []byte) (*parsed, error) {
+	var board trelloBoard
+	if err := json.Unmarshal(data, &board); err != nil {
+		return nil, err
+	}
+	if board.Cards == nil {
+		return nil, fmt.Errorf("not a Trello board export")
+	}
+
+	lists := map[string]string{}
+	closed := map[string]bool{}
+	for _, l := range board.Lists {
+		lists[l.ID] = l.Name
+		closed[l.ID] = l.Closed
+	}
+	sort.SliceStable(board.Checklists, func(i, j int) bool { return board.Checklists[i].Pos < board.Checklists[j].Pos })
+	checklists := map[string][]checklist{}
+	for _, c := range board.Checklists {
+		sort.SliceStable(c.CheckItems, func(i, j int) bool { return c.CheckItems[i].Pos < c.CheckItems[j].Pos })
+		list := checklist{Name: c.Name}
+		for _, ci := range c.CheckItems {
+			list.Items = append(list.Items, checkItem{Text: ci.Name, Done: ci.State == "complete"})
+		}
+		checklists[c.IDCard] = append(checklists[c.IDCard], list)
+	}
+
+	p := &parsed{}
+	for _, card := range board.Cards {
+		if card.Closed || closed[card.IDList] {
+			p.Skipped++
+			continue
+		}
+		imported := item{
+			ExternalID:  "trello:" + card.ID,
+			Title:       card.Name,
+			Description: card.Desc,
+			Project:     board.Name,
+			Completed:   card.DueComplete,
+			Checklists:  checklists[card.ID],
+		}
+		if name := lists[card.IDList]; name != "" {
+			imported.Labels = append(imported.Labels, name)
+		}
+		for _, label := range card.Labels {
+			name := label.Name
+			if name == "" {
+				name = label.Color
+			}
+			imported.Labels = append(imported.Labels, name)
+		}
+		// A single checklist needs no heading.
+		if len(imported.Checklists) == 1 {
+			imported.Checklists[0].Name = ""
+		}
+		if card.Due != nil && *card.Due != "" {
+			if at, ok := parseDate(*card.Due); ok {
+				imported.DueAt = &at
+			} else {
+				imported.Warnings = append(imported.Warnings, fmt.Sprintf("due date %q was not understood", *card.Due))
+			}
+		}
+		p.Items = append(p.Items, imported)
+	}
+	return p, nil
+}
@@ -0,0 +1,62 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTrello(t *testing.T) {
+	data := `{
+	  "name": "Launch",
+	  "lists": [
+	    {"id": "l1", "name": "Doing"},
+	    {"id": "l2", "name": "Old", "closed": true}
+	  ],
+	  "cards": [
+	    {"id": "c1", "name": "Write copy", "desc": "For the site", "idList": "l1",
+	     "due": "2024-06-01T12:00:00.000Z", "dueComplete": true,
+	     "labels": [{"name": "Marketing", "color": "green"}, {"name": "", "color": "red"}]},
+	    {"id": "c2", "name": "Archived", "idList": "l1", "closed": true},
+	    {"id": "c3", "name": "In archived list", "idList": "l2"},
+	    {"id": "c4", "name": "Review", "idList": "l1", "due": "soon"}
+	  ],
+	  "checklists": [
+	    {"id": "k2", "idCard": "c4", "name": "Second", "pos": 2,
+	     "checkItems": [{"name": "b", "state": "incomplete", "pos": 2}, {"name": "a", "state": "complete", "pos": 1}]},
+	    {"id": "k1", "idCard": "c4", "name": "First", "pos": 1, "checkItems": [{"name": "x", "state": "complete", "pos": 1}]},
+	    {"id": "k3", "idCard": "c1", "name": "Only", "pos": 1, "checkItems": [{"name": "draft", "state": "incomplete", "pos": 1}]}
+	  ]
+	}`
+
+	p, err := parseTrello([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Skipped != 2 || len(p.Items) != 2 {
+		t.Fatalf("Skipped = %d, items = %+v", p.Skipped, p.Items)
+	}
+
+	card := p.Items[0]
+	if card.ExternalID != "trello:c1" || card.Title != "Write copy" || card.Description != "For the site" ||
+		card.Project != "Launch" || !card.Completed {
+		t.Errorf("first card = %+v", card)
+	}
+	if strings.Join(card.Labels, ",") != "Doing,Marketing,red" {
+		t.Errorf("Labels = %q", card.Labels)
+	}
+	if !sameTime(card.DueAt, timePtr(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))) {
+		t.Errorf("DueAt = %v", card.DueAt)
+	}
+	if len(card.Checklists) != 1 || card.Checklists[0].Name != "" {
+		t.Errorf("a single checklist should have no heading: %+v", card.Checklists)
+	}
+
+	review := p.Items[1]
+	if review.DueAt != nil || len(review.Warnings) != 1 {
+		t.Errorf("second card = %+v", review)
+	}
+	if want := "**First**\n- [x] x\n\n**Second**\n- [x] a\n- [ ] b"; review.description() != want {
+		t.Errorf("checklists rendered as %q, want %q", review.description(), want)
+	}
+}
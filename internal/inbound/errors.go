@@ -0,0 +1,10 @@
+package inbound
+
+import "errors"
+
+var (
+	ErrNotFound         = errors.New("inbound address not found")
+	ErrInvalidProject   = errors.New("project must be one you can add tasks to")
+	ErrInvalidMessage   = errors.New("invalid email message")
+	ErrUnknownRecipient = errors.New("no inbound address matches the recipient")
+)
@@ -0,0 +1,115 @@
+package inbound
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/task"
+	"github.com/sudarshanmg/gotask/pkg/response"
+)
+
+// maxMessageBytes bounds a raw message; attachments are base64, so this
+// allows a little over 20 MB of files.
+const maxMessageBytes = 30 << 20
+
+type Handler struct {
+	service InboundService
+	// secret is the bearer token the mail server sends with messages;
+	// receiving is off without one.
+	secret string
+}
+
+func NewHandler(service InboundService, secret string) *Handler {
+	return &Handler{service: service, secret: secret}
+}
+
+func writeServiceError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidProject), errors.Is(err, ErrInvalidMessage),
+		strings.HasPrefix(err.Error(), "validation failed:"):
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrUnknownRecipient):
+		response.WriteError(w, http.StatusNotFound, err.Error())
+	case task.IsClientError(err):
+		// The message is fine but cannot become a task, e.g. because the
+		// user lost access to the address's project.
+		response.WriteError(w, http.StatusUnprocessableEntity, err.Error())
+	default:
+		response.WriteError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+func (h *Handler) CreateAddress(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var req CreateAddressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		response.WriteError(w, http.StatusBadRequest, "invalid request payload")
+		return
+	}
+
+	address, err := h.service.CreateAddress(auth.GetCaller(r), req)
+	if err != nil {
+		writeServiceError(w, err, "failed to create inbound address")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, address)
+}
+
+func (h *Handler) GetAddress(w http.ResponseWriter, r *http.Request) {
+	address, err := h.service.GetAddress(auth.GetCaller(r))
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch inbound address")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, address)
+}
+
+func (h *Handler) DeleteAddress(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteAddress(auth.GetCaller(r)); err != nil {
+		writeServiceError(w, err, "failed to delete inbound address")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Receive accepts a raw RFC 5322 message from the mail server, which
+// authenticates with "Authorization: Bearer <INBOUND_SECRET>". The
+// envelope recipient may be passed as ?recipient=.
+func (h *Handler) Receive(w http.ResponseWriter, r *http.Request) {
+	if h.secret == "" {
+		response.WriteError(w, http.StatusNotFound, "inbound email is not configured")
+		return
+	}
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.secret)) != 1 {
+		response.WriteError(w, http.StatusUnauthorized, "invalid inbound secret")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxMessageBytes)
+	result, err := h.service.Receive(r.URL.Query().Get("recipient"), r.Body)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		response.WriteError(w, http.StatusRequestEntityTooLarge, "message is too large")
+		return
+	}
+	if err != nil {
+		writeServiceError(w, err, "failed to receive message")
+		return
+	}
+
+	status := http.StatusCreated
+	if !result.Created {
+		status = http.StatusOK
+	}
+	response.WriteJSON(w, status, result)
+}
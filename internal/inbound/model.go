@@ -0,0 +1,56 @@
+package inbound
+
+import "time"
+
+// Address is a user's inbound email address in a workspace: Token@domain.
+// Mail sent to it becomes tasks of the user, in ProjectID if set.
+type Address struct {
+	ID          int64
+	WorkspaceID int64
+	UserID      int64
+	Token       string
+	ProjectID   *int64
+	CreatedAt   time.Time
+	LastUsedAt  *time.Time
+}
+
+type AddressResponse struct {
+	Address    string     `json:"address"`
+	ProjectID  *int64     `json:"project_id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// CreateAddressRequest optionally sends tasks to a project instead of the
+// user's personal tasks.
+type CreateAddressRequest struct {
+	ProjectID *int64 `json:"project_id,omitempty" validate:"omitempty,gt=0"`
+}
+
+// Message is the part of an email that becomes a task.
+type Message struct {
+	MessageID string
+	Subject   string
+	// Body is the plain text of the message, taken from its HTML when it
+	// has no text part.
+	Body        string
+	Attachments []File
+	// Recipients are the addresses in the delivery and address headers,
+	// used when the MTA does not name the recipient.
+	Recipients []string
+}
+
+type File struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Result reports what a message became.
+type Result struct {
+	TaskID int64 `json:"task_id"`
+	// Created is false when the message was delivered before.
+	Created     bool     `json:"created"`
+	Attachments int      `json:"attachments"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
@@ -0,0 +1,238 @@
+package inbound
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxDepth bounds how deeply multipart bodies may nest.
+const maxDepth = 10
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+// charsetReader lets encoded headers use the Latin-1 family besides UTF-8.
+func charsetReader(charset string, r io.Reader) (io.Reader, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return strings.NewReader(decodeCharset(data, charset)), nil
+}
+
+// decodeCharset converts text to UTF-8. Charsets other than UTF-8 and
+// Latin-1 keep their ASCII; other bytes are replaced.
+func decodeCharset(data []byte, charset string) string {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "iso-8859-15", "latin1", "windows-1252", "cp1252":
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	}
+	return strings.ToValidUTF8(string(data), "�")
+}
+
+func decodeHeader(value string) string {
+	decoded, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// ParseMessage reads an RFC 5322 message with MIME parts. The first text
+// part is the body; parts with a filename, and any that are not text, are
+// attachments.
+func ParseMessage(r io.Reader) (*Message, error) {
+	m, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidMessage, err)
+	}
+
+	msg := &Message{
+		MessageID: strings.Trim(strings.TrimSpace(m.Header.Get("Message-Id")), "<>"),
+		Subject:   strings.TrimSpace(decodeHeader(m.Header.Get("Subject"))),
+	}
+	for _, name := range []string{"X-Original-To", "Delivered-To", "To", "Cc"} {
+		for _, value := range m.Header[textproto.CanonicalMIMEHeaderKey(name)] {
+			list, err := mail.ParseAddressList(value)
+			if err != nil {
+				continue
+			}
+			for _, a := range list {
+				msg.Recipients = append(msg.Recipients, a.Address)
+			}
+		}
+	}
+
+	p := &parts{msg: msg}
+	if err := p.walk(textproto.MIMEHeader(m.Header), m.Body, 0, false); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidMessage, err)
+	}
+	msg.Body = strings.TrimSpace(p.text)
+	if msg.Body == "" {
+		msg.Body = htmlToText(p.html)
+	}
+	return msg, nil
+}
+
+type parts struct {
+	msg  *Message
+	text string
+	html string
+}
+
+// walk reads a part. decoded is set for parts of a multipart.Reader, which
+// has already undone quoted-printable.
+func (p *parts) walk(header textproto.MIMEHeader, body io.Reader, depth int, decoded bool) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth >= maxDepth {
+			return fmt.Errorf("parts are nested too deeply")
+		}
+		if params["boundary"] == "" {
+			return fmt.Errorf("multipart body without a boundary")
+		}
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := p.walk(part.Header, part, depth+1, true); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		if !decoded {
+			body = quotedprintable.NewReader(body)
+		}
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	disposition, dparams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := dparams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+	filename = decodeHeader(filename)
+
+	isText := mediaType == "text/plain" || mediaType == "text/html"
+	if disposition == "attachment" || filename != "" || !isText {
+		if len(data) == 0 {
+			return nil
+		}
+		if filename == "" {
+			filename = defaultFilename(mediaType)
+		}
+		p.msg.Attachments = append(p.msg.Attachments, File{Filename: filename, ContentType: mediaType, Data: data})
+		return nil
+	}
+
+	text := decodeCharset(data, params["charset"])
+	if mediaType == "text/plain" && p.text == "" {
+		p.text = text
+	} else if mediaType == "text/html" && p.html == "" {
+		p.html = text
+	}
+	return nil
+}
+
+func defaultFilename(mediaType string) string {
+	if mediaType == "message/rfc822" {
+		return "message.eml"
+	}
+	if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+		return "attachment" + exts[0]
+	}
+	return "attachment"
+}
+
+var (
+	htmlHidden = regexp.MustCompile(`(?is)<(script|style|head)\b.*?</(script|style|head)\s*>`)
+	htmlBreak  = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|li|tr|h[1-6])\s*>`)
+	htmlTag    = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToText keeps the text of an HTML body and its line breaks.
+func htmlToText(s string) string {
+	s = htmlHidden.ReplaceAllString(s, "")
+	s = htmlBreak.ReplaceAllString(s, "\n")
+	s = html.UnescapeString(htmlTag.ReplaceAllString(s, ""))
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+var (
+	// replyPrefix matches "Re:", "Fwd:" and their translations.
+	replyPrefix = regexp.MustCompile(`(?i)^(re|fwd?|aw|wg|sv|vs|tr)(\[\d+\])?\s*:\s*`)
+	labelToken  = regexp.MustCompile(`^#(\pL[\pL\pN_\-/.]*)$`)
+	priorityTag = regexp.MustCompile(`(?i)^!p([1-4])$`)
+)
+
+// parseSubject turns a subject into a title, taking out reply prefixes,
+// #label tokens and a !p1 to !p4 priority. A # followed by a digit, as in
+// "issue #12", is kept.
+func parseSubject(subject string) (string, []string, *int) {
+	subject = strings.TrimSpace(subject)
+	for {
+		trimmed := replyPrefix.ReplaceAllString(subject, "")
+		if trimmed == subject {
+			break
+		}
+		subject = strings.TrimSpace(trimmed)
+	}
+
+	var words, labels []string
+	var priority *int
+	seen := map[string]bool{}
+	for _, word := range strings.FieldsFunc(subject, unicode.IsSpace) {
+		if m := labelToken.FindStringSubmatch(word); m != nil {
+			label := strings.ToLower(m[1])
+			if !seen[label] && utf8.RuneCountInString(label) <= 50 && len(labels) < 20 {
+				seen[label] = true
+				labels = append(labels, label)
+			}
+			continue
+		}
+		if m := priorityTag.FindStringSubmatch(word); m != nil {
+			p := int(m[1][0] - '0')
+			priority = &p
+			continue
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, " "), labels, priority
+}
@@ -0,0 +1,75 @@
+package inbound
+
+import (
+	"database/sql"
+	"errors"
+)
+
+type InboundRepository interface {
+	// Save creates the user's address in the workspace, or replaces its
+	// token and project.
+	Save(a *Address) error
+	Find(workspaceID, userID int64) (*Address, error)
+	Delete(workspaceID, userID int64) error
+	// FindByToken finds an address whose user is still a member of its
+	// workspace, and marks it used.
+	FindByToken(token string) (*Address, error)
+}
+
+type PostgresInboundRepository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) InboundRepository {
+	return &PostgresInboundRepository{DB: db}
+}
+
+const addressColumns = `id, workspace_id, user_id, token, project_id, created_at, last_used_at`
+
+func scanAddress(row *sql.Row) (*Address, error) {
+	a := Address{}
+	err := row.Scan(&a.ID, &a.WorkspaceID, &a.UserID, &a.Token, &a.ProjectID, &a.CreatedAt, &a.LastUsedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *PostgresInboundRepository) Save(a *Address) error {
+	query := `INSERT INTO inbound_addresses (workspace_id, user_id, token, project_id)
+            VALUES ($1, $2, $3, $4)
+            ON CONFLICT (workspace_id, user_id)
+            DO UPDATE SET token = EXCLUDED.token, project_id = EXCLUDED.project_id,
+                          created_at = NOW(), last_used_at = NULL
+            RETURNING ` + addressColumns + `;`
+
+	saved, err := scanAddress(r.DB.QueryRow(query, a.WorkspaceID, a.UserID, a.Token, a.ProjectID))
+	if err != nil {
+		return err
+	}
+	*a = *saved
+	return nil
+}
+
+func (r *PostgresInboundRepository) Find(workspaceID, userID int64) (*Address, error) {
+	query := `SELECT ` + addressColumns + ` FROM inbound_addresses WHERE workspace_id = $1 AND user_id = $2;`
+	return scanAddress(r.DB.QueryRow(query, workspaceID, userID))
+}
+
+func (r *PostgresInboundRepository) Delete(workspaceID, userID int64) error {
+	_, err := r.DB.Exec(`DELETE FROM inbound_addresses WHERE workspace_id = $1 AND user_id = $2;`,
+		workspaceID, userID)
+	return err
+}
+
+func (r *PostgresInboundRepository) FindByToken(token string) (*Address, error) {
+	query := `UPDATE inbound_addresses a SET last_used_at = NOW()
+            WHERE token = $1
+              AND EXISTS (SELECT 1 FROM workspace_members m
+                          WHERE m.workspace_id = a.workspace_id AND m.user_id = a.user_id)
+            RETURNING ` + addressColumns + `;`
+	return scanAddress(r.DB.QueryRow(query, token))
+}
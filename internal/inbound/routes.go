@@ -0,0 +1,17 @@
+package inbound
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Get("/inbound/address", h.GetAddress)
+	r.Post("/inbound/address", h.CreateAddress)
+	r.Delete("/inbound/address", h.DeleteAddress)
+}
+
+// RegisterPublicRoutes mounts the endpoint the mail server delivers to. It
+// checks its own secret and must stay outside the AuthMiddleware group.
+func RegisterPublicRoutes(r chi.Router, h *Handler) {
+	r.Post("/inbound/messages", h.Receive)
+}
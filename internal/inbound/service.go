@@ -0,0 +1,250 @@
+package inbound
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/project"
+	"github.com/sudarshanmg/gotask/internal/task"
+	"github.com/sudarshanmg/gotask/pkg/validation"
+)
+
+var validate = validator.New()
+
+const (
+	// maxTitle and maxDescription are the task limits.
+	maxTitle       = 100
+	maxDescription = 500
+	// maxAttachments bounds the files kept from one message.
+	maxAttachments = 20
+)
+
+type InboundService interface {
+	// CreateAddress gives the caller an inbound address, replacing any
+	// earlier one.
+	CreateAddress(caller auth.Caller, req CreateAddressRequest) (*AddressResponse, error)
+	GetAddress(caller auth.Caller) (*AddressResponse, error)
+	DeleteAddress(caller auth.Caller) error
+	// Receive creates a task from a raw message. recipient is the envelope
+	// recipient; without it the message's headers are searched for an
+	// inbound address.
+	Receive(recipient string, raw io.Reader) (*Result, error)
+}
+
+type inboundService struct {
+	repo     InboundRepository
+	tasks    task.TaskService
+	projects project.ProjectService
+	domain   string
+}
+
+// NewService creates the service for addresses at domain.
+func NewService(repo InboundRepository, tasks task.TaskService, projects project.ProjectService, domain string) InboundService {
+	return &inboundService{repo: repo, tasks: tasks, projects: projects, domain: strings.ToLower(domain)}
+}
+
+// generateToken returns 120 random bits in lowercase letters and digits,
+// since the local part of an address may not keep its case.
+func generateToken() (string, error) {
+	bytes := make([]byte, 15)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return strings.ToLower(base32.StdEncoding.EncodeToString(bytes)), nil
+}
+
+func (s *inboundService) mapAddressToResponse(a *Address) *AddressResponse {
+	return &AddressResponse{
+		Address:    a.Token + "@" + s.domain,
+		ProjectID:  a.ProjectID,
+		CreatedAt:  a.CreatedAt,
+		LastUsedAt: a.LastUsedAt,
+	}
+}
+
+func (s *inboundService) CreateAddress(caller auth.Caller, req CreateAddressRequest) (*AddressResponse, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+	if req.ProjectID != nil {
+		projects, err := s.projects.GetAll(caller)
+		if err != nil {
+			return nil, err
+		}
+		writable := false
+		for _, p := range projects {
+			if p.ID == *req.ProjectID && p.Role.CanWrite() {
+				writable = true
+			}
+		}
+		if !writable {
+			return nil, ErrInvalidProject
+		}
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	a := Address{WorkspaceID: caller.WorkspaceID, UserID: caller.UserID, Token: token, ProjectID: req.ProjectID}
+	if err := s.repo.Save(&a); err != nil {
+		return nil, err
+	}
+	return s.mapAddressToResponse(&a), nil
+}
+
+func (s *inboundService) GetAddress(caller auth.Caller) (*AddressResponse, error) {
+	a, err := s.repo.Find(caller.WorkspaceID, caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if a == nil {
+		return nil, ErrNotFound
+	}
+	return s.mapAddressToResponse(a), nil
+}
+
+func (s *inboundService) DeleteAddress(caller auth.Caller) error {
+	return s.repo.Delete(caller.WorkspaceID, caller.UserID)
+}
+
+// token returns the inbound token of an address at the service's domain.
+func (s *inboundService) token(address string) string {
+	if a, err := mail.ParseAddress(address); err == nil {
+		address = a.Address
+	}
+	at := strings.LastIndex(address, "@")
+	if at <= 0 || !strings.EqualFold(address[at+1:], s.domain) {
+		return ""
+	}
+	return strings.ToLower(address[:at])
+}
+
+func (s *inboundService) address(recipients []string) (*Address, error) {
+	for _, r := range recipients {
+		token := s.token(r)
+		if token == "" {
+			continue
+		}
+		a, err := s.repo.FindByToken(token)
+		if err != nil {
+			return nil, err
+		}
+		if a != nil {
+			return a, nil
+		}
+	}
+	return nil, ErrUnknownRecipient
+}
+
+// externalID makes a redelivered message find the task it created.
+func externalID(messageID string) string {
+	if messageID == "" {
+		return ""
+	}
+	if len(messageID) > 190 {
+		sum := sha1.Sum([]byte(messageID))
+		messageID = hex.EncodeToString(sum[:])
+	}
+	return "email:" + messageID
+}
+
+// shorten cuts s to at most n characters.
+func shorten(s string, n int) (string, bool) {
+	if utf8.RuneCountInString(s) <= n {
+		return s, false
+	}
+	return string([]rune(s)[:n]), true
+}
+
+// Receive goes through the same checks as POST /tasks, as the address's
+// owner. A message that was delivered before, by its Message-ID, returns
+// the task it created. The body is kept whole as message.txt when it is
+// too long for the description.
+func (s *inboundService) Receive(recipient string, raw io.Reader) (*Result, error) {
+	msg, err := ParseMessage(raw)
+	if err != nil {
+		return nil, err
+	}
+	recipients := msg.Recipients
+	if recipient != "" {
+		recipients = []string{recipient}
+	}
+	a, err := s.address(recipients)
+	if err != nil {
+		return nil, err
+	}
+	caller := auth.Caller{UserID: a.UserID, WorkspaceID: a.WorkspaceID}
+
+	result := &Result{}
+	title, labels, priority := parseSubject(msg.Subject)
+	if title == "" {
+		title = "(no subject)"
+	}
+	if short, cut := shorten(title, maxTitle); cut {
+		title = short
+		result.Warnings = append(result.Warnings, fmt.Sprintf("title was cut to %d characters", maxTitle))
+	}
+	description, cut := shorten(msg.Body, maxDescription)
+	files := msg.Attachments
+	if cut {
+		result.Warnings = append(result.Warnings,
+			fmt.Sprintf("description was cut to %d characters; the full text is attached as message.txt", maxDescription))
+		body := File{Filename: "message.txt", ContentType: "text/plain; charset=utf-8", Data: []byte(msg.Body)}
+		files = append([]File{body}, files...)
+	}
+
+	attachments := []task.CreateAttachmentRequest{}
+	for _, f := range files {
+		if len(attachments) == maxAttachments {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("only the first %d attachments were kept", maxAttachments))
+			break
+		}
+		switch {
+		case len(f.Data) == 0:
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s was skipped: %v", f.Filename, task.ErrEmptyAttachment))
+			continue
+		case len(f.Data) > task.MaxAttachmentBytes:
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s was skipped: %v", f.Filename, task.ErrAttachmentTooLarge))
+			continue
+		}
+		attachments = append(attachments, task.CreateAttachmentRequest{
+			Filename:    f.Filename,
+			ContentType: f.ContentType,
+			Data:        f.Data,
+		})
+	}
+
+	// The attachments are stored with the task, so a redelivery after a
+	// failure either finds both or creates both.
+	req := task.ImportTaskRequest{
+		ExternalID: externalID(msg.MessageID),
+		Task: task.CreateTaskRequest{
+			Title:       title,
+			Description: description,
+			ProjectID:   a.ProjectID,
+			Labels:      labels,
+			Priority:    priority,
+		},
+		Attachments: attachments,
+	}
+	res, created, err := s.tasks.Import(caller, req, false)
+	if err != nil {
+		return nil, err
+	}
+	result.TaskID, result.Created = res.ID, created
+	if created {
+		result.Attachments = len(attachments)
+	}
+	return result, nil
+}
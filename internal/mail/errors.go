@@ -0,0 +1,8 @@
+package mail
+
+import "errors"
+
+var (
+	ErrUnknownTemplate = errors.New("unknown email template")
+	ErrNoAddress       = errors.New("user has no verified email address")
+)
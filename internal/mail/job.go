@@ -0,0 +1,16 @@
+package mail
+
+// OutboxJob sends queued mail. Every replica may run it: messages are
+// claimed with FOR UPDATE SKIP LOCKED, so each is sent by a single server.
+type OutboxJob struct {
+	Service MailService
+	Batch   int
+}
+
+func (j *OutboxJob) Name() string {
+	return "mail outbox"
+}
+
+func (j *OutboxJob) Run() (int, error) {
+	return j.Service.ProcessOutbox(j.Batch)
+}
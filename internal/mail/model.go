@@ -0,0 +1,33 @@
+package mail
+
+import (
+	"time"
+)
+
+// Message is a rendered email ready to be queued.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusSent    Status = "sent"
+	StatusFailed  Status = "failed"
+)
+
+type OutboxMessage struct {
+	ID            int64
+	Message       Message
+	DedupeKey     string
+	Status        Status
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	SentAt        *time.Time
+	CreatedAt     time.Time
+}
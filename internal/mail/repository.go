@@ -0,0 +1,124 @@
+package mail
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// maxAttempts is how many times a message is tried before it is marked as
+// failed.
+const maxAttempts = 5
+
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// Enqueue adds msg to the outbox inside tx, so it is only sent if tx commits.
+// Messages whose dedupe key is already queued are skipped.
+func Enqueue(tx *sql.Tx, msg Message, dedupeKey string) error {
+	return enqueue(tx, msg, dedupeKey)
+}
+
+func enqueue(db execer, msg Message, dedupeKey string) error {
+	query := `INSERT INTO mail_outbox (to_address, subject, text_body, html_body, dedupe_key)
+            VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+            ON CONFLICT (dedupe_key) WHERE dedupe_key IS NOT NULL DO NOTHING;`
+
+	_, err := db.Exec(query, msg.To, msg.Subject, msg.Text, msg.HTML, dedupeKey)
+	return err
+}
+
+type OutboxRepository interface {
+	Enqueue(msg Message, dedupeKey string) error
+	VerifiedEmail(userID int64) (string, error)
+	// ProcessPending claims up to limit messages that are due and hands each
+	// to send. Claimed rows stay locked until the batch commits, so
+	// concurrent callers on other replicas skip them.
+	ProcessPending(limit int, send func(msg Message) error) (int, error)
+}
+
+type PostgresOutboxRepository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) OutboxRepository {
+	return &PostgresOutboxRepository{DB: db}
+}
+
+func (r *PostgresOutboxRepository) Enqueue(msg Message, dedupeKey string) error {
+	return enqueue(r.DB, msg, dedupeKey)
+}
+
+func (r *PostgresOutboxRepository) VerifiedEmail(userID int64) (string, error) {
+	query := `SELECT email FROM users WHERE id = $1 AND email IS NOT NULL AND email_verified_at IS NOT NULL;`
+
+	var email string
+	err := r.DB.QueryRow(query, userID).Scan(&email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNoAddress
+	}
+	return email, err
+}
+
+func (r *PostgresOutboxRepository) ProcessPending(limit int, send func(msg Message) error) (int, error) {
+	claim := `SELECT id, to_address, subject, text_body, html_body, attempts
+          FROM mail_outbox
+          WHERE status = 'pending' AND next_attempt_at <= NOW()
+          ORDER BY next_attempt_at
+          LIMIT $1
+          FOR UPDATE SKIP LOCKED;`
+
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(claim, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	batch := []OutboxMessage{}
+	for rows.Next() {
+		m := OutboxMessage{}
+		if err := rows.Scan(&m.ID, &m.Message.To, &m.Message.Subject, &m.Message.Text, &m.Message.HTML,
+			&m.Attempts); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, m := range batch {
+		sendErr := send(m.Message)
+
+		switch {
+		case sendErr == nil:
+			_, err = tx.Exec(`UPDATE mail_outbox
+                    SET status = 'sent', sent_at = NOW(), attempts = attempts + 1, last_error = ''
+                    WHERE id = $1;`, m.ID)
+		case m.Attempts+1 >= maxAttempts:
+			_, err = tx.Exec(`UPDATE mail_outbox SET status = 'failed', attempts = attempts + 1, last_error = $2
+                    WHERE id = $1;`, m.ID, sendErr.Error())
+		default:
+			backoff := time.Duration(1<<m.Attempts) * time.Minute
+			_, err = tx.Exec(`UPDATE mail_outbox
+                    SET next_attempt_at = NOW() + $2 * interval '1 second', attempts = attempts + 1, last_error = $3
+                    WHERE id = $1;`, m.ID, int64(backoff/time.Second), sendErr.Error())
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(batch), nil
+}
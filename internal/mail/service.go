@@ -0,0 +1,42 @@
+package mail
+
+// MailService queues templated email through the outbox. Use Enqueue
+// directly when the email must commit together with other writes.
+type MailService interface {
+	Send(to, template string, data any, dedupeKey string) error
+	SendToUser(userID int64, template string, data any, dedupeKey string) error
+	ProcessOutbox(limit int) (int, error)
+}
+
+type mailService struct {
+	repo   OutboxRepository
+	sender Sender
+}
+
+func NewService(repo OutboxRepository, sender Sender) MailService {
+	return &mailService{repo: repo, sender: sender}
+}
+
+func (s *mailService) Send(to, template string, data any, dedupeKey string) error {
+	msg, err := Render(template, data)
+	if err != nil {
+		return err
+	}
+	msg.To = to
+	return s.repo.Enqueue(*msg, dedupeKey)
+}
+
+// SendToUser queues the email for the user's verified address, and returns
+// ErrNoAddress if they have none.
+func (s *mailService) SendToUser(userID int64, template string, data any, dedupeKey string) error {
+	to, err := s.repo.VerifiedEmail(userID)
+	if err != nil {
+		return err
+	}
+	return s.Send(to, template, data, dedupeKey)
+}
+
+// ProcessOutbox sends up to limit queued messages.
+func (s *mailService) ProcessOutbox(limit int) (int, error) {
+	return s.repo.ProcessPending(limit, s.sender.Send)
+}
@@ -0,0 +1,10 @@
+package milestone
+
+import "errors"
+
+var (
+	ErrNotFound        = errors.New("milestone not found")
+	ErrInvalidID       = errors.New("invalid milestone ID")
+	ErrForbidden       = errors.New("you do not have permission to plan this project")
+	ErrInvalidTimezone = errors.New("invalid time zone")
+)
@@ -0,0 +1,139 @@
+package milestone
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/pkg/response"
+)
+
+type Handler struct {
+	service MilestoneService
+}
+
+func NewHandler(service MilestoneService) *Handler {
+	return &Handler{service: service}
+}
+
+func writeServiceError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidID), errors.Is(err, ErrInvalidTimezone),
+		strings.HasPrefix(err.Error(), "validation failed:"):
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrNotFound):
+		response.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrForbidden):
+		response.WriteError(w, http.StatusForbidden, err.Error())
+	default:
+		response.WriteError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+// pathID reads the {id} URL parameter: a project on /projects routes and a
+// milestone on /milestones routes.
+func pathID(r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	return id, err == nil
+}
+
+func (h *Handler) CreateMilestone(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	projectID, ok := pathID(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	var req CreateMilestoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	milestone, err := h.service.Create(auth.GetCaller(r), projectID, req)
+	if err != nil {
+		writeServiceError(w, err, "failed to create milestone")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, milestone)
+}
+
+// GetAllMilestones lists a project's milestones by target date with their
+// progress, reading the dates in ?tz=.
+func (h *Handler) GetAllMilestones(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := pathID(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	milestones, err := h.service.GetAll(auth.GetCaller(r), projectID, r.URL.Query().Get("tz"))
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch milestones")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, milestones)
+}
+
+// GetMilestone returns a milestone with its progress and linked tasks,
+// reading its target date in ?tz=.
+func (h *Handler) GetMilestone(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	milestone, err := h.service.GetByID(auth.GetCaller(r), id, r.URL.Query().Get("tz"))
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch the milestone")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, milestone)
+}
+
+func (h *Handler) UpdateMilestone(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id, ok := pathID(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	var req UpdateMilestoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	milestone, err := h.service.Update(auth.GetCaller(r), id, req)
+	if err != nil {
+		writeServiceError(w, err, "failed to update milestone")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, milestone)
+}
+
+func (h *Handler) DeleteMilestone(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	if err := h.service.Delete(auth.GetCaller(r), id); err != nil {
+		writeServiceError(w, err, "failed to delete milestone")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{"message": "milestone deleted successfully"})
+}
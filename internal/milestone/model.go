@@ -0,0 +1,122 @@
+package milestone
+
+import (
+	"math"
+	"time"
+)
+
+// Milestone is a goal of a project, such as a release. It is due by the end
+// of TargetDate, a calendar date (YYYY-MM-DD), in the viewer's time zone.
+type Milestone struct {
+	ID          int64     `json:"id"`
+	WorkspaceID int64     `json:"workspace_id"`
+	ProjectID   int64     `json:"project_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	TargetDate  string    `json:"target_date"`
+	CreatedBy   int64     `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Progress    *Progress `json:"progress,omitempty"`
+	// Tasks are only listed when a single milestone is fetched.
+	Tasks []TaskSummary `json:"tasks,omitempty"`
+}
+
+// Progress is computed from the milestone's linked tasks as of now.
+type Progress struct {
+	TotalTasks     int `json:"total_tasks"`
+	CompletedTasks int `json:"completed_tasks"`
+	// Percent is the share of the work that is done. Tasks weigh their
+	// estimate; those without one weigh the average estimate, and all tasks
+	// weigh the same when none is estimated.
+	Percent float64 `json:"percent"`
+	// RemainingMinutes is the estimated work left on open tasks: each
+	// estimate less the time already tracked, never below zero. Open tasks
+	// without an estimate are counted in UnestimatedTasks instead.
+	RemainingMinutes int64 `json:"remaining_minutes"`
+	UnestimatedTasks int   `json:"unestimated_tasks"`
+	// MinutesLeft is the time until the end of the target date; it is
+	// negative once the date has passed.
+	MinutesLeft int64 `json:"minutes_left"`
+	// AtRisk means open work exceeds the time left, which includes every
+	// milestone past its date with open tasks.
+	AtRisk   bool   `json:"at_risk"`
+	Timezone string `json:"timezone"`
+}
+
+// TaskSummary is a task linked to a milestone.
+type TaskSummary struct {
+	ID              int64      `json:"id"`
+	Title           string     `json:"title"`
+	Completed       bool       `json:"completed"`
+	StatusID        *int64     `json:"status_id"`
+	AssigneeIDs     []int64    `json:"assignee_ids"`
+	DueAt           *time.Time `json:"due_at"`
+	EstimateMinutes *int       `json:"estimate_minutes"`
+	TrackedMinutes  int64      `json:"tracked_minutes"`
+}
+
+// deadline is the end of the target date in loc.
+func deadline(targetDate string, loc *time.Location) (time.Time, error) {
+	d, err := time.Parse("2006-01-02", targetDate)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(d.Year(), d.Month(), d.Day()+1, 0, 0, 0, 0, loc), nil
+}
+
+// measure computes the progress of tasks towards a milestone due at due.
+func measure(tasks []TaskSummary, due, now time.Time) Progress {
+	p := Progress{TotalTasks: len(tasks), MinutesLeft: int64(due.Sub(now) / time.Minute)}
+
+	estimated, sum := 0, 0
+	for _, t := range tasks {
+		if t.EstimateMinutes != nil {
+			estimated++
+			sum += *t.EstimateMinutes
+		}
+	}
+	fallback := 1.0
+	if estimated > 0 {
+		fallback = float64(sum) / float64(estimated)
+	}
+
+	var total, done float64
+	open := 0
+	for _, t := range tasks {
+		weight := fallback
+		if t.EstimateMinutes != nil {
+			weight = float64(*t.EstimateMinutes)
+		}
+		total += weight
+		if t.Completed {
+			p.CompletedTasks++
+			done += weight
+			continue
+		}
+
+		open++
+		if t.EstimateMinutes == nil {
+			p.UnestimatedTasks++
+		} else {
+			p.RemainingMinutes += max(int64(*t.EstimateMinutes)-t.TrackedMinutes, 0)
+		}
+	}
+	if total > 0 {
+		p.Percent = math.Round(done/total*1000) / 10
+	}
+	p.AtRisk = open > 0 && p.RemainingMinutes > p.MinutesLeft
+	return p
+}
+
+type CreateMilestoneRequest struct {
+	Title       string `json:"title" validate:"required,max=100"`
+	Description string `json:"description" validate:"max=500"`
+	TargetDate  string `json:"target_date" validate:"required,datetime=2006-01-02"`
+}
+
+type UpdateMilestoneRequest struct {
+	Title       *string `json:"title,omitempty" validate:"omitempty,min=1,max=100"`
+	Description *string `json:"description,omitempty" validate:"omitempty,max=500"`
+	TargetDate  *string `json:"target_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+}
@@ -0,0 +1,225 @@
+package milestone
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/sudarshanmg/gotask/pkg/db"
+)
+
+// MilestoneRepository methods are scoped to a workspace; every query filters
+// on it and runs under db.WithTenant so row-level security applies as well.
+type MilestoneRepository interface {
+	Create(milestone *Milestone) error
+	FindByID(workspaceID, id int64) (*Milestone, error)
+	FindByProject(workspaceID, projectID int64) ([]Milestone, error)
+	Update(milestone *Milestone) error
+	// Delete unlinks the milestone's tasks and deletes it, returning the IDs
+	// of the tasks it unlinked.
+	Delete(workspaceID, id int64) ([]int64, error)
+	// Tasks returns the tasks linked to each of the milestones.
+	Tasks(workspaceID int64, ids []int64) (map[int64][]TaskSummary, error)
+	// Timezone returns the user's preferred time zone, or "" if they have
+	// not chosen one.
+	Timezone(userID int64) (string, error)
+}
+
+type PostgresMilestoneRepository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) MilestoneRepository {
+	return &PostgresMilestoneRepository{DB: db}
+}
+
+const milestoneColumns = `id, workspace_id, project_id, title, description, to_char(target_date, 'YYYY-MM-DD'),
+                 COALESCE(created_by, 0), created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanMilestone(row rowScanner, m *Milestone) error {
+	return row.Scan(&m.ID, &m.WorkspaceID, &m.ProjectID, &m.Title, &m.Description, &m.TargetDate,
+		&m.CreatedBy, &m.CreatedAt, &m.UpdatedAt)
+}
+
+func (r *PostgresMilestoneRepository) Create(milestone *Milestone) error {
+	query := `INSERT INTO milestones (workspace_id, project_id, title, description, target_date, created_by)
+            VALUES ($1, $2, $3, $4, $5, $6)
+            RETURNING id, created_at, updated_at;`
+
+	return db.WithTenant(r.DB, milestone.WorkspaceID, func(tx *sql.Tx) error {
+		return tx.QueryRow(query, milestone.WorkspaceID, milestone.ProjectID, milestone.Title,
+			milestone.Description, milestone.TargetDate, milestone.CreatedBy).
+			Scan(&milestone.ID, &milestone.CreatedAt, &milestone.UpdatedAt)
+	})
+}
+
+func (r *PostgresMilestoneRepository) FindByID(workspaceID, id int64) (*Milestone, error) {
+	query := `SELECT ` + milestoneColumns + ` FROM milestones WHERE workspace_id = $1 AND id = $2;`
+
+	var found *Milestone
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		m := Milestone{}
+		err := scanMilestone(tx.QueryRow(query, workspaceID, id), &m)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		found = &m
+		return nil
+	})
+	return found, err
+}
+
+func (r *PostgresMilestoneRepository) FindByProject(workspaceID, projectID int64) ([]Milestone, error) {
+	query := `SELECT ` + milestoneColumns + ` FROM milestones
+            WHERE workspace_id = $1 AND project_id = $2
+            ORDER BY target_date, id;`
+
+	milestones := []Milestone{}
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, workspaceID, projectID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			m := Milestone{}
+			if err := scanMilestone(rows, &m); err != nil {
+				return err
+			}
+			milestones = append(milestones, m)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return milestones, nil
+}
+
+func (r *PostgresMilestoneRepository) Update(milestone *Milestone) error {
+	query := `UPDATE milestones
+            SET title = $3, description = $4, target_date = $5, updated_at = NOW()
+            WHERE workspace_id = $1 AND id = $2
+            RETURNING updated_at;`
+
+	return db.WithTenant(r.DB, milestone.WorkspaceID, func(tx *sql.Tx) error {
+		err := tx.QueryRow(query, milestone.WorkspaceID, milestone.ID, milestone.Title, milestone.Description,
+			milestone.TargetDate).Scan(&milestone.UpdatedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	})
+}
+
+// Delete clears the tasks' milestone itself rather than leaving it to the
+// foreign key, so that each task is stamped with a change number for sync
+// clients.
+func (r *PostgresMilestoneRepository) Delete(workspaceID, id int64) ([]int64, error) {
+	ids := []int64{}
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		if err := db.LockTaskChanges(tx, workspaceID); err != nil {
+			return err
+		}
+		rows, err := tx.Query(`UPDATE tasks SET milestone_id = NULL, change_seq = nextval('task_change_seq'),
+                updated_at = NOW() AT TIME ZONE 'UTC'
+            WHERE workspace_id = $1 AND milestone_id = $2
+            RETURNING id;`, workspaceID, id)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var taskID int64
+			if err := rows.Scan(&taskID); err != nil {
+				return err
+			}
+			ids = append(ids, taskID)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		rows.Close()
+
+		_, err = tx.Exec(`UPDATE tasks SET field_seqs = field_seqs || jsonb_build_object('milestone_id', change_seq)
+            WHERE id = ANY($1);`, pq.Array(ids))
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.Exec(`DELETE FROM milestones WHERE workspace_id = $1 AND id = $2;`, workspaceID, id)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// Tasks counts running timers up to now in the time tracked. Time entries
+// are stored in UTC.
+func (r *PostgresMilestoneRepository) Tasks(workspaceID int64, ids []int64) (map[int64][]TaskSummary, error) {
+	query := `
+          SELECT t.milestone_id, t.id, t.title, t.completed, t.status_id,
+                 ARRAY(SELECT a.user_id FROM task_assignees a WHERE a.task_id = t.id ORDER BY a.user_id),
+                 t.due_at, t.estimate_minutes,
+                 (SELECT COALESCE(SUM(EXTRACT(EPOCH FROM
+                          COALESCE(e.ended_at, NOW() AT TIME ZONE 'UTC') - e.started_at)), 0)::bigint / 60
+                  FROM time_entries e WHERE e.task_id = t.id)
+          FROM tasks t
+          WHERE t.workspace_id = $1 AND t.milestone_id = ANY($2)
+          ORDER BY t.completed, t.due_at NULLS LAST, t.id;`
+
+	tasks := map[int64][]TaskSummary{}
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, workspaceID, pq.Array(ids))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var milestoneID int64
+			t := TaskSummary{}
+			err := rows.Scan(&milestoneID, &t.ID, &t.Title, &t.Completed, &t.StatusID, pq.Array(&t.AssigneeIDs),
+				&t.DueAt, &t.EstimateMinutes, &t.TrackedMinutes)
+			if err != nil {
+				return err
+			}
+			tasks[milestoneID] = append(tasks[milestoneID], t)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (r *PostgresMilestoneRepository) Timezone(userID int64) (string, error) {
+	var tz string
+	err := r.DB.QueryRow(`SELECT timezone FROM digest_settings WHERE user_id = $1;`, userID).Scan(&tz)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return tz, err
+}
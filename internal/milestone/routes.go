@@ -0,0 +1,17 @@
+package milestone
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Route("/projects/{id}/milestones", func(r chi.Router) {
+		r.Get("/", h.GetAllMilestones)
+		r.Post("/", h.CreateMilestone)
+	})
+	r.Route("/milestones", func(r chi.Router) {
+		r.Get("/{id}", h.GetMilestone)
+		r.Put("/{id}", h.UpdateMilestone)
+		r.Delete("/{id}", h.DeleteMilestone)
+	})
+}
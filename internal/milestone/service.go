@@ -0,0 +1,212 @@
+package milestone
+
+import (
+	"time"
+	_ "time/tzdata"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/project"
+	"github.com/sudarshanmg/gotask/internal/task"
+	"github.com/sudarshanmg/gotask/pkg/validation"
+)
+
+var validate = validator.New()
+
+// Reads take the time zone the target dates are read in; "" falls back to
+// the caller's digest settings, then UTC.
+type MilestoneService interface {
+	Create(caller auth.Caller, projectID int64, req CreateMilestoneRequest) (*Milestone, error)
+	GetAll(caller auth.Caller, projectID int64, tz string) ([]Milestone, error)
+	GetByID(caller auth.Caller, id int64, tz string) (*Milestone, error)
+	Update(caller auth.Caller, id int64, req UpdateMilestoneRequest) (*Milestone, error)
+	Delete(caller auth.Caller, id int64) error
+}
+
+type milestoneService struct {
+	repo     MilestoneRepository
+	projects project.ProjectRepository
+	tasks    task.TaskService
+}
+
+func NewService(repo MilestoneRepository, projects project.ProjectRepository, tasks task.TaskService) MilestoneService {
+	return &milestoneService{repo: repo, projects: projects, tasks: tasks}
+}
+
+// checkProject ensures the caller can see the project and, when write is
+// set, plan it. Planning is open to everyone who can edit the project's
+// tasks. Projects the caller cannot see are reported as not found.
+func (s *milestoneService) checkProject(caller auth.Caller, projectID int64, write bool) error {
+	role, err := s.projects.GetMemberRole(caller.WorkspaceID, projectID, caller.UserID)
+	if err != nil {
+		return err
+	}
+	if !role.CanRead() {
+		return ErrNotFound
+	}
+	if write && !role.CanWrite() {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// load fetches a milestone under the rules of checkProject.
+func (s *milestoneService) load(caller auth.Caller, id int64, write bool) (*Milestone, error) {
+	if id <= 0 {
+		return nil, ErrInvalidID
+	}
+	milestone, err := s.repo.FindByID(caller.WorkspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	if milestone == nil {
+		return nil, ErrNotFound
+	}
+	if err := s.checkProject(caller, milestone.ProjectID, write); err != nil {
+		return nil, err
+	}
+	return milestone, nil
+}
+
+func (s *milestoneService) location(caller auth.Caller, tz string) (*time.Location, error) {
+	if tz == "" {
+		var err error
+		if tz, err = s.repo.Timezone(caller.UserID); err != nil {
+			return nil, err
+		}
+	}
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, ErrInvalidTimezone
+	}
+	return loc, nil
+}
+
+// measureAll sets the progress of each milestone, and lists its tasks when
+// withTasks is set.
+func (s *milestoneService) measureAll(caller auth.Caller, milestones []Milestone, tz string, withTasks bool) error {
+	if len(milestones) == 0 {
+		return nil
+	}
+	loc, err := s.location(caller, tz)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int64, len(milestones))
+	for i, m := range milestones {
+		ids[i] = m.ID
+	}
+	tasks, err := s.repo.Tasks(caller.WorkspaceID, ids)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := range milestones {
+		m := &milestones[i]
+		due, err := deadline(m.TargetDate, loc)
+		if err != nil {
+			return err
+		}
+		progress := measure(tasks[m.ID], due, now)
+		progress.Timezone = loc.String()
+		m.Progress = &progress
+		if withTasks {
+			m.Tasks = tasks[m.ID]
+			if m.Tasks == nil {
+				m.Tasks = []TaskSummary{}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *milestoneService) Create(caller auth.Caller, projectID int64, req CreateMilestoneRequest) (*Milestone, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+	if err := s.checkProject(caller, projectID, true); err != nil {
+		return nil, err
+	}
+
+	milestone := Milestone{
+		WorkspaceID: caller.WorkspaceID,
+		ProjectID:   projectID,
+		Title:       req.Title,
+		Description: req.Description,
+		TargetDate:  req.TargetDate,
+		CreatedBy:   caller.UserID,
+	}
+	if err := s.repo.Create(&milestone); err != nil {
+		return nil, err
+	}
+	return &milestone, nil
+}
+
+func (s *milestoneService) GetAll(caller auth.Caller, projectID int64, tz string) ([]Milestone, error) {
+	if err := s.checkProject(caller, projectID, false); err != nil {
+		return nil, err
+	}
+	milestones, err := s.repo.FindByProject(caller.WorkspaceID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.measureAll(caller, milestones, tz, false); err != nil {
+		return nil, err
+	}
+	return milestones, nil
+}
+
+func (s *milestoneService) GetByID(caller auth.Caller, id int64, tz string) (*Milestone, error) {
+	milestone, err := s.load(caller, id, false)
+	if err != nil {
+		return nil, err
+	}
+	milestones := []Milestone{*milestone}
+	if err := s.measureAll(caller, milestones, tz, true); err != nil {
+		return nil, err
+	}
+	return &milestones[0], nil
+}
+
+func (s *milestoneService) Update(caller auth.Caller, id int64, req UpdateMilestoneRequest) (*Milestone, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+
+	milestone, err := s.load(caller, id, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Title != nil {
+		milestone.Title = *req.Title
+	}
+	if req.Description != nil {
+		milestone.Description = *req.Description
+	}
+	if req.TargetDate != nil {
+		milestone.TargetDate = *req.TargetDate
+	}
+
+	if err := s.repo.Update(milestone); err != nil {
+		return nil, err
+	}
+	return milestone, nil
+}
+
+func (s *milestoneService) Delete(caller auth.Caller, id int64) error {
+	if _, err := s.load(caller, id, true); err != nil {
+		return err
+	}
+	unlinked, err := s.repo.Delete(caller.WorkspaceID, id)
+	if err != nil {
+		return err
+	}
+	s.tasks.PublishUpdated(caller, unlinked)
+	return nil
+}
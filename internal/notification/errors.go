@@ -0,0 +1,8 @@
+package notification
+
+import "errors"
+
+var (
+	ErrNotFound  = errors.New("notification not found")
+	ErrInvalidID = errors.New("invalid notification ID")
+)
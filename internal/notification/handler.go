@@ -0,0 +1,87 @@
+package notification
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/pkg/response"
+)
+
+type Handler struct {
+	service NotificationService
+}
+
+func NewHandler(service NotificationService) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) GetAllNotifications(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	filter := NotificationFilter{UnreadOnly: r.URL.Query().Get("unread") == "true"}
+
+	notifications, total, totalPages, err := h.service.GetAll(auth.GetCaller(r), page, limit, filter)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch notifications")
+		return
+	}
+
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	w.Header().Set("X-Total-Pages", strconv.Itoa(totalPages))
+
+	response.WriteJSON(w, http.StatusOK, notifications)
+}
+
+func (h *Handler) GetUnreadCount(w http.ResponseWriter, r *http.Request) {
+	count, err := h.service.UnreadCount(auth.GetCaller(r))
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to count notifications")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, UnreadCountResponse{Unread: count})
+}
+
+func (h *Handler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	err = h.service.MarkRead(auth.GetCaller(r), id)
+	if errors.Is(err, ErrInvalidID) {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.Is(err, ErrNotFound) {
+		response.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to mark notification as read")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{"message": "notification marked as read"})
+}
+
+func (h *Handler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	updated, err := h.service.MarkAllRead(auth.GetCaller(r))
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to mark notifications as read")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]int64{"updated": updated})
+}
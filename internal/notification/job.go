@@ -0,0 +1,22 @@
+package notification
+
+import (
+	"time"
+)
+
+// DueSoonJob creates "due soon" notifications for tasks due within Window.
+// It is safe to run on every replica because the notifications it creates
+// are deduplicated in the database.
+type DueSoonJob struct {
+	Service NotificationService
+	Window  time.Duration
+}
+
+func (j *DueSoonJob) Name() string {
+	return "due-soon notifications"
+}
+
+func (j *DueSoonJob) Run() (int, error) {
+	n, err := j.Service.NotifyDueSoon(j.Window)
+	return int(n), err
+}
@@ -0,0 +1,43 @@
+package notification
+
+import (
+	"regexp"
+	"strings"
+)
+
+// mentionPattern matches @username when the @ starts a word, so e-mail
+// addresses such as bob@example.com are not treated as mentions.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([\w][\w.-]{2,31})`)
+
+// ParseMentions returns the distinct usernames mentioned in text, in order of
+// first appearance.
+func ParseMentions(text string) []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for _, m := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		name := strings.TrimRight(m[1], ".-")
+		if len(name) < 3 || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// NewMentions returns the usernames mentioned in after but not in before, so
+// editing a description does not notify the same people again.
+func NewMentions(before, after string) []string {
+	old := map[string]bool{}
+	for _, name := range ParseMentions(before) {
+		old[name] = true
+	}
+
+	names := []string{}
+	for _, name := range ParseMentions(after) {
+		if !old[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
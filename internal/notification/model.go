@@ -0,0 +1,37 @@
+package notification
+
+import (
+	"time"
+)
+
+type Type string
+
+const (
+	TypeMention  Type = "mention"
+	TypeAssigned Type = "assigned"
+	TypeDueSoon  Type = "due_soon"
+	TypeReminder Type = "reminder"
+	// TypeAutomation notifications are sent by automation rules.
+	TypeAutomation Type = "automation"
+)
+
+type Notification struct {
+	ID          int64      `json:"id"`
+	WorkspaceID int64      `json:"workspace_id"`
+	UserID      int64      `json:"user_id"`
+	Type        Type       `json:"type"`
+	TaskID      *int64     `json:"task_id"`
+	ActorID     *int64     `json:"actor_id"`
+	Message     string     `json:"message"`
+	DedupeKey   string     `json:"-"`
+	ReadAt      *time.Time `json:"read_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type NotificationFilter struct {
+	UnreadOnly bool
+}
+
+type UnreadCountResponse struct {
+	Unread int64 `json:"unread"`
+}
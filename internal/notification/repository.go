@@ -0,0 +1,141 @@
+package notification
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/sudarshanmg/gotask/pkg/db"
+)
+
+type NotificationRepository interface {
+	Create(n *Notification) error
+	FindForUser(workspaceID, userID int64, filter NotificationFilter, offset, limit int) ([]Notification, error)
+	CountForUser(workspaceID, userID int64, filter NotificationFilter) (int64, error)
+	MarkRead(workspaceID, userID, id int64) error
+	MarkAllRead(workspaceID, userID int64) (int64, error)
+	CreateDueSoon(window time.Duration) (int64, error)
+}
+
+type PostgresNotificationRepository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) NotificationRepository {
+	return &PostgresNotificationRepository{DB: db}
+}
+
+// Create inserts the notification. Notifications with a dedupe key that the
+// user already has are silently skipped.
+func (r *PostgresNotificationRepository) Create(n *Notification) error {
+	query := `INSERT INTO notifications (workspace_id, user_id, type, task_id, actor_id, message, dedupe_key)
+            VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''))
+            ON CONFLICT (user_id, dedupe_key) WHERE dedupe_key IS NOT NULL DO NOTHING
+            RETURNING id, created_at;`
+
+	err := r.DB.QueryRow(query, n.WorkspaceID, n.UserID, n.Type, n.TaskID, n.ActorID, n.Message,
+		n.DedupeKey).Scan(&n.ID, &n.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	return err
+}
+
+func (r *PostgresNotificationRepository) FindForUser(workspaceID, userID int64, filter NotificationFilter, offset, limit int) ([]Notification, error) {
+	query := `
+          SELECT id, workspace_id, user_id, type, task_id, actor_id, message, read_at, created_at
+          FROM notifications
+          WHERE workspace_id = $1 AND user_id = $2 AND (NOT $3 OR read_at IS NULL)
+          ORDER BY id DESC
+          LIMIT $4 OFFSET $5;`
+
+	rows, err := r.DB.Query(query, workspaceID, userID, filter.UnreadOnly, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []Notification{}
+	for rows.Next() {
+		n := Notification{}
+		if err := rows.Scan(&n.ID, &n.WorkspaceID, &n.UserID, &n.Type, &n.TaskID, &n.ActorID, &n.Message,
+			&n.ReadAt, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+func (r *PostgresNotificationRepository) CountForUser(workspaceID, userID int64, filter NotificationFilter) (int64, error) {
+	query := `SELECT COUNT(*) FROM notifications
+            WHERE workspace_id = $1 AND user_id = $2 AND (NOT $3 OR read_at IS NULL);`
+
+	var count int64
+	err := r.DB.QueryRow(query, workspaceID, userID, filter.UnreadOnly).Scan(&count)
+	return count, err
+}
+
+func (r *PostgresNotificationRepository) MarkRead(workspaceID, userID, id int64) error {
+	query := `UPDATE notifications SET read_at = COALESCE(read_at, NOW())
+            WHERE workspace_id = $1 AND user_id = $2 AND id = $3;`
+
+	res, err := r.DB.Exec(query, workspaceID, userID, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresNotificationRepository) MarkAllRead(workspaceID, userID int64) (int64, error) {
+	query := `UPDATE notifications SET read_at = NOW()
+            WHERE workspace_id = $1 AND user_id = $2 AND read_at IS NULL;`
+
+	res, err := r.DB.Exec(query, workspaceID, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// CreateDueSoon notifies the assignees of every open task due within the
+// window, or its creator when nobody is assigned. It spans all workspaces,
+// under db.AcrossTenants. The dedupe key includes the due date, so each user
+// hears about a given deadline once, even with several replicas running the
+// sweep, and again if the deadline moves.
+func (r *PostgresNotificationRepository) CreateDueSoon(window time.Duration) (int64, error) {
+	query := `
+          INSERT INTO notifications (workspace_id, user_id, type, task_id, message, dedupe_key)
+          SELECT t.workspace_id, rcpt.user_id, $1, t.id,
+                 'Task "' || t.title || '" is due soon',
+                 'due_soon:' || t.id || ':' || EXTRACT(EPOCH FROM t.due_at)::bigint
+          FROM tasks t
+          CROSS JOIN LATERAL (
+            SELECT a.user_id FROM task_assignees a WHERE a.task_id = t.id
+            UNION
+            SELECT t.created_by
+            WHERE t.created_by IS NOT NULL
+              AND NOT EXISTS (SELECT 1 FROM task_assignees a WHERE a.task_id = t.id)
+          ) rcpt
+          WHERE NOT t.completed
+            AND t.due_at > NOW() AT TIME ZONE 'UTC'
+            AND t.due_at <= (NOW() AT TIME ZONE 'UTC') + $2 * INTERVAL '1 second'
+          ON CONFLICT (user_id, dedupe_key) WHERE dedupe_key IS NOT NULL DO NOTHING;`
+
+	var created int64
+	err := db.AcrossTenants(r.DB, func(tx *sql.Tx) error {
+		res, err := tx.Exec(query, TypeDueSoon, int64(window.Seconds()))
+		if err != nil {
+			return err
+		}
+		created, err = res.RowsAffected()
+		return err
+	})
+	return created, err
+}
@@ -0,0 +1,14 @@
+package notification
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Route("/notifications", func(r chi.Router) {
+		r.Get("/", h.GetAllNotifications)
+		r.Get("/unread-count", h.GetUnreadCount)
+		r.Post("/read-all", h.MarkAllRead)
+		r.Post("/{id}/read", h.MarkRead)
+	})
+}
@@ -0,0 +1,69 @@
+package notification
+
+import (
+	"time"
+
+	"github.com/sudarshanmg/gotask/internal/auth"
+)
+
+type NotificationService interface {
+	Notify(n Notification) error
+	GetAll(caller auth.Caller, page, limit int, filter NotificationFilter) ([]Notification, int64, int, error)
+	UnreadCount(caller auth.Caller) (int64, error)
+	MarkRead(caller auth.Caller, id int64) error
+	MarkAllRead(caller auth.Caller) (int64, error)
+	NotifyDueSoon(window time.Duration) (int64, error)
+}
+
+type notificationService struct {
+	repo NotificationRepository
+}
+
+func NewService(repo NotificationRepository) NotificationService {
+	return &notificationService{repo: repo}
+}
+
+// Notify stores a notification for n.UserID. Users are never notified about
+// their own actions.
+func (s *notificationService) Notify(n Notification) error {
+	if n.ActorID != nil && *n.ActorID == n.UserID {
+		return nil
+	}
+	return s.repo.Create(&n)
+}
+
+func (s *notificationService) GetAll(caller auth.Caller, page, limit int, filter NotificationFilter) ([]Notification, int64, int, error) {
+	offset := (page - 1) * limit
+
+	notifications, err := s.repo.FindForUser(caller.WorkspaceID, caller.UserID, filter, offset, limit)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	total, err := s.repo.CountForUser(caller.WorkspaceID, caller.UserID, filter)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+	return notifications, total, totalPages, nil
+}
+
+func (s *notificationService) UnreadCount(caller auth.Caller) (int64, error) {
+	return s.repo.CountForUser(caller.WorkspaceID, caller.UserID, NotificationFilter{UnreadOnly: true})
+}
+
+func (s *notificationService) MarkRead(caller auth.Caller, id int64) error {
+	if id <= 0 {
+		return ErrInvalidID
+	}
+	return s.repo.MarkRead(caller.WorkspaceID, caller.UserID, id)
+}
+
+func (s *notificationService) MarkAllRead(caller auth.Caller) (int64, error) {
+	return s.repo.MarkAllRead(caller.WorkspaceID, caller.UserID)
+}
+
+func (s *notificationService) NotifyDueSoon(window time.Duration) (int64, error) {
+	return s.repo.CreateDueSoon(window)
+}
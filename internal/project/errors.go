@@ -0,0 +1,18 @@
+package project
+
+import "errors"
+
+var (
+	ErrNotFound      = errors.New("project not found")
+	ErrInvalidID     = errors.New("invalid project ID")
+	ErrForbidden     = errors.New("you do not have permission to perform this action")
+	ErrMemberMissing = errors.New("member not found")
+	ErrUserMissing   = errors.New("user not found")
+	ErrInvalidMember = errors.New("invalid member")
+	ErrOwnerRemoval  = errors.New("the project owner cannot be removed or demoted")
+	ErrStatusMissing = errors.New("status not found")
+	ErrStatusName    = errors.New("a status with this name already exists")
+	ErrStatusInUse   = errors.New("status is used by tasks; move them first")
+	ErrStatusLast    = errors.New("a project needs at least one todo and one done status")
+	ErrScaleInUse    = errors.New("tasks are estimated on the current point scale; clear their story points first")
+)
@@ -0,0 +1,310 @@
+package project
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/authz"
+	"github.com/sudarshanmg/gotask/pkg/response"
+)
+
+type Handler struct {
+	service ProjectService
+	policy  authz.Policy
+}
+
+func NewHandler(service ProjectService, policy authz.Policy) *Handler {
+	return &Handler{service: service, policy: policy}
+}
+
+var projectResource = authz.Resource{Type: authz.ResourceProject}
+
+func writeServiceError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidID), errors.Is(err, ErrInvalidMember), errors.Is(err, ErrOwnerRemoval),
+		strings.HasPrefix(err.Error(), "validation failed:"):
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrMemberMissing), errors.Is(err, ErrUserMissing),
+		errors.Is(err, ErrStatusMissing):
+		response.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrStatusName), errors.Is(err, ErrStatusInUse), errors.Is(err, ErrStatusLast),
+		errors.Is(err, ErrScaleInUse):
+		response.WriteError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, ErrForbidden):
+		response.WriteError(w, http.StatusForbidden, err.Error())
+	default:
+		response.WriteError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+func parseID(r *http.Request, param string) (int64, error) {
+	return strconv.ParseInt(chi.URLParam(r, param), 10, 64)
+}
+
+func (h *Handler) CreateProject(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	if !authz.Authorize(w, r, h.policy, authz.ActionCreate, projectResource) {
+		return
+	}
+
+	var req CreateProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	project, err := h.service.Create(auth.GetCaller(r), req)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, project)
+}
+
+func (h *Handler) GetAllProjects(w http.ResponseWriter, r *http.Request) {
+	if !authz.Authorize(w, r, h.policy, authz.ActionRead, projectResource) {
+		return
+	}
+	projects, err := h.service.GetAll(auth.GetCaller(r))
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch projects")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, projects)
+}
+
+func (h *Handler) GetProjectByID(w http.ResponseWriter, r *http.Request) {
+	if !authz.Authorize(w, r, h.policy, authz.ActionRead, projectResource) {
+		return
+	}
+	id, err := parseID(r, "id")
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	project, err := h.service.GetById(auth.GetCaller(r), id)
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch the project")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, project)
+}
+
+func (h *Handler) UpdateProject(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	if !authz.Authorize(w, r, h.policy, authz.ActionManage, projectResource) {
+		return
+	}
+	id, err := parseID(r, "id")
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	var req UpdateProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	project, err := h.service.Update(auth.GetCaller(r), id, req)
+	if err != nil {
+		writeServiceError(w, err, "failed to update project")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, project)
+}
+
+func (h *Handler) DeleteProject(w http.ResponseWriter, r *http.Request) {
+	if !authz.Authorize(w, r, h.policy, authz.ActionDelete, projectResource) {
+		return
+	}
+	id, err := parseID(r, "id")
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	if err := h.service.Delete(auth.GetCaller(r), id); err != nil {
+		writeServiceError(w, err, "failed to delete project")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{"message": "project deleted successfully"})
+}
+
+func (h *Handler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	if !authz.Authorize(w, r, h.policy, authz.ActionRead, projectResource) {
+		return
+	}
+	id, err := parseID(r, "id")
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	members, err := h.service.ListMembers(auth.GetCaller(r), id)
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch members")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, members)
+}
+
+func (h *Handler) AddMember(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	if !authz.Authorize(w, r, h.policy, authz.ActionManage, projectResource) {
+		return
+	}
+	id, err := parseID(r, "id")
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	var req AddMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := h.service.AddMember(auth.GetCaller(r), id, req); err != nil {
+		writeServiceError(w, err, "failed to add member")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{"message": "member saved successfully"})
+}
+
+func (h *Handler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	if !authz.Authorize(w, r, h.policy, authz.ActionRead, projectResource) {
+		return
+	}
+	id, err := parseID(r, "id")
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+	userID, err := parseID(r, "userID")
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid user ID format")
+		return
+	}
+
+	if err := h.service.RemoveMember(auth.GetCaller(r), id, userID); err != nil {
+		writeServiceError(w, err, "failed to remove member")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{"message": "member removed successfully"})
+}
+
+func (h *Handler) ListStatuses(w http.ResponseWriter, r *http.Request) {
+	if !authz.Authorize(w, r, h.policy, authz.ActionRead, projectResource) {
+		return
+	}
+	id, err := parseID(r, "id")
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	statuses, err := h.service.ListStatuses(auth.GetCaller(r), id)
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch statuses")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, statuses)
+}
+
+func (h *Handler) CreateStatus(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	if !authz.Authorize(w, r, h.policy, authz.ActionManage, projectResource) {
+		return
+	}
+	id, err := parseID(r, "id")
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	var req CreateStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	status, err := h.service.CreateStatus(auth.GetCaller(r), id, req)
+	if err != nil {
+		writeServiceError(w, err, "failed to create status")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, status)
+}
+
+func (h *Handler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	if !authz.Authorize(w, r, h.policy, authz.ActionManage, projectResource) {
+		return
+	}
+	id, err := parseID(r, "id")
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+	statusID, err := parseID(r, "statusID")
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid status ID format")
+		return
+	}
+
+	var req UpdateStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	status, err := h.service.UpdateStatus(auth.GetCaller(r), id, statusID, req)
+	if err != nil {
+		writeServiceError(w, err, "failed to update status")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, status)
+}
+
+func (h *Handler) DeleteStatus(w http.ResponseWriter, r *http.Request) {
+	if !authz.Authorize(w, r, h.policy, authz.ActionManage, projectResource) {
+		return
+	}
+	id, err := parseID(r, "id")
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+	statusID, err := parseID(r, "statusID")
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid status ID format")
+		return
+	}
+
+	if err := h.service.DeleteStatus(auth.GetCaller(r), id, statusID); err != nil {
+		writeServiceError(w, err, "failed to delete status")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{"message": "status deleted successfully"})
+}
@@ -0,0 +1,138 @@
+package project
+
+import (
+	"time"
+)
+
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleEditor Role = "editor"
+	RoleViewer Role = "viewer"
+)
+
+func (r Role) Valid() bool {
+	return r == RoleOwner || r == RoleEditor || r == RoleViewer
+}
+
+// CanRead reports whether the role may see the project and its tasks.
+func (r Role) CanRead() bool {
+	return r.Valid()
+}
+
+// CanWrite reports whether the role may create, edit and delete tasks.
+func (r Role) CanWrite() bool {
+	return r == RoleOwner || r == RoleEditor
+}
+
+// CanManage reports whether the role may change membership or delete the project.
+func (r Role) CanManage() bool {
+	return r == RoleOwner
+}
+
+// PointScale is the set of story-point values a project estimates with.
+type PointScale string
+
+const (
+	ScaleFibonacci PointScale = "fibonacci"
+	ScaleTShirt    PointScale = "tshirt"
+)
+
+// pointScales maps each value of a scale to the points it counts for in
+// velocity; T-shirt sizes count as the Fibonacci number of their rank.
+var pointScales = map[PointScale]map[string]int{
+	ScaleFibonacci: {"0": 0, "1": 1, "2": 2, "3": 3, "5": 5, "8": 8, "13": 13, "21": 21, "34": 34, "55": 55, "89": 89},
+	ScaleTShirt:    {"XS": 1, "S": 2, "M": 3, "L": 5, "XL": 8, "XXL": 13},
+}
+
+// Points returns what value counts for on the scale, and whether the scale
+// has it.
+func (s PointScale) Points(value string) (int, bool) {
+	points, ok := pointScales[s][value]
+	return points, ok
+}
+
+type Project struct {
+	ID          int64      `json:"id"`
+	WorkspaceID int64      `json:"workspace_id"`
+	Name        string     `json:"name"`
+	OwnerID     int64      `json:"owner_id"`
+	PointScale  PointScale `json:"point_scale"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// StatusCategory is what a workflow status means for its tasks.
+type StatusCategory string
+
+const (
+	CategoryTodo       StatusCategory = "todo"
+	CategoryInProgress StatusCategory = "in_progress"
+	CategoryDone       StatusCategory = "done"
+)
+
+// Status is a column of the project's workflow. Tasks in a done status are
+// completed.
+type Status struct {
+	ID        int64          `json:"id"`
+	ProjectID int64          `json:"project_id"`
+	Name      string         `json:"name"`
+	Category  StatusCategory `json:"category"`
+	Position  int            `json:"position"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// DefaultStatuses are created with every project.
+var DefaultStatuses = []Status{
+	{Name: "To do", Category: CategoryTodo, Position: 0},
+	{Name: "In progress", Category: CategoryInProgress, Position: 1},
+	{Name: "Done", Category: CategoryDone, Position: 2},
+}
+
+type Member struct {
+	ProjectID int64     `json:"project_id"`
+	UserID    int64     `json:"user_id"`
+	Username  string    `json:"username"`
+	Role      Role      `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateProjectRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+	// PointScale defaults to Fibonacci.
+	PointScale PointScale `json:"point_scale,omitempty" validate:"omitempty,oneof=fibonacci tshirt"`
+}
+
+type UpdateProjectRequest struct {
+	Name       *string     `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	PointScale *PointScale `json:"point_scale,omitempty" validate:"omitempty,oneof=fibonacci tshirt"`
+}
+
+type AddMemberRequest struct {
+	UserID int64 `json:"user_id" validate:"required,gt=0"`
+	Role   Role  `json:"role" validate:"required,oneof=owner editor viewer"`
+}
+
+type CreateStatusRequest struct {
+	Name     string         `json:"name" validate:"required,max=50"`
+	Category StatusCategory `json:"category" validate:"required,oneof=todo in_progress done"`
+	// Position defaults to after the last status.
+	Position *int `json:"position,omitempty" validate:"omitempty,gte=0"`
+}
+
+type UpdateStatusRequest struct {
+	Name     *string         `json:"name,omitempty" validate:"omitempty,min=1,max=50"`
+	Category *StatusCategory `json:"category,omitempty" validate:"omitempty,oneof=todo in_progress done"`
+	Position *int            `json:"position,omitempty" validate:"omitempty,gte=0"`
+}
+
+type ProjectResponse struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	OwnerID    int64      `json:"owner_id"`
+	Role       Role       `json:"role"`
+	PointScale PointScale `json:"point_scale"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,551 @@
+package project
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sudarshanmg/gotask/pkg/db"
+)
+
+// ProjectRepository methods are scoped to a workspace; every query filters on
+// it and runs under db.WithTenant so row-level security applies as well.
+type ProjectRepository interface {
+	Create(project *Project) (int64, error)
+	FindById(workspaceID, id int64) (*Project, error)
+	FindForUser(workspaceID, userID int64) ([]Project, []Role, error)
+	Update(project *Project) error
+	// Delete deletes the project with its tasks, returning the tasks' IDs.
+	Delete(workspaceID, id int64) ([]int64, error)
+	GetMemberRole(workspaceID, projectID, userID int64) (Role, error)
+	ListMembers(workspaceID, projectID int64) ([]Member, error)
+	UpsertMember(workspaceID, projectID, userID int64, role Role) error
+	RemoveMember(workspaceID, projectID, userID int64) error
+	ListStatuses(workspaceID, projectID int64) ([]Status, error)
+	CreateStatus(workspaceID int64, status *Status) error
+	UpdateStatus(workspaceID int64, status *Status) error
+	DeleteStatus(workspaceID, projectID, id int64) error
+}
+
+type PostgresProjectRepository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) ProjectRepository {
+	return &PostgresProjectRepository{DB: db}
+}
+
+// Create inserts the project with the default statuses and registers its
+// owner as a member in the same transaction, so a project never exists
+// without an owner.
+func (r *PostgresProjectRepository) Create(project *Project) (int64, error) {
+	project.CreatedAt = time.Now()
+	project.UpdatedAt = project.CreatedAt
+
+	var id int64
+	err := db.WithTenant(r.DB, project.WorkspaceID, func(tx *sql.Tx) error {
+		query := `INSERT INTO projects (workspace_id, name, owner_id, point_scale, created_at, updated_at)
+            VALUES ($1, $2, $3, $4, $5, $6)
+            RETURNING id;`
+
+		err := tx.QueryRow(query, project.WorkspaceID, project.Name, project.OwnerID, project.PointScale,
+			project.CreatedAt, project.UpdatedAt).Scan(&id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`INSERT INTO project_members (project_id, user_id, role) VALUES ($1, $2, $3);`,
+			id, project.OwnerID, RoleOwner)
+		if err != nil {
+			return err
+		}
+
+		for _, st := range DefaultStatuses {
+			_, err = tx.Exec(`INSERT INTO project_statuses (project_id, name, category, position) VALUES ($1, $2, $3, $4);`,
+				id, st.Name, st.Category, st.Position)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	project.ID = id
+	return id, nil
+}
+
+func (r *PostgresProjectRepository) FindById(workspaceID, id int64) (*Project, error) {
+	query := `SELECT id, workspace_id, name, owner_id, point_scale, created_at, updated_at
+            FROM projects WHERE workspace_id = $1 AND id = $2;`
+
+	var p *Project
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		found := Project{}
+		err := tx.QueryRow(query, workspaceID, id).Scan(&found.ID, &found.WorkspaceID, &found.Name,
+			&found.OwnerID, &found.PointScale, &found.CreatedAt, &found.UpdatedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		p = &found
+		return nil
+	})
+	return p, err
+}
+
+func (r *PostgresProjectRepository) FindForUser(workspaceID, userID int64) ([]Project, []Role, error) {
+	query := `
+          SELECT p.id, p.workspace_id, p.name, p.owner_id, p.point_scale, p.created_at, p.updated_at, m.role
+          FROM projects p
+          JOIN project_members m ON m.project_id = p.id
+          WHERE p.workspace_id = $1 AND m.user_id = $2
+          ORDER BY p.id;`
+
+	projects := []Project{}
+	roles := []Role{}
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, workspaceID, userID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			p := Project{}
+			var role Role
+			if err := rows.Scan(&p.ID, &p.WorkspaceID, &p.Name, &p.OwnerID, &p.PointScale,
+				&p.CreatedAt, &p.UpdatedAt, &role); err != nil {
+				return err
+			}
+			projects = append(projects, p)
+			roles = append(roles, role)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return projects, roles, nil
+}
+
+// Update saves the name and point scale. The scale cannot change while
+// tasks in the project have story points, since those would no longer be on
+// it.
+func (r *PostgresProjectRepository) Update(project *Project) error {
+	project.UpdatedAt = time.Now()
+
+	return db.WithTenant(r.DB, project.WorkspaceID, func(tx *sql.Tx) error {
+		var scale PointScale
+		err := tx.QueryRow(`SELECT point_scale FROM projects WHERE workspace_id = $1 AND id = $2 FOR UPDATE;`,
+			project.WorkspaceID, project.ID).Scan(&scale)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if scale != project.PointScale {
+			var estimated bool
+			err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM tasks WHERE project_id = $1 AND story_points IS NOT NULL);`,
+				project.ID).Scan(&estimated)
+			if err != nil {
+				return err
+			}
+			if estimated {
+				return ErrScaleInUse
+			}
+		}
+
+		_, err = tx.Exec(`UPDATE projects SET name = $3, point_scale = $4, updated_at = $5
+                WHERE workspace_id = $1 AND id = $2;`,
+			project.WorkspaceID, project.ID, project.Name, project.PointScale, project.UpdatedAt)
+		return err
+	})
+}
+
+func (r *PostgresProjectRepository) Delete(workspaceID, id int64) ([]int64, error) {
+	ids := []int64{}
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		// The project's tasks go with it; sync clients learn from their
+		// tombstones. They are deleted here rather than by the foreign key
+		// so that their IDs are known.
+		if err := db.LockTaskChanges(tx, workspaceID); err != nil {
+			return err
+		}
+		if err := db.TombstoneTasks(tx, `t.workspace_id = $1 AND t.project_id = $2`, workspaceID, id); err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(`DELETE FROM tasks WHERE workspace_id = $1 AND project_id = $2 RETURNING id;`,
+			workspaceID, id)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var taskID int64
+			if err := rows.Scan(&taskID); err != nil {
+				return err
+			}
+			ids = append(ids, taskID)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		rows.Close()
+
+		res, err := tx.Exec(`DELETE FROM projects WHERE workspace_id = $1 AND id = $2;`, workspaceID, id)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return errors.New("no project deleted")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// GetMemberRole returns an empty role when the user is not a member or the
+// project belongs to another workspace.
+func (r *PostgresProjectRepository) GetMemberRole(workspaceID, projectID, userID int64) (Role, error) {
+	query := `SELECT m.role
+            FROM project_members m
+            JOIN projects p ON p.id = m.project_id
+            WHERE p.workspace_id = $1 AND m.project_id = $2 AND m.user_id = $3;`
+
+	var role Role
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		err := tx.QueryRow(query, workspaceID, projectID, userID).Scan(&role)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	})
+	return role, err
+}
+
+func (r *PostgresProjectRepository) ListMembers(workspaceID, projectID int64) ([]Member, error) {
+	query := `
+          SELECT m.project_id, m.user_id, u.username, m.role, m.created_at
+          FROM project_members m
+          JOIN projects p ON p.id = m.project_id
+          JOIN users u ON u.id = m.user_id
+          WHERE p.workspace_id = $1 AND m.project_id = $2
+          ORDER BY m.created_at;`
+
+	members := []Member{}
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, workspaceID, projectID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			m := Member{}
+			if err := rows.Scan(&m.ProjectID, &m.UserID, &m.Username, &m.Role, &m.CreatedAt); err != nil {
+				return err
+			}
+			members = append(members, m)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// UpsertMember only accepts users who belong to the project's workspace. A
+// user who joins the project gets a visibility change for each of its tasks,
+// so sync clients list them.
+func (r *PostgresProjectRepository) UpsertMember(workspaceID, projectID, userID int64, role Role) error {
+	query := `INSERT INTO project_members (project_id, user_id, role)
+            SELECT p.id, wm.user_id, $4
+            FROM projects p
+            JOIN workspace_members wm ON wm.workspace_id = p.workspace_id
+            WHERE p.workspace_id = $1 AND p.id = $2 AND wm.user_id = $3
+            ON CONFLICT (project_id, user_id) DO UPDATE SET role = EXCLUDED.role;`
+
+	return db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		if err := db.LockTaskChanges(tx, workspaceID); err != nil {
+			return err
+		}
+
+		var member bool
+		err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM project_members WHERE project_id = $1 AND user_id = $2);`,
+			projectID, userID).Scan(&member)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.Exec(query, workspaceID, projectID, userID, role)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return ErrUserMissing
+		}
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrUserMissing
+		}
+		if member {
+			return nil
+		}
+
+		return recordVisibility(tx, workspaceID, projectID, userID, true)
+	})
+}
+
+// recordVisibility records that the user gained or lost sight of every task
+// in the project. It must run in a transaction holding LockTaskChanges.
+func recordVisibility(tx *sql.Tx, workspaceID, projectID, userID int64, visible bool) error {
+	_, err := tx.Exec(`INSERT INTO task_visibility_changes (task_id, workspace_id, user_id, visible, change_seq)
+            SELECT id, workspace_id, $3, $4, nextval('task_change_seq')
+            FROM tasks
+            WHERE workspace_id = $1 AND project_id = $2
+            ORDER BY id
+            ON CONFLICT (task_id, user_id)
+            DO UPDATE SET visible = EXCLUDED.visible, change_seq = EXCLUDED.change_seq;`,
+		workspaceID, projectID, userID, visible)
+	return err
+}
+
+// RemoveMember drops the membership and any task assignments the user held
+// inside the project. The unassigned tasks get a change number, and every
+// task in the project a visibility change for the user, so sync clients see
+// both.
+func (r *PostgresProjectRepository) RemoveMember(workspaceID, projectID, userID int64) error {
+	return db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		if err := db.LockTaskChanges(tx, workspaceID); err != nil {
+			return err
+		}
+
+		res, err := tx.Exec(`DELETE FROM project_members
+            WHERE user_id = $3 AND project_id IN (SELECT id FROM projects WHERE workspace_id = $1 AND id = $2);`,
+			workspaceID, projectID, userID)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrMemberMissing
+		}
+
+		if err := recordVisibility(tx, workspaceID, projectID, userID, false); err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(`DELETE FROM task_assignees
+            WHERE user_id = $3 AND task_id IN (SELECT id FROM tasks WHERE workspace_id = $1 AND project_id = $2)
+            RETURNING task_id;`,
+			workspaceID, projectID, userID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		ids := []int64{}
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		rows.Close()
+
+		_, err = tx.Exec(`UPDATE tasks SET change_seq = nextval('task_change_seq')
+                WHERE workspace_id = $1 AND id = ANY($2);`, workspaceID, pq.Array(ids))
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE tasks SET field_seqs = field_seqs || jsonb_build_object('assignee_ids', change_seq)
+                WHERE workspace_id = $1 AND id = ANY($2);`, workspaceID, pq.Array(ids))
+		return err
+	})
+}
+
+// statusOfProject restricts project_statuses s to project $2 of workspace $1.
+const statusOfProject = `s.project_id IN (SELECT id FROM projects WHERE workspace_id = $1 AND id = $2)`
+
+func (r *PostgresProjectRepository) ListStatuses(workspaceID, projectID int64) ([]Status, error) {
+	query := `SELECT s.id, s.project_id, s.name, s.category, s.position, s.created_at
+            FROM project_statuses s
+            WHERE ` + statusOfProject + `
+            ORDER BY s.position, s.id;`
+
+	statuses := []Status{}
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, workspaceID, projectID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			st := Status{}
+			if err := rows.Scan(&st.ID, &st.ProjectID, &st.Name, &st.Category, &st.Position, &st.CreatedAt); err != nil {
+				return err
+			}
+			statuses = append(statuses, st)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// isUniqueViolation reports whether err is a duplicate status name.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func (r *PostgresProjectRepository) CreateStatus(workspaceID int64, status *Status) error {
+	query := `INSERT INTO project_statuses (project_id, name, category, position)
+            SELECT p.id, $3, $4, $5 FROM projects p WHERE p.workspace_id = $1 AND p.id = $2
+            RETURNING id, created_at;`
+
+	return db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		err := tx.QueryRow(query, workspaceID, status.ProjectID, status.Name, status.Category, status.Position).
+			Scan(&status.ID, &status.CreatedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if isUniqueViolation(err) {
+			return ErrStatusName
+		}
+		return err
+	})
+}
+
+// statusCategories counts the project's statuses per category, leaving out
+// the status being changed or deleted in $3.
+const statusCategories = `SELECT COUNT(*) FILTER (WHERE s.category = 'todo'),
+                   COUNT(*) FILTER (WHERE s.category = 'done')
+            FROM project_statuses s
+            WHERE ` + statusOfProject + ` AND s.id <> $3;`
+
+// checkStatusChange refuses to change the category of, or delete, a status
+// that tasks are in, or the last todo or done status of the project. The
+// project row is locked so that concurrent changes are checked in turn.
+func checkStatusChange(tx *sql.Tx, workspaceID, projectID, id int64, category *StatusCategory) error {
+	var locked int64
+	err := tx.QueryRow(`SELECT id FROM projects WHERE workspace_id = $1 AND id = $2 FOR UPDATE;`,
+		workspaceID, projectID).Scan(&locked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrStatusMissing
+	}
+	if err != nil {
+		return err
+	}
+
+	var inUse bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM tasks WHERE status_id = $1);`, id).Scan(&inUse); err != nil {
+		return err
+	}
+	if inUse {
+		return ErrStatusInUse
+	}
+
+	var todo, done int64
+	if err := tx.QueryRow(statusCategories, workspaceID, projectID, id).Scan(&todo, &done); err != nil {
+		return err
+	}
+	if category != nil {
+		switch *category {
+		case CategoryTodo:
+			todo++
+		case CategoryDone:
+			done++
+		}
+	}
+	if todo == 0 || done == 0 {
+		return ErrStatusLast
+	}
+	return nil
+}
+
+// UpdateStatus changes a status's name and position freely; its category
+// only under the rules of checkStatusChange, since tasks in it would
+// otherwise be completed or reopened behind their backs.
+func (r *PostgresProjectRepository) UpdateStatus(workspaceID int64, status *Status) error {
+	return db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		var category StatusCategory
+		err := tx.QueryRow(`SELECT s.category FROM project_statuses s WHERE `+statusOfProject+` AND s.id = $3;`,
+			workspaceID, status.ProjectID, status.ID).Scan(&category)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrStatusMissing
+		}
+		if err != nil {
+			return err
+		}
+		if category != status.Category {
+			if err := checkStatusChange(tx, workspaceID, status.ProjectID, status.ID, &status.Category); err != nil {
+				return err
+			}
+		}
+
+		err = tx.QueryRow(`UPDATE project_statuses s SET name = $4, category = $5, position = $6
+                WHERE `+statusOfProject+` AND s.id = $3
+                RETURNING s.created_at;`,
+			workspaceID, status.ProjectID, status.ID, status.Name, status.Category, status.Position).
+			Scan(&status.CreatedAt)
+		if isUniqueViolation(err) {
+			return ErrStatusName
+		}
+		return err
+	})
+}
+
+func (r *PostgresProjectRepository) DeleteStatus(workspaceID, projectID, id int64) error {
+	return db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		if err := checkStatusChange(tx, workspaceID, projectID, id, nil); err != nil {
+			return err
+		}
+
+		res, err := tx.Exec(`DELETE FROM project_statuses s WHERE `+statusOfProject+` AND s.id = $3;`,
+			workspaceID, projectID, id)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrStatusMissing
+		}
+		return nil
+	})
+}
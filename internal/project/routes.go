@@ -0,0 +1,22 @@
+package project
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Route("/projects", func(r chi.Router) {
+		r.Get("/", h.GetAllProjects)
+		r.Post("/", h.CreateProject)
+		r.Get("/{id}", h.GetProjectByID)
+		r.Put("/{id}", h.UpdateProject)
+		r.Delete("/{id}", h.DeleteProject)
+		r.Get("/{id}/members", h.ListMembers)
+		r.Put("/{id}/members", h.AddMember)
+		r.Delete("/{id}/members/{userID}", h.RemoveMember)
+		r.Get("/{id}/statuses", h.ListStatuses)
+		r.Post("/{id}/statuses", h.CreateStatus)
+		r.Put("/{id}/statuses/{statusID}", h.UpdateStatus)
+		r.Delete("/{id}/statuses/{statusID}", h.DeleteStatus)
+	})
+}
@@ -0,0 +1,321 @@
+package project
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/authz"
+	"github.com/sudarshanmg/gotask/pkg/validation"
+)
+
+var validate = validator.New()
+
+type ProjectService interface {
+	Create(caller auth.Caller, req CreateProjectRequest) (*ProjectResponse, error)
+	GetAll(caller auth.Caller) ([]ProjectResponse, error)
+	GetById(caller auth.Caller, id int64) (*ProjectResponse, error)
+	Update(caller auth.Caller, id int64, req UpdateProjectRequest) (*ProjectResponse, error)
+	Delete(caller auth.Caller, id int64) error
+	ListMembers(caller auth.Caller, projectID int64) ([]Member, error)
+	AddMember(caller auth.Caller, projectID int64, req AddMemberRequest) error
+	RemoveMember(caller auth.Caller, projectID, memberID int64) error
+	Actions(subject authz.Subject, id int64) ([]authz.Action, error)
+	ListStatuses(caller auth.Caller, projectID int64) ([]Status, error)
+	CreateStatus(caller auth.Caller, projectID int64, req CreateStatusRequest) (*Status, error)
+	UpdateStatus(caller auth.Caller, projectID, id int64, req UpdateStatusRequest) (*Status, error)
+	DeleteStatus(caller auth.Caller, projectID, id int64) error
+}
+
+// TaskEvents publishes events for the tasks deleted with a project. The
+// task service implements it; it cannot be named here since the task
+// package imports this one.
+type TaskEvents interface {
+	DeletingProject(caller auth.Caller, projectID int64) (func(deleted []int64), error)
+}
+
+type projectService struct {
+	repo  ProjectRepository
+	tasks TaskEvents
+}
+
+func NewService(repo ProjectRepository, tasks TaskEvents) ProjectService {
+	return &projectService{repo: repo, tasks: tasks}
+}
+
+func mapProjectToResponse(p *Project, role Role) ProjectResponse {
+	return ProjectResponse{
+		ID:         p.ID,
+		Name:       p.Name,
+		OwnerID:    p.OwnerID,
+		Role:       role,
+		PointScale: p.PointScale,
+		CreatedAt:  p.CreatedAt,
+		UpdatedAt:  p.UpdatedAt,
+	}
+}
+
+// load returns the project together with the caller's role. Projects the
+// caller is not a member of are reported as not found.
+func (s *projectService) load(caller auth.Caller, id int64) (*Project, Role, error) {
+	if id <= 0 {
+		return nil, "", ErrInvalidID
+	}
+
+	role, err := s.repo.GetMemberRole(caller.WorkspaceID, id, caller.UserID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !role.CanRead() {
+		return nil, "", ErrNotFound
+	}
+
+	p, err := s.repo.FindById(caller.WorkspaceID, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if p == nil {
+		return nil, "", ErrNotFound
+	}
+	return p, role, nil
+}
+
+func (s *projectService) Create(caller auth.Caller, req CreateProjectRequest) (*ProjectResponse, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+
+	p := Project{
+		WorkspaceID: caller.WorkspaceID,
+		Name:        req.Name,
+		OwnerID:     caller.UserID,
+		PointScale:  req.PointScale,
+	}
+	if p.PointScale == "" {
+		p.PointScale = ScaleFibonacci
+	}
+	if _, err := s.repo.Create(&p); err != nil {
+		return nil, err
+	}
+
+	res := mapProjectToResponse(&p, RoleOwner)
+	return &res, nil
+}
+
+func (s *projectService) GetAll(caller auth.Caller) ([]ProjectResponse, error) {
+	projects, roles, err := s.repo.FindForUser(caller.WorkspaceID, caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]ProjectResponse, 0, len(projects))
+	for i := range projects {
+		responses = append(responses, mapProjectToResponse(&projects[i], roles[i]))
+	}
+	return responses, nil
+}
+
+func (s *projectService) GetById(caller auth.Caller, id int64) (*ProjectResponse, error) {
+	p, role, err := s.load(caller, id)
+	if err != nil {
+		return nil, err
+	}
+	res := mapProjectToResponse(p, role)
+	return &res, nil
+}
+
+func (s *projectService) Update(caller auth.Caller, id int64, req UpdateProjectRequest) (*ProjectResponse, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+
+	p, role, err := s.load(caller, id)
+	if err != nil {
+		return nil, err
+	}
+	if !role.CanManage() {
+		return nil, ErrForbidden
+	}
+
+	if req.Name != nil {
+		p.Name = *req.Name
+	}
+	if req.PointScale != nil {
+		p.PointScale = *req.PointScale
+	}
+	if err := s.repo.Update(p); err != nil {
+		return nil, err
+	}
+
+	res := mapProjectToResponse(p, role)
+	return &res, nil
+}
+
+func (s *projectService) Delete(caller auth.Caller, id int64) error {
+	_, role, err := s.load(caller, id)
+	if err != nil {
+		return err
+	}
+	if !role.CanManage() {
+		return ErrForbidden
+	}
+
+	publish, err := s.tasks.DeletingProject(caller, id)
+	if err != nil {
+		return err
+	}
+	deleted, err := s.repo.Delete(caller.WorkspaceID, id)
+	if err != nil {
+		return err
+	}
+	publish(deleted)
+	return nil
+}
+
+func (s *projectService) ListMembers(caller auth.Caller, projectID int64) ([]Member, error) {
+	if _, _, err := s.load(caller, projectID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListMembers(caller.WorkspaceID, projectID)
+}
+
+func (s *projectService) AddMember(caller auth.Caller, projectID int64, req AddMemberRequest) error {
+	if err := validate.Struct(req); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMember, validation.FormatValidationError(err))
+	}
+
+	p, role, err := s.load(caller, projectID)
+	if err != nil {
+		return err
+	}
+	if !role.CanManage() {
+		return ErrForbidden
+	}
+	if req.UserID == p.OwnerID && req.Role != RoleOwner {
+		return ErrOwnerRemoval
+	}
+
+	return s.repo.UpsertMember(caller.WorkspaceID, projectID, req.UserID, req.Role)
+}
+
+func (s *projectService) RemoveMember(caller auth.Caller, projectID, memberID int64) error {
+	p, role, err := s.load(caller, projectID)
+	if err != nil {
+		return err
+	}
+	// Members may always leave a project on their own.
+	if !role.CanManage() && memberID != caller.UserID {
+		return ErrForbidden
+	}
+	if memberID == p.OwnerID {
+		return ErrOwnerRemoval
+	}
+
+	return s.repo.RemoveMember(caller.WorkspaceID, projectID, memberID)
+}
+
+// Actions reports what the user may do with a single project; it is
+// registered as the authz scope for project resources.
+func (s *projectService) Actions(subject authz.Subject, id int64) ([]authz.Action, error) {
+	role, err := s.repo.GetMemberRole(subject.WorkspaceID, id, subject.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := []authz.Action{}
+	if role.CanRead() {
+		actions = append(actions, authz.ActionRead)
+	}
+	if role.CanWrite() {
+		actions = append(actions, authz.ActionUpdate)
+	}
+	if role.CanManage() {
+		actions = append(actions, authz.ActionDelete, authz.ActionManage)
+	}
+	return actions, nil
+}
+
+func (s *projectService) ListStatuses(caller auth.Caller, projectID int64) ([]Status, error) {
+	if _, _, err := s.load(caller, projectID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListStatuses(caller.WorkspaceID, projectID)
+}
+
+// manage loads a project whose workflow the caller may change, together
+// with its statuses.
+func (s *projectService) manage(caller auth.Caller, projectID int64) ([]Status, error) {
+	_, role, err := s.load(caller, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if !role.CanManage() {
+		return nil, ErrForbidden
+	}
+	return s.repo.ListStatuses(caller.WorkspaceID, projectID)
+}
+
+func (s *projectService) CreateStatus(caller auth.Caller, projectID int64, req CreateStatusRequest) (*Status, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+
+	statuses, err := s.manage(caller, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	st := Status{ProjectID: projectID, Name: strings.TrimSpace(req.Name), Category: req.Category}
+	if req.Position != nil {
+		st.Position = *req.Position
+	} else if len(statuses) > 0 {
+		st.Position = statuses[len(statuses)-1].Position + 1
+	}
+	if err := s.repo.CreateStatus(caller.WorkspaceID, &st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+func (s *projectService) UpdateStatus(caller auth.Caller, projectID, id int64, req UpdateStatusRequest) (*Status, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+
+	statuses, err := s.manage(caller, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var st *Status
+	for i := range statuses {
+		if statuses[i].ID == id {
+			st = &statuses[i]
+		}
+	}
+	if st == nil {
+		return nil, ErrStatusMissing
+	}
+
+	if req.Name != nil {
+		st.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Category != nil {
+		st.Category = *req.Category
+	}
+	if req.Position != nil {
+		st.Position = *req.Position
+	}
+	if err := s.repo.UpdateStatus(caller.WorkspaceID, st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+func (s *projectService) DeleteStatus(caller auth.Caller, projectID, id int64) error {
+	if _, err := s.manage(caller, projectID); err != nil {
+		return err
+	}
+	return s.repo.DeleteStatus(caller.WorkspaceID, projectID, id)
+}
@@ -0,0 +1,14 @@
+package reminder
+
+import "errors"
+
+var (
+	ErrNotFound      = errors.New("reminder not found")
+	ErrInvalidID     = errors.New("invalid reminder ID")
+	ErrTaskNotFound  = errors.New("task not found")
+	ErrNoDueDate     = errors.New("the task has no due date to remind relative to")
+	ErrInPast        = errors.New("the reminder time has already passed")
+	ErrWebhookURL    = errors.New("webhook_url is required for the webhook channel")
+	ErrNoNotifier    = errors.New("reminders are not available on this channel")
+	ErrTaskCompleted = errors.New("task is already completed")
+)
@@ -0,0 +1,86 @@
+package reminder
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/pkg/response"
+)
+
+type Handler struct {
+	service ReminderService
+}
+
+func NewHandler(service ReminderService) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) CreateReminder(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var req CreateReminderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	reminder, err := h.service.Create(auth.GetCaller(r), req)
+	if errors.Is(err, ErrTaskNotFound) {
+		response.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, reminder)
+}
+
+func (h *Handler) GetAllReminders(w http.ResponseWriter, r *http.Request) {
+	filter := ReminderFilter{}
+	if v := r.URL.Query().Get("task_id"); v != "" {
+		taskID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			response.WriteError(w, http.StatusBadRequest, "invalid task_id")
+			return
+		}
+		filter.TaskID = &taskID
+	}
+
+	reminders, err := h.service.GetAll(auth.GetCaller(r), filter)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch reminders")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, reminders)
+}
+
+func (h *Handler) DeleteReminder(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	err = h.service.Delete(auth.GetCaller(r), id)
+	if errors.Is(err, ErrInvalidID) {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.Is(err, ErrNotFound) {
+		response.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to delete reminder")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{"message": "reminder deleted successfully"})
+}
@@ -0,0 +1,17 @@
+package reminder
+
+// Job fires due reminders. Every replica may run it: reminders are claimed
+// with FOR UPDATE SKIP LOCKED and marked fired in the same transaction, so
+// each one is handed to its notifier by a single server.
+type Job struct {
+	Service ReminderService
+	Batch   int
+}
+
+func (j *Job) Name() string {
+	return "reminders"
+}
+
+func (j *Job) Run() (int, error) {
+	return j.Service.ProcessDue(j.Batch)
+}
@@ -0,0 +1,50 @@
+package reminder
+
+import (
+	"time"
+)
+
+type Channel string
+
+const (
+	ChannelInApp   Channel = "in_app"
+	ChannelEmail   Channel = "email"
+	ChannelWebhook Channel = "webhook"
+)
+
+type Reminder struct {
+	ID               int64      `json:"id"`
+	WorkspaceID      int64      `json:"workspace_id"`
+	TaskID           int64      `json:"task_id"`
+	UserID           int64      `json:"user_id"`
+	RemindAt         *time.Time `json:"remind_at"`
+	MinutesBeforeDue *int       `json:"minutes_before_due"`
+	Channel          Channel    `json:"channel"`
+	WebhookURL       string     `json:"webhook_url,omitempty"`
+	FireAt           *time.Time `json:"fire_at"`
+	FiredAt          *time.Time `json:"fired_at"`
+	FailedAt         *time.Time `json:"failed_at"`
+	Attempts         int        `json:"attempts"`
+	LastError        string     `json:"last_error,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// Delivery is a due reminder together with the task it is about, as handed
+// to a Notifier.
+type Delivery struct {
+	Reminder  Reminder
+	TaskTitle string
+	TaskDueAt *time.Time
+}
+
+type CreateReminderRequest struct {
+	TaskID           int64      `json:"task_id" validate:"required,gt=0"`
+	RemindAt         *time.Time `json:"remind_at,omitempty" validate:"required_without=MinutesBeforeDue,excluded_with=MinutesBeforeDue"`
+	MinutesBeforeDue *int       `json:"minutes_before_due,omitempty" validate:"omitempty,min=0,max=525600"`
+	Channel          Channel    `json:"channel" validate:"required,oneof=in_app email webhook"`
+	WebhookURL       string     `json:"webhook_url,omitempty" validate:"omitempty,http_url,max=2000"`
+}
+
+type ReminderFilter struct {
+	TaskID *int64
+}
@@ -0,0 +1,140 @@
+package reminder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sudarshanmg/gotask/internal/mail"
+	"github.com/sudarshanmg/gotask/internal/notification"
+	"github.com/sudarshanmg/gotask/pkg/outbound"
+)
+
+// Notifier delivers a due reminder on one channel. Returning an error makes
+// the scheduler retry the reminder later with backoff.
+type Notifier interface {
+	Channel() Channel
+	Notify(d Delivery) error
+}
+
+func message(d Delivery) string {
+	if d.TaskDueAt != nil {
+		return fmt.Sprintf("Reminder: %q is due %s", d.TaskTitle, d.TaskDueAt.Format(time.RFC1123))
+	}
+	return fmt.Sprintf("Reminder: %q", d.TaskTitle)
+}
+
+// InAppNotifier puts the reminder in the user's notification inbox. The
+// dedupe key makes redelivery after a crash a no-op, so in-app reminders
+// arrive exactly once.
+type InAppNotifier struct {
+	Notifications notification.NotificationService
+}
+
+func (n *InAppNotifier) Channel() Channel {
+	return ChannelInApp
+}
+
+func (n *InAppNotifier) Notify(d Delivery) error {
+	taskID := d.Reminder.TaskID
+	return n.Notifications.Notify(notification.Notification{
+		WorkspaceID: d.Reminder.WorkspaceID,
+		UserID:      d.Reminder.UserID,
+		Type:        notification.TypeReminder,
+		TaskID:      &taskID,
+		Message:     message(d),
+		DedupeKey:   fmt.Sprintf("reminder:%d", d.Reminder.ID),
+	})
+}
+
+// EmailNotifier queues the reminder for the user's verified email address.
+// The outbox dedupe key keeps a reminder from being mailed twice.
+type EmailNotifier struct {
+	Mail mail.MailService
+}
+
+func (n *EmailNotifier) Channel() Channel {
+	return ChannelEmail
+}
+
+func (n *EmailNotifier) Notify(d Delivery) error {
+	data := struct {
+		Title string
+		DueAt *time.Time
+	}{d.TaskTitle, d.TaskDueAt}
+	return n.Mail.SendToUser(d.Reminder.UserID, "reminder", data, fmt.Sprintf("reminder:%d", d.Reminder.ID))
+}
+
+// WebhookPayload is the JSON body posted to a reminder's webhook_url.
+type WebhookPayload struct {
+	ReminderID int64      `json:"reminder_id"`
+	TaskID     int64      `json:"task_id"`
+	Title      string     `json:"title"`
+	DueAt      *time.Time `json:"due_at"`
+	Message    string     `json:"message"`
+}
+
+// WebhookNotifier posts the reminder to its webhook_url, which may only be
+// a public address. Delivery is at-least-once: if the scheduler cannot
+// record a successful post, it posts again. Every attempt for the same
+// occurrence carries the same Idempotency-Key header, so receivers can drop
+// the duplicates.
+type WebhookNotifier struct {
+	Client *http.Client
+}
+
+func NewWebhookNotifier() *WebhookNotifier {
+	return &WebhookNotifier{Client: outbound.NewClient(10 * time.Second)}
+}
+
+func (n *WebhookNotifier) Channel() Channel {
+	return ChannelWebhook
+}
+
+func (n *WebhookNotifier) Notify(d Delivery) error {
+	body, err := json.Marshal(WebhookPayload{
+		ReminderID: d.Reminder.ID,
+		TaskID:     d.Reminder.TaskID,
+		Title:      d.TaskTitle,
+		DueAt:      d.TaskDueAt,
+		Message:    message(d),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, d.Reminder.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gotask-reminders")
+	req.Header.Set("Idempotency-Key", idempotencyKey(d))
+
+	res, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", res.Status)
+	}
+	return nil
+}
+
+// idempotencyKey identifies one occurrence of a reminder: its ID and the
+// time it was set for. Retries keep the key even though they move fire_at.
+func idempotencyKey(d Delivery) string {
+	r := d.Reminder
+	var at time.Time
+	switch {
+	case r.RemindAt != nil:
+		at = *r.RemindAt
+	case r.MinutesBeforeDue != nil && d.TaskDueAt != nil:
+		at = d.TaskDueAt.Add(-time.Duration(*r.MinutesBeforeDue) * time.Minute)
+	}
+	return fmt.Sprintf("reminder-%d-%d", r.ID, at.Unix())
+}
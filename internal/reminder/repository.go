@@ -0,0 +1,181 @@
+package reminder
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/sudarshanmg/gotask/pkg/db"
+)
+
+const (
+	// maxAttempts is how many times delivery is tried before a reminder is
+	// marked as failed.
+	maxAttempts = 5
+
+	reminderColumns = `r.id, r.workspace_id, r.task_id, r.user_id, r.remind_at, r.minutes_before_due, r.channel,
+            r.webhook_url, r.fire_at, r.fired_at, r.failed_at, r.attempts, r.last_error, r.created_at`
+)
+
+type ReminderRepository interface {
+	Create(reminder *Reminder) error
+	FindForUser(workspaceID, userID int64, filter ReminderFilter) ([]Reminder, error)
+	Delete(workspaceID, userID, id int64) error
+	// ProcessDue claims up to limit due reminders across all workspaces and
+	// hands each to deliver. Claimed rows stay locked until the batch
+	// commits, so concurrent callers on other replicas skip them.
+	ProcessDue(limit int, deliver func(d Delivery) error) (int, error)
+}
+
+type PostgresReminderRepository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) ReminderRepository {
+	return &PostgresReminderRepository{DB: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanReminder(row rowScanner, r *Reminder) error {
+	return row.Scan(&r.ID, &r.WorkspaceID, &r.TaskID, &r.UserID, &r.RemindAt, &r.MinutesBeforeDue, &r.Channel,
+		&r.WebhookURL, &r.FireAt, &r.FiredAt, &r.FailedAt, &r.Attempts, &r.LastError, &r.CreatedAt)
+}
+
+func (r *PostgresReminderRepository) Create(reminder *Reminder) error {
+	query := `INSERT INTO task_reminders
+              (workspace_id, task_id, user_id, remind_at, minutes_before_due, channel, webhook_url, fire_at)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+            RETURNING id, attempts, last_error, created_at;`
+
+	return r.DB.QueryRow(query, reminder.WorkspaceID, reminder.TaskID, reminder.UserID, reminder.RemindAt,
+		reminder.MinutesBeforeDue, reminder.Channel, reminder.WebhookURL, reminder.FireAt).
+		Scan(&reminder.ID, &reminder.Attempts, &reminder.LastError, &reminder.CreatedAt)
+}
+
+func (r *PostgresReminderRepository) FindForUser(workspaceID, userID int64, filter ReminderFilter) ([]Reminder, error) {
+	query := `SELECT ` + reminderColumns + `
+          FROM task_reminders r
+          WHERE r.workspace_id = $1 AND r.user_id = $2 AND ($3::int IS NULL OR r.task_id = $3)
+          ORDER BY r.fire_at NULLS LAST, r.id;`
+
+	rows, err := r.DB.Query(query, workspaceID, userID, filter.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reminders := []Reminder{}
+	for rows.Next() {
+		reminder := Reminder{}
+		if err := scanReminder(rows, &reminder); err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, reminder)
+	}
+	return reminders, rows.Err()
+}
+
+func (r *PostgresReminderRepository) Delete(workspaceID, userID, id int64) error {
+	query := `DELETE FROM task_reminders WHERE workspace_id = $1 AND user_id = $2 AND id = $3;`
+
+	res, err := r.DB.Exec(query, workspaceID, userID, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresReminderRepository) ProcessDue(limit int, deliver func(d Delivery) error) (int, error) {
+	claim := `SELECT ` + reminderColumns + `, t.title, t.due_at, t.completed
+          FROM task_reminders r
+          JOIN tasks t ON t.id = r.task_id
+          WHERE r.fired_at IS NULL AND r.failed_at IS NULL AND r.fire_at <= NOW() AT TIME ZONE 'UTC'
+          ORDER BY r.fire_at
+          LIMIT $1
+          FOR UPDATE OF r SKIP LOCKED;`
+
+	processed := 0
+	err := db.AcrossTenants(r.DB, func(tx *sql.Tx) error {
+		rows, err := tx.Query(claim, limit)
+		if err != nil {
+			return err
+		}
+
+		type claimed struct {
+			delivery  Delivery
+			completed bool
+		}
+		batch := []claimed{}
+		for rows.Next() {
+			c := claimed{}
+			if err := scanReminderWithTask(rows, &c.delivery, &c.completed); err != nil {
+				rows.Close()
+				return err
+			}
+			batch = append(batch, c)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, c := range batch {
+			id := c.delivery.Reminder.ID
+
+			var deliverErr error
+			if c.completed {
+				deliverErr = ErrTaskCompleted
+			} else {
+				deliverErr = deliver(c.delivery)
+			}
+
+			switch {
+			case deliverErr == nil:
+				_, err = tx.Exec(`UPDATE task_reminders
+                        SET fired_at = NOW() AT TIME ZONE 'UTC', attempts = attempts + 1, last_error = ''
+                        WHERE id = $1;`, id)
+			case errors.Is(deliverErr, ErrTaskCompleted):
+				// Nothing to remind about any more; retire the reminder.
+				_, err = tx.Exec(`UPDATE task_reminders SET fired_at = NOW() AT TIME ZONE 'UTC', last_error = $2
+                        WHERE id = $1;`, id, deliverErr.Error())
+			case c.delivery.Reminder.Attempts+1 >= maxAttempts:
+				_, err = tx.Exec(`UPDATE task_reminders
+                        SET failed_at = NOW() AT TIME ZONE 'UTC', attempts = attempts + 1, last_error = $2
+                        WHERE id = $1;`, id, deliverErr.Error())
+			default:
+				backoff := time.Duration(1<<c.delivery.Reminder.Attempts) * time.Minute
+				_, err = tx.Exec(`UPDATE task_reminders
+                        SET fire_at = (NOW() AT TIME ZONE 'UTC') + $2 * interval '1 second',
+                            attempts = attempts + 1, last_error = $3
+                        WHERE id = $1;`, id, int64(backoff/time.Second), deliverErr.Error())
+			}
+			if err != nil {
+				return err
+			}
+		}
+		processed = len(batch)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return processed, nil
+}
+
+func scanReminderWithTask(row rowScanner, d *Delivery, completed *bool) error {
+	r := &d.Reminder
+	return row.Scan(&r.ID, &r.WorkspaceID, &r.TaskID, &r.UserID, &r.RemindAt, &r.MinutesBeforeDue, &r.Channel,
+		&r.WebhookURL, &r.FireAt, &r.FiredAt, &r.FailedAt, &r.Attempts, &r.LastError, &r.CreatedAt,
+		&d.TaskTitle, &d.TaskDueAt, completed)
+}
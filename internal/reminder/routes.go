@@ -0,0 +1,13 @@
+package reminder
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Route("/reminders", func(r chi.Router) {
+		r.Get("/", h.GetAllReminders)
+		r.Post("/", h.CreateReminder)
+		r.Delete("/{id}", h.DeleteReminder)
+	})
+}
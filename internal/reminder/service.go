@@ -0,0 +1,117 @@
+package reminder
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/task"
+	"github.com/sudarshanmg/gotask/pkg/validation"
+)
+
+var validate = validator.New()
+
+type ReminderService interface {
+	Create(caller auth.Caller, req CreateReminderRequest) (*Reminder, error)
+	GetAll(caller auth.Caller, filter ReminderFilter) ([]Reminder, error)
+	Delete(caller auth.Caller, id int64) error
+	ProcessDue(limit int) (int, error)
+}
+
+type reminderService struct {
+	repo      ReminderRepository
+	tasks     task.TaskService
+	notifiers map[Channel]Notifier
+}
+
+func NewService(repo ReminderRepository, tasks task.TaskService, notifiers ...Notifier) ReminderService {
+	byChannel := make(map[Channel]Notifier, len(notifiers))
+	for _, n := range notifiers {
+		byChannel[n.Channel()] = n
+	}
+	return &reminderService{repo: repo, tasks: tasks, notifiers: byChannel}
+}
+
+// Create sets a reminder for the caller on a task they can see, either at
+// an absolute time or a number of minutes before the task's due date.
+func (s *reminderService) Create(caller auth.Caller, req CreateReminderRequest) (*Reminder, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+	if _, ok := s.notifiers[req.Channel]; !ok {
+		return nil, ErrNoNotifier
+	}
+	if req.Channel == ChannelWebhook && req.WebhookURL == "" {
+		return nil, ErrWebhookURL
+	}
+	if req.Channel != ChannelWebhook {
+		req.WebhookURL = ""
+	}
+
+	t, err := s.tasks.GetById(caller, req.TaskID)
+	if errors.Is(err, task.ErrNotFound) {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.Completed {
+		return nil, ErrTaskCompleted
+	}
+
+	// Times are stored in UTC because the columns have no time zone.
+	var fireAt time.Time
+	if req.RemindAt != nil {
+		remindAt := req.RemindAt.UTC()
+		req.RemindAt = &remindAt
+		fireAt = remindAt
+	} else {
+		if t.DueAt == nil {
+			return nil, ErrNoDueDate
+		}
+		fireAt = t.DueAt.UTC().Add(-time.Duration(*req.MinutesBeforeDue) * time.Minute)
+	}
+	if fireAt.Before(time.Now()) {
+		return nil, ErrInPast
+	}
+
+	reminder := &Reminder{
+		WorkspaceID:      caller.WorkspaceID,
+		TaskID:           req.TaskID,
+		UserID:           caller.UserID,
+		RemindAt:         req.RemindAt,
+		MinutesBeforeDue: req.MinutesBeforeDue,
+		Channel:          req.Channel,
+		WebhookURL:       req.WebhookURL,
+		FireAt:           &fireAt,
+	}
+	if err := s.repo.Create(reminder); err != nil {
+		return nil, err
+	}
+	return reminder, nil
+}
+
+func (s *reminderService) GetAll(caller auth.Caller, filter ReminderFilter) ([]Reminder, error) {
+	return s.repo.FindForUser(caller.WorkspaceID, caller.UserID, filter)
+}
+
+func (s *reminderService) Delete(caller auth.Caller, id int64) error {
+	if id <= 0 {
+		return ErrInvalidID
+	}
+	return s.repo.Delete(caller.WorkspaceID, caller.UserID, id)
+}
+
+// ProcessDue delivers up to limit due reminders through the notifier for
+// their channel.
+func (s *reminderService) ProcessDue(limit int) (int, error) {
+	return s.repo.ProcessDue(limit, func(d Delivery) error {
+		n, ok := s.notifiers[d.Reminder.Channel]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrNoNotifier, d.Reminder.Channel)
+		}
+		return n.Notify(d)
+	})
+}
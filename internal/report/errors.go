@@ -0,0 +1,10 @@
+package report
+
+import "errors"
+
+var (
+	ErrNotFound        = errors.New("project not found")
+	ErrInvalidRange    = errors.New("invalid date range")
+	ErrInvalidTimezone = errors.New("invalid time zone")
+	ErrInvalidUnit     = errors.New("unit must be tasks or estimate")
+)
@@ -0,0 +1,144 @@
+package report
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/pkg/response"
+)
+
+type Handler struct {
+	service ReportService
+}
+
+func NewHandler(service ReportService) *Handler {
+	return &Handler{service: service}
+}
+
+func writeServiceError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidRange), errors.Is(err, ErrInvalidTimezone), errors.Is(err, ErrInvalidUnit):
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrNotFound):
+		response.WriteError(w, http.StatusNotFound, err.Error())
+	default:
+		response.WriteError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+// parseFilter reads the project from the path and ?from=, ?to= (local
+// dates, YYYY-MM-DD), ?tz= and ?unit=tasks|estimate from the query.
+func parseFilter(r *http.Request) (Filter, error) {
+	q := r.URL.Query()
+	filter := Filter{Timezone: q.Get("tz"), Unit: Unit(q.Get("unit"))}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		return filter, errors.New("invalid ID format")
+	}
+	filter.ProjectID = id
+
+	for _, p := range []struct {
+		name string
+		dest *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return filter, errors.New("invalid " + p.name + " date")
+		}
+		*p.dest = d
+	}
+	return filter, nil
+}
+
+// writeCSV sends records as a CSV attachment.
+func writeCSV(w http.ResponseWriter, filename string, records [][]string) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	cw.WriteAll(records)
+}
+
+// optional formats a value that may be missing as an empty cell.
+func optional(v *int64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatInt(*v, 10)
+}
+
+// GetBurndown returns JSON, or CSV with ?format=csv.
+func (h *Handler) GetBurndown(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	burndown, err := h.service.Burndown(auth.GetCaller(r), filter)
+	if err != nil {
+		writeServiceError(w, err, "failed to compute burndown")
+		return
+	}
+
+	if r.URL.Query().Get("format") != "csv" {
+		response.WriteJSON(w, http.StatusOK, burndown)
+		return
+	}
+	records := [][]string{{"date", "scope", "remaining", "ideal"}}
+	for _, p := range burndown.Points {
+		records = append(records, []string{p.Date, optional(p.Scope), optional(p.Remaining),
+			strconv.FormatFloat(p.Ideal, 'f', -1, 64)})
+	}
+	writeCSV(w, fmt.Sprintf("burndown-%d-%s-%s.csv", burndown.ProjectID, burndown.From, burndown.To), records)
+}
+
+// GetCumulativeFlow returns JSON, or CSV with ?format=csv and a column per
+// status.
+func (h *Handler) GetCumulativeFlow(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	flow, err := h.service.CumulativeFlow(auth.GetCaller(r), filter)
+	if err != nil {
+		writeServiceError(w, err, "failed to compute cumulative flow")
+		return
+	}
+
+	if r.URL.Query().Get("format") != "csv" {
+		response.WriteJSON(w, http.StatusOK, flow)
+		return
+	}
+	header := []string{"date"}
+	for _, st := range flow.Statuses {
+		header = append(header, st.Name)
+	}
+	records := [][]string{header}
+	for _, p := range flow.Points {
+		record := []string{p.Date}
+		for i := range flow.Statuses {
+			if p.Counts == nil {
+				record = append(record, "")
+				continue
+			}
+			record = append(record, strconv.FormatInt(p.Counts[i], 10))
+		}
+		records = append(records, record)
+	}
+	writeCSV(w, fmt.Sprintf("cumulative-flow-%d-%s-%s.csv", flow.ProjectID, flow.From, flow.To), records)
+}
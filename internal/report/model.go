@@ -0,0 +1,80 @@
+package report
+
+import (
+	"time"
+
+	"github.com/sudarshanmg/gotask/internal/project"
+)
+
+// Unit is what the reports count: tasks, or their estimates in minutes.
+// Tasks without an estimate count as zero minutes.
+type Unit string
+
+const (
+	UnitTasks    Unit = "tasks"
+	UnitEstimate Unit = "estimate"
+)
+
+// Filter selects a project and a window of local dates in Timezone, both
+// inclusive.
+type Filter struct {
+	WorkspaceID int64
+	ProjectID   int64
+	From        time.Time
+	To          time.Time
+	Timezone    string
+	Unit        Unit
+}
+
+// Progress is the state of a project at one point in time: the work in it
+// and how much of that was in a done status.
+type Progress struct {
+	Scope int64
+	Done  int64
+}
+
+// BurndownPoint is the state at the end of a local day. Scope and Remaining
+// are nil for days that have not started yet; today's are as of now.
+type BurndownPoint struct {
+	Date      string `json:"date"`
+	Scope     *int64 `json:"scope"`
+	Remaining *int64 `json:"remaining"`
+	// Ideal falls in a straight line from the first day's scope to zero on
+	// the last day.
+	Ideal float64 `json:"ideal"`
+}
+
+type Burndown struct {
+	ProjectID int64           `json:"project_id"`
+	From      string          `json:"from"`
+	To        string          `json:"to"`
+	Timezone  string          `json:"timezone"`
+	Unit      Unit            `json:"unit"`
+	Points    []BurndownPoint `json:"points"`
+}
+
+type FlowStatus struct {
+	ID       int64                  `json:"id"`
+	Name     string                 `json:"name"`
+	Category project.StatusCategory `json:"category"`
+}
+
+// FlowPoint counts the work in each status at the end of a local day, in
+// the order of the report's statuses. Counts is nil for days that have not
+// started yet.
+type FlowPoint struct {
+	Date   string  `json:"date"`
+	Counts []int64 `json:"counts"`
+}
+
+// CumulativeFlow has a band per current status of the project. Time tasks
+// spent in statuses that have since been deleted is left out.
+type CumulativeFlow struct {
+	ProjectID int64        `json:"project_id"`
+	From      string       `json:"from"`
+	To        string       `json:"to"`
+	Timezone  string       `json:"timezone"`
+	Unit      Unit         `json:"unit"`
+	Statuses  []FlowStatus `json:"statuses"`
+	Points    []FlowPoint  `json:"points"`
+}
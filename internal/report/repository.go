@@ -0,0 +1,146 @@
+package report
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/sudarshanmg/gotask/pkg/db"
+)
+
+type ReportRepository interface {
+	// Burndown returns the project's progress at the end of each day of the
+	// filter, keyed by local date; from today on it is as of now.
+	Burndown(filter Filter) (map[string]Progress, error)
+	// Flow returns the work in each status at the end of each day of the
+	// filter, keyed by local date and status ID.
+	Flow(filter Filter) (map[string]map[int64]int64, error)
+	// Timezone returns the user's preferred time zone, or "" if they have
+	// not chosen one.
+	Timezone(userID int64) (string, error)
+}
+
+type PostgresReportRepository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) ReportRepository {
+	return &PostgresReportRepository{DB: db}
+}
+
+// series lists the local dates $4 to $5 in time zone $3 with the UTC time
+// each is reported at: its end, or now for today and later days. work weighs every task of
+// project $2 in workspace $1 by the unit in $6.
+const series = `days AS (
+            SELECT d::date AS day,
+                   LEAST(((d::date + 1)::timestamp AT TIME ZONE $3) AT TIME ZONE 'UTC',
+                         NOW() AT TIME ZONE 'UTC') AS at
+            FROM generate_series($4::date, $5::date, interval '1 day') d
+          ),
+          work AS (
+            SELECT t.id, t.created_at,
+                   CASE WHEN $6 = 'estimate' THEN COALESCE(t.estimate_minutes, 0) ELSE 1 END AS weight
+            FROM tasks t
+            WHERE t.workspace_id = $1 AND t.project_id = $2
+          )`
+
+// statusAt joins each task w to the last status it entered before d.at.
+const statusAt = `JOIN LATERAL (
+              SELECT h.status_id, h.category
+              FROM task_status_history h
+              WHERE h.task_id = w.id AND h.entered_at < d.at
+              ORDER BY h.entered_at DESC, h.id DESC
+              LIMIT 1
+          ) h ON true`
+
+func args(filter Filter) []any {
+	return []any{filter.WorkspaceID, filter.ProjectID, filter.Timezone,
+		filter.From.Format("2006-01-02"), filter.To.Format("2006-01-02"), string(filter.Unit)}
+}
+
+func (r *PostgresReportRepository) Burndown(filter Filter) (map[string]Progress, error) {
+	query := `
+          WITH ` + series + `
+          SELECT to_char(d.day, 'YYYY-MM-DD'),
+                 COALESCE(SUM(w.weight), 0),
+                 COALESCE(SUM(w.weight) FILTER (WHERE h.category = 'done'), 0)
+          FROM days d
+          LEFT JOIN work w ON w.created_at < d.at
+          LEFT ` + statusAt + `
+          GROUP BY d.day
+          ORDER BY d.day;`
+
+	progress := map[string]Progress{}
+	err := db.WithTenant(r.DB, filter.WorkspaceID, func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, args(filter)...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var date string
+			var p Progress
+			if err := rows.Scan(&date, &p.Scope, &p.Done); err != nil {
+				return err
+			}
+			progress[date] = p
+		}
+		return rows.Err()
+	})
+	if db.IsUnknownTimezone(err) {
+		return nil, ErrInvalidTimezone
+	}
+	if err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+func (r *PostgresReportRepository) Flow(filter Filter) (map[string]map[int64]int64, error) {
+	query := `
+          WITH ` + series + `
+          SELECT to_char(d.day, 'YYYY-MM-DD'), h.status_id, SUM(w.weight)
+          FROM days d
+          JOIN work w ON w.created_at < d.at
+          ` + statusAt + `
+          WHERE h.status_id IS NOT NULL
+          GROUP BY d.day, h.status_id;`
+
+	flow := map[string]map[int64]int64{}
+	err := db.WithTenant(r.DB, filter.WorkspaceID, func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, args(filter)...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var date string
+			var statusID, value int64
+			if err := rows.Scan(&date, &statusID, &value); err != nil {
+				return err
+			}
+			if flow[date] == nil {
+				flow[date] = map[int64]int64{}
+			}
+			flow[date][statusID] = value
+		}
+		return rows.Err()
+	})
+	if db.IsUnknownTimezone(err) {
+		return nil, ErrInvalidTimezone
+	}
+	if err != nil {
+		return nil, err
+	}
+	return flow, nil
+}
+
+func (r *PostgresReportRepository) Timezone(userID int64) (string, error) {
+	var tz string
+	err := r.DB.QueryRow(`SELECT timezone FROM digest_settings WHERE user_id = $1;`, userID).Scan(&tz)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return tz, err
+}
@@ -0,0 +1,10 @@
+package report
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Get("/projects/{id}/reports/burndown", h.GetBurndown)
+	r.Get("/projects/{id}/reports/cumulative-flow", h.GetCumulativeFlow)
+}
@@ -0,0 +1,177 @@
+package report
+
+import (
+	"math"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/project"
+)
+
+// maxDays bounds the window of one report.
+const maxDays = 366
+
+type ReportService interface {
+	Burndown(caller auth.Caller, filter Filter) (*Burndown, error)
+	CumulativeFlow(caller auth.Caller, filter Filter) (*CumulativeFlow, error)
+}
+
+type reportService struct {
+	repo     ReportRepository
+	projects project.ProjectRepository
+}
+
+func NewService(repo ReportRepository, projects project.ProjectRepository) ReportService {
+	return &reportService{repo: repo, projects: projects}
+}
+
+// prepare checks that the caller can read the project and fills in the
+// filter's defaults: the time zone from the caller's digest settings, then
+// UTC; the two weeks up to today; and counting tasks. It returns the local
+// dates of the window.
+func (s *reportService) prepare(caller auth.Caller, filter *Filter) ([]time.Time, *time.Location, error) {
+	if filter.ProjectID <= 0 {
+		return nil, nil, ErrNotFound
+	}
+	role, err := s.projects.GetMemberRole(caller.WorkspaceID, filter.ProjectID, caller.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !role.CanRead() {
+		return nil, nil, ErrNotFound
+	}
+
+	if filter.Unit == "" {
+		filter.Unit = UnitTasks
+	}
+	if filter.Unit != UnitTasks && filter.Unit != UnitEstimate {
+		return nil, nil, ErrInvalidUnit
+	}
+
+	if filter.Timezone == "" {
+		tz, err := s.repo.Timezone(caller.UserID)
+		if err != nil {
+			return nil, nil, err
+		}
+		filter.Timezone = tz
+	}
+	if filter.Timezone == "" {
+		filter.Timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(filter.Timezone)
+	if err != nil {
+		return nil, nil, ErrInvalidTimezone
+	}
+
+	if filter.To.IsZero() {
+		now := time.Now().In(loc)
+		filter.To = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	if filter.From.IsZero() {
+		filter.From = filter.To.AddDate(0, 0, -13)
+	}
+	days := filter.To.Sub(filter.From).Hours() / 24
+	if days < 0 || days >= maxDays {
+		return nil, nil, ErrInvalidRange
+	}
+
+	filter.WorkspaceID = caller.WorkspaceID
+	dates := []time.Time{}
+	for d := filter.From; !d.After(filter.To); d = d.AddDate(0, 0, 1) {
+		dates = append(dates, d)
+	}
+	return dates, loc, nil
+}
+
+// started reports whether the local date has begun in loc.
+func started(date time.Time, loc *time.Location) bool {
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+	return !start.After(time.Now())
+}
+
+func (s *reportService) Burndown(caller auth.Caller, filter Filter) (*Burndown, error) {
+	dates, loc, err := s.prepare(caller, &filter)
+	if err != nil {
+		return nil, err
+	}
+
+	progress, err := s.repo.Burndown(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// The ideal line starts from the scope on the first day. Days that
+	// have not started are reported as of now, so for a window in the
+	// future that is the current scope.
+	start := progress[filter.From.Format("2006-01-02")].Scope
+
+	points := make([]BurndownPoint, 0, len(dates))
+	for i, d := range dates {
+		date := d.Format("2006-01-02")
+		point := BurndownPoint{Date: date, Ideal: float64(start)}
+		if len(dates) > 1 {
+			point.Ideal = math.Round(float64(start)*float64(len(dates)-1-i)/float64(len(dates)-1)*100) / 100
+		}
+		if started(d, loc) {
+			p := progress[date]
+			remaining := p.Scope - p.Done
+			point.Scope = &p.Scope
+			point.Remaining = &remaining
+		}
+		points = append(points, point)
+	}
+
+	return &Burndown{
+		ProjectID: filter.ProjectID,
+		From:      filter.From.Format("2006-01-02"),
+		To:        filter.To.Format("2006-01-02"),
+		Timezone:  filter.Timezone,
+		Unit:      filter.Unit,
+		Points:    points,
+	}, nil
+}
+
+func (s *reportService) CumulativeFlow(caller auth.Caller, filter Filter) (*CumulativeFlow, error) {
+	dates, loc, err := s.prepare(caller, &filter)
+	if err != nil {
+		return nil, err
+	}
+
+	projectStatuses, err := s.projects.ListStatuses(filter.WorkspaceID, filter.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]FlowStatus, 0, len(projectStatuses))
+	for _, st := range projectStatuses {
+		statuses = append(statuses, FlowStatus{ID: st.ID, Name: st.Name, Category: st.Category})
+	}
+
+	flow, err := s.repo.Flow(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]FlowPoint, 0, len(dates))
+	for _, d := range dates {
+		date := d.Format("2006-01-02")
+		point := FlowPoint{Date: date}
+		if started(d, loc) {
+			point.Counts = make([]int64, len(statuses))
+			for i, st := range statuses {
+				point.Counts[i] = flow[date][st.ID]
+			}
+		}
+		points = append(points, point)
+	}
+
+	return &CumulativeFlow{
+		ProjectID: filter.ProjectID,
+		From:      filter.From.Format("2006-01-02"),
+		To:        filter.To.Format("2006-01-02"),
+		Timezone:  filter.Timezone,
+		Unit:      filter.Unit,
+		Statuses:  statuses,
+		Points:    points,
+	}, nil
+}
@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Job is a unit of background work run periodically by every replica. Jobs
+// must coordinate through the database (row locks, unique keys) so that
+// running them concurrently on several servers is safe.
+type Job interface {
+	Name() string
+	// Run processes whatever work is due and reports how many items it
+	// handled.
+	Run() (int, error)
+}
+
+type entry struct {
+	job      Job
+	interval time.Duration
+}
+
+type Scheduler struct {
+	entries []entry
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func New() *Scheduler {
+	return &Scheduler{stop: make(chan struct{})}
+}
+
+// Add registers a job to run every interval. It must be called before Start.
+func (s *Scheduler) Add(job Job, interval time.Duration) {
+	s.entries = append(s.entries, entry{job: job, interval: interval})
+}
+
+// Start runs every job once immediately and then on its interval, each in
+// its own goroutine.
+func (s *Scheduler) Start() {
+	for _, e := range s.entries {
+		s.wg.Add(1)
+		go s.loop(e)
+	}
+}
+
+// Stop signals all jobs to finish and waits for runs in progress.
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(e entry) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := e.job.Run(); err != nil {
+			log.Printf("scheduler: %s failed: %v", e.job.Name(), err)
+		} else if n > 0 {
+			log.Printf("scheduler: %s processed %d items", e.job.Name(), n)
+		}
+
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
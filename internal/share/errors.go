@@ -0,0 +1,12 @@
+package share
+
+import "errors"
+
+var (
+	ErrNotFound         = errors.New("share link not found or expired")
+	ErrInvalidID        = errors.New("invalid share link ID")
+	ErrForbidden        = errors.New("you do not have permission to share this resource")
+	ErrPasswordRequired = errors.New("this share link is password protected")
+	ErrWrongPassword    = errors.New("incorrect password")
+	ErrTooManyAttempts  = errors.New("too many incorrect passwords, try again later")
+)
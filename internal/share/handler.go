@@ -0,0 +1,114 @@
+package share
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/pkg/response"
+)
+
+type Handler struct {
+	service ShareService
+}
+
+func NewHandler(service ShareService) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) CreateLink(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var req CreateLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	link, err := h.service.Create(auth.GetCaller(r), req)
+	if err != nil && strings.HasPrefix(err.Error(), "validation failed:") {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.Is(err, ErrNotFound) {
+		response.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if errors.Is(err, ErrForbidden) {
+		response.WriteError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to create share link")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, link)
+}
+
+func (h *Handler) GetAllLinks(w http.ResponseWriter, r *http.Request) {
+	links, err := h.service.GetAll(auth.GetCaller(r))
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch share links")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, links)
+}
+
+func (h *Handler) RevokeLink(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	err = h.service.Revoke(auth.GetCaller(r), id)
+	if errors.Is(err, ErrInvalidID) {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.Is(err, ErrNotFound) {
+		response.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if errors.Is(err, ErrForbidden) {
+		response.WriteError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to revoke share link")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{"message": "share link revoked successfully"})
+}
+
+// ViewShared serves a shared resource without authentication. The password
+// of a protected link is read from the X-Share-Password header.
+func (h *Handler) ViewShared(w http.ResponseWriter, r *http.Request) {
+	shared, err := h.service.View(chi.URLParam(r, "token"), r.Header.Get("X-Share-Password"))
+	if errors.Is(err, ErrNotFound) {
+		response.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if errors.Is(err, ErrPasswordRequired) || errors.Is(err, ErrWrongPassword) {
+		response.WriteError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if errors.Is(err, ErrTooManyAttempts) {
+		response.WriteError(w, http.StatusTooManyRequests, err.Error())
+		return
+	}
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to load shared resource")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	response.WriteJSON(w, http.StatusOK, shared)
+}
@@ -0,0 +1,68 @@
+package share
+
+import (
+	"time"
+)
+
+type Link struct {
+	ID           int64      `json:"id"`
+	WorkspaceID  int64      `json:"workspace_id"`
+	Token        string     `json:"token"`
+	ResourceType string     `json:"resource_type"`
+	ResourceID   int64      `json:"resource_id"`
+	CreatedBy    int64      `json:"created_by"`
+	PasswordHash string     `json:"-"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	Revoked      bool       `json:"revoked"`
+	ViewCount    int64      `json:"view_count"`
+	LastViewedAt *time.Time `json:"last_viewed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	// LockedUntil is set after too many wrong passwords.
+	LockedUntil *time.Time `json:"-"`
+}
+
+// Active reports whether the link can still be opened.
+func (l *Link) Active(now time.Time) bool {
+	return !l.Revoked && (l.ExpiresAt == nil || l.ExpiresAt.After(now))
+}
+
+type CreateLinkRequest struct {
+	ResourceType   string `json:"resource_type" validate:"required,oneof=task project"`
+	ResourceID     int64  `json:"resource_id" validate:"required,gt=0"`
+	ExpiresInHours int    `json:"expires_in_hours" validate:"omitempty,min=1,max=8760"`
+	Password       string `json:"password" validate:"omitempty,min=4,max=64"`
+}
+
+type LinkResponse struct {
+	ID                int64      `json:"id"`
+	ResourceType      string     `json:"resource_type"`
+	ResourceID        int64      `json:"resource_id"`
+	URL               string     `json:"url"`
+	PasswordProtected bool       `json:"password_protected"`
+	ExpiresAt         *time.Time `json:"expires_at"`
+	Revoked           bool       `json:"revoked"`
+	ViewCount         int64      `json:"view_count"`
+	LastViewedAt      *time.Time `json:"last_viewed_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// SharedTask is the read-only view of a task shown to anonymous visitors. It
+// leaves out user IDs and other workspace internals.
+type SharedTask struct {
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Completed   bool      `json:"completed"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type SharedProject struct {
+	Name  string       `json:"name"`
+	Tasks []SharedTask `json:"tasks"`
+}
+
+type SharedResponse struct {
+	ResourceType string         `json:"resource_type"`
+	Task         *SharedTask    `json:"task,omitempty"`
+	Project      *SharedProject `json:"project,omitempty"`
+}
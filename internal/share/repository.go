@@ -0,0 +1,141 @@
+package share
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type ShareRepository interface {
+	Create(link *Link) (int64, error)
+	FindByToken(token string) (*Link, error)
+	FindById(workspaceID, id int64) (*Link, error)
+	FindForUser(workspaceID, userID int64) ([]Link, error)
+	Revoke(workspaceID, id int64) error
+	RecordView(id int64) error
+	// RecordFailedAttempt counts a wrong password, locking the link for
+	// lockout after every maxAttempts of them in a row.
+	RecordFailedAttempt(id int64, maxAttempts int, lockout time.Duration) error
+}
+
+type PostgresShareRepository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) ShareRepository {
+	return &PostgresShareRepository{DB: db}
+}
+
+const linkColumns = `id, workspace_id, token, resource_type, resource_id, COALESCE(created_by, 0),
+                 COALESCE(password_hash, ''), expires_at, revoked, view_count, last_viewed_at, created_at,
+                 locked_until`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanLink(row rowScanner, l *Link) error {
+	return row.Scan(&l.ID, &l.WorkspaceID, &l.Token, &l.ResourceType, &l.ResourceID, &l.CreatedBy,
+		&l.PasswordHash, &l.ExpiresAt, &l.Revoked, &l.ViewCount, &l.LastViewedAt, &l.CreatedAt, &l.LockedUntil)
+}
+
+func (r *PostgresShareRepository) Create(link *Link) (int64, error) {
+	query := `INSERT INTO share_links (workspace_id, token, resource_type, resource_id, created_by, password_hash, expires_at, created_at)
+            VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8)
+            RETURNING id;`
+
+	link.CreatedAt = time.Now()
+	err := r.DB.QueryRow(query, link.WorkspaceID, link.Token, link.ResourceType, link.ResourceID,
+		link.CreatedBy, link.PasswordHash, link.ExpiresAt, link.CreatedAt).Scan(&link.ID)
+	if err != nil {
+		return 0, err
+	}
+	return link.ID, nil
+}
+
+// FindByToken is the one lookup not scoped to a workspace: anonymous
+// visitors only have the token, which identifies the workspace itself.
+func (r *PostgresShareRepository) FindByToken(token string) (*Link, error) {
+	query := `SELECT ` + linkColumns + ` FROM share_links WHERE token = $1;`
+
+	link := Link{}
+	err := scanLink(r.DB.QueryRow(query, token), &link)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *PostgresShareRepository) FindById(workspaceID, id int64) (*Link, error) {
+	query := `SELECT ` + linkColumns + ` FROM share_links WHERE workspace_id = $1 AND id = $2;`
+
+	link := Link{}
+	err := scanLink(r.DB.QueryRow(query, workspaceID, id), &link)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *PostgresShareRepository) FindForUser(workspaceID, userID int64) ([]Link, error) {
+	query := `SELECT ` + linkColumns + `
+            FROM share_links
+            WHERE workspace_id = $1 AND created_by = $2
+            ORDER BY id DESC;`
+
+	rows, err := r.DB.Query(query, workspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []Link{}
+	for rows.Next() {
+		link := Link{}
+		if err := scanLink(rows, &link); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+func (r *PostgresShareRepository) Revoke(workspaceID, id int64) error {
+	res, err := r.DB.Exec(`UPDATE share_links SET revoked = true WHERE workspace_id = $1 AND id = $2;`,
+		workspaceID, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RecordView also clears the count of wrong passwords.
+func (r *PostgresShareRepository) RecordView(id int64) error {
+	_, err := r.DB.Exec(`UPDATE share_links
+            SET view_count = view_count + 1, last_viewed_at = NOW(), failed_attempts = 0
+            WHERE id = $1;`, id)
+	return err
+}
+
+func (r *PostgresShareRepository) RecordFailedAttempt(id int64, maxAttempts int, lockout time.Duration) error {
+	_, err := r.DB.Exec(`UPDATE share_links
+            SET failed_attempts = failed_attempts + 1,
+                locked_until = CASE WHEN (failed_attempts + 1) % $2 = 0
+                                    THEN (NOW() AT TIME ZONE 'UTC') + $3 * interval '1 second'
+                                    ELSE locked_until END
+            WHERE id = $1;`, id, maxAttempts, int64(lockout/time.Second))
+	return err
+}
@@ -0,0 +1,19 @@
+package share
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Route("/shares", func(r chi.Router) {
+		r.Get("/", h.GetAllLinks)
+		r.Post("/", h.CreateLink)
+		r.Delete("/{id}", h.RevokeLink)
+	})
+}
+
+// RegisterPublicRoutes mounts the anonymous share viewer; it must stay
+// outside the AuthMiddleware group.
+func RegisterPublicRoutes(r chi.Router, h *Handler) {
+	r.Get("/s/{token}", h.ViewShared)
+}
@@ -0,0 +1,253 @@
+package share
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/authz"
+	"github.com/sudarshanmg/gotask/internal/project"
+	"github.com/sudarshanmg/gotask/internal/task"
+	"github.com/sudarshanmg/gotask/pkg/validation"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var validate = validator.New()
+
+const (
+	// maxAttempts wrong passwords in a row lock a link for lockout.
+	maxAttempts = 5
+	lockout     = 15 * time.Minute
+)
+
+type ShareService interface {
+	Create(caller auth.Caller, req CreateLinkRequest) (*LinkResponse, error)
+	GetAll(caller auth.Caller) ([]LinkResponse, error)
+	Revoke(caller auth.Caller, id int64) error
+	View(token, password string) (*SharedResponse, error)
+}
+
+type shareService struct {
+	repo     ShareRepository
+	tasks    task.TaskRepository
+	projects project.ProjectRepository
+	policy   authz.Policy
+}
+
+func NewService(repo ShareRepository, tasks task.TaskRepository, projects project.ProjectRepository, policy authz.Policy) ShareService {
+	return &shareService{repo: repo, tasks: tasks, projects: projects, policy: policy}
+}
+
+// generateShareToken returns 256 bits of randomness, URL-safe so it can be
+// used directly as a path segment.
+func generateShareToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+func mapLinkToResponse(l *Link) LinkResponse {
+	return LinkResponse{
+		ID:                l.ID,
+		ResourceType:      l.ResourceType,
+		ResourceID:        l.ResourceID,
+		URL:               "/s/" + l.Token,
+		PasswordProtected: l.PasswordHash != "",
+		ExpiresAt:         l.ExpiresAt,
+		Revoked:           l.Revoked,
+		ViewCount:         l.ViewCount,
+		LastViewedAt:      l.LastViewedAt,
+		CreatedAt:         l.CreatedAt,
+	}
+}
+
+func mapSharedTask(t *task.Task) SharedTask {
+	return SharedTask{
+		Title:       t.Title,
+		Description: t.Description,
+		Completed:   t.Completed,
+		CreatedAt:   t.CreatedAt,
+		UpdatedAt:   t.UpdatedAt,
+	}
+}
+
+// shareAction is what the caller must be allowed to do with a resource
+// before exposing it publicly: edit a task, or manage a project.
+func shareAction(resourceType string) authz.Action {
+	if resourceType == authz.ResourceProject {
+		return authz.ActionManage
+	}
+	return authz.ActionUpdate
+}
+
+func (s *shareService) Create(caller auth.Caller, req CreateLinkRequest) (*LinkResponse, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+
+	subject := authz.Subject{UserID: caller.UserID, WorkspaceID: caller.WorkspaceID}
+	resource := authz.Resource{Type: req.ResourceType, ID: req.ResourceID}
+	ok, err := s.policy.Can(subject, shareAction(req.ResourceType), resource)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrForbidden
+	}
+
+	token, err := generateShareToken()
+	if err != nil {
+		return nil, err
+	}
+
+	link := Link{
+		WorkspaceID:  caller.WorkspaceID,
+		Token:        token,
+		ResourceType: req.ResourceType,
+		ResourceID:   req.ResourceID,
+		CreatedBy:    caller.UserID,
+	}
+	if req.ExpiresInHours > 0 {
+		expires := time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		link.ExpiresAt = &expires
+	}
+	if req.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		link.PasswordHash = string(hash)
+	}
+
+	if _, err := s.repo.Create(&link); err != nil {
+		return nil, err
+	}
+
+	res := mapLinkToResponse(&link)
+	return &res, nil
+}
+
+func (s *shareService) GetAll(caller auth.Caller) ([]LinkResponse, error) {
+	links, err := s.repo.FindForUser(caller.WorkspaceID, caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]LinkResponse, 0, len(links))
+	for i := range links {
+		responses = append(responses, mapLinkToResponse(&links[i]))
+	}
+	return responses, nil
+}
+
+// Revoke is allowed for the link's creator and for anyone who could create
+// the same link today.
+func (s *shareService) Revoke(caller auth.Caller, id int64) error {
+	if id <= 0 {
+		return ErrInvalidID
+	}
+
+	link, err := s.repo.FindById(caller.WorkspaceID, id)
+	if err != nil {
+		return err
+	}
+	if link == nil {
+		return ErrNotFound
+	}
+
+	if link.CreatedBy != caller.UserID {
+		subject := authz.Subject{UserID: caller.UserID, WorkspaceID: caller.WorkspaceID}
+		resource := authz.Resource{Type: link.ResourceType, ID: link.ResourceID}
+		ok, err := s.policy.Can(subject, shareAction(link.ResourceType), resource)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrForbidden
+		}
+	}
+
+	return s.repo.Revoke(caller.WorkspaceID, id)
+}
+
+// View resolves a token for an anonymous visitor. Revoked, expired and
+// dangling links all look the same from the outside, as do links whose
+// creator could no longer create them.
+func (s *shareService) View(token, password string) (*SharedResponse, error) {
+	link, err := s.repo.FindByToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil || !link.Active(time.Now()) {
+		return nil, ErrNotFound
+	}
+
+	creator := authz.Subject{UserID: link.CreatedBy, WorkspaceID: link.WorkspaceID}
+	resource := authz.Resource{Type: link.ResourceType, ID: link.ResourceID}
+	ok, err := s.policy.Can(creator, shareAction(link.ResourceType), resource)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	if link.PasswordHash != "" {
+		if password == "" {
+			return nil, ErrPasswordRequired
+		}
+		if link.LockedUntil != nil && link.LockedUntil.After(time.Now()) {
+			return nil, ErrTooManyAttempts
+		}
+		if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) != nil {
+			if err := s.repo.RecordFailedAttempt(link.ID, maxAttempts, lockout); err != nil {
+				return nil, err
+			}
+			return nil, ErrWrongPassword
+		}
+	}
+
+	res := &SharedResponse{ResourceType: link.ResourceType}
+	switch link.ResourceType {
+	case authz.ResourceTask:
+		t, err := s.tasks.FindById(link.WorkspaceID, link.ResourceID)
+		if err != nil {
+			return nil, err
+		}
+		if t == nil {
+			return nil, ErrNotFound
+		}
+		shared := mapSharedTask(t)
+		res.Task = &shared
+
+	case authz.ResourceProject:
+		p, err := s.projects.FindById(link.WorkspaceID, link.ResourceID)
+		if err != nil {
+			return nil, err
+		}
+		if p == nil {
+			return nil, ErrNotFound
+		}
+		tasks, err := s.tasks.FindByProject(link.WorkspaceID, p.ID)
+		if err != nil {
+			return nil, err
+		}
+		shared := SharedProject{Name: p.Name, Tasks: make([]SharedTask, 0, len(tasks))}
+		for i := range tasks {
+			shared.Tasks = append(shared.Tasks, mapSharedTask(&tasks[i]))
+		}
+		res.Project = &shared
+
+	default:
+		return nil, ErrNotFound
+	}
+
+	if err := s.repo.RecordView(link.ID); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,12 @@
+package sprint
+
+import "errors"
+
+var (
+	ErrNotFound      = errors.New("sprint not found")
+	ErrInvalidID     = errors.New("invalid sprint ID")
+	ErrForbidden     = errors.New("you do not have permission to plan this project")
+	ErrInvalidDates  = errors.New("end_date must not be before start_date")
+	ErrClosed        = errors.New("sprint is closed")
+	ErrInvalidTarget = errors.New("sprint_id must be another open sprint of the project")
+)
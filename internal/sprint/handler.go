@@ -0,0 +1,178 @@
+package sprint
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/pkg/response"
+)
+
+type Handler struct {
+	service SprintService
+}
+
+func NewHandler(service SprintService) *Handler {
+	return &Handler{service: service}
+}
+
+func writeServiceError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidID), errors.Is(err, ErrInvalidDates), errors.Is(err, ErrInvalidTarget),
+		strings.HasPrefix(err.Error(), "validation failed:"):
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrNotFound):
+		response.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrForbidden):
+		response.WriteError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, ErrClosed):
+		response.WriteError(w, http.StatusConflict, err.Error())
+	default:
+		response.WriteError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+// pathID reads the {id} URL parameter: a project on /projects routes and a
+// sprint on /sprints routes.
+func pathID(r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	return id, err == nil
+}
+
+func (h *Handler) CreateSprint(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	projectID, ok := pathID(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	var req CreateSprintRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	sprint, err := h.service.Create(auth.GetCaller(r), projectID, req)
+	if err != nil {
+		writeServiceError(w, err, "failed to create sprint")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, sprint)
+}
+
+func (h *Handler) GetAllSprints(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := pathID(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	sprints, err := h.service.GetAll(auth.GetCaller(r), projectID)
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch sprints")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, sprints)
+}
+
+func (h *Handler) GetVelocity(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := pathID(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	velocity, err := h.service.Velocity(auth.GetCaller(r), projectID)
+	if err != nil {
+		writeServiceError(w, err, "failed to compute velocity")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, velocity)
+}
+
+func (h *Handler) GetSprint(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	sprint, err := h.service.GetByID(auth.GetCaller(r), id)
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch the sprint")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, sprint)
+}
+
+func (h *Handler) UpdateSprint(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id, ok := pathID(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	var req UpdateSprintRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	sprint, err := h.service.Update(auth.GetCaller(r), id, req)
+	if err != nil {
+		writeServiceError(w, err, "failed to update sprint")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, sprint)
+}
+
+func (h *Handler) DeleteSprint(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	if err := h.service.Delete(auth.GetCaller(r), id); err != nil {
+		writeServiceError(w, err, "failed to delete sprint")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{"message": "sprint deleted successfully"})
+}
+
+func (h *Handler) CloseSprint(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id, ok := pathID(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	// The body is optional.
+	var req CloseSprintRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	res, err := h.service.Close(auth.GetCaller(r), id, req)
+	if err != nil {
+		writeServiceError(w, err, "failed to close sprint")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, res)
+}
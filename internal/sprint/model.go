@@ -0,0 +1,97 @@
+package sprint
+
+import (
+	"time"
+
+	"github.com/sudarshanmg/gotask/internal/project"
+)
+
+// Sprint is a time-box of a project. StartDate and EndDate are calendar
+// dates (YYYY-MM-DD), both inclusive.
+type Sprint struct {
+	ID          int64      `json:"id"`
+	WorkspaceID int64      `json:"workspace_id"`
+	ProjectID   int64      `json:"project_id"`
+	Name        string     `json:"name"`
+	Goal        string     `json:"goal"`
+	StartDate   string     `json:"start_date"`
+	EndDate     string     `json:"end_date"`
+	ClosedAt    *time.Time `json:"closed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	// Progress is recorded when the sprint is closed; until then it is
+	// computed from the sprint's tasks when a single sprint is fetched.
+	Progress *Progress `json:"progress,omitempty"`
+}
+
+// Progress counts a sprint's work. Points only count tasks with story
+// points; OpenTasks are the unfinished tasks, carried over on close.
+type Progress struct {
+	CommittedPoints int `json:"committed_points"`
+	CompletedPoints int `json:"completed_points"`
+	CompletedTasks  int `json:"completed_tasks"`
+	OpenTasks       int `json:"open_tasks"`
+}
+
+// sprintTask is what progress needs to know about a task.
+type sprintTask struct {
+	Completed   bool
+	StoryPoints *string
+}
+
+// tally computes the progress of tasks whose story points are on scale.
+func tally(tasks []sprintTask, scale project.PointScale) Progress {
+	p := Progress{}
+	for _, t := range tasks {
+		points := 0
+		if t.StoryPoints != nil {
+			points, _ = scale.Points(*t.StoryPoints)
+		}
+		p.CommittedPoints += points
+		if t.Completed {
+			p.CompletedPoints += points
+			p.CompletedTasks++
+		} else {
+			p.OpenTasks++
+		}
+	}
+	return p
+}
+
+type CreateSprintRequest struct {
+	Name      string `json:"name" validate:"required,max=100"`
+	Goal      string `json:"goal" validate:"max=500"`
+	StartDate string `json:"start_date" validate:"required,datetime=2006-01-02"`
+	EndDate   string `json:"end_date" validate:"required,datetime=2006-01-02"`
+}
+
+type UpdateSprintRequest struct {
+	Name      *string `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	Goal      *string `json:"goal,omitempty" validate:"omitempty,max=500"`
+	StartDate *string `json:"start_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	EndDate   *string `json:"end_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+}
+
+// CloseSprintRequest says where unfinished tasks go: SprintID names an open
+// sprint of the project; otherwise MoveTo "next" (the default) picks the
+// next open sprint by start date, falling back to the backlog, and
+// "backlog" sends them there.
+type CloseSprintRequest struct {
+	MoveTo   string `json:"move_to,omitempty" validate:"omitempty,oneof=next backlog"`
+	SprintID *int64 `json:"sprint_id,omitempty" validate:"omitempty,gt=0"`
+}
+
+type CloseSprintResponse struct {
+	Sprint Sprint `json:"sprint"`
+	// MovedTo is the sprint unfinished tasks went to, or nil for the
+	// backlog.
+	MovedTo *int64 `json:"moved_to"`
+}
+
+// Velocity lists the closed sprints of a project, oldest first.
+type Velocity struct {
+	ProjectID int64    `json:"project_id"`
+	Sprints   []Sprint `json:"sprints"`
+	// AveragePoints is the mean of the completed points of the last three
+	// closed sprints, or nil before the first one closes.
+	AveragePoints *float64 `json:"average_points"`
+}
@@ -0,0 +1,317 @@
+package sprint
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/sudarshanmg/gotask/internal/project"
+	"github.com/sudarshanmg/gotask/pkg/db"
+)
+
+// SprintRepository methods are scoped to a workspace; every query filters on
+// it and runs under db.WithTenant so row-level security applies as well.
+type SprintRepository interface {
+	Create(sprint *Sprint) error
+	FindByID(workspaceID, id int64) (*Sprint, error)
+	FindByProject(workspaceID, projectID int64) ([]Sprint, error)
+	// FindClosed returns the project's closed sprints in the order they
+	// were closed.
+	FindClosed(workspaceID, projectID int64) ([]Sprint, error)
+	Update(sprint *Sprint) error
+	// Delete moves the sprint's tasks to the backlog and deletes it,
+	// returning the IDs of the tasks it moved.
+	Delete(workspaceID, id int64) ([]int64, error)
+	// Progress computes the sprint's progress from its current tasks.
+	Progress(workspaceID, id int64, scale project.PointScale) (*Progress, error)
+	// Close records the sprint's progress and moves its unfinished tasks to
+	// the target sprint, or to the backlog when target is nil, returning the
+	// IDs of the tasks it moved.
+	Close(sprint *Sprint, target *int64, scale project.PointScale) ([]int64, error)
+}
+
+type PostgresSprintRepository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) SprintRepository {
+	return &PostgresSprintRepository{DB: db}
+}
+
+const sprintColumns = `id, workspace_id, project_id, name, goal,
+                 to_char(start_date, 'YYYY-MM-DD'), to_char(end_date, 'YYYY-MM-DD'), closed_at, created_at,
+                 committed_points, completed_points, completed_tasks, open_tasks`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSprint(row rowScanner, s *Sprint) error {
+	var committed, completed, completedTasks, openTasks sql.NullInt64
+	err := row.Scan(&s.ID, &s.WorkspaceID, &s.ProjectID, &s.Name, &s.Goal, &s.StartDate, &s.EndDate,
+		&s.ClosedAt, &s.CreatedAt, &committed, &completed, &completedTasks, &openTasks)
+	if err != nil {
+		return err
+	}
+	if s.ClosedAt != nil {
+		s.Progress = &Progress{
+			CommittedPoints: int(committed.Int64),
+			CompletedPoints: int(completed.Int64),
+			CompletedTasks:  int(completedTasks.Int64),
+			OpenTasks:       int(openTasks.Int64),
+		}
+	}
+	return nil
+}
+
+func (r *PostgresSprintRepository) Create(sprint *Sprint) error {
+	query := `INSERT INTO sprints (workspace_id, project_id, name, goal, start_date, end_date)
+            VALUES ($1, $2, $3, $4, $5, $6)
+            RETURNING id, created_at;`
+
+	return db.WithTenant(r.DB, sprint.WorkspaceID, func(tx *sql.Tx) error {
+		return tx.QueryRow(query, sprint.WorkspaceID, sprint.ProjectID, sprint.Name, sprint.Goal,
+			sprint.StartDate, sprint.EndDate).Scan(&sprint.ID, &sprint.CreatedAt)
+	})
+}
+
+func (r *PostgresSprintRepository) FindByID(workspaceID, id int64) (*Sprint, error) {
+	query := `SELECT ` + sprintColumns + ` FROM sprints WHERE workspace_id = $1 AND id = $2;`
+
+	var found *Sprint
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		s := Sprint{}
+		err := scanSprint(tx.QueryRow(query, workspaceID, id), &s)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		found = &s
+		return nil
+	})
+	return found, err
+}
+
+func (r *PostgresSprintRepository) list(workspaceID int64, query string, args ...any) ([]Sprint, error) {
+	sprints := []Sprint{}
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			s := Sprint{}
+			if err := scanSprint(rows, &s); err != nil {
+				return err
+			}
+			sprints = append(sprints, s)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sprints, nil
+}
+
+func (r *PostgresSprintRepository) FindByProject(workspaceID, projectID int64) ([]Sprint, error) {
+	return r.list(workspaceID, `SELECT `+sprintColumns+` FROM sprints
+            WHERE workspace_id = $1 AND project_id = $2
+            ORDER BY start_date, id;`, workspaceID, projectID)
+}
+
+func (r *PostgresSprintRepository) FindClosed(workspaceID, projectID int64) ([]Sprint, error) {
+	return r.list(workspaceID, `SELECT `+sprintColumns+` FROM sprints
+            WHERE workspace_id = $1 AND project_id = $2 AND closed_at IS NOT NULL
+            ORDER BY closed_at, id;`, workspaceID, projectID)
+}
+
+func (r *PostgresSprintRepository) Update(sprint *Sprint) error {
+	query := `UPDATE sprints SET name = $3, goal = $4, start_date = $5, end_date = $6
+            WHERE workspace_id = $1 AND id = $2 AND closed_at IS NULL;`
+
+	return db.WithTenant(r.DB, sprint.WorkspaceID, func(tx *sql.Tx) error {
+		res, err := tx.Exec(query, sprint.WorkspaceID, sprint.ID, sprint.Name, sprint.Goal,
+			sprint.StartDate, sprint.EndDate)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrClosed
+		}
+		return nil
+	})
+}
+
+// moveTasks moves the sprint's tasks matched by filter (over tasks) to
+// target, or to the backlog, stamping each with a change number for sync
+// clients, and returns their IDs. It must run in a transaction holding
+// db.LockTaskChanges.
+func moveTasks(tx *sql.Tx, workspaceID, sprintID int64, filter string, target *int64) ([]int64, error) {
+	rows, err := tx.Query(`UPDATE tasks SET sprint_id = $3, change_seq = nextval('task_change_seq'),
+                updated_at = NOW() AT TIME ZONE 'UTC'
+            WHERE workspace_id = $1 AND sprint_id = $2 AND `+filter+`
+            RETURNING id;`, workspaceID, sprintID, target)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	_, err = tx.Exec(`UPDATE tasks SET field_seqs = field_seqs || jsonb_build_object('sprint_id', change_seq)
+            WHERE id = ANY($1);`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *PostgresSprintRepository) Delete(workspaceID, id int64) ([]int64, error) {
+	var moved []int64
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		if err := db.LockTaskChanges(tx, workspaceID); err != nil {
+			return err
+		}
+		var err error
+		if moved, err = moveTasks(tx, workspaceID, id, "true", nil); err != nil {
+			return err
+		}
+
+		res, err := tx.Exec(`DELETE FROM sprints WHERE workspace_id = $1 AND id = $2;`, workspaceID, id)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return moved, nil
+}
+
+// sprintTasks loads what progress needs to know about the sprint's tasks.
+func sprintTasks(tx *sql.Tx, workspaceID, id int64) ([]sprintTask, error) {
+	rows, err := tx.Query(`SELECT completed, story_points FROM tasks WHERE workspace_id = $1 AND sprint_id = $2;`,
+		workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []sprintTask{}
+	for rows.Next() {
+		t := sprintTask{}
+		if err := rows.Scan(&t.Completed, &t.StoryPoints); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+func (r *PostgresSprintRepository) Progress(workspaceID, id int64, scale project.PointScale) (*Progress, error) {
+	var progress Progress
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		tasks, err := sprintTasks(tx, workspaceID, id)
+		if err != nil {
+			return err
+		}
+		progress = tally(tasks, scale)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+func (r *PostgresSprintRepository) Close(sprint *Sprint, target *int64, scale project.PointScale) ([]int64, error) {
+	var moved []int64
+	err := db.WithTenant(r.DB, sprint.WorkspaceID, func(tx *sql.Tx) error {
+		if err := db.LockTaskChanges(tx, sprint.WorkspaceID); err != nil {
+			return err
+		}
+
+		var closed bool
+		err := tx.QueryRow(`SELECT closed_at IS NOT NULL FROM sprints WHERE workspace_id = $1 AND id = $2 FOR UPDATE;`,
+			sprint.WorkspaceID, sprint.ID).Scan(&closed)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if closed {
+			return ErrClosed
+		}
+
+		// The target is checked again under the lock, since it may have been
+		// closed since the service picked it.
+		if target != nil {
+			var open bool
+			err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM sprints
+                    WHERE workspace_id = $1 AND project_id = $2 AND id = $3 AND closed_at IS NULL);`,
+				sprint.WorkspaceID, sprint.ProjectID, *target).Scan(&open)
+			if err != nil {
+				return err
+			}
+			if !open {
+				return ErrInvalidTarget
+			}
+		}
+
+		tasks, err := sprintTasks(tx, sprint.WorkspaceID, sprint.ID)
+		if err != nil {
+			return err
+		}
+		progress := tally(tasks, scale)
+
+		if moved, err = moveTasks(tx, sprint.WorkspaceID, sprint.ID, "NOT completed", target); err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(`UPDATE sprints
+                SET closed_at = NOW() AT TIME ZONE 'UTC', committed_points = $3, completed_points = $4,
+                    completed_tasks = $5, open_tasks = $6
+                WHERE workspace_id = $1 AND id = $2
+                RETURNING closed_at;`,
+			sprint.WorkspaceID, sprint.ID, progress.CommittedPoints, progress.CompletedPoints,
+			progress.CompletedTasks, progress.OpenTasks).Scan(&sprint.ClosedAt)
+		if err != nil {
+			return err
+		}
+		sprint.Progress = &progress
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return moved, nil
+}
@@ -0,0 +1,19 @@
+package sprint
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Route("/projects/{id}/sprints", func(r chi.Router) {
+		r.Get("/", h.GetAllSprints)
+		r.Post("/", h.CreateSprint)
+	})
+	r.Get("/projects/{id}/velocity", h.GetVelocity)
+	r.Route("/sprints", func(r chi.Router) {
+		r.Get("/{id}", h.GetSprint)
+		r.Put("/{id}", h.UpdateSprint)
+		r.Delete("/{id}", h.DeleteSprint)
+		r.Post("/{id}/close", h.CloseSprint)
+	})
+}
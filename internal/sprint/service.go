@@ -0,0 +1,250 @@
+package sprint
+
+import (
+	"math"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/project"
+	"github.com/sudarshanmg/gotask/internal/task"
+	"github.com/sudarshanmg/gotask/pkg/validation"
+)
+
+var validate = validator.New()
+
+// velocityWindow is the number of recent sprints the average velocity is
+// taken over.
+const velocityWindow = 3
+
+type SprintService interface {
+	Create(caller auth.Caller, projectID int64, req CreateSprintRequest) (*Sprint, error)
+	GetAll(caller auth.Caller, projectID int64) ([]Sprint, error)
+	GetByID(caller auth.Caller, id int64) (*Sprint, error)
+	Update(caller auth.Caller, id int64, req UpdateSprintRequest) (*Sprint, error)
+	Delete(caller auth.Caller, id int64) error
+	Close(caller auth.Caller, id int64, req CloseSprintRequest) (*CloseSprintResponse, error)
+	Velocity(caller auth.Caller, projectID int64) (*Velocity, error)
+}
+
+type sprintService struct {
+	repo     SprintRepository
+	projects project.ProjectRepository
+	tasks    task.TaskService
+}
+
+func NewService(repo SprintRepository, projects project.ProjectRepository, tasks task.TaskService) SprintService {
+	return &sprintService{repo: repo, projects: projects, tasks: tasks}
+}
+
+// checkProject ensures the caller can see the project and, when write is
+// set, plan it. Planning is open to everyone who can edit the project's
+// tasks. Projects the caller cannot see are reported as not found.
+func (s *sprintService) checkProject(caller auth.Caller, projectID int64, write bool) (*project.Project, error) {
+	role, err := s.projects.GetMemberRole(caller.WorkspaceID, projectID, caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !role.CanRead() {
+		return nil, ErrNotFound
+	}
+	if write && !role.CanWrite() {
+		return nil, ErrForbidden
+	}
+
+	p, err := s.projects.FindById(caller.WorkspaceID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrNotFound
+	}
+	return p, nil
+}
+
+// load fetches a sprint together with its project, under the rules of
+// checkProject.
+func (s *sprintService) load(caller auth.Caller, id int64, write bool) (*Sprint, *project.Project, error) {
+	if id <= 0 {
+		return nil, nil, ErrInvalidID
+	}
+	sprint, err := s.repo.FindByID(caller.WorkspaceID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sprint == nil {
+		return nil, nil, ErrNotFound
+	}
+	p, err := s.checkProject(caller, sprint.ProjectID, write)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sprint, p, nil
+}
+
+func (s *sprintService) Create(caller auth.Caller, projectID int64, req CreateSprintRequest) (*Sprint, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+	// Dates in YYYY-MM-DD order the same as strings.
+	if req.EndDate < req.StartDate {
+		return nil, ErrInvalidDates
+	}
+	if _, err := s.checkProject(caller, projectID, true); err != nil {
+		return nil, err
+	}
+
+	sprint := Sprint{
+		WorkspaceID: caller.WorkspaceID,
+		ProjectID:   projectID,
+		Name:        req.Name,
+		Goal:        req.Goal,
+		StartDate:   req.StartDate,
+		EndDate:     req.EndDate,
+	}
+	if err := s.repo.Create(&sprint); err != nil {
+		return nil, err
+	}
+	sprint.Progress = &Progress{}
+	return &sprint, nil
+}
+
+func (s *sprintService) GetAll(caller auth.Caller, projectID int64) ([]Sprint, error) {
+	if _, err := s.checkProject(caller, projectID, false); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByProject(caller.WorkspaceID, projectID)
+}
+
+func (s *sprintService) GetByID(caller auth.Caller, id int64) (*Sprint, error) {
+	sprint, p, err := s.load(caller, id, false)
+	if err != nil {
+		return nil, err
+	}
+	if sprint.ClosedAt == nil {
+		sprint.Progress, err = s.repo.Progress(caller.WorkspaceID, id, p.PointScale)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sprint, nil
+}
+
+func (s *sprintService) Update(caller auth.Caller, id int64, req UpdateSprintRequest) (*Sprint, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+
+	sprint, _, err := s.load(caller, id, true)
+	if err != nil {
+		return nil, err
+	}
+	if sprint.ClosedAt != nil {
+		return nil, ErrClosed
+	}
+
+	if req.Name != nil {
+		sprint.Name = *req.Name
+	}
+	if req.Goal != nil {
+		sprint.Goal = *req.Goal
+	}
+	if req.StartDate != nil {
+		sprint.StartDate = *req.StartDate
+	}
+	if req.EndDate != nil {
+		sprint.EndDate = *req.EndDate
+	}
+	if sprint.EndDate < sprint.StartDate {
+		return nil, ErrInvalidDates
+	}
+
+	if err := s.repo.Update(sprint); err != nil {
+		return nil, err
+	}
+	return sprint, nil
+}
+
+func (s *sprintService) Delete(caller auth.Caller, id int64) error {
+	if _, _, err := s.load(caller, id, true); err != nil {
+		return err
+	}
+	moved, err := s.repo.Delete(caller.WorkspaceID, id)
+	if err != nil {
+		return err
+	}
+	s.tasks.PublishUpdated(caller, moved)
+	return nil
+}
+
+// next picks the open sprint after sprint by start date, or nil.
+func next(sprints []Sprint, sprint *Sprint) *int64 {
+	for _, other := range sprints {
+		if other.ID == sprint.ID || other.ClosedAt != nil {
+			continue
+		}
+		if other.StartDate > sprint.StartDate || (other.StartDate == sprint.StartDate && other.ID > sprint.ID) {
+			id := other.ID
+			return &id
+		}
+	}
+	return nil
+}
+
+func (s *sprintService) Close(caller auth.Caller, id int64, req CloseSprintRequest) (*CloseSprintResponse, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+
+	sprint, p, err := s.load(caller, id, true)
+	if err != nil {
+		return nil, err
+	}
+	if sprint.ClosedAt != nil {
+		return nil, ErrClosed
+	}
+
+	var target *int64
+	switch {
+	case req.SprintID != nil:
+		if *req.SprintID == sprint.ID {
+			return nil, ErrInvalidTarget
+		}
+		target = req.SprintID
+	case req.MoveTo != "backlog":
+		sprints, err := s.repo.FindByProject(caller.WorkspaceID, sprint.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		target = next(sprints, sprint)
+	}
+
+	moved, err := s.repo.Close(sprint, target, p.PointScale)
+	if err != nil {
+		return nil, err
+	}
+	s.tasks.PublishUpdated(caller, moved)
+	return &CloseSprintResponse{Sprint: *sprint, MovedTo: target}, nil
+}
+
+func (s *sprintService) Velocity(caller auth.Caller, projectID int64) (*Velocity, error) {
+	if _, err := s.checkProject(caller, projectID, false); err != nil {
+		return nil, err
+	}
+
+	sprints, err := s.repo.FindClosed(caller.WorkspaceID, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	velocity := &Velocity{ProjectID: projectID, Sprints: sprints}
+	if len(sprints) > 0 {
+		recent := sprints[max(0, len(sprints)-velocityWindow):]
+		total := 0
+		for _, sp := range recent {
+			total += sp.Progress.CompletedPoints
+		}
+		avg := math.Round(float64(total)/float64(len(recent))*100) / 100
+		velocity.AveragePoints = &avg
+	}
+	return velocity, nil
+}
@@ -0,0 +1,8 @@
+package stats
+
+import "errors"
+
+var (
+	ErrInvalidRange    = errors.New("invalid date range")
+	ErrInvalidTimezone = errors.New("invalid time zone")
+)
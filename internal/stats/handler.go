@@ -0,0 +1,75 @@
+package stats
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/pkg/response"
+)
+
+type Handler struct {
+	service StatsService
+}
+
+func NewHandler(service StatsService) *Handler {
+	return &Handler{service: service}
+}
+
+// GetStats accepts ?from= and ?to= (local dates, YYYY-MM-DD), ?tz=,
+// ?assignee=me|{userID} and ?project={id}.
+func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	caller := auth.GetCaller(r)
+	filter := Filter{Timezone: q.Get("tz")}
+
+	if v := q.Get("assignee"); v == "me" {
+		filter.AssigneeID = &caller.UserID
+	} else if v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			response.WriteError(w, http.StatusBadRequest, "invalid assignee")
+			return
+		}
+		filter.AssigneeID = &id
+	}
+
+	if v := q.Get("project"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			response.WriteError(w, http.StatusBadRequest, "invalid project")
+			return
+		}
+		filter.ProjectID = &id
+	}
+
+	for _, p := range []struct {
+		name string
+		dest *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			response.WriteError(w, http.StatusBadRequest, "invalid "+p.name+" date")
+			return
+		}
+		*p.dest = d
+	}
+
+	stats, err := h.service.Get(caller, filter)
+	if errors.Is(err, ErrInvalidRange) || errors.Is(err, ErrInvalidTimezone) {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to compute stats")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, stats)
+}
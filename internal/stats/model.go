@@ -0,0 +1,63 @@
+package stats
+
+import (
+	"time"
+)
+
+// Filter selects the tasks counted. From and To are local dates in
+// Timezone, both inclusive.
+type Filter struct {
+	WorkspaceID int64
+	ViewerID    int64
+	AssigneeID  *int64
+	ProjectID   *int64
+	From        time.Time
+	To          time.Time
+	Timezone    string
+}
+
+type DayCount struct {
+	Date  string `json:"date"`
+	Count int64  `json:"count"`
+}
+
+// Streak counts consecutive local days with at least one completed task.
+// The current streak survives until a whole day passes without one.
+type Streak struct {
+	Current int64 `json:"current"`
+	Longest int64 `json:"longest"`
+}
+
+// ProjectStats breaks the numbers down per project; personal tasks have no
+// project ID.
+type ProjectStats struct {
+	ProjectID *int64 `json:"project_id"`
+	Name      string `json:"name"`
+	Open      int64  `json:"open"`
+	Completed int64  `json:"completed"`
+	Overdue   int64  `json:"overdue"`
+	// AverageCycleHours is nil when nothing was completed in the range.
+	AverageCycleHours *float64 `json:"average_cycle_hours"`
+}
+
+// Stats covers tasks created and completed between From and To. Open and
+// overdue counts are as of now.
+type Stats struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Timezone string `json:"timezone"`
+
+	Created   int64 `json:"created"`
+	Completed int64 `json:"completed"`
+	// CompletedLate counts tasks completed after their due date.
+	CompletedLate int64 `json:"completed_late"`
+	Open          int64 `json:"open"`
+	Overdue       int64 `json:"overdue"`
+	// AverageCycleHours is the mean time from creation to completion of
+	// the tasks completed in the range.
+	AverageCycleHours *float64 `json:"average_cycle_hours"`
+
+	CompletedPerDay []DayCount     `json:"completed_per_day"`
+	Streak          Streak         `json:"streak"`
+	Projects        []ProjectStats `json:"projects"`
+}
@@ -0,0 +1,179 @@
+package stats
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+
+	"github.com/sudarshanmg/gotask/pkg/db"
+)
+
+type StatsRepository interface {
+	// Stats computes everything but the response's From, To and Timezone.
+	Stats(filter Filter) (*Stats, error)
+	// Timezone returns the user's preferred time zone, or "" if they have
+	// not chosen one.
+	Timezone(userID int64) (string, error)
+}
+
+type PostgresStatsRepository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) StatsRepository {
+	return &PostgresStatsRepository{DB: db}
+}
+
+// scope restricts tasks t to those the viewer in $2 can see in workspace
+// $1, narrowed by the optional assignee ($3) and project ($4).
+const scope = `t.workspace_id = $1
+            AND ((t.project_id IS NULL AND t.created_by = $2)
+              OR t.project_id IN (SELECT project_id FROM project_members WHERE user_id = $2))
+            AND ($3::int IS NULL OR EXISTS (
+                  SELECT 1 FROM task_assignees a WHERE a.task_id = t.id AND a.user_id = $3))
+            AND ($4::int IS NULL OR t.project_id = $4)`
+
+// bounds turns the local dates $6 and $7 in time zone $5 into the UTC range
+// [lo, hi) the timestamp columns are compared with, and gives the current
+// UTC time as now.
+const bounds = `bounds AS (
+            SELECT (($6::date)::timestamp AT TIME ZONE $5) AT TIME ZONE 'UTC' AS lo,
+                   (($7::date + 1)::timestamp AT TIME ZONE $5) AT TIME ZONE 'UTC' AS hi,
+                   NOW() AT TIME ZONE 'UTC' AS now
+          )`
+
+// localDate converts a UTC timestamp column to a date in time zone $5.
+func localDate(column string) string {
+	return `((` + column + ` AT TIME ZONE 'UTC') AT TIME ZONE $5)::date`
+}
+
+func hours(seconds sql.NullFloat64) *float64 {
+	if !seconds.Valid {
+		return nil
+	}
+	h := math.Round(seconds.Float64/36) / 100
+	return &h
+}
+
+func (r *PostgresStatsRepository) Stats(filter Filter) (*Stats, error) {
+	summary := `
+          WITH ` + bounds + `
+          SELECT
+            COUNT(*) FILTER (WHERE t.created_at >= b.lo AND t.created_at < b.hi),
+            COUNT(*) FILTER (WHERE t.completed_at >= b.lo AND t.completed_at < b.hi),
+            COUNT(*) FILTER (WHERE t.completed_at >= b.lo AND t.completed_at < b.hi AND t.completed_at > t.due_at),
+            COUNT(*) FILTER (WHERE NOT t.completed),
+            COUNT(*) FILTER (WHERE NOT t.completed AND t.due_at < b.now),
+            AVG(EXTRACT(EPOCH FROM t.completed_at - t.created_at))
+              FILTER (WHERE t.completed_at >= b.lo AND t.completed_at < b.hi)
+          FROM tasks t CROSS JOIN bounds b
+          WHERE ` + scope + `;`
+
+	// Every day of the range is listed, including those with nothing
+	// completed.
+	perDay := `
+          SELECT to_char(d, 'YYYY-MM-DD'), COUNT(t.id)
+          FROM generate_series($6::date, $7::date, interval '1 day') d
+          LEFT JOIN tasks t ON ` + scope + ` AND ` + localDate("t.completed_at") + ` = d::date
+          GROUP BY d
+          ORDER BY d;`
+
+	// Consecutive days form islands: day minus its rank is constant within
+	// a run.
+	streak := `
+          WITH days AS (
+            SELECT DISTINCT ` + localDate("t.completed_at") + ` AS day
+            FROM tasks t
+            WHERE ` + scope + ` AND t.completed_at IS NOT NULL
+          ), runs AS (
+            SELECT MAX(day) AS last_day, COUNT(*) AS length
+            FROM (SELECT day, day - (ROW_NUMBER() OVER (ORDER BY day))::int AS grp FROM days) islands
+            GROUP BY grp
+          )
+          SELECT
+            COALESCE(MAX(length) FILTER (WHERE last_day >= (NOW() AT TIME ZONE $5)::date - 1), 0),
+            COALESCE(MAX(length), 0)
+          FROM runs;`
+
+	perProject := `
+          WITH ` + bounds + `
+          SELECT t.project_id, COALESCE(p.name, ''),
+            COUNT(*) FILTER (WHERE NOT t.completed),
+            COUNT(*) FILTER (WHERE t.completed_at >= b.lo AND t.completed_at < b.hi),
+            COUNT(*) FILTER (WHERE NOT t.completed AND t.due_at < b.now),
+            AVG(EXTRACT(EPOCH FROM t.completed_at - t.created_at))
+              FILTER (WHERE t.completed_at >= b.lo AND t.completed_at < b.hi)
+          FROM tasks t
+          CROSS JOIN bounds b
+          LEFT JOIN projects p ON p.id = t.project_id
+          WHERE ` + scope + `
+          GROUP BY t.project_id, p.name
+          ORDER BY p.name NULLS FIRST, t.project_id;`
+
+	args := []any{filter.WorkspaceID, filter.ViewerID, filter.AssigneeID, filter.ProjectID, filter.Timezone,
+		filter.From.Format("2006-01-02"), filter.To.Format("2006-01-02")}
+
+	stats := &Stats{CompletedPerDay: []DayCount{}, Projects: []ProjectStats{}}
+	err := db.WithTenant(r.DB, filter.WorkspaceID, func(tx *sql.Tx) error {
+		var cycle sql.NullFloat64
+		err := tx.QueryRow(summary, args...).Scan(&stats.Created, &stats.Completed, &stats.CompletedLate,
+			&stats.Open, &stats.Overdue, &cycle)
+		if err != nil {
+			return err
+		}
+		stats.AverageCycleHours = hours(cycle)
+
+		// Streaks look at all history, not just the range.
+		if err := tx.QueryRow(streak, args[:5]...).Scan(&stats.Streak.Current, &stats.Streak.Longest); err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(perDay, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			d := DayCount{}
+			if err := rows.Scan(&d.Date, &d.Count); err != nil {
+				return err
+			}
+			stats.CompletedPerDay = append(stats.CompletedPerDay, d)
+		}
+		if rows.Err() != nil {
+			return rows.Err()
+		}
+		rows.Close()
+
+		rows, err = tx.Query(perProject, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			p := ProjectStats{}
+			if err := rows.Scan(&p.ProjectID, &p.Name, &p.Open, &p.Completed, &p.Overdue, &cycle); err != nil {
+				return err
+			}
+			p.AverageCycleHours = hours(cycle)
+			stats.Projects = append(stats.Projects, p)
+		}
+		return rows.Err()
+	})
+	if db.IsUnknownTimezone(err) {
+		return nil, ErrInvalidTimezone
+	}
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (r *PostgresStatsRepository) Timezone(userID int64) (string, error) {
+	var tz string
+	err := r.DB.QueryRow(`SELECT timezone FROM digest_settings WHERE user_id = $1;`, userID).Scan(&tz)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return tz, err
+}
@@ -0,0 +1,67 @@
+package stats
+
+import (
+	"time"
+	_ "time/tzdata"
+
+	"github.com/sudarshanmg/gotask/internal/auth"
+)
+
+// maxDays bounds the range of one request, and so the length of the daily
+// series.
+const maxDays = 366
+
+type StatsService interface {
+	Get(caller auth.Caller, filter Filter) (*Stats, error)
+}
+
+type statsService struct {
+	repo StatsRepository
+}
+
+func NewService(repo StatsRepository) StatsService {
+	return &statsService{repo: repo}
+}
+
+// Get computes statistics over the tasks the caller can see. Without a time
+// zone it uses the one from the caller's digest settings, then UTC; without
+// dates it covers the last 30 days.
+func (s *statsService) Get(caller auth.Caller, filter Filter) (*Stats, error) {
+	if filter.Timezone == "" {
+		tz, err := s.repo.Timezone(caller.UserID)
+		if err != nil {
+			return nil, err
+		}
+		filter.Timezone = tz
+	}
+	if filter.Timezone == "" {
+		filter.Timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(filter.Timezone)
+	if err != nil {
+		return nil, ErrInvalidTimezone
+	}
+
+	if filter.To.IsZero() {
+		filter.To = time.Now().In(loc)
+	}
+	if filter.From.IsZero() {
+		filter.From = filter.To.AddDate(0, 0, -29)
+	}
+	days := filter.To.Sub(filter.From).Hours() / 24
+	if days < 0 || days >= maxDays {
+		return nil, ErrInvalidRange
+	}
+
+	filter.WorkspaceID = caller.WorkspaceID
+	filter.ViewerID = caller.UserID
+	stats, err := s.repo.Stats(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.From = filter.From.Format("2006-01-02")
+	stats.To = filter.To.Format("2006-01-02")
+	stats.Timezone = filter.Timezone
+	return stats, nil
+}
@@ -0,0 +1,116 @@
+package stream
+
+import (
+	"sync"
+)
+
+// subscriptionBuffer is how many messages a slow client may fall behind
+// before it is disconnected. It can then resume with Last-Event-ID.
+const subscriptionBuffer = 64
+
+type Subscription struct {
+	C           chan Message
+	workspaceID int64
+}
+
+// Broker fans messages out to the subscribers on this server and keeps the
+// most recent ones for clients that reconnect.
+type Broker struct {
+	mu     sync.Mutex
+	buffer []Message
+	size   int
+	// oldest is the first sequence number this broker can replay from;
+	// latest is the last one it has seen.
+	oldest int64
+	latest int64
+	subs   map[*Subscription]struct{}
+}
+
+func NewBroker(size int) *Broker {
+	return &Broker{size: size, oldest: 1, subs: map[*Subscription]struct{}{}}
+}
+
+// Subscribe registers a subscriber for a workspace and returns the buffered
+// messages after lastSeq. When some of those have already left the buffer,
+// the replay starts with a reset message.
+func (b *Broker) Subscribe(workspaceID, lastSeq int64) (*Subscription, []Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &Subscription{C: make(chan Message, subscriptionBuffer), workspaceID: workspaceID}
+	b.subs[sub] = struct{}{}
+
+	if lastSeq <= 0 || lastSeq >= b.latest {
+		return sub, nil
+	}
+
+	replay := []Message{}
+	if lastSeq < b.oldest-1 {
+		replay = append(replay, Message{Seq: b.oldest - 1, Reset: true})
+	}
+	for _, m := range b.buffer {
+		if m.Seq > lastSeq && m.Event.WorkspaceID == workspaceID {
+			replay = append(replay, m)
+		}
+	}
+	return sub, replay
+}
+
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.C)
+	}
+}
+
+// Publish buffers the message and sends it to the workspace's subscribers.
+// Subscribers that are too far behind are dropped.
+func (b *Broker) Publish(m Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if m.Seq <= b.latest {
+		return
+	}
+	b.latest = m.Seq
+
+	b.buffer = append(b.buffer, m)
+	if len(b.buffer) > b.size {
+		b.buffer = b.buffer[len(b.buffer)-b.size:]
+		b.oldest = b.buffer[0].Seq
+	}
+
+	for sub := range b.subs {
+		if sub.workspaceID != m.Event.WorkspaceID {
+			continue
+		}
+		b.send(sub, m)
+	}
+}
+
+// Reset forgets the buffer after events may have been missed, starting
+// again after seq, and tells every subscriber.
+func (b *Broker) Reset(seq int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buffer = nil
+	b.latest = seq
+	b.oldest = seq + 1
+
+	for sub := range b.subs {
+		b.send(sub, Message{Seq: seq, Reset: true})
+	}
+}
+
+func (b *Broker) send(sub *Subscription, m Message) {
+	select {
+	case sub.C <- m:
+	default:
+		delete(b.subs, sub)
+		close(sub.C)
+	}
+}
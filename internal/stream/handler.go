@@ -0,0 +1,144 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/project"
+	"github.com/sudarshanmg/gotask/pkg/response"
+)
+
+const heartbeatInterval = 15 * time.Second
+
+type Handler struct {
+	broker   *Broker
+	projects project.ProjectRepository
+}
+
+func NewHandler(broker *Broker, projects project.ProjectRepository) *Handler {
+	return &Handler{broker: broker, projects: projects}
+}
+
+func writeMessage(w http.ResponseWriter, m Message) error {
+	if m.Reset {
+		_, err := fmt.Fprintf(w, "id: %d\nevent: reset\ndata: {}\n\n", m.Seq)
+		return err
+	}
+
+	data, err := json.Marshal(m.Event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", m.Seq, m.Event.Type, data)
+	return err
+}
+
+// Stream sends the caller's task events as Server-Sent Events until the
+// client disconnects or its access token expires. Clients resume with the
+// Last-Event-ID header (or ?last_event_id=); a "reset" event means some
+// events could not be replayed and the client should refetch its tasks.
+func (h *Handler) Stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		response.WriteError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	var lastSeq int64
+	if lastID != "" {
+		seq, err := strconv.ParseInt(lastID, 10, 64)
+		if err != nil {
+			response.WriteError(w, http.StatusBadRequest, "invalid Last-Event-ID")
+			return
+		}
+		lastSeq = seq
+	}
+
+	caller := auth.GetCaller(r)
+	sub, replay := h.broker.Subscribe(caller.WorkspaceID, lastSeq)
+	defer h.broker.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	visible := NewVisibility(caller, h.projects)
+	send := func(m Message) bool {
+		if !m.Reset && m.Seq <= lastSeq {
+			return true
+		}
+		if !m.Reset {
+			ok, err := visible.CanSee(m.Event)
+			if err != nil {
+				log.Printf("event stream: visibility check failed: %v", err)
+				return false
+			}
+			if !ok {
+				return true
+			}
+		}
+		if err := writeMessage(w, m); err != nil {
+			return false
+		}
+		lastSeq = m.Seq
+		flusher.Flush()
+		return true
+	}
+
+	fmt.Fprintf(w, "retry: 3000\n\n")
+	flusher.Flush()
+
+	for _, m := range replay {
+		if !send(m) {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	// The stream ends with the access token; the client reconnects with a
+	// fresh one and resumes from its last event.
+	var expired <-chan time.Time
+	if expiresAt := auth.GetTokenExpiry(r); !expiresAt.IsZero() {
+		timer := time.NewTimer(time.Until(expiresAt))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-expired:
+			fmt.Fprintf(w, "event: token_expired\ndata: {}\n\n")
+			flusher.Flush()
+			return
+		case m, ok := <-sub.C:
+			if !ok {
+				// Dropped for falling behind; the client reconnects and
+				// resumes from its last event.
+				return
+			}
+			if !send(m) {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprintf(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
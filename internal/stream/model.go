@@ -0,0 +1,17 @@
+package stream
+
+import (
+	"github.com/sudarshanmg/gotask/internal/task"
+)
+
+// Message is a task event with its position in the global event order.
+// Reset messages tell clients that events may have been missed and they
+// should refetch their tasks.
+type Message struct {
+	Seq   int64      `json:"seq"`
+	Event task.Event `json:"event"`
+	// Audience carries Event.Audience between servers; it is not sent to
+	// clients.
+	Audience []int64 `json:"audience,omitempty"`
+	Reset    bool    `json:"-"`
+}
@@ -0,0 +1,124 @@
+package stream
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sudarshanmg/gotask/internal/task"
+)
+
+const (
+	channel = "task_events"
+	// publishLock serializes publishers so that sequence numbers are
+	// notified, and therefore received, in order.
+	publishLock = 7_341_200_035
+	// maxPayload stays under Postgres' 8000 byte NOTIFY limit.
+	maxPayload = 7900
+)
+
+// Publisher sends task events to every server through Postgres NOTIFY.
+type Publisher struct {
+	DB *sql.DB
+}
+
+func NewPublisher(db *sql.DB) *Publisher {
+	return &Publisher{DB: db}
+}
+
+func (p *Publisher) Publish(event task.Event) error {
+	tx, err := p.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1);`, publishLock); err != nil {
+		return err
+	}
+
+	m := Message{Event: event, Audience: event.Audience}
+	if err := tx.QueryRow(`SELECT nextval('task_event_seq');`).Scan(&m.Seq); err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxPayload {
+		// Long descriptions are left out; clients can fetch the task.
+		m.Event.Task.Description = ""
+		if payload, err = json.Marshal(m); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`SELECT pg_notify($1, $2);`, channel, string(payload)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// currentSeq returns the last sequence number handed out.
+func currentSeq(db *sql.DB) (int64, error) {
+	var seq int64
+	err := db.QueryRow(`SELECT CASE WHEN is_called THEN last_value ELSE 0 END FROM task_event_seq;`).Scan(&seq)
+	return seq, err
+}
+
+// Listen feeds task events notified by any server into the broker until
+// stop is closed. Whenever the connection is re-established the broker is
+// reset, because notifications sent in the meantime are lost.
+func Listen(db *sql.DB, url string, broker *Broker, stop <-chan struct{}) error {
+	listener := pq.NewListener(url, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("event stream listener: %v", err)
+		}
+	})
+	if err := listener.Listen(channel); err != nil {
+		listener.Close()
+		return err
+	}
+
+	seq, err := currentSeq(db)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	broker.Reset(seq)
+
+	go func() {
+		defer listener.Close()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case n := <-listener.Notify:
+				if n == nil {
+					seq, err := currentSeq(db)
+					if err != nil {
+						log.Printf("event stream listener: %v", err)
+						continue
+					}
+					broker.Reset(seq)
+					continue
+				}
+
+				var m Message
+				if err := json.Unmarshal([]byte(n.Extra), &m); err != nil {
+					log.Printf("event stream listener: bad payload: %v", err)
+					continue
+				}
+				m.Event.Audience = m.Audience
+				broker.Publish(m)
+			case <-time.After(90 * time.Second):
+				go listener.Ping()
+			}
+		}
+	}()
+	return nil
+}
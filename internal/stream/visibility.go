@@ -0,0 +1,57 @@
+package stream
+
+import (
+	"slices"
+	"time"
+
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/project"
+	"github.com/sudarshanmg/gotask/internal/task"
+)
+
+// membershipTTL is how long a subscriber trusts a cached project
+// membership, and so how long a removed member may keep seeing events.
+const membershipTTL = time.Minute
+
+type membership struct {
+	canRead   bool
+	checkedAt time.Time
+}
+
+// Visibility decides which task events one subscriber may see, with the same
+// rules as task reads: personal tasks are visible to their creator and
+// project tasks to project members. Events that carry an audience are only
+// visible to it. It is not safe for concurrent use.
+type Visibility struct {
+	caller   auth.Caller
+	projects project.ProjectRepository
+	cache    map[int64]membership
+}
+
+func NewVisibility(caller auth.Caller, projects project.ProjectRepository) *Visibility {
+	return &Visibility{caller: caller, projects: projects, cache: map[int64]membership{}}
+}
+
+func (v *Visibility) CanSee(event task.Event) (bool, error) {
+	if event.WorkspaceID != v.caller.WorkspaceID {
+		return false, nil
+	}
+	if event.Audience != nil {
+		return slices.Contains(event.Audience, v.caller.UserID), nil
+	}
+	if event.Task.ProjectID == nil {
+		return event.Task.CreatedBy == v.caller.UserID, nil
+	}
+
+	projectID := *event.Task.ProjectID
+	if m, ok := v.cache[projectID]; ok && time.Since(m.checkedAt) < membershipTTL {
+		return m.canRead, nil
+	}
+
+	role, err := v.projects.GetMemberRole(v.caller.WorkspaceID, projectID, v.caller.UserID)
+	if err != nil {
+		return false, err
+	}
+	v.cache[projectID] = membership{canRead: role.CanRead(), checkedAt: time.Now()}
+	return role.CanRead(), nil
+}
@@ -0,0 +1,52 @@
+package task
+
+import (
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/pkg/markdown"
+	"github.com/sudarshanmg/gotask/pkg/validation"
+)
+
+// Descriptions are CommonMark with GFM task list items. Items are numbered
+// from 0 in the order they appear, which is how clients address them.
+
+// RenderHTML sets DescriptionHTML from the description.
+func (t *TaskResponse) RenderHTML() {
+	html := markdown.RenderSafe(t.Description)
+	t.DescriptionHTML = &html
+}
+
+func (s *taskService) Checklist(caller auth.Caller, id int64) ([]markdown.TaskItem, error) {
+	task, _, err := s.load(caller, id)
+	if err != nil {
+		return nil, err
+	}
+	return markdown.Tasks(task.Description), nil
+}
+
+// SetChecklistItem rewrites only the item's box, so that the rest of the
+// description stays as it was written. Setting an item to the state it is
+// in changes nothing.
+func (s *taskService) SetChecklistItem(caller auth.Caller, id int64, index int, req SetChecklistItemRequest) (*TaskResponse, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+
+	task, canWrite, err := s.load(caller, id)
+	if err != nil {
+		return nil, err
+	}
+	if !canWrite {
+		return nil, ErrForbidden
+	}
+
+	description, found := markdown.SetTask(task.Description, index, *req.Checked)
+	if !found {
+		return nil, ErrChecklistNotFound
+	}
+	if description != task.Description {
+		if err := s.Update(caller, id, UpdateTaskRequest{Description: &description}); err != nil {
+			return nil, err
+		}
+	}
+	return s.GetById(caller, id)
+}
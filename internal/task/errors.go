@@ -3,7 +3,25 @@ package task
 import "errors"
 
 var (
-	ErrNotFound     = errors.New("task not found")
-	ErrInvalidID    = errors.New("invalid task ID")
-	ErrTitleMissing = errors.New("title is required")
+	ErrNotFound           = errors.New("task not found")
+	ErrInvalidID          = errors.New("invalid task ID")
+	ErrTitleMissing       = errors.New("title is required")
+	ErrForbidden          = errors.New("you do not have permission to modify this task")
+	ErrInvalidAssignee    = errors.New("assignees must be members of the task's project")
+	ErrInvalidStatus      = errors.New("status must be one of the task's project statuses")
+	ErrStatusConflict     = errors.New("status does not match completed")
+	ErrInvalidSprint      = errors.New("sprint must be an open sprint of the task's project")
+	ErrInvalidPoints      = errors.New("story points are not on the project's point scale")
+	ErrInvalidMilestone   = errors.New("milestone must be a milestone of the task's project")
+	ErrInvalidBlocker     = errors.New("blockers must be other tasks of the same project")
+	ErrDependencyCycle    = errors.New("dependencies would form a cycle")
+	ErrInvalidSchedule    = errors.New("start_at must not be after due_at")
+	ErrCommentNotFound    = errors.New("comment not found")
+	ErrAttachmentNotFound = errors.New("attachment not found")
+	ErrChecklistNotFound  = errors.New("checklist item not found")
+	ErrAttachmentTooLarge = errors.New("attachments are limited to 10 MB")
+	ErrEmptyAttachment    = errors.New("attachment is empty")
+	ErrInvalidToken       = errors.New("invalid sync token")
+	ErrSyncTarget         = errors.New("task_id or task_client_id is required")
+	ErrChanged            = errors.New("task was changed since it was read")
 )
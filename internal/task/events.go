@@ -0,0 +1,74 @@
+package task
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/sudarshanmg/gotask/internal/auth"
+)
+
+type EventType string
+
+const (
+	EventTaskCreated   EventType = "task.created"
+	EventTaskUpdated   EventType = "task.updated"
+	EventTaskCompleted EventType = "task.completed"
+	EventTaskDeleted   EventType = "task.deleted"
+)
+
+// Event describes a change to a task. TaskService emits one after every
+// successful write, whichever entry point made it.
+type Event struct {
+	ID          string       `json:"id"`
+	Type        EventType    `json:"type"`
+	WorkspaceID int64        `json:"workspace_id"`
+	ActorID     int64        `json:"actor_id"`
+	Task        TaskResponse `json:"task"`
+	OccurredAt  time.Time    `json:"occurred_at"`
+	// Previous is the task before an update, and RuleChain the automation
+	// rules that caused the change. Both are only seen by publishers in
+	// this process.
+	Previous  *TaskResponse `json:"-"`
+	RuleChain []int64       `json:"-"`
+	// Audience, when set, lists the users who could see the task. It is
+	// captured for tasks deleted with their project, whose memberships are
+	// gone by the time the event is published.
+	Audience []int64 `json:"-"`
+}
+
+func newEvent(eventType EventType, caller auth.Caller, task *Task, previous *TaskResponse) Event {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	return Event{
+		ID:          hex.EncodeToString(id),
+		Type:        eventType,
+		WorkspaceID: caller.WorkspaceID,
+		ActorID:     caller.UserID,
+		Task:        mapTasktoResponse(task),
+		OccurredAt:  time.Now(),
+		Previous:    previous,
+		RuleChain:   caller.RuleChain,
+	}
+}
+
+// EventPublisher receives task events. Publishing happens after the change
+// is stored, so a failing publisher never fails the write.
+type EventPublisher interface {
+	Publish(event Event) error
+}
+
+// Publishers fans events out to several publishers.
+type Publishers []EventPublisher
+
+func (p Publishers) Publish(event Event) error {
+	var errs []error
+	for _, publisher := range p {
+		if err := publisher.Publish(event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
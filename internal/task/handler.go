@@ -3,24 +3,53 @@ package task
 import (
 	"encoding/json"
 	"errors"
+	"io"
 	"log"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/authz"
 	"github.com/sudarshanmg/gotask/pkg/response"
 )
 
 type Handler struct {
 	service TaskService
+	policy  authz.Policy
 }
 
-func NewHandler(service TaskService) *Handler {
-	return &Handler{service: service}
+func NewHandler(service TaskService, policy authz.Policy) *Handler {
+	return &Handler{service: service, policy: policy}
+}
+
+var taskResource = authz.Resource{Type: authz.ResourceTask}
+
+// wantsHTML reports whether the request asked for rendered descriptions
+// with ?render=html. It answers with 400 for any other render value.
+func wantsHTML(w http.ResponseWriter, r *http.Request) (html, ok bool) {
+	switch r.URL.Query().Get("render") {
+	case "":
+		return false, true
+	case "html":
+		return true, true
+	default:
+		response.WriteError(w, http.StatusBadRequest, "render must be html")
+		return false, false
+	}
 }
 
 func (s *Handler) CreateTask(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
+	if !authz.Authorize(w, r, s.policy, authz.ActionCreate, taskResource) {
+		return
+	}
+	html, ok := wantsHTML(w, r)
+	if !ok {
+		return
+	}
 
 	var req CreateTaskRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -28,18 +57,32 @@ func (s *Handler) CreateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task, err := s.service.Create(req)
+	task, err := s.service.Create(auth.GetCaller(r), req)
+	if errors.Is(err, ErrForbidden) {
+		response.WriteError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	if errors.Is(err, ErrInvalidAssignee) || errors.Is(err, ErrInvalidStatus) || errors.Is(err, ErrInvalidSprint) ||
+		errors.Is(err, ErrInvalidPoints) || errors.Is(err, ErrInvalidMilestone) || errors.Is(err, ErrInvalidBlocker) ||
+		errors.Is(err, ErrDependencyCycle) || errors.Is(err, ErrInvalidSchedule) {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
 		response.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	if html {
+		task.RenderHTML()
+	}
 
 	response.WriteJSON(w, http.StatusCreated, task)
 }
 
 func (s *Handler) GetAllTasks(w http.ResponseWriter, r *http.Request) {
-	pageStr := r.URL.Query().Get("page")
-	limitStr := r.URL.Query().Get("limit")
+	if !authz.Authorize(w, r, s.policy, authz.ActionRead, taskResource) {
+		return
+	}
 	completedStr := r.URL.Query().Get("completed")
 	sort := r.URL.Query().Get("sort")
 	order := r.URL.Query().Get("order")
@@ -59,6 +102,72 @@ func (s *Handler) GetAllTasks(w http.ResponseWriter, r *http.Request) {
 		Order:     order,
 	}
 
+	if assigneeStr := r.URL.Query().Get("assignee"); assigneeStr == "me" {
+		userID := auth.GetUserID(r)
+		filter.AssigneeID = &userID
+	} else if assigneeStr != "" {
+		assigneeID, err := strconv.ParseInt(assigneeStr, 10, 64)
+		if err != nil {
+			response.WriteError(w, http.StatusBadRequest, "invalid assignee")
+			return
+		}
+		filter.AssigneeID = &assigneeID
+	}
+
+	if label := r.URL.Query().Get("label"); label != "" {
+		label = strings.ToLower(strings.TrimSpace(label))
+		filter.Label = &label
+	}
+
+	if projectStr := r.URL.Query().Get("project"); projectStr != "" {
+		projectID, err := strconv.ParseInt(projectStr, 10, 64)
+		if err != nil {
+			response.WriteError(w, http.StatusBadRequest, "invalid project")
+			return
+		}
+		filter.ProjectID = &projectID
+	}
+
+	if sprintStr := r.URL.Query().Get("sprint"); sprintStr == "backlog" {
+		filter.Backlog = true
+	} else if sprintStr != "" {
+		sprintID, err := strconv.ParseInt(sprintStr, 10, 64)
+		if err != nil {
+			response.WriteError(w, http.StatusBadRequest, "invalid sprint")
+			return
+		}
+		filter.SprintID = &sprintID
+	}
+
+	if milestoneStr := r.URL.Query().Get("milestone"); milestoneStr != "" {
+		milestoneID, err := strconv.ParseInt(milestoneStr, 10, 64)
+		if err != nil {
+			response.WriteError(w, http.StatusBadRequest, "invalid milestone")
+			return
+		}
+		filter.MilestoneID = &milestoneID
+	}
+
+	s.listTasks(w, r, filter)
+}
+
+// GetAssignedTasks lists the caller's assignments across every project they
+// belong to. It accepts the same query parameters as GetAllTasks.
+func (s *Handler) GetAssignedTasks(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	q.Set("assignee", "me")
+	r.URL.RawQuery = q.Encode()
+	s.GetAllTasks(w, r)
+}
+
+func (s *Handler) listTasks(w http.ResponseWriter, r *http.Request, filter TaskFilter) {
+	html, ok := wantsHTML(w, r)
+	if !ok {
+		return
+	}
+	pageStr := r.URL.Query().Get("page")
+	limitStr := r.URL.Query().Get("limit")
+
 	page, _ := strconv.Atoi(pageStr)
 	if page <= 0 {
 		page = 1
@@ -68,11 +177,16 @@ func (s *Handler) GetAllTasks(w http.ResponseWriter, r *http.Request) {
 		limit = 10
 	}
 
-	tasks, total, totalPages, err := s.service.GetAll(page, limit, filter)
+	tasks, total, totalPages, err := s.service.GetAll(auth.GetCaller(r), page, limit, filter)
 	if err != nil {
 		response.WriteError(w, http.StatusInternalServerError, "failed to fetch tasks")
 		return
 	}
+	if html {
+		for i := range tasks {
+			tasks[i].RenderHTML()
+		}
+	}
 
 	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
 	w.Header().Set("X-Total-Pages", strconv.Itoa(totalPages))
@@ -83,14 +197,21 @@ func (s *Handler) GetAllTasks(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Handler) GetTaskByID(w http.ResponseWriter, r *http.Request) {
+	if !authz.Authorize(w, r, s.policy, authz.ActionRead, taskResource) {
+		return
+	}
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
 		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
 		return
 	}
+	html, ok := wantsHTML(w, r)
+	if !ok {
+		return
+	}
 
-	task, err := s.service.GetById(id)
+	task, err := s.service.GetById(auth.GetCaller(r), id)
 	if errors.Is(err, ErrInvalidID) {
 		response.WriteError(w, http.StatusBadRequest, err.Error())
 		return
@@ -103,12 +224,18 @@ func (s *Handler) GetTaskByID(w http.ResponseWriter, r *http.Request) {
 		response.WriteError(w, http.StatusInternalServerError, "failed to fetch the task")
 		return
 	}
+	if html {
+		task.RenderHTML()
+	}
 
 	response.WriteJSON(w, http.StatusOK, task)
 }
 
 func (s *Handler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
+	if !authz.Authorize(w, r, s.policy, authz.ActionUpdate, taskResource) {
+		return
+	}
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
@@ -123,8 +250,11 @@ func (s *Handler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = s.service.Update(id, req)
-	if errors.Is(err, ErrInvalidID) {
+	err = s.service.Update(auth.GetCaller(r), id, req)
+	if errors.Is(err, ErrInvalidID) || errors.Is(err, ErrInvalidAssignee) || errors.Is(err, ErrInvalidStatus) ||
+		errors.Is(err, ErrStatusConflict) || errors.Is(err, ErrInvalidSprint) || errors.Is(err, ErrInvalidPoints) ||
+		errors.Is(err, ErrInvalidMilestone) || errors.Is(err, ErrInvalidBlocker) || errors.Is(err, ErrDependencyCycle) ||
+		errors.Is(err, ErrInvalidSchedule) {
 		response.WriteError(w, http.StatusBadRequest, err.Error())
 		return
 	}
@@ -132,6 +262,10 @@ func (s *Handler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		response.WriteError(w, http.StatusNotFound, err.Error())
 		return
 	}
+	if errors.Is(err, ErrForbidden) {
+		response.WriteError(w, http.StatusForbidden, err.Error())
+		return
+	}
 	log.Printf("Update error: %+v", err)
 	if err != nil {
 		response.WriteError(w, http.StatusInternalServerError, "failed to update task")
@@ -142,6 +276,9 @@ func (s *Handler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Handler) DeleteTask(w http.ResponseWriter, r *http.Request) {
+	if !authz.Authorize(w, r, s.policy, authz.ActionDelete, taskResource) {
+		return
+	}
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
@@ -149,7 +286,7 @@ func (s *Handler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = s.service.Delete(id)
+	err = s.service.Delete(auth.GetCaller(r), id)
 	if errors.Is(err, ErrInvalidID) {
 		response.WriteError(w, http.StatusBadRequest, err.Error())
 		return
@@ -158,6 +295,10 @@ func (s *Handler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 		response.WriteError(w, http.StatusNotFound, err.Error())
 		return
 	}
+	if errors.Is(err, ErrForbidden) {
+		response.WriteError(w, http.StatusForbidden, err.Error())
+		return
+	}
 	if err != nil {
 		response.WriteError(w, http.StatusInternalServerError, "failed to delete task")
 		return
@@ -165,3 +306,353 @@ func (s *Handler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 
 	response.WriteJSON(w, http.StatusOK, map[string]string{"message": "task deleted successfully"})
 }
+
+func (s *Handler) ListComments(w http.ResponseWriter, r *http.Request) {
+	if !authz.Authorize(w, r, s.policy, authz.ActionRead, taskResource) {
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	comments, err := s.service.ListComments(auth.GetCaller(r), id)
+	if errors.Is(err, ErrInvalidID) {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.Is(err, ErrNotFound) {
+		response.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch comments")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, comments)
+}
+
+func (s *Handler) AddComment(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	if !authz.Authorize(w, r, s.policy, authz.ActionRead, taskResource) {
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	var req CreateCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	comment, err := s.service.AddComment(auth.GetCaller(r), id, req)
+	if errors.Is(err, ErrNotFound) {
+		response.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, comment)
+}
+
+func (s *Handler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	if !authz.Authorize(w, r, s.policy, authz.ActionRead, taskResource) {
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+	commentID, err := strconv.ParseInt(chi.URLParam(r, "commentID"), 10, 64)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid comment ID format")
+		return
+	}
+
+	err = s.service.DeleteComment(auth.GetCaller(r), id, commentID)
+	if errors.Is(err, ErrInvalidID) {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrCommentNotFound) {
+		response.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if errors.Is(err, ErrForbidden) {
+		response.WriteError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to delete comment")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{"message": "comment deleted successfully"})
+}
+
+// writeChecklistError answers with the status for a checklist error.
+func writeChecklistError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidID):
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+	case strings.HasPrefix(err.Error(), "validation failed:"):
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrChecklistNotFound):
+		response.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrForbidden):
+		response.WriteError(w, http.StatusForbidden, err.Error())
+	default:
+		response.WriteError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+// GetChecklist lists the "- [ ]" items of the task's description.
+func (s *Handler) GetChecklist(w http.ResponseWriter, r *http.Request) {
+	if !authz.Authorize(w, r, s.policy, authz.ActionRead, taskResource) {
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	items, err := s.service.Checklist(auth.GetCaller(r), id)
+	if err != nil {
+		writeChecklistError(w, err, "failed to fetch checklist")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, items)
+}
+
+// SetChecklistItem checks or unchecks the index-th item of the checklist
+// and returns the updated task.
+func (s *Handler) SetChecklistItem(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	if !authz.Authorize(w, r, s.policy, authz.ActionUpdate, taskResource) {
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+	index, err := strconv.Atoi(chi.URLParam(r, "index"))
+	if err != nil || index < 0 {
+		response.WriteError(w, http.StatusBadRequest, "invalid checklist item")
+		return
+	}
+	html, ok := wantsHTML(w, r)
+	if !ok {
+		return
+	}
+
+	var req SetChecklistItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	task, err := s.service.SetChecklistItem(auth.GetCaller(r), id, index, req)
+	if err != nil {
+		writeChecklistError(w, err, "failed to update checklist")
+		return
+	}
+	if html {
+		task.RenderHTML()
+	}
+
+	response.WriteJSON(w, http.StatusOK, task)
+}
+
+// writeAttachmentError answers with the status for an attachment error.
+func writeAttachmentError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidID), errors.Is(err, ErrEmptyAttachment):
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrAttachmentNotFound):
+		response.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrForbidden):
+		response.WriteError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, ErrAttachmentTooLarge):
+		response.WriteError(w, http.StatusRequestEntityTooLarge, err.Error())
+	default:
+		response.WriteError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+func (s *Handler) ListAttachments(w http.ResponseWriter, r *http.Request) {
+	if !authz.Authorize(w, r, s.policy, authz.ActionRead, taskResource) {
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	attachments, err := s.service.ListAttachments(auth.GetCaller(r), id)
+	if err != nil {
+		writeAttachmentError(w, err, "failed to fetch attachments")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, attachments)
+}
+
+// AddAttachment uploads the file in the "file" field of a multipart form.
+func (s *Handler) AddAttachment(w http.ResponseWriter, r *http.Request) {
+	if !authz.Authorize(w, r, s.policy, authz.ActionUpdate, taskResource) {
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	// The form may be a little larger than the file it carries.
+	r.Body = http.MaxBytesReader(w, r.Body, MaxAttachmentBytes+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeAttachmentError(w, ErrAttachmentTooLarge, "")
+			return
+		}
+		response.WriteError(w, http.StatusBadRequest, "file is required")
+		return
+	}
+	defer file.Close()
+	defer r.MultipartForm.RemoveAll()
+	data, err := io.ReadAll(io.LimitReader(file, MaxAttachmentBytes+1))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "failed to read file")
+		return
+	}
+
+	attachment, err := s.service.AddAttachment(auth.GetCaller(r), id, CreateAttachmentRequest{
+		Filename:    header.Filename,
+		ContentType: header.Header.Get("Content-Type"),
+		Data:        data,
+	})
+	if err != nil {
+		writeAttachmentError(w, err, "failed to add attachment")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, attachment)
+}
+
+// DownloadAttachment serves an attachment's contents. They are always
+// offered as a download, so that an uploaded page cannot run in the app's
+// origin.
+func (s *Handler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	if !authz.Authorize(w, r, s.policy, authz.ActionRead, taskResource) {
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+	attachmentID, err := strconv.ParseInt(chi.URLParam(r, "attachmentID"), 10, 64)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid attachment ID format")
+		return
+	}
+
+	attachment, err := s.service.GetAttachment(auth.GetCaller(r), id, attachmentID)
+	if err != nil {
+		writeAttachmentError(w, err, "failed to fetch attachment")
+		return
+	}
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.WriteHeader(http.StatusOK)
+	w.Write(attachment.Data)
+}
+
+func (s *Handler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	if !authz.Authorize(w, r, s.policy, authz.ActionRead, taskResource) {
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+	attachmentID, err := strconv.ParseInt(chi.URLParam(r, "attachmentID"), 10, 64)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid attachment ID format")
+		return
+	}
+
+	if err := s.service.DeleteAttachment(auth.GetCaller(r), id, attachmentID); err != nil {
+		writeAttachmentError(w, err, "failed to delete attachment")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Sync applies a client's offline change log and returns the server's
+// changes since the client's token. The caller needs the permission for
+// every kind of change in the log.
+func (s *Handler) Sync(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var req SyncRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	actions := map[authz.Action]bool{authz.ActionRead: true}
+	for _, c := range req.Changes {
+		switch c.Op {
+		case SyncCreate:
+			actions[authz.ActionCreate] = true
+		case SyncUpdate:
+			actions[authz.ActionUpdate] = true
+		case SyncDelete:
+			actions[authz.ActionDelete] = true
+		}
+	}
+	for _, action := range []authz.Action{authz.ActionRead, authz.ActionCreate, authz.ActionUpdate, authz.ActionDelete} {
+		if actions[action] && !authz.Authorize(w, r, s.policy, action, taskResource) {
+			return
+		}
+	}
+
+	res, err := s.service.Sync(auth.GetCaller(r), req)
+	if errors.Is(err, ErrInvalidToken) {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil && strings.HasPrefix(err.Error(), "validation failed:") {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("sync error: %v", err)
+		response.WriteError(w, http.StatusInternalServerError, "failed to sync")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, res)
+}
@@ -5,36 +5,274 @@ import (
 )
 
 type Task struct {
-	Id          int64     `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Completed   bool      `json:"completed"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	Id          int64      `json:"id"`
+	WorkspaceID int64      `json:"workspace_id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Completed   bool       `json:"completed"`
+	ProjectID   *int64     `json:"project_id"`
+	StatusID    *int64     `json:"status_id"`
+	SprintID    *int64     `json:"sprint_id"`
+	MilestoneID *int64     `json:"milestone_id"`
+	StoryPoints *string    `json:"story_points"`
+	Priority    *int       `json:"priority"`
+	CreatedBy   int64      `json:"created_by"`
+	AssigneeIDs []int64    `json:"assignee_ids"`
+	BlockedBy   []int64    `json:"blocked_by"`
+	Labels      []string   `json:"labels"`
+	StartAt     *time.Time `json:"start_at"`
+	DueAt       *time.Time `json:"due_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	// EstimateMinutes is the planned effort; TrackedSeconds the time logged
+	// against the task so far, including running timers.
+	EstimateMinutes *int  `json:"estimate_minutes"`
+	TrackedSeconds  int64 `json:"tracked_seconds"`
+	// ChangeSeq is the change number of the last write to the task, and
+	// FieldSeqs that of the last write to each field.
+	ChangeSeq int64            `json:"-"`
+	FieldSeqs map[string]int64 `json:"-"`
+	// ClientID is set on tasks created through sync.
+	ClientID *string `json:"-"`
+	// ExternalID is the task's ID in the source it was imported from.
+	ExternalID *string `json:"external_id"`
+	// Attachments are stored by Create in the same transaction as the task.
+	Attachments []Attachment `json:"-"`
 }
 
 type CreateTaskRequest struct {
 	Title       string `json:"title" validate:"required,max=100"`
 	Description string `json:"description" validate:"max=500"`
+	ProjectID   *int64 `json:"project_id,omitempty" validate:"omitempty,gt=0"`
+	// StatusID is one of the project's statuses; project tasks without one
+	// start in the first todo status.
+	StatusID *int64 `json:"status_id,omitempty" validate:"omitempty,gt=0"`
+	// SprintID is an open sprint of the task's project; without one, project
+	// tasks go to the backlog.
+	SprintID *int64 `json:"sprint_id,omitempty" validate:"omitempty,gt=0"`
+	// MilestoneID is a milestone of the task's project.
+	MilestoneID *int64 `json:"milestone_id,omitempty" validate:"omitempty,gt=0"`
+	// StoryPoints is a value of the project's point scale, e.g. "5" or "M".
+	StoryPoints *string `json:"story_points,omitempty" validate:"omitempty,max=5"`
+	// Priority runs from 1, the most urgent, to 4; unset means no priority.
+	Priority    *int    `json:"priority,omitempty" validate:"omitempty,min=1,max=4"`
+	AssigneeIDs []int64 `json:"assignee_ids,omitempty" validate:"omitempty,max=20,dive,gt=0"`
+	// BlockedBy lists tasks of the same project that must be done first.
+	BlockedBy []int64    `json:"blocked_by,omitempty" validate:"omitempty,max=20,dive,gt=0"`
+	Labels    []string   `json:"labels,omitempty" validate:"omitempty,max=20,dive,required,max=50"`
+	StartAt   *time.Time `json:"start_at,omitempty"`
+	DueAt     *time.Time `json:"due_at,omitempty"`
+	// EstimateMinutes is capped at 1000 hours.
+	EstimateMinutes *int `json:"estimate_minutes,omitempty" validate:"omitempty,gt=0,lte=60000"`
 }
 
 type UpdateTaskRequest struct {
 	Title       *string `json:"title,omitempty" validate:"omitempty,max=100"`
 	Description *string `json:"description,omitempty" validate:"omitempty,max=500"`
 	Completed   *bool   `json:"completed,omitempty"`
+	// StatusID moves a project task; tasks in a done status are completed.
+	// Setting only Completed moves the task to the first done or todo status.
+	StatusID *int64 `json:"status_id,omitempty" validate:"omitempty,gt=0"`
+	// SprintID moves a project task into an open sprint; ClearSprint moves
+	// it back to the backlog.
+	SprintID         *int64     `json:"sprint_id,omitempty" validate:"omitempty,gt=0"`
+	ClearSprint      bool       `json:"clear_sprint,omitempty"`
+	MilestoneID      *int64     `json:"milestone_id,omitempty" validate:"omitempty,gt=0"`
+	ClearMilestone   bool       `json:"clear_milestone,omitempty"`
+	StoryPoints      *string    `json:"story_points,omitempty" validate:"omitempty,max=5"`
+	ClearStoryPoints bool       `json:"clear_story_points,omitempty"`
+	Priority         *int       `json:"priority,omitempty" validate:"omitempty,min=1,max=4"`
+	ClearPriority    bool       `json:"clear_priority,omitempty"`
+	AssigneeIDs      *[]int64   `json:"assignee_ids,omitempty" validate:"omitempty,max=20,dive,gt=0"`
+	BlockedBy        *[]int64   `json:"blocked_by,omitempty" validate:"omitempty,max=20,dive,gt=0"`
+	Labels           *[]string  `json:"labels,omitempty" validate:"omitempty,max=20,dive,required,max=50"`
+	StartAt          *time.Time `json:"start_at,omitempty"`
+	ClearStartAt     bool       `json:"clear_start_at,omitempty"`
+	DueAt            *time.Time `json:"due_at,omitempty"`
+	// ClearDueAt removes the due date; a null due_at cannot be told apart
+	// from an omitted one.
+	ClearDueAt      bool `json:"clear_due_at,omitempty"`
+	EstimateMinutes *int `json:"estimate_minutes,omitempty" validate:"omitempty,gt=0,lte=60000"`
+	ClearEstimate   bool `json:"clear_estimate,omitempty"`
+	// IfChangeSeq only applies the update while the task is still at this
+	// change number, and fails with ErrChanged otherwise. It is not part
+	// of the API.
+	IfChangeSeq *int64 `json:"-"`
+}
+
+// ScheduleChange sets a task's start and due dates, as given.
+type ScheduleChange struct {
+	TaskID  int64
+	StartAt *time.Time
+	DueAt   *time.Time
 }
 
 type TaskResponse struct {
+	ID          int64      `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Completed   bool       `json:"completed"`
+	ProjectID   *int64     `json:"project_id"`
+	StatusID    *int64     `json:"status_id"`
+	SprintID    *int64     `json:"sprint_id"`
+	MilestoneID *int64     `json:"milestone_id"`
+	StoryPoints *string    `json:"story_points"`
+	Priority    *int       `json:"priority"`
+	CreatedBy   int64      `json:"created_by"`
+	AssigneeIDs []int64    `json:"assignee_ids"`
+	BlockedBy   []int64    `json:"blocked_by"`
+	Labels      []string   `json:"labels"`
+	StartAt     *time.Time `json:"start_at"`
+	DueAt       *time.Time `json:"due_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	// Estimate versus actual effort, both in minutes.
+	EstimateMinutes *int    `json:"estimate_minutes"`
+	TrackedMinutes  int64   `json:"tracked_minutes"`
+	ExternalID      *string `json:"external_id"`
+	// DescriptionHTML is the Markdown description rendered to sanitized
+	// HTML, only returned with ?render=html.
+	DescriptionHTML *string `json:"description_html,omitempty"`
+}
+
+// ImportTaskRequest is a task read from an import file. ExternalID is its ID
+// in the source; without one, every import creates the task again.
+type ImportTaskRequest struct {
+	ExternalID string `json:"external_id" validate:"max=200"`
+	Completed  bool   `json:"completed"`
+	Task       CreateTaskRequest
+	// Attachments are stored with a new task, so it is never created
+	// without them.
+	Attachments []CreateAttachmentRequest `json:"-"`
+}
+
+// TaskFilter narrows a listing. WorkspaceID and ViewerID are always set by
+// the service from the caller so that only tasks the caller may read are
+// returned.
+type TaskFilter struct {
+	WorkspaceID int64
+	ViewerID    int64
+	Completed   *bool
+	AssigneeID  *int64
+	ProjectID   *int64
+	Label       *string
+	SprintID    *int64
+	// Backlog keeps project tasks that are in no sprint.
+	Backlog     bool
+	MilestoneID *int64
+	// Time windows are half-open: After is inclusive, Before exclusive.
+	DueAfter        *time.Time
+	DueBefore       *time.Time
+	CompletedAfter  *time.Time
+	CompletedBefore *time.Time
+	// AssignedAfter keeps tasks assigned to AssigneeID (or the viewer) at or
+	// after the given time.
+	AssignedAfter *time.Time
+	SortBy        string
+	Order         string
+}
+
+type Comment struct {
 	ID          int64     `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Completed   bool      `json:"completed"`
+	WorkspaceID int64     `json:"workspace_id"`
+	TaskID      int64     `json:"task_id"`
+	AuthorID    int64     `json:"author_id"`
+	Body        string    `json:"body"`
 	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
 }
 
-type TaskFilter struct {
-	Completed *bool
-	SortBy    string
-	Order     string
+type CreateCommentRequest struct {
+	Body string `json:"body" validate:"required,max=2000"`
+}
+
+// SetChecklistItemRequest checks or unchecks a "- [ ]" item of the
+// description.
+type SetChecklistItemRequest struct {
+	Checked *bool `json:"checked" validate:"required"`
+}
+
+// Attachment is a file attached to a task. Data is only loaded when the
+// file is downloaded.
+type Attachment struct {
+	ID          int64     `json:"id"`
+	WorkspaceID int64     `json:"workspace_id"`
+	TaskID      int64     `json:"task_id"`
+	UploadedBy  int64     `json:"uploaded_by"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+	Data        []byte    `json:"-"`
+}
+
+type CreateAttachmentRequest struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Change is one entry of the task change log: the task's current state, or
+// a tombstone when it was deleted. Task is nil for tombstones and for tasks
+// deleted after the change was listed.
+type Change struct {
+	Seq     int64
+	TaskID  int64
+	Deleted bool
+	Task    *Task
+}
+
+type SyncOp string
+
+const (
+	SyncCreate SyncOp = "create"
+	SyncUpdate SyncOp = "update"
+	SyncDelete SyncOp = "delete"
+)
+
+// SyncChange is one entry of a client's offline change log. Updates and
+// deletes name their task by TaskID, or by TaskClientID for a task the
+// client created offline.
+type SyncChange struct {
+	ClientID     string             `json:"client_id" validate:"required,max=64"`
+	Op           SyncOp             `json:"op" validate:"required,oneof=create update delete"`
+	TaskID       int64              `json:"task_id,omitempty" validate:"omitempty,gt=0"`
+	TaskClientID string             `json:"task_client_id,omitempty" validate:"omitempty,max=64"`
+	Create       *CreateTaskRequest `json:"create,omitempty" validate:"required_if=Op create"`
+	Update       *UpdateTaskRequest `json:"update,omitempty" validate:"required_if=Op update"`
+}
+
+type SyncRequest struct {
+	// Token is the token from the previous sync; empty for a full sync.
+	Token   string       `json:"token"`
+	Changes []SyncChange `json:"changes" validate:"max=200"`
+}
+
+type SyncStatus string
+
+const (
+	SyncApplied   SyncStatus = "applied"
+	SyncDuplicate SyncStatus = "duplicate"
+	SyncDeleted   SyncStatus = "deleted"
+	SyncRejected  SyncStatus = "rejected"
+)
+
+type SyncResult struct {
+	ClientID string     `json:"client_id"`
+	Status   SyncStatus `json:"status"`
+	TaskID   int64      `json:"task_id,omitempty"`
+	// Overwritten lists fields someone else changed after the client's
+	// token. The client's value won, but it may want to tell the user.
+	Overwritten []string `json:"overwritten,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+type SyncResponse struct {
+	Token   string         `json:"token"`
+	Results []SyncResult   `json:"results"`
+	Tasks   []TaskResponse `json:"tasks"`
+	Deleted []int64        `json:"deleted"`
+	// HasMore means there are more changes; sync again with the new token.
+	HasMore bool `json:"has_more"`
 }
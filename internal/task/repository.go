@@ -2,17 +2,49 @@ package task
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"time"
+
+	"github.com/lib/pq"
+	"github.com/sudarshanmg/gotask/pkg/db"
 )
 
+// TaskRepository methods are scoped to a workspace; every query filters on
+// it and runs under db.WithTenant so row-level security applies as well.
 type TaskRepository interface {
 	Create(task *Task) (int64, error)
 	FindAll(offset, limit int, filter TaskFilter) ([]Task, error)
-	FindById(id int64) (*Task, error)
-	Update(task *Task) error
-	Delete(id int64) error
-	CountAll() (int64, error)
+	FindById(workspaceID, id int64) (*Task, error)
+	FindByProject(workspaceID, projectID int64) ([]Task, error)
+	FindByIDs(workspaceID int64, ids []int64) ([]Task, error)
+	// Update writes the task. With ifChangeSeq set, it only does so while
+	// the task is still at that change number, and fails with ErrChanged.
+	Update(task *Task, ifChangeSeq *int64) error
+	// UpdateSchedules saves the start and due dates of the tasks in one
+	// transaction.
+	UpdateSchedules(workspaceID int64, tasks []*Task) error
+	Delete(workspaceID, id int64) error
+	Count(filter TaskFilter) (int64, error)
+	CreateComment(comment *Comment) (int64, error)
+	FindComments(workspaceID, taskID int64) ([]Comment, error)
+	FindComment(workspaceID, id int64) (*Comment, error)
+	DeleteComment(workspaceID, id int64) error
+	CreateAttachment(a *Attachment) (int64, error)
+	FindAttachments(workspaceID, taskID int64) ([]Attachment, error)
+	// FindAttachment returns the attachment with its contents.
+	FindAttachment(workspaceID, id int64) (*Attachment, error)
+	DeleteAttachment(workspaceID, id int64) error
+	FindByClientID(workspaceID, userID int64, clientID string) (*Task, error)
+	FindByExternalID(workspaceID, userID int64, externalID string) (*Task, error)
+	IsDeleted(workspaceID, id int64) (bool, error)
+	Changes(workspaceID, viewerID, since int64, limit int) ([]Change, error)
+	// SprintOpen reports whether the sprint belongs to the project and has
+	// not been closed.
+	SprintOpen(workspaceID, projectID, sprintID int64) (bool, error)
+	// MilestoneInProject reports whether the milestone belongs to the
+	// project.
+	MilestoneInProject(workspaceID, projectID, milestoneID int64) (bool, error)
 }
 
 type PostgresTaskRepository struct {
@@ -23,19 +55,273 @@ func NewRepository(db *sql.DB) TaskRepository {
 	return &PostgresTaskRepository{DB: db}
 }
 
+// visibleTasks restricts a query on tasks t to the rows of workspace $1 that
+// the viewer in $2 can read: their own personal tasks and every task in a
+// project they belong to.
+const visibleTasks = `t.workspace_id = $1
+            AND ((t.project_id IS NULL AND t.created_by = $2)
+              OR t.project_id IN (SELECT project_id FROM project_members WHERE user_id = $2))`
+
+// filteredTasks applies the optional TaskFilter fields in $3-$14 on top of
+// visibleTasks.
+const filteredTasks = visibleTasks + `
+            AND ($3::bool IS NULL OR t.completed = $3)
+            AND ($4::bigint IS NULL OR EXISTS (
+                  SELECT 1 FROM task_assignees a WHERE a.task_id = t.id AND a.user_id = $4))
+            AND ($5::bigint IS NULL OR t.project_id = $5)
+            AND ($6::timestamp IS NULL OR t.due_at >= $6)
+            AND ($7::timestamp IS NULL OR t.due_at < $7)
+            AND ($8::timestamp IS NULL OR t.completed_at >= $8)
+            AND ($9::timestamp IS NULL OR t.completed_at < $9)
+            AND ($10::timestamp IS NULL OR EXISTS (
+                  SELECT 1 FROM task_assignees a
+                  WHERE a.task_id = t.id AND a.user_id = COALESCE($4, $2) AND a.assigned_at >= $10))
+            AND ($11::text IS NULL OR $11 = ANY(t.labels))
+            AND ($12::bigint IS NULL OR t.sprint_id = $12)
+            AND (NOT $13::bool OR (t.project_id IS NOT NULL AND t.sprint_id IS NULL))
+            AND ($14::bigint IS NULL OR t.milestone_id = $14)`
+
+// utc returns t in UTC. Times are written and compared in UTC because the
+// columns are stored without a time zone.
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// filterArgs returns the arguments for filteredTasks, with times in UTC.
+func filterArgs(filter TaskFilter) []any {
+	return []any{filter.WorkspaceID, filter.ViewerID, filter.Completed, filter.AssigneeID, filter.ProjectID,
+		utc(filter.DueAfter), utc(filter.DueBefore), utc(filter.CompletedAfter), utc(filter.CompletedBefore),
+		utc(filter.AssignedAfter), filter.Label, filter.SprintID, filter.Backlog,
+		filter.MilestoneID}
+}
+
+// taskColumns includes the time tracked on the task, counting running
+// timers up to now. Time entries are stored in UTC.
+const taskColumns = `t.id, t.workspace_id, t.title, t.description, t.completed, t.project_id, t.status_id,
+                 t.sprint_id, t.milestone_id, t.story_points, t.priority, t.start_at,
+                 COALESCE(t.created_by, 0), t.labels, t.due_at, t.completed_at, t.created_at, t.updated_at,
+                 t.estimate_minutes,
+                 (SELECT COALESCE(SUM(EXTRACT(EPOCH FROM
+                          COALESCE(e.ended_at, NOW() AT TIME ZONE 'UTC') - e.started_at)), 0)::bigint
+                  FROM time_entries e WHERE e.task_id = t.id),
+                 t.change_seq, t.field_seqs, t.external_id`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTask(row rowScanner, task *Task) error {
+	var fieldSeqs []byte
+	err := row.Scan(&task.Id, &task.WorkspaceID, &task.Title, &task.Description, &task.Completed,
+		&task.ProjectID, &task.StatusID, &task.SprintID, &task.MilestoneID, &task.StoryPoints, &task.Priority, &task.StartAt, &task.CreatedBy, pq.Array(&task.Labels), &task.DueAt, &task.CompletedAt,
+		&task.CreatedAt, &task.UpdatedAt, &task.EstimateMinutes, &task.TrackedSeconds,
+		&task.ChangeSeq, &fieldSeqs, &task.ExternalID)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(fieldSeqs, &task.FieldSeqs)
+}
+
+// recordStatus adds the task's current status to its history if it is not
+// already the latest entry. Times are in UTC.
+func recordStatus(tx *sql.Tx, task *Task, at time.Time) error {
+	if task.ProjectID == nil || task.StatusID == nil {
+		return nil
+	}
+	_, err := tx.Exec(`INSERT INTO task_status_history (workspace_id, task_id, project_id, status_id, category, entered_at)
+            SELECT $1, $2, s.project_id, s.id, s.category, $4
+            FROM project_statuses s
+            WHERE s.id = $3
+              AND s.id IS DISTINCT FROM (SELECT h.status_id FROM task_status_history h
+                                         WHERE h.task_id = $2 ORDER BY h.entered_at DESC, h.id DESC LIMIT 1);`,
+		task.WorkspaceID, task.Id, *task.StatusID, at.UTC())
+	return err
+}
+
+// replaceAssignees sets the task's assignees to userIDs and reports whether
+// that changed anything. Users who stay assigned keep their original
+// assigned_at.
+func replaceAssignees(tx *sql.Tx, taskID int64, userIDs []int64) (bool, error) {
+	res, err := tx.Exec(`DELETE FROM task_assignees WHERE task_id = $1 AND NOT (user_id = ANY($2::int[]));`,
+		taskID, pq.Array(userIDs))
+	if err != nil {
+		return false, err
+	}
+	removed, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if len(userIDs) == 0 {
+		return removed > 0, nil
+	}
+	res, err = tx.Exec(`INSERT INTO task_assignees (task_id, user_id)
+            SELECT $1, unnest($2::int[])
+            ON CONFLICT DO NOTHING;`, taskID, pq.Array(userIDs))
+	if err != nil {
+		return false, err
+	}
+	added, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return removed > 0 || added > 0, nil
+}
+
+// replaceDependencies sets the tasks blocking taskID and reports whether that
+// changed anything. It refuses blockers that already wait on the task,
+// directly or through others.
+func replaceDependencies(tx *sql.Tx, taskID int64, blockerIDs []int64) (bool, error) {
+	if len(blockerIDs) > 0 {
+		var cycle bool
+		err := tx.QueryRow(`WITH RECURSIVE upstream (id) AS (
+                SELECT unnest($2::int[])
+                UNION
+                SELECT d.blocked_by_id FROM task_dependencies d JOIN upstream u ON d.task_id = u.id
+            )
+            SELECT EXISTS (SELECT 1 FROM upstream WHERE id = $1);`, taskID, pq.Array(blockerIDs)).Scan(&cycle)
+		if err != nil {
+			return false, err
+		}
+		if cycle {
+			return false, ErrDependencyCycle
+		}
+	}
+
+	res, err := tx.Exec(`DELETE FROM task_dependencies WHERE task_id = $1 AND NOT (blocked_by_id = ANY($2::int[]));`,
+		taskID, pq.Array(blockerIDs))
+	if err != nil {
+		return false, err
+	}
+	removed, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if len(blockerIDs) == 0 {
+		return removed > 0, nil
+	}
+	res, err = tx.Exec(`INSERT INTO task_dependencies (task_id, blocked_by_id)
+            SELECT $1, unnest($2::int[])
+            ON CONFLICT DO NOTHING;`, taskID, pq.Array(blockerIDs))
+	if err != nil {
+		return false, err
+	}
+	added, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return removed > 0 || added > 0, nil
+}
+
+// loadRelations fills AssigneeIDs and BlockedBy for the given tasks with a
+// query each.
+func loadRelations(tx *sql.Tx, tasks []Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(tasks))
+	index := make(map[int64]int, len(tasks))
+	for i := range tasks {
+		tasks[i].AssigneeIDs = []int64{}
+		tasks[i].BlockedBy = []int64{}
+		ids = append(ids, tasks[i].Id)
+		index[tasks[i].Id] = i
+	}
+
+	rows, err := tx.Query(`SELECT task_id, blocked_by_id FROM task_dependencies
+            WHERE task_id = ANY($1) ORDER BY blocked_by_id;`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID, blockerID int64
+		if err := rows.Scan(&taskID, &blockerID); err != nil {
+			return err
+		}
+		t := &tasks[index[taskID]]
+		t.BlockedBy = append(t.BlockedBy, blockerID)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	return loadAssignees(tx, tasks, ids, index)
+}
+
+// loadAssignees fills AssigneeIDs for the tasks with the given IDs and
+// positions.
+func loadAssignees(tx *sql.Tx, tasks []Task, ids []int64, index map[int64]int) error {
+
+	rows, err := tx.Query(`SELECT task_id, user_id FROM task_assignees
+            WHERE task_id = ANY($1) ORDER BY user_id;`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID, userID int64
+		if err := rows.Scan(&taskID, &userID); err != nil {
+			return err
+		}
+		t := &tasks[index[taskID]]
+		t.AssigneeIDs = append(t.AssigneeIDs, userID)
+	}
+	return rows.Err()
+}
+
 func (r *PostgresTaskRepository) Create(task *Task) (int64, error) {
 	var id int64
 
-	query := `INSERT INTO tasks (title, description, completed, created_at, updated_at)
-            VALUES ($1, $2, $3, $4, $5)
-            RETURNING id;
+	query := `INSERT INTO tasks (workspace_id, title, description, completed, project_id, created_by, due_at, created_at, updated_at, client_id,
+                               labels, estimate_minutes, status_id, sprint_id, story_points, start_at, milestone_id, external_id,
+                               priority, completed_at)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19,
+                    CASE WHEN $4 THEN $8::timestamp END)
+            RETURNING id, change_seq, completed_at;
           `
 
-	task.Completed = false
-	task.CreatedAt = time.Now()
-	task.UpdatedAt = time.Now()
+	// Tasks are created open unless they start in a done status.
+	task.CreatedAt = time.Now().UTC()
+	task.UpdatedAt = task.CreatedAt
 
-	err := r.DB.QueryRow(query, task.Title, task.Description, task.Completed, task.CreatedAt, task.UpdatedAt).Scan(&id)
+	err := db.WithTenant(r.DB, task.WorkspaceID, func(tx *sql.Tx) error {
+		if err := db.LockTaskChanges(tx, task.WorkspaceID); err != nil {
+			return err
+		}
+		err := tx.QueryRow(query, task.WorkspaceID, task.Title, task.Description, task.Completed,
+			task.ProjectID, task.CreatedBy, utc(task.DueAt), task.CreatedAt, task.UpdatedAt, task.ClientID,
+			pq.Array(task.Labels), task.EstimateMinutes, task.StatusID, task.SprintID, task.StoryPoints, utc(task.StartAt),
+			task.MilestoneID, task.ExternalID, task.Priority).
+			Scan(&id, &task.ChangeSeq, &task.CompletedAt)
+		if err != nil {
+			return err
+		}
+		task.Id = id
+		if err := recordStatus(tx, task, task.CreatedAt); err != nil {
+			return err
+		}
+		if _, err := replaceAssignees(tx, id, task.AssigneeIDs); err != nil {
+			return err
+		}
+		if _, err := replaceDependencies(tx, id, task.BlockedBy); err != nil {
+			return err
+		}
+		for i := range task.Attachments {
+			task.Attachments[i].WorkspaceID, task.Attachments[i].TaskID = task.WorkspaceID, id
+			if err := insertAttachment(tx, &task.Attachments[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 
 	if err != nil {
 		return 0, err
@@ -54,110 +340,654 @@ func (r *PostgresTaskRepository) FindAll(offset, limit int, filter TaskFilter) (
 	}
 
 	validSortFields := map[string]bool{
-		"id": true, "title": true, "created_at": true, "updated_at": true,
+		"id": true, "title": true, "created_at": true, "updated_at": true, "due_at": true,
 	}
 	if !validSortFields[filter.SortBy] {
 		filter.SortBy = "id"
 	}
 	query := `
-          SELECT id, title, description, completed, created_at, updated_at
-          FROM tasks
-          WHERE ($1::bool IS NULL OR completed = $1)
-          ORDER BY ` + filter.SortBy + ` ` + filter.Order + `
-          LIMIT $2 OFFSET $3;`
+          SELECT ` + taskColumns + `
+          FROM tasks t
+          WHERE ` + filteredTasks + `
+          ORDER BY t.` + filter.SortBy + ` ` + filter.Order + `
+          LIMIT $15 OFFSET $16;`
 
-	rows, err := r.DB.Query(query, filter.Completed, limit, offset)
+	tasks := []Task{}
+
+	err := db.WithTenant(r.DB, filter.WorkspaceID, func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, append(filterArgs(filter), limit, offset)...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			task := Task{}
+			if err := scanTask(rows, &task); err != nil {
+				return err
+			}
+			tasks = append(tasks, task)
+		}
+		if rows.Err() != nil {
+			return rows.Err()
+		}
+		rows.Close()
+
+		return loadRelations(tx, tasks)
+	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	tasks := []Task{}
+	return tasks, nil
+}
 
-	for rows.Next() {
+func (r *PostgresTaskRepository) FindById(workspaceID, id int64) (*Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM tasks t WHERE t.workspace_id = $1 AND t.id = $2;`
+
+	var found *Task
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
 		task := Task{}
-		if err := rows.Scan(&task.Id, &task.Title, &task.Description, &task.Completed, &task.CreatedAt, &task.UpdatedAt); err != nil {
-			return nil, err
+		err := scanTask(tx.QueryRow(query, workspaceID, id), &task)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		tasks := []Task{task}
+		if err := loadRelations(tx, tasks); err != nil {
+			return err
 		}
-		tasks = append(tasks, task)
+		found = &tasks[0]
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
-	if rows.Err() != nil {
-		return nil, rows.Err()
+	return found, nil
+}
+
+// FindByProject returns every task in the project without applying viewer
+// visibility; callers are responsible for authorizing access to the project.
+func (r *PostgresTaskRepository) FindByProject(workspaceID, projectID int64) ([]Task, error) {
+	query := `SELECT ` + taskColumns + `
+          FROM tasks t
+          WHERE t.workspace_id = $1 AND t.project_id = $2
+          ORDER BY t.completed, t.id;`
+
+	tasks := []Task{}
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, workspaceID, projectID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			task := Task{}
+			if err := scanTask(rows, &task); err != nil {
+				return err
+			}
+			tasks = append(tasks, task)
+		}
+		if rows.Err() != nil {
+			return rows.Err()
+		}
+		rows.Close()
+
+		return loadRelations(tx, tasks)
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
 	return tasks, nil
 }
 
-func (r *PostgresTaskRepository) FindById(id int64) (*Task, error) {
-	query := `SELECT id, title, description, completed, created_at, updated_at FROM tasks WHERE id = $1;`
+// FindByIDs returns the tasks with the given IDs, in ID order, without
+// applying viewer visibility. IDs of tasks that no longer exist are skipped.
+func (r *PostgresTaskRepository) FindByIDs(workspaceID int64, ids []int64) ([]Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM tasks t WHERE t.workspace_id = $1 AND t.id = ANY($2) ORDER BY t.id;`
 
-	task := Task{}
-	err := r.DB.QueryRow(query, id).Scan(&task.Id, &task.Title, &task.Description, &task.Completed, &task.CreatedAt, &task.UpdatedAt)
+	tasks := []Task{}
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, workspaceID, pq.Array(ids))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			task := Task{}
+			if err := scanTask(rows, &task); err != nil {
+				return err
+			}
+			tasks = append(tasks, task)
+		}
+		if rows.Err() != nil {
+			return rows.Err()
+		}
+		rows.Close()
+
+		return loadRelations(tx, tasks)
+	})
 
-	if errors.Is(err, sql.ErrNoRows) {
-		return nil, nil
-	}
 	if err != nil {
 		return nil, err
 	}
 
-	return &task, nil
+	return tasks, nil
 }
 
-func (r *PostgresTaskRepository) Update(task *Task) error {
+func (r *PostgresTaskRepository) Update(task *Task, ifChangeSeq *int64) error {
+	// Fields whose value changes are stamped with the new change number;
+	// in SET expressions the column names still refer to the old values.
 	query := `UPDATE tasks
-            SET title = $1, description = $2, completed = $3, updated_at = $4
-            WHERE id = $5;
+            SET title = $1, description = $2, completed = $3, due_at = $4, updated_at = $5,
+                completed_at = CASE WHEN NOT $3 THEN NULL ELSE COALESCE(completed_at, $5) END,
+                labels = $8, estimate_minutes = $9, status_id = $10, sprint_id = $11, story_points = $12,
+                start_at = $13, milestone_id = $14, priority = $15,
+                change_seq = s.seq,
+                field_seqs = field_seqs || jsonb_strip_nulls(jsonb_build_object(
+                    'title', CASE WHEN title IS DISTINCT FROM $1 THEN s.seq END,
+                    'description', CASE WHEN description IS DISTINCT FROM $2 THEN s.seq END,
+                    'completed', CASE WHEN completed IS DISTINCT FROM $3 THEN s.seq END,
+                    'status_id', CASE WHEN status_id IS DISTINCT FROM $10 THEN s.seq END,
+                    'due_at', CASE WHEN due_at IS DISTINCT FROM $4 THEN s.seq END,
+                    'labels', CASE WHEN labels IS DISTINCT FROM $8 THEN s.seq END,
+                    'estimate_minutes', CASE WHEN estimate_minutes IS DISTINCT FROM $9 THEN s.seq END,
+                    'sprint_id', CASE WHEN sprint_id IS DISTINCT FROM $11 THEN s.seq END,
+                    'story_points', CASE WHEN story_points IS DISTINCT FROM $12 THEN s.seq END,
+                    'start_at', CASE WHEN start_at IS DISTINCT FROM $13 THEN s.seq END,
+                    'milestone_id', CASE WHEN milestone_id IS DISTINCT FROM $14 THEN s.seq END,
+                    'priority', CASE WHEN priority IS DISTINCT FROM $15 THEN s.seq END))
+            FROM (SELECT nextval('task_change_seq') AS seq) s
+            WHERE workspace_id = $6 AND id = $7 AND ($16::bigint IS NULL OR change_seq = $16)
+            RETURNING completed_at, change_seq;
           `
 
-	task.UpdatedAt = time.Now()
-	res, err := r.DB.Exec(query, task.Title, task.Description, task.Completed, task.UpdatedAt, task.Id)
+	task.UpdatedAt = time.Now().UTC()
 
-	if err != nil {
+	return db.WithTenant(r.DB, task.WorkspaceID, func(tx *sql.Tx) error {
+		if err := db.LockTaskChanges(tx, task.WorkspaceID); err != nil {
+			return err
+		}
+		err := tx.QueryRow(query, task.Title, task.Description, task.Completed, utc(task.DueAt), task.UpdatedAt,
+			task.WorkspaceID, task.Id, pq.Array(task.Labels), task.EstimateMinutes, task.StatusID,
+			task.SprintID, task.StoryPoints, utc(task.StartAt), task.MilestoneID, task.Priority, ifChangeSeq).
+			Scan(&task.CompletedAt, &task.ChangeSeq)
+		if errors.Is(err, sql.ErrNoRows) && ifChangeSeq != nil {
+			return ErrChanged
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("no rows updated")
+		}
+		if err != nil {
+			return err
+		}
+		if err := recordStatus(tx, task, task.UpdatedAt); err != nil {
+			return err
+		}
+
+		// Relative reminders follow the due date; without one they wait.
+		_, err = tx.Exec(`UPDATE task_reminders
+                SET fire_at = $1::timestamp - minutes_before_due * interval '1 minute'
+                WHERE task_id = $2 AND minutes_before_due IS NOT NULL
+                  AND fired_at IS NULL AND failed_at IS NULL;`, utc(task.DueAt), task.Id)
+		if err != nil {
+			return err
+		}
+
+		assigneesChanged, err := replaceAssignees(tx, task.Id, task.AssigneeIDs)
+		if err != nil {
+			return err
+		}
+		blockersChanged, err := replaceDependencies(tx, task.Id, task.BlockedBy)
+		if err != nil || (!assigneesChanged && !blockersChanged) {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE tasks SET field_seqs = field_seqs || jsonb_strip_nulls(jsonb_build_object(
+                    'assignee_ids', CASE WHEN $2 THEN change_seq END,
+                    'blocked_by', CASE WHEN $3 THEN change_seq END))
+                WHERE id = $1;`, task.Id, assigneesChanged, blockersChanged)
 		return err
+	})
+}
+
+func (r *PostgresTaskRepository) UpdateSchedules(workspaceID int64, tasks []*Task) error {
+	query := `UPDATE tasks
+            SET start_at = $3, due_at = $4, updated_at = $5,
+                change_seq = s.seq,
+                field_seqs = field_seqs || jsonb_strip_nulls(jsonb_build_object(
+                    'start_at', CASE WHEN start_at IS DISTINCT FROM $3 THEN s.seq END,
+                    'due_at', CASE WHEN due_at IS DISTINCT FROM $4 THEN s.seq END))
+            FROM (SELECT nextval('task_change_seq') AS seq) s
+            WHERE workspace_id = $1 AND id = $2
+            RETURNING change_seq;`
+
+	now := time.Now().UTC()
+
+	return db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		if err := db.LockTaskChanges(tx, workspaceID); err != nil {
+			return err
+		}
+		for _, task := range tasks {
+			task.UpdatedAt = now
+			err := tx.QueryRow(query, workspaceID, task.Id, utc(task.StartAt), utc(task.DueAt), task.UpdatedAt).
+				Scan(&task.ChangeSeq)
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.New("no rows updated")
+			}
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.Exec(`UPDATE task_reminders
+                    SET fire_at = $1::timestamp - minutes_before_due * interval '1 minute'
+                    WHERE task_id = $2 AND minutes_before_due IS NOT NULL
+                      AND fired_at IS NULL AND failed_at IS NULL;`, utc(task.DueAt), task.Id)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *PostgresTaskRepository) Delete(workspaceID, id int64) error {
+	query := `DELETE FROM tasks WHERE workspace_id = $1 AND id = $2;`
+
+	return db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		if err := db.LockTaskChanges(tx, workspaceID); err != nil {
+			return err
+		}
+		if err := db.TombstoneTasks(tx, `t.workspace_id = $1 AND t.id = $2`, workspaceID, id); err != nil {
+			return err
+		}
+
+		// Tasks it blocked lose a blocker.
+		_, err := tx.Exec(`UPDATE tasks SET change_seq = nextval('task_change_seq')
+                WHERE workspace_id = $1 AND id IN (SELECT task_id FROM task_dependencies WHERE blocked_by_id = $2);`,
+			workspaceID, id)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE tasks SET field_seqs = field_seqs || jsonb_build_object('blocked_by', change_seq)
+                WHERE workspace_id = $1 AND id IN (SELECT task_id FROM task_dependencies WHERE blocked_by_id = $2);`,
+			workspaceID, id)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.Exec(query, workspaceID, id)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return errors.New("no task deleted")
+		}
+		return nil
+	})
+}
+
+func (r *PostgresTaskRepository) Count(filter TaskFilter) (int64, error) {
+	query := `SELECT COUNT(*) FROM tasks t WHERE ` + filteredTasks + `;`
+
+	var count int64
+	err := db.WithTenant(r.DB, filter.WorkspaceID, func(tx *sql.Tx) error {
+		return tx.QueryRow(query, filterArgs(filter)...).Scan(&count)
+	})
+	if err != nil {
+		return 0, err
 	}
+	return count, nil
+}
 
-	rowsAffected, err := res.RowsAffected()
+func (r *PostgresTaskRepository) CreateComment(comment *Comment) (int64, error) {
+	query := `INSERT INTO task_comments (workspace_id, task_id, author_id, body, created_at)
+            VALUES ($1, $2, $3, $4, $5)
+            RETURNING id;`
 
+	comment.CreatedAt = time.Now().UTC()
+	err := db.WithTenant(r.DB, comment.WorkspaceID, func(tx *sql.Tx) error {
+		return tx.QueryRow(query, comment.WorkspaceID, comment.TaskID, comment.AuthorID, comment.Body,
+			comment.CreatedAt).Scan(&comment.ID)
+	})
 	if err != nil {
-		return err
+		return 0, err
 	}
+	return comment.ID, nil
+}
 
-	if rowsAffected == 0 {
-		return errors.New("no rows updated")
+func (r *PostgresTaskRepository) FindComments(workspaceID, taskID int64) ([]Comment, error) {
+	query := `SELECT id, workspace_id, task_id, COALESCE(author_id, 0), body, created_at
+            FROM task_comments
+            WHERE workspace_id = $1 AND task_id = $2
+            ORDER BY id;`
+
+	comments := []Comment{}
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, workspaceID, taskID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			c := Comment{}
+			if err := rows.Scan(&c.ID, &c.WorkspaceID, &c.TaskID, &c.AuthorID, &c.Body, &c.CreatedAt); err != nil {
+				return err
+			}
+			comments = append(comments, c)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
 	}
+	return comments, nil
+}
 
-	return nil
+func (r *PostgresTaskRepository) FindComment(workspaceID, id int64) (*Comment, error) {
+	query := `SELECT id, workspace_id, task_id, COALESCE(author_id, 0), body, created_at
+            FROM task_comments
+            WHERE workspace_id = $1 AND id = $2;`
+
+	var found *Comment
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		c := Comment{}
+		err := tx.QueryRow(query, workspaceID, id).Scan(&c.ID, &c.WorkspaceID, &c.TaskID, &c.AuthorID,
+			&c.Body, &c.CreatedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		found = &c
+		return nil
+	})
+	return found, err
+}
+
+func (r *PostgresTaskRepository) DeleteComment(workspaceID, id int64) error {
+	return db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		res, err := tx.Exec(`DELETE FROM task_comments WHERE workspace_id = $1 AND id = $2;`, workspaceID, id)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrCommentNotFound
+		}
+		return nil
+	})
 }
 
-func (r *PostgresTaskRepository) Delete(id int64) error {
-	query := `DELETE FROM tasks WHERE id = $1;`
+func insertAttachment(tx *sql.Tx, a *Attachment) error {
+	query := `INSERT INTO task_attachments (workspace_id, task_id, uploaded_by, filename, content_type, size, data, created_at)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+            RETURNING id;`
 
-	res, err := r.DB.Exec(query, id)
+	a.Size = int64(len(a.Data))
+	a.CreatedAt = time.Now().UTC()
+	return tx.QueryRow(query, a.WorkspaceID, a.TaskID, a.UploadedBy, a.Filename, a.ContentType, a.Size,
+		a.Data, a.CreatedAt).Scan(&a.ID)
+}
 
+func (r *PostgresTaskRepository) CreateAttachment(a *Attachment) (int64, error) {
+	err := db.WithTenant(r.DB, a.WorkspaceID, func(tx *sql.Tx) error {
+		return insertAttachment(tx, a)
+	})
 	if err != nil {
-		return err
+		return 0, err
 	}
+	return a.ID, nil
+}
 
-	rowsAffected, err := res.RowsAffected()
-
+func (r *PostgresTaskRepository) FindAttachments(workspaceID, taskID int64) ([]Attachment, error) {
+	query := `SELECT id, workspace_id, task_id, COALESCE(uploaded_by, 0), filename, content_type, size, created_at
+            FROM task_attachments
+            WHERE workspace_id = $1 AND task_id = $2
+            ORDER BY id;`
+
+	attachments := []Attachment{}
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, workspaceID, taskID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			a := Attachment{}
+			if err := rows.Scan(&a.ID, &a.WorkspaceID, &a.TaskID, &a.UploadedBy, &a.Filename, &a.ContentType,
+				&a.Size, &a.CreatedAt); err != nil {
+				return err
+			}
+			attachments = append(attachments, a)
+		}
+		return rows.Err()
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return attachments, nil
+}
 
-	if rowsAffected == 0 {
-		return errors.New("no task deleted")
-	}
+func (r *PostgresTaskRepository) FindAttachment(workspaceID, id int64) (*Attachment, error) {
+	query := `SELECT id, workspace_id, task_id, COALESCE(uploaded_by, 0), filename, content_type, size, created_at, data
+            FROM task_attachments
+            WHERE workspace_id = $1 AND id = $2;`
+
+	var found *Attachment
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		a := Attachment{}
+		err := tx.QueryRow(query, workspaceID, id).Scan(&a.ID, &a.WorkspaceID, &a.TaskID, &a.UploadedBy,
+			&a.Filename, &a.ContentType, &a.Size, &a.CreatedAt, &a.Data)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		found = &a
+		return nil
+	})
+	return found, err
+}
 
-	return nil
+func (r *PostgresTaskRepository) DeleteAttachment(workspaceID, id int64) error {
+	return db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		res, err := tx.Exec(`DELETE FROM task_attachments WHERE workspace_id = $1 AND id = $2;`, workspaceID, id)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrAttachmentNotFound
+		}
+		return nil
+	})
 }
 
-func (r *PostgresTaskRepository) CountAll() (int64, error) {
-	var count int64
-	err := r.DB.QueryRow("SELECT COUNT(*) FROM tasks").Scan(&count)
+// FindByClientID returns the task the user created through sync with the
+// given client ID, or nil.
+func (r *PostgresTaskRepository) FindByClientID(workspaceID, userID int64, clientID string) (*Task, error) {
+	return r.findCreated(workspaceID, userID, "client_id", clientID)
+}
+
+// FindByExternalID returns the task the user imported with the given
+// external ID, or nil.
+func (r *PostgresTaskRepository) FindByExternalID(workspaceID, userID int64, externalID string) (*Task, error) {
+	return r.findCreated(workspaceID, userID, "external_id", externalID)
+}
+
+// findCreated returns the task created by the user whose column, one of the
+// uniquely indexed client_id and external_id, holds value.
+func (r *PostgresTaskRepository) findCreated(workspaceID, userID int64, column, value string) (*Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM tasks t
+            WHERE t.workspace_id = $1 AND t.created_by = $2 AND t.` + column + ` = $3;`
+
+	var found *Task
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		task := Task{}
+		err := scanTask(tx.QueryRow(query, workspaceID, userID, value), &task)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		tasks := []Task{task}
+		if err := loadRelations(tx, tasks); err != nil {
+			return err
+		}
+		found = &tasks[0]
+		return nil
+	})
+	return found, err
+}
+
+func (r *PostgresTaskRepository) IsDeleted(workspaceID, id int64) (bool, error) {
+	var deleted bool
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		return tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM task_tombstones WHERE workspace_id = $1 AND task_id = $2);`,
+			workspaceID, id).Scan(&deleted)
+	})
+	return deleted, err
+}
+
+// Changes returns up to limit changes after since that the viewer can see,
+// in change order. Tasks the viewer gained sight of by joining a project are
+// listed from when they joined, and tasks they lost sight of by leaving one
+// as deleted unless they can see them again. Tasks and tombstones are read
+// in one statement so both come from the same snapshot.
+func (r *PostgresTaskRepository) Changes(workspaceID, viewerID, since int64, limit int) ([]Change, error) {
+	query := `
+          SELECT c.seq, c.task_id, c.deleted FROM (
+            SELECT GREATEST(t.change_seq, v.change_seq) AS seq, t.id AS task_id, false AS deleted
+            FROM tasks t
+            LEFT JOIN task_visibility_changes v ON v.task_id = t.id AND v.user_id = $2 AND v.visible
+            WHERE ` + visibleTasks + ` AND GREATEST(t.change_seq, v.change_seq) > $3
+            UNION ALL
+            SELECT t.change_seq, t.task_id, true
+            FROM task_tombstones t
+            WHERE t.workspace_id = $1 AND $2 = ANY(t.visible_to) AND t.change_seq > $3
+            UNION ALL
+            SELECT v.change_seq, v.task_id, true
+            FROM task_visibility_changes v
+            WHERE v.workspace_id = $1 AND v.user_id = $2 AND NOT v.visible AND v.change_seq > $3
+              AND NOT EXISTS (SELECT 1 FROM tasks t WHERE ` + visibleTasks + ` AND t.id = v.task_id)
+          ) c
+          ORDER BY c.seq
+          LIMIT $4;`
+
+	changes := []Change{}
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, workspaceID, viewerID, since, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		ids := []int64{}
+		for rows.Next() {
+			c := Change{}
+			if err := rows.Scan(&c.Seq, &c.TaskID, &c.Deleted); err != nil {
+				return err
+			}
+			if !c.Deleted {
+				ids = append(ids, c.TaskID)
+			}
+			changes = append(changes, c)
+		}
+		if rows.Err() != nil {
+			return rows.Err()
+		}
+		rows.Close()
+
+		if len(ids) == 0 {
+			return nil
+		}
+
+		// A task may have changed again since the listing; its newer state
+		// is returned, and it is listed again on the next sync.
+		rows, err = tx.Query(`SELECT `+taskColumns+` FROM tasks t WHERE t.workspace_id = $1 AND t.id = ANY($2);`,
+			workspaceID, pq.Array(ids))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		tasks := []Task{}
+		for rows.Next() {
+			task := Task{}
+			if err := scanTask(rows, &task); err != nil {
+				return err
+			}
+			tasks = append(tasks, task)
+		}
+		if rows.Err() != nil {
+			return rows.Err()
+		}
+		rows.Close()
+
+		if err := loadRelations(tx, tasks); err != nil {
+			return err
+		}
+
+		byID := make(map[int64]*Task, len(tasks))
+		for i := range tasks {
+			byID[tasks[i].Id] = &tasks[i]
+		}
+		for i := range changes {
+			if !changes[i].Deleted {
+				changes[i].Task = byID[changes[i].TaskID]
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	return count, nil
+	return changes, nil
+}
+
+func (r *PostgresTaskRepository) SprintOpen(workspaceID, projectID, sprintID int64) (bool, error) {
+	var open bool
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		return tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM sprints
+                WHERE workspace_id = $1 AND project_id = $2 AND id = $3 AND closed_at IS NULL);`,
+			workspaceID, projectID, sprintID).Scan(&open)
+	})
+	return open, err
+}
+
+func (r *PostgresTaskRepository) MilestoneInProject(workspaceID, projectID, milestoneID int64) (bool, error) {
+	var found bool
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		return tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM milestones
+                WHERE workspace_id = $1 AND project_id = $2 AND id = $3);`,
+			workspaceID, projectID, milestoneID).Scan(&found)
+	})
+	return found, err
 }
@@ -8,8 +8,19 @@ func RegisterRoutes(r chi.Router, h *Handler) {
 	r.Route("/tasks", func(r chi.Router) {
 		r.Get("/", h.GetAllTasks)
 		r.Post("/", h.CreateTask)
+		r.Get("/assigned", h.GetAssignedTasks)
 		r.Get("/{id}", h.GetTaskByID)
 		r.Put("/{id}", h.UpdateTask)
 		r.Delete("/{id}", h.DeleteTask)
+		r.Get("/{id}/comments", h.ListComments)
+		r.Post("/{id}/comments", h.AddComment)
+		r.Delete("/{id}/comments/{commentID}", h.DeleteComment)
+		r.Get("/{id}/checklist", h.GetChecklist)
+		r.Put("/{id}/checklist/{index}", h.SetChecklistItem)
+		r.Get("/{id}/attachments", h.ListAttachments)
+		r.Post("/{id}/attachments", h.AddAttachment)
+		r.Get("/{id}/attachments/{attachmentID}", h.DownloadAttachment)
+		r.Delete("/{id}/attachments/{attachmentID}", h.DeleteAttachment)
 	})
+	r.Post("/sync", h.Sync)
 }
@@ -1,67 +1,488 @@
 package task
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/authz"
+	"github.com/sudarshanmg/gotask/internal/notification"
+	"github.com/sudarshanmg/gotask/internal/project"
+	"github.com/sudarshanmg/gotask/pkg/markdown"
 	"github.com/sudarshanmg/gotask/pkg/validation"
 )
 
 var validate = validator.New()
 
+// MaxAttachmentBytes bounds the size of a single attachment.
+const MaxAttachmentBytes = 10 << 20
+
+// TaskService methods take the calling user and workspace so that every
+// entry point enforces the same tenancy, visibility and permission rules.
 type TaskService interface {
-	Create(req CreateTaskRequest) (*TaskResponse, error)
-	GetAll(page, limit int, filter TaskFilter) ([]TaskResponse, int64, int, error)
-	GetById(id int64) (*TaskResponse, error)
-	Update(id int64, req UpdateTaskRequest) error
-	Delete(id int64) error
+	Create(caller auth.Caller, req CreateTaskRequest) (*TaskResponse, error)
+	GetAll(caller auth.Caller, page, limit int, filter TaskFilter) ([]TaskResponse, int64, int, error)
+	GetById(caller auth.Caller, id int64) (*TaskResponse, error)
+	Update(caller auth.Caller, id int64, req UpdateTaskRequest) error
+	// Reschedule sets the dates of several tasks at once. Nothing is saved
+	// unless the caller may update every one of them.
+	Reschedule(caller auth.Caller, changes []ScheduleChange) error
+	Delete(caller auth.Caller, id int64) error
+	// PublishUpdated publishes task.updated for tasks another service
+	// changed in bulk, such as closing a sprint, once the change is stored.
+	PublishUpdated(caller auth.Caller, ids []int64)
+	// DeletingProject captures the project's tasks and members before the
+	// project is deleted with them. The returned function publishes
+	// task.deleted, addressed to those members, for the tasks whose IDs it
+	// is given, once the delete is stored.
+	DeletingProject(caller auth.Caller, projectID int64) (func(deleted []int64), error)
+	Actions(subject authz.Subject, id int64) ([]authz.Action, error)
+	ListComments(caller auth.Caller, taskID int64) ([]Comment, error)
+	AddComment(caller auth.Caller, taskID int64, req CreateCommentRequest) (*Comment, error)
+	DeleteComment(caller auth.Caller, taskID, commentID int64) error
+	ListAttachments(caller auth.Caller, taskID int64) ([]Attachment, error)
+	AddAttachment(caller auth.Caller, taskID int64, req CreateAttachmentRequest) (*Attachment, error)
+	// GetAttachment returns the attachment with its contents.
+	GetAttachment(caller auth.Caller, taskID, attachmentID int64) (*Attachment, error)
+	DeleteAttachment(caller auth.Caller, taskID, attachmentID int64) error
+	// Checklist lists the task list items of the description.
+	Checklist(caller auth.Caller, id int64) ([]markdown.TaskItem, error)
+	SetChecklistItem(caller auth.Caller, id int64, index int, req SetChecklistItemRequest) (*TaskResponse, error)
+	Sync(caller auth.Caller, req SyncRequest) (*SyncResponse, error)
+	Import(caller auth.Caller, req ImportTaskRequest, dryRun bool) (*TaskResponse, bool, error)
 }
 
 type taskService struct {
-	repo TaskRepository
+	repo          TaskRepository
+	projects      project.ProjectRepository
+	users         auth.AuthRepository
+	notifications notification.NotificationService
+	events        EventPublisher
+}
+
+func NewService(repo TaskRepository, projects project.ProjectRepository, users auth.AuthRepository,
+	notifications notification.NotificationService, events EventPublisher) TaskService {
+	return &taskService{repo: repo, projects: projects, users: users, notifications: notifications, events: events}
 }
 
-func NewService(repo TaskRepository) TaskService {
-	return &taskService{repo: repo}
+// emit publishes a task event; previous is the task before an update. Like
+// notifications, failures are logged and never fail the write that caused
+// them.
+func (s *taskService) emit(eventType EventType, caller auth.Caller, task *Task, previous *TaskResponse) {
+	s.publish(newEvent(eventType, caller, task, previous))
+}
+
+func (s *taskService) publish(event Event) {
+	if s.events == nil {
+		return
+	}
+	if err := s.events.Publish(event); err != nil {
+		log.Printf("failed to publish %s for task %d: %v", event.Type, event.Task.ID, err)
+	}
 }
 
 func mapTasktoResponse(task *Task) TaskResponse {
+	assignees := task.AssigneeIDs
+	if assignees == nil {
+		assignees = []int64{}
+	}
+	labels := task.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+	blockers := task.BlockedBy
+	if blockers == nil {
+		blockers = []int64{}
+	}
 	res := TaskResponse{
-		ID:          task.Id,
-		Title:       task.Title,
-		Description: task.Description,
-		Completed:   task.Completed,
-		CreatedAt:   task.CreatedAt,
-		UpdatedAt:   task.UpdatedAt,
+		ID:              task.Id,
+		Title:           task.Title,
+		Description:     task.Description,
+		Completed:       task.Completed,
+		ProjectID:       task.ProjectID,
+		StatusID:        task.StatusID,
+		SprintID:        task.SprintID,
+		MilestoneID:     task.MilestoneID,
+		StoryPoints:     task.StoryPoints,
+		Priority:        task.Priority,
+		ExternalID:      task.ExternalID,
+		CreatedBy:       task.CreatedBy,
+		AssigneeIDs:     assignees,
+		BlockedBy:       blockers,
+		Labels:          labels,
+		StartAt:         task.StartAt,
+		DueAt:           task.DueAt,
+		CompletedAt:     task.CompletedAt,
+		CreatedAt:       task.CreatedAt,
+		UpdatedAt:       task.UpdatedAt,
+		EstimateMinutes: task.EstimateMinutes,
+		TrackedMinutes:  task.TrackedSeconds / 60,
 	}
 	return res
 }
 
-func (s *taskService) Create(req CreateTaskRequest) (*TaskResponse, error) {
+// normalizeLabels trims and lowercases labels and drops duplicates, keeping
+// the first occurrence's position.
+func normalizeLabels(labels []string) []string {
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, l := range labels {
+		l = strings.ToLower(strings.TrimSpace(l))
+		if l == "" || seen[l] {
+			continue
+		}
+		seen[l] = true
+		normalized = append(normalized, l)
+	}
+	return normalized
+}
+
+// access reports whether the user may read and modify the task. Personal
+// tasks belong to their creator; project tasks follow the project role.
+func (s *taskService) access(caller auth.Caller, task *Task) (canRead, canWrite bool, err error) {
+	if task.ProjectID == nil {
+		owner := task.CreatedBy == caller.UserID
+		return owner, owner, nil
+	}
+
+	role, err := s.projects.GetMemberRole(task.WorkspaceID, *task.ProjectID, caller.UserID)
+	if err != nil {
+		return false, false, err
+	}
+	return role.CanRead(), role.CanWrite(), nil
+}
+
+// checkAssignees ensures every assignee can see the task: project members for
+// project tasks, and only the creator for personal tasks.
+func (s *taskService) checkAssignees(task *Task, assignees []int64) error {
+	for _, id := range assignees {
+		if task.ProjectID == nil {
+			if id != task.CreatedBy {
+				return ErrInvalidAssignee
+			}
+			continue
+		}
+
+		role, err := s.projects.GetMemberRole(task.WorkspaceID, *task.ProjectID, id)
+		if err != nil {
+			return err
+		}
+		if !role.CanRead() {
+			return ErrInvalidAssignee
+		}
+	}
+	return nil
+}
+
+// applyStatus keeps a project task's status and completion in step. A
+// status given by statusID decides whether the task is completed; otherwise
+// the task stays in its status unless that no longer matches completed, in
+// which case it moves to the first done or todo status. Personal tasks have
+// no status.
+func (s *taskService) applyStatus(task *Task, statusID *int64, completed *bool) error {
+	if task.ProjectID == nil {
+		if statusID != nil {
+			return ErrInvalidStatus
+		}
+		if completed != nil {
+			task.Completed = *completed
+		}
+		return nil
+	}
+
+	statuses, err := s.projects.ListStatuses(task.WorkspaceID, *task.ProjectID)
+	if err != nil {
+		return err
+	}
+
+	if statusID != nil {
+		for _, st := range statuses {
+			if st.ID != *statusID {
+				continue
+			}
+			done := st.Category == project.CategoryDone
+			if completed != nil && *completed != done {
+				return ErrStatusConflict
+			}
+			task.StatusID = &st.ID
+			task.Completed = done
+			return nil
+		}
+		return ErrInvalidStatus
+	}
+
+	done := task.Completed
+	if completed != nil {
+		done = *completed
+	}
+	task.Completed = done
+	want := project.CategoryTodo
+	if done {
+		want = project.CategoryDone
+	}
+
+	for _, st := range statuses {
+		if task.StatusID != nil && st.ID == *task.StatusID && (st.Category == project.CategoryDone) == done {
+			return nil
+		}
+	}
+	for _, st := range statuses {
+		if st.Category == want {
+			task.StatusID = &st.ID
+			return nil
+		}
+	}
+	task.StatusID = nil
+	return nil
+}
+
+// checkPlanning validates the task's story points against its project's
+// point scale, or Fibonacci for personal tasks, and a sprint or milestone
+// it is moved into. T-shirt sizes are stored in upper case.
+func (s *taskService) checkPlanning(task *Task, sprintChanged, milestoneChanged bool) error {
+	if task.StoryPoints != nil {
+		scale := project.ScaleFibonacci
+		if task.ProjectID != nil {
+			p, err := s.projects.FindById(task.WorkspaceID, *task.ProjectID)
+			if err != nil {
+				return err
+			}
+			if p == nil {
+				return ErrNotFound
+			}
+			scale = p.PointScale
+		}
+		points := strings.ToUpper(strings.TrimSpace(*task.StoryPoints))
+		if _, ok := scale.Points(points); !ok {
+			return ErrInvalidPoints
+		}
+		task.StoryPoints = &points
+	}
+
+	if task.MilestoneID != nil && milestoneChanged {
+		if task.ProjectID == nil {
+			return ErrInvalidMilestone
+		}
+		found, err := s.repo.MilestoneInProject(task.WorkspaceID, *task.ProjectID, *task.MilestoneID)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ErrInvalidMilestone
+		}
+	}
+
+	if task.SprintID == nil || !sprintChanged {
+		return nil
+	}
+	if task.ProjectID == nil {
+		return ErrInvalidSprint
+	}
+	open, err := s.repo.SprintOpen(task.WorkspaceID, *task.ProjectID, *task.SprintID)
+	if err != nil {
+		return err
+	}
+	if !open {
+		return ErrInvalidSprint
+	}
+	return nil
+}
+
+// checkSchedule validates the task's dates and blockers: tasks of the same
+// project, or personal tasks of the same user, other than the task itself.
+// Duplicates are dropped. Cycles are caught by the repository.
+func (s *taskService) checkSchedule(task *Task, blockers []int64) ([]int64, error) {
+	if task.StartAt != nil && task.DueAt != nil && task.StartAt.After(*task.DueAt) {
+		return nil, ErrInvalidSchedule
+	}
+
+	seen := map[int64]bool{}
+	unique := []int64{}
+	for _, id := range blockers {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if id == task.Id {
+			return nil, ErrInvalidBlocker
+		}
+
+		blocker, err := s.repo.FindById(task.WorkspaceID, id)
+		if err != nil {
+			return nil, err
+		}
+		if blocker == nil {
+			return nil, ErrInvalidBlocker
+		}
+		sameProject := task.ProjectID != nil && blocker.ProjectID != nil && *task.ProjectID == *blocker.ProjectID
+		samePersonal := task.ProjectID == nil && blocker.ProjectID == nil && task.CreatedBy == blocker.CreatedBy
+		if !sameProject && !samePersonal {
+			return nil, ErrInvalidBlocker
+		}
+		unique = append(unique, id)
+	}
+	return unique, nil
+}
+
+// load fetches a task the user can read. Tasks outside the user's visibility
+// are reported as not found so their existence is not leaked.
+func (s *taskService) load(caller auth.Caller, id int64) (*Task, bool, error) {
+	if id <= 0 {
+		return nil, false, ErrInvalidID
+	}
+
+	task, err := s.repo.FindById(caller.WorkspaceID, id)
+	if err != nil {
+		return nil, false, err
+	}
+	if task == nil {
+		return nil, false, ErrNotFound
+	}
+
+	canRead, canWrite, err := s.access(caller, task)
+	if err != nil {
+		return nil, false, err
+	}
+	if !canRead {
+		return nil, false, ErrNotFound
+	}
+	return task, canWrite, nil
+}
+
+func (s *taskService) Create(caller auth.Caller, req CreateTaskRequest) (*TaskResponse, error) {
+	return s.create(caller, req, nil)
+}
+
+// create creates a task; clientID is set for tasks created through sync.
+func (s *taskService) create(caller auth.Caller, req CreateTaskRequest, clientID *string) (*TaskResponse, error) {
+	task, err := s.prepare(caller, req, nil)
+	if err != nil {
+		return nil, err
+	}
+	task.ClientID = clientID
+	return s.save(caller, task)
+}
+
+// prepare builds and checks a new task without saving it. A task is
+// created open unless completed or its status says otherwise.
+func (s *taskService) prepare(caller auth.Caller, req CreateTaskRequest, completed *bool) (*Task, error) {
 	if err := validate.Struct(req); err != nil {
 		return nil, validation.FormatValidationError(err)
 	}
 
 	task := Task{
-		Title:       req.Title,
-		Description: req.Description,
-		Completed:   false,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		WorkspaceID:     caller.WorkspaceID,
+		Title:           req.Title,
+		Description:     req.Description,
+		Completed:       false,
+		ProjectID:       req.ProjectID,
+		CreatedBy:       caller.UserID,
+		AssigneeIDs:     req.AssigneeIDs,
+		Labels:          normalizeLabels(req.Labels),
+		StartAt:         utc(req.StartAt),
+		DueAt:           utc(req.DueAt),
+		CreatedAt:       time.Now().UTC(),
+		UpdatedAt:       time.Now().UTC(),
+		EstimateMinutes: req.EstimateMinutes,
+		SprintID:        req.SprintID,
+		MilestoneID:     req.MilestoneID,
+		StoryPoints:     req.StoryPoints,
+		Priority:        req.Priority,
 	}
 
-	_, err := s.repo.Create(&task)
+	if _, canWrite, err := s.access(caller, &task); err != nil {
+		return nil, err
+	} else if !canWrite {
+		return nil, ErrForbidden
+	}
 
+	if err := s.checkAssignees(&task, task.AssigneeIDs); err != nil {
+		return nil, err
+	}
+	if err := s.applyStatus(&task, req.StatusID, completed); err != nil {
+		return nil, err
+	}
+	if err := s.checkPlanning(&task, true, true); err != nil {
+		return nil, err
+	}
+	blockers, err := s.checkSchedule(&task, req.BlockedBy)
 	if err != nil {
 		return nil, err
 	}
+	task.BlockedBy = blockers
+	return &task, nil
+}
+
+func (s *taskService) save(caller auth.Caller, task *Task) (*TaskResponse, error) {
+	if _, err := s.repo.Create(task); err != nil {
+		return nil, err
+	}
 
-	res := mapTasktoResponse(&task)
+	s.notifyAssigned(caller, task, nil)
+	s.notifyMentions(caller, task, "", task.Description)
+	s.emit(EventTaskCreated, caller, task, nil)
+
+	res := mapTasktoResponse(task)
 	return &res, nil
 }
 
-func (s *taskService) GetAll(page, limit int, filter TaskFilter) ([]TaskResponse, int64, int, error) {
+// Import creates a task read from an import file, together with its
+// attachments. A task the user already imported with the same external ID
+// is returned instead, with created false, so importing a file again is
+// safe. With dryRun the task is only checked, and no task is returned for
+// a new one.
+func (s *taskService) Import(caller auth.Caller, req ImportTaskRequest, dryRun bool) (*TaskResponse, bool, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, false, validation.FormatValidationError(err)
+	}
+
+	if req.ExternalID != "" {
+		existing, err := s.repo.FindByExternalID(caller.WorkspaceID, caller.UserID, req.ExternalID)
+		if err != nil {
+			return nil, false, err
+		}
+		if existing != nil {
+			res := mapTasktoResponse(existing)
+			return &res, false, nil
+		}
+	}
+
+	for _, a := range req.Attachments {
+		if err := checkAttachment(a); err != nil {
+			return nil, false, err
+		}
+	}
+
+	task, err := s.prepare(caller, req.Task, &req.Completed)
+	if err != nil {
+		return nil, false, err
+	}
+	if dryRun {
+		return nil, true, nil
+	}
+	if req.ExternalID != "" {
+		task.ExternalID = &req.ExternalID
+	}
+	for _, a := range req.Attachments {
+		task.Attachments = append(task.Attachments, newAttachment(caller, 0, a))
+	}
+	res, err := s.save(caller, task)
+	if err != nil {
+		return nil, false, err
+	}
+	return res, true, nil
+}
+
+func (s *taskService) GetAll(caller auth.Caller, page, limit int, filter TaskFilter) ([]TaskResponse, int64, int, error) {
 	offset := (page - 1) * limit
+	filter.WorkspaceID = caller.WorkspaceID
+	filter.ViewerID = caller.UserID
 
 	tasks, err := s.repo.FindAll(offset, limit, filter)
 	if err != nil {
@@ -69,7 +490,7 @@ func (s *taskService) GetAll(page, limit int, filter TaskFilter) ([]TaskResponse
 	}
 
 	validateSortFields := map[string]bool{
-		"id": true, "title": true, "created_at": true, "updated_at": true,
+		"id": true, "title": true, "created_at": true, "updated_at": true, "due_at": true,
 	}
 
 	if !validateSortFields[filter.SortBy] {
@@ -80,7 +501,7 @@ func (s *taskService) GetAll(page, limit int, filter TaskFilter) ([]TaskResponse
 		filter.Order = "asc"
 	}
 
-	total, err := s.repo.CountAll()
+	total, err := s.repo.Count(filter)
 	if err != nil {
 		return nil, 0, 0, err
 	}
@@ -96,25 +517,17 @@ func (s *taskService) GetAll(page, limit int, filter TaskFilter) ([]TaskResponse
 	return responses, total, totalPages, nil
 }
 
-func (s *taskService) GetById(id int64) (*TaskResponse, error) {
-	if id <= 0 {
-		return nil, ErrInvalidID
-	}
-
-	task, err := s.repo.FindById(id)
-
+func (s *taskService) GetById(caller auth.Caller, id int64) (*TaskResponse, error) {
+	task, _, err := s.load(caller, id)
 	if err != nil {
 		return nil, err
 	}
-	if task == nil {
-		return nil, ErrNotFound
-	}
 
 	res := mapTasktoResponse(task)
 	return &res, nil
 }
 
-func (s *taskService) Update(id int64, req UpdateTaskRequest) error {
+func (s *taskService) Update(caller auth.Caller, id int64, req UpdateTaskRequest) error {
 	if id <= 0 {
 		return ErrInvalidID
 	}
@@ -122,40 +535,435 @@ func (s *taskService) Update(id int64, req UpdateTaskRequest) error {
 	if err := validate.Struct(req); err != nil {
 		return validation.FormatValidationError(err)
 	}
-	task, err := s.repo.FindById(id)
 
+	task, canWrite, err := s.load(caller, id)
 	if err != nil {
 		return err
 	}
-
-	if task == nil {
-		return ErrNotFound
+	if !canWrite {
+		return ErrForbidden
 	}
+
+	previous := mapTasktoResponse(task)
+	previousDescription := task.Description
+	previousAssignees := task.AssigneeIDs
+	wasCompleted := task.Completed
+
 	if req.Title != nil {
 		task.Title = *req.Title
 	}
 	if req.Description != nil {
 		task.Description = *req.Description
 	}
-	if req.Completed != nil {
-		task.Completed = *req.Completed
+	if err := s.applyStatus(task, req.StatusID, req.Completed); err != nil {
+		return err
+	}
+	if req.AssigneeIDs != nil {
+		if err := s.checkAssignees(task, *req.AssigneeIDs); err != nil {
+			return err
+		}
+		task.AssigneeIDs = *req.AssigneeIDs
+	}
+	if req.DueAt != nil {
+		task.DueAt = utc(req.DueAt)
+	}
+	if req.ClearDueAt {
+		task.DueAt = nil
+	}
+	if req.StartAt != nil {
+		task.StartAt = utc(req.StartAt)
+	}
+	if req.ClearStartAt {
+		task.StartAt = nil
+	}
+	blockers := task.BlockedBy
+	if req.BlockedBy != nil {
+		blockers = *req.BlockedBy
+	}
+	if task.BlockedBy, err = s.checkSchedule(task, blockers); err != nil {
+		return err
+	}
+	if req.Labels != nil {
+		task.Labels = normalizeLabels(*req.Labels)
+	}
+	if req.EstimateMinutes != nil {
+		task.EstimateMinutes = req.EstimateMinutes
+	}
+	if req.ClearEstimate {
+		task.EstimateMinutes = nil
+	}
+	previousSprint := task.SprintID
+	if req.SprintID != nil {
+		task.SprintID = req.SprintID
+	}
+	if req.ClearSprint {
+		task.SprintID = nil
+	}
+	previousMilestone := task.MilestoneID
+	if req.MilestoneID != nil {
+		task.MilestoneID = req.MilestoneID
+	}
+	if req.ClearMilestone {
+		task.MilestoneID = nil
+	}
+	if req.StoryPoints != nil {
+		task.StoryPoints = req.StoryPoints
+	}
+	if req.ClearStoryPoints {
+		task.StoryPoints = nil
+	}
+	if req.Priority != nil {
+		task.Priority = req.Priority
+	}
+	if req.ClearPriority {
+		task.Priority = nil
+	}
+	sprintChanged := task.SprintID != nil && (previousSprint == nil || *previousSprint != *task.SprintID)
+	milestoneChanged := task.MilestoneID != nil && (previousMilestone == nil || *previousMilestone != *task.MilestoneID)
+	if err := s.checkPlanning(task, sprintChanged, milestoneChanged); err != nil {
+		return err
+	}
+
+	task.UpdatedAt = time.Now().UTC()
+	if err := s.repo.Update(task, req.IfChangeSeq); err != nil {
+		return err
 	}
 
-	task.UpdatedAt = time.Now()
-	return s.repo.Update(task)
+	s.notifyAssigned(caller, task, previousAssignees)
+	s.notifyMentions(caller, task, previousDescription, task.Description)
+
+	// Completing a task is reported as task.completed rather than
+	// task.updated.
+	if task.Completed && !wasCompleted {
+		s.emit(EventTaskCompleted, caller, task, &previous)
+	} else {
+		s.emit(EventTaskUpdated, caller, task, &previous)
+	}
+	return nil
 }
 
-func (s *taskService) Delete(id int64) error {
-	if id <= 0 {
-		return ErrInvalidID
+func (s *taskService) Reschedule(caller auth.Caller, changes []ScheduleChange) error {
+	tasks := make([]*Task, 0, len(changes))
+	previous := make([]TaskResponse, 0, len(changes))
+	for _, change := range changes {
+		task, canWrite, err := s.load(caller, change.TaskID)
+		if err != nil {
+			return err
+		}
+		if !canWrite {
+			return ErrForbidden
+		}
+		previous = append(previous, mapTasktoResponse(task))
+
+		task.StartAt = utc(change.StartAt)
+		task.DueAt = utc(change.DueAt)
+		if task.StartAt != nil && task.DueAt != nil && task.StartAt.After(*task.DueAt) {
+			return ErrInvalidSchedule
+		}
+		tasks = append(tasks, task)
 	}
-	task, err := s.repo.FindById(id)
+
+	if err := s.repo.UpdateSchedules(caller.WorkspaceID, tasks); err != nil {
+		return err
+	}
+
+	for i, task := range tasks {
+		s.emit(EventTaskUpdated, caller, task, &previous[i])
+	}
+	return nil
+}
+
+func (s *taskService) Delete(caller auth.Caller, id int64) error {
+	task, canWrite, err := s.load(caller, id)
 	if err != nil {
 		return err
 	}
-	if task == nil {
-		return ErrNotFound
+	if !canWrite {
+		return ErrForbidden
+	}
+	if err := s.repo.Delete(caller.WorkspaceID, id); err != nil {
+		return err
+	}
+
+	s.emit(EventTaskDeleted, caller, task, nil)
+	return nil
+}
+
+func (s *taskService) PublishUpdated(caller auth.Caller, ids []int64) {
+	if len(ids) == 0 {
+		return
+	}
+	tasks, err := s.repo.FindByIDs(caller.WorkspaceID, ids)
+	if err != nil {
+		log.Printf("failed to load %d changed tasks to publish: %v", len(ids), err)
+		return
+	}
+	for i := range tasks {
+		s.emit(EventTaskUpdated, caller, &tasks[i], nil)
+	}
+}
+
+func (s *taskService) DeletingProject(caller auth.Caller, projectID int64) (func(deleted []int64), error) {
+	tasks, err := s.repo.FindByProject(caller.WorkspaceID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.projects.ListMembers(caller.WorkspaceID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	audience := []int64{}
+	for _, m := range members {
+		if m.Role.CanRead() {
+			audience = append(audience, m.UserID)
+		}
+	}
+
+	return func(deleted []int64) {
+		gone := make(map[int64]bool, len(deleted))
+		for _, id := range deleted {
+			gone[id] = true
+		}
+		for i := range tasks {
+			if gone[tasks[i].Id] {
+				event := newEvent(EventTaskDeleted, caller, &tasks[i], nil)
+				event.Audience = audience
+				s.publish(event)
+			}
+		}
+	}, nil
+}
+
+// Actions reports what the user may do with a single task; it is registered
+// as the authz scope for task resources.
+func (s *taskService) Actions(subject authz.Subject, id int64) ([]authz.Action, error) {
+	caller := auth.Caller{UserID: subject.UserID, WorkspaceID: subject.WorkspaceID}
+	_, canWrite, err := s.load(caller, id)
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrInvalidID) {
+		return []authz.Action{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !canWrite {
+		return []authz.Action{authz.ActionRead}, nil
+	}
+	return []authz.Action{authz.ActionRead, authz.ActionUpdate, authz.ActionDelete}, nil
+}
+
+func (s *taskService) ListComments(caller auth.Caller, taskID int64) ([]Comment, error) {
+	if _, _, err := s.load(caller, taskID); err != nil {
+		return nil, err
+	}
+	return s.repo.FindComments(caller.WorkspaceID, taskID)
+}
+
+// AddComment is open to everyone who can read the task, including viewers.
+func (s *taskService) AddComment(caller auth.Caller, taskID int64, req CreateCommentRequest) (*Comment, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+
+	task, _, err := s.load(caller, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := Comment{
+		WorkspaceID: caller.WorkspaceID,
+		TaskID:      taskID,
+		AuthorID:    caller.UserID,
+		Body:        req.Body,
+	}
+	if _, err := s.repo.CreateComment(&comment); err != nil {
+		return nil, err
 	}
-	return s.repo.Delete(id)
 
+	s.notifyMentions(caller, task, "", comment.Body)
+	return &comment, nil
+}
+
+// DeleteComment is allowed for the comment's author and for anyone who can
+// edit the task.
+func (s *taskService) DeleteComment(caller auth.Caller, taskID, commentID int64) error {
+	_, canWrite, err := s.load(caller, taskID)
+	if err != nil {
+		return err
+	}
+
+	comment, err := s.repo.FindComment(caller.WorkspaceID, commentID)
+	if err != nil {
+		return err
+	}
+	if comment == nil || comment.TaskID != taskID {
+		return ErrCommentNotFound
+	}
+	if comment.AuthorID != caller.UserID && !canWrite {
+		return ErrForbidden
+	}
+
+	return s.repo.DeleteComment(caller.WorkspaceID, commentID)
+}
+
+func (s *taskService) ListAttachments(caller auth.Caller, taskID int64) ([]Attachment, error) {
+	if _, _, err := s.load(caller, taskID); err != nil {
+		return nil, err
+	}
+	return s.repo.FindAttachments(caller.WorkspaceID, taskID)
+}
+
+// AddAttachment needs the right to edit the task. A missing content type
+// is guessed from the contents.
+func (s *taskService) AddAttachment(caller auth.Caller, taskID int64, req CreateAttachmentRequest) (*Attachment, error) {
+	if err := checkAttachment(req); err != nil {
+		return nil, err
+	}
+
+	_, canWrite, err := s.load(caller, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if !canWrite {
+		return nil, ErrForbidden
+	}
+
+	a := newAttachment(caller, taskID, req)
+	if _, err := s.repo.CreateAttachment(&a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func checkAttachment(req CreateAttachmentRequest) error {
+	if len(req.Data) == 0 {
+		return ErrEmptyAttachment
+	}
+	if len(req.Data) > MaxAttachmentBytes {
+		return ErrAttachmentTooLarge
+	}
+	return nil
+}
+
+func newAttachment(caller auth.Caller, taskID int64, req CreateAttachmentRequest) Attachment {
+	a := Attachment{
+		WorkspaceID: caller.WorkspaceID,
+		TaskID:      taskID,
+		UploadedBy:  caller.UserID,
+		Filename:    attachmentFilename(req.Filename),
+		ContentType: req.ContentType,
+		Data:        req.Data,
+	}
+	if a.ContentType == "" {
+		a.ContentType = http.DetectContentType(req.Data)
+	}
+	return a
+}
+
+// attachmentFilename drops any directories from a filename and bounds its
+// length.
+func attachmentFilename(name string) string {
+	name = strings.TrimSpace(path.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	if runes := []rune(name); len(runes) > 200 {
+		name = string(runes[len(runes)-200:])
+	}
+	return name
+}
+
+func (s *taskService) GetAttachment(caller auth.Caller, taskID, attachmentID int64) (*Attachment, error) {
+	if _, _, err := s.load(caller, taskID); err != nil {
+		return nil, err
+	}
+
+	a, err := s.repo.FindAttachment(caller.WorkspaceID, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+	if a == nil || a.TaskID != taskID {
+		return nil, ErrAttachmentNotFound
+	}
+	return a, nil
+}
+
+// DeleteAttachment is allowed for the uploader and for anyone who can edit
+// the task.
+func (s *taskService) DeleteAttachment(caller auth.Caller, taskID, attachmentID int64) error {
+	a, err := s.GetAttachment(caller, taskID, attachmentID)
+	if err != nil {
+		return err
+	}
+	if a.UploadedBy != caller.UserID {
+		if _, canWrite, err := s.load(caller, taskID); err != nil {
+			return err
+		} else if !canWrite {
+			return ErrForbidden
+		}
+	}
+
+	return s.repo.DeleteAttachment(caller.WorkspaceID, attachmentID)
+}
+
+// notifyAssigned tells users newly added to the task's assignees. Failing to
+// notify never fails the change itself.
+func (s *taskService) notifyAssigned(caller auth.Caller, task *Task, previous []int64) {
+	before := map[int64]bool{}
+	for _, id := range previous {
+		before[id] = true
+	}
+
+	for _, id := range task.AssigneeIDs {
+		if before[id] {
+			continue
+		}
+		err := s.notifications.Notify(notification.Notification{
+			WorkspaceID: task.WorkspaceID,
+			UserID:      id,
+			Type:        notification.TypeAssigned,
+			TaskID:      &task.Id,
+			ActorID:     &caller.UserID,
+			Message:     fmt.Sprintf("You were assigned to %q", task.Title),
+		})
+		if err != nil {
+			log.Printf("failed to notify assignee %d of task %d: %v", id, task.Id, err)
+		}
+	}
+}
+
+// notifyMentions tells users who are newly @mentioned in text. Mentions of
+// users outside the workspace, or who cannot see the task, are ignored.
+func (s *taskService) notifyMentions(caller auth.Caller, task *Task, before, after string) {
+	for _, username := range notification.NewMentions(before, after) {
+		user, err := s.users.FindByUsernameInWorkspace(task.WorkspaceID, username)
+		if err != nil {
+			log.Printf("failed to resolve mention @%s: %v", username, err)
+			continue
+		}
+		if user == nil {
+			continue
+		}
+
+		canRead, _, err := s.access(auth.Caller{UserID: user.ID, WorkspaceID: task.WorkspaceID}, task)
+		if err != nil {
+			log.Printf("failed to check access for mention @%s: %v", username, err)
+			continue
+		}
+		if !canRead {
+			continue
+		}
+
+		err = s.notifications.Notify(notification.Notification{
+			WorkspaceID: task.WorkspaceID,
+			UserID:      user.ID,
+			Type:        notification.TypeMention,
+			TaskID:      &task.Id,
+			ActorID:     &caller.UserID,
+			Message:     fmt.Sprintf("You were mentioned in %q", task.Title),
+		})
+		if err != nil {
+			log.Printf("failed to notify @%s: %v", username, err)
+		}
+	}
 }
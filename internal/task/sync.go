@@ -0,0 +1,213 @@
+package task
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/pkg/validation"
+)
+
+// syncPageSize caps the changes returned by one sync; clients keep syncing
+// while HasMore is set.
+const syncPageSize = 500
+
+const tokenPrefix = "v1:"
+
+// Sync tokens are opaque to clients; today they carry the last change
+// number the client has seen.
+func encodeToken(seq int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(tokenPrefix + strconv.FormatInt(seq, 10)))
+}
+
+func decodeToken(token string) (int64, error) {
+	if token == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || !strings.HasPrefix(string(raw), tokenPrefix) {
+		return 0, ErrInvalidToken
+	}
+	seq, err := strconv.ParseInt(strings.TrimPrefix(string(raw), tokenPrefix), 10, 64)
+	if err != nil || seq < 0 {
+		return 0, ErrInvalidToken
+	}
+	return seq, nil
+}
+
+// Sync applies the client's offline changes in order and returns every
+// change after the client's token, including the client's own.
+//
+// Conflicts are resolved per field, last writer wins by server clock: a
+// change only writes the fields it sets, and it is ordered by when the
+// server applies it, not by the client's clock. A deleted task stays
+// deleted; later updates to it are dropped. Every change can safely be
+// sent again, so when Sync fails the client retries the same request.
+func (s *taskService) Sync(caller auth.Caller, req SyncRequest) (*SyncResponse, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+	since, err := decodeToken(req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]SyncResult, 0, len(req.Changes))
+	for _, change := range req.Changes {
+		result, err := s.applyChange(caller, since, change)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	changes, err := s.repo.Changes(caller.WorkspaceID, caller.UserID, since, syncPageSize+1)
+	if err != nil {
+		return nil, err
+	}
+
+	res := SyncResponse{Results: results, Tasks: []TaskResponse{}, Deleted: []int64{}}
+	if len(changes) > syncPageSize {
+		res.HasMore = true
+		changes = changes[:syncPageSize]
+	}
+	for _, c := range changes {
+		if c.Deleted {
+			res.Deleted = append(res.Deleted, c.TaskID)
+		} else if c.Task != nil {
+			res.Tasks = append(res.Tasks, mapTasktoResponse(c.Task))
+		}
+		since = c.Seq
+	}
+	res.Token = encodeToken(since)
+	return &res, nil
+}
+
+// applyChange applies one client change. Problems with the change itself are
+// reported in the result; only unexpected errors are returned.
+func (s *taskService) applyChange(caller auth.Caller, since int64, change SyncChange) (SyncResult, error) {
+	result := SyncResult{ClientID: change.ClientID}
+	reject := func(err error) (SyncResult, error) {
+		if !IsClientError(err) {
+			return result, err
+		}
+		result.Status = SyncRejected
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	if err := validate.Struct(change); err != nil {
+		return reject(validation.FormatValidationError(err))
+	}
+
+	if change.Op == SyncCreate {
+		existing, err := s.repo.FindByClientID(caller.WorkspaceID, caller.UserID, change.ClientID)
+		if err != nil {
+			return result, err
+		}
+		if existing != nil {
+			result.Status = SyncDuplicate
+			result.TaskID = existing.Id
+			return result, nil
+		}
+
+		task, err := s.create(caller, *change.Create, &change.ClientID)
+		if err != nil {
+			return reject(err)
+		}
+		result.Status = SyncApplied
+		result.TaskID = task.ID
+		return result, nil
+	}
+
+	id := change.TaskID
+	if id == 0 {
+		if change.TaskClientID == "" {
+			return reject(ErrSyncTarget)
+		}
+		created, err := s.repo.FindByClientID(caller.WorkspaceID, caller.UserID, change.TaskClientID)
+		if err != nil {
+			return result, err
+		}
+		if created == nil {
+			return reject(ErrNotFound)
+		}
+		id = created.Id
+	}
+	result.TaskID = id
+
+	task, _, err := s.load(caller, id)
+	if errors.Is(err, ErrNotFound) {
+		deleted, derr := s.repo.IsDeleted(caller.WorkspaceID, id)
+		if derr != nil {
+			return result, derr
+		}
+		if deleted {
+			result.Status = SyncDeleted
+			return result, nil
+		}
+	}
+	if err != nil {
+		return reject(err)
+	}
+
+	if change.Op == SyncDelete {
+		err = s.Delete(caller, id)
+	} else {
+		result.Overwritten = overwrittenFields(task, *change.Update, since)
+		err = s.Update(caller, id, *change.Update)
+	}
+	if err != nil {
+		return reject(err)
+	}
+	result.Status = SyncApplied
+	return result, nil
+}
+
+// overwrittenFields lists the fields the update sets that were written by
+// someone else after since. Without a token there is nothing to compare.
+func overwrittenFields(task *Task, req UpdateTaskRequest, since int64) []string {
+	if since == 0 {
+		return nil
+	}
+
+	set := map[string]bool{
+		"title":            req.Title != nil,
+		"description":      req.Description != nil,
+		"completed":        req.Completed != nil,
+		"status_id":        req.StatusID != nil,
+		"assignee_ids":     req.AssigneeIDs != nil,
+		"due_at":           req.DueAt != nil || req.ClearDueAt,
+		"labels":           req.Labels != nil,
+		"estimate_minutes": req.EstimateMinutes != nil || req.ClearEstimate,
+		"sprint_id":        req.SprintID != nil || req.ClearSprint,
+		"milestone_id":     req.MilestoneID != nil || req.ClearMilestone,
+		"story_points":     req.StoryPoints != nil || req.ClearStoryPoints,
+		"priority":         req.Priority != nil || req.ClearPriority,
+		"blocked_by":       req.BlockedBy != nil,
+		"start_at":         req.StartAt != nil || req.ClearStartAt,
+	}
+	fields := []string{}
+	for _, field := range []string{"title", "description", "completed", "status_id", "assignee_ids", "due_at",
+		"labels", "estimate_minutes", "sprint_id", "milestone_id", "story_points",
+		"priority", "blocked_by", "start_at"} {
+		if set[field] && task.FieldSeqs[field] > since {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// IsClientError reports whether err is a problem with the requested change,
+// from sync or an import, rather than with the server. Validation errors have no sentinel and are recognized by
+// their message.
+func IsClientError(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, ErrForbidden) || errors.Is(err, ErrInvalidID) ||
+		errors.Is(err, ErrInvalidAssignee) || errors.Is(err, ErrInvalidStatus) || errors.Is(err, ErrStatusConflict) ||
+		errors.Is(err, ErrInvalidSprint) || errors.Is(err, ErrInvalidPoints) || errors.Is(err, ErrInvalidMilestone) ||
+		errors.Is(err, ErrInvalidBlocker) || errors.Is(err, ErrDependencyCycle) || errors.Is(err, ErrInvalidSchedule) ||
+		errors.Is(err, ErrSyncTarget) ||
+		strings.HasPrefix(err.Error(), "validation failed:")
+}
@@ -0,0 +1,16 @@
+package timeentry
+
+import "errors"
+
+var (
+	ErrNotFound      = errors.New("time entry not found")
+	ErrInvalidID     = errors.New("invalid time entry ID")
+	ErrTaskNotFound  = errors.New("task not found")
+	ErrForbidden     = errors.New("you do not have permission to log time on this task")
+	ErrTimerRunning  = errors.New("you already have a running timer; stop it or start with switch")
+	ErrNoTimer       = errors.New("no running timer on this task")
+	ErrTooLong       = errors.New("a time entry cannot be longer than 24 hours")
+	ErrInFuture      = errors.New("a time entry cannot end in the future")
+	ErrInvalidRange  = errors.New("ended_at must be after started_at")
+	ErrInvalidReport = errors.New("invalid report range")
+)
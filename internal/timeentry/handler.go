@@ -0,0 +1,227 @@
+package timeentry
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/pkg/response"
+)
+
+type Handler struct {
+	service EntryService
+}
+
+func NewHandler(service EntryService) *Handler {
+	return &Handler{service: service}
+}
+
+func writeServiceError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidID), errors.Is(err, ErrTooLong), errors.Is(err, ErrInFuture),
+		errors.Is(err, ErrInvalidRange), errors.Is(err, ErrInvalidReport),
+		strings.HasPrefix(err.Error(), "validation failed:"):
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrTaskNotFound), errors.Is(err, ErrNoTimer):
+		response.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrForbidden):
+		response.WriteError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, ErrTimerRunning):
+		response.WriteError(w, http.StatusConflict, err.Error())
+	default:
+		response.WriteError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+func taskID(r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	return id, err == nil
+}
+
+func (h *Handler) StartTimer(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id, ok := taskID(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	// The body is optional.
+	var req StartTimerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	entry, err := h.service.Start(auth.GetCaller(r), id, req)
+	if err != nil {
+		writeServiceError(w, err, "failed to start timer")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, entry)
+}
+
+func (h *Handler) StopTimer(w http.ResponseWriter, r *http.Request) {
+	id, ok := taskID(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	entry, err := h.service.Stop(auth.GetCaller(r), id)
+	if err != nil {
+		writeServiceError(w, err, "failed to stop timer")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, entry)
+}
+
+func (h *Handler) GetRunningTimer(w http.ResponseWriter, r *http.Request) {
+	entry, err := h.service.Running(auth.GetCaller(r))
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch running timer")
+		return
+	}
+	if entry == nil {
+		response.WriteError(w, http.StatusNotFound, "no running timer")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, entry)
+}
+
+func (h *Handler) CreateEntry(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id, ok := taskID(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	var req CreateEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	entry, err := h.service.Create(auth.GetCaller(r), id, req)
+	if err != nil {
+		writeServiceError(w, err, "failed to create time entry")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, entry)
+}
+
+func (h *Handler) GetAllEntries(w http.ResponseWriter, r *http.Request) {
+	id, ok := taskID(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	entries, err := h.service.GetAll(auth.GetCaller(r), id)
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch time entries")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, entries)
+}
+
+func (h *Handler) UpdateEntry(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id, ok := taskID(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+	entryID, err := strconv.ParseInt(chi.URLParam(r, "entryID"), 10, 64)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid time entry ID format")
+		return
+	}
+
+	var req UpdateEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	entry, err := h.service.Update(auth.GetCaller(r), id, entryID, req)
+	if err != nil {
+		writeServiceError(w, err, "failed to update time entry")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, entry)
+}
+
+func (h *Handler) DeleteEntry(w http.ResponseWriter, r *http.Request) {
+	id, ok := taskID(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+	entryID, err := strconv.ParseInt(chi.URLParam(r, "entryID"), 10, 64)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid time entry ID format")
+		return
+	}
+
+	if err := h.service.Delete(auth.GetCaller(r), id, entryID); err != nil {
+		writeServiceError(w, err, "failed to delete time entry")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{"message": "time entry deleted successfully"})
+}
+
+// GetReport aggregates time for ?group_by=project|label|day between the
+// local dates ?from= and ?to= (YYYY-MM-DD) in ?tz=. ?user=all includes
+// everyone's time on tasks the caller can see; the default is their own.
+func (h *Handler) GetReport(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	caller := auth.GetCaller(r)
+
+	filter := ReportFilter{
+		UserID:   &caller.UserID,
+		Timezone: q.Get("tz"),
+		GroupBy:  GroupBy(q.Get("group_by")),
+	}
+	if q.Get("user") == "all" {
+		filter.UserID = nil
+	}
+
+	for _, p := range []struct {
+		name string
+		dest *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			response.WriteError(w, http.StatusBadRequest, "invalid "+p.name+" date")
+			return
+		}
+		*p.dest = d
+	}
+
+	report, err := h.service.Report(caller, filter)
+	if err != nil {
+		writeServiceError(w, err, "failed to build report")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, report)
+}
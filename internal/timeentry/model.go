@@ -0,0 +1,84 @@
+package timeentry
+
+import (
+	"time"
+)
+
+// Entry is time a user spent on a task. A running timer is an entry
+// without an end. Times are stored and returned in UTC.
+type Entry struct {
+	ID          int64      `json:"id"`
+	WorkspaceID int64      `json:"workspace_id"`
+	TaskID      int64      `json:"task_id"`
+	UserID      int64      `json:"user_id"`
+	StartedAt   time.Time  `json:"started_at"`
+	EndedAt     *time.Time `json:"ended_at"`
+	Running     bool       `json:"running"`
+	// DurationSeconds counts up to now for a running timer.
+	DurationSeconds int64     `json:"duration_seconds"`
+	Note            string    `json:"note"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+type StartTimerRequest struct {
+	Note string `json:"note" validate:"max=500"`
+	// Switch stops the caller's running timer, if any, instead of failing.
+	Switch bool `json:"switch"`
+}
+
+type CreateEntryRequest struct {
+	StartedAt time.Time `json:"started_at" validate:"required"`
+	EndedAt   time.Time `json:"ended_at" validate:"required,gtfield=StartedAt"`
+	Note      string    `json:"note" validate:"max=500"`
+}
+
+type UpdateEntryRequest struct {
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	Note      *string    `json:"note,omitempty" validate:"omitempty,max=500"`
+}
+
+type GroupBy string
+
+const (
+	GroupByProject GroupBy = "project"
+	GroupByLabel   GroupBy = "label"
+	GroupByDay     GroupBy = "day"
+)
+
+// ReportFilter selects the entries for a report. From and To are local
+// dates in Timezone, both inclusive.
+type ReportFilter struct {
+	WorkspaceID int64
+	ViewerID    int64
+	// UserID limits the report to one user's entries; nil includes everyone
+	// who logged time on tasks the viewer can see.
+	UserID   *int64
+	From     time.Time
+	To       time.Time
+	Timezone string
+	GroupBy  GroupBy
+}
+
+// ReportRow is one group of a report. Key is the project ID, label or
+// date; Name is what to show for it. Entries without a project or label
+// are grouped under an empty key.
+type ReportRow struct {
+	Key     string  `json:"key"`
+	Name    string  `json:"name"`
+	Seconds int64   `json:"seconds"`
+	Hours   float64 `json:"hours"`
+	Entries int64   `json:"entries"`
+}
+
+type Report struct {
+	From     string  `json:"from"`
+	To       string  `json:"to"`
+	Timezone string  `json:"timezone"`
+	GroupBy  GroupBy `json:"group_by"`
+	// TotalSeconds counts each entry once, even when it appears under
+	// several labels.
+	TotalSeconds int64       `json:"total_seconds"`
+	TotalHours   float64     `json:"total_hours"`
+	Rows         []ReportRow `json:"rows"`
+}
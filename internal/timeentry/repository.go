@@ -0,0 +1,320 @@
+package timeentry
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sudarshanmg/gotask/pkg/db"
+)
+
+// entryColumns computes the duration in SQL so running timers are measured
+// against the database clock.
+const entryColumns = `e.id, e.workspace_id, e.task_id, e.user_id, e.started_at, e.ended_at, e.note, e.created_at,
+            EXTRACT(EPOCH FROM COALESCE(e.ended_at, NOW() AT TIME ZONE 'UTC') - e.started_at)::bigint`
+
+type EntryRepository interface {
+	// Start begins a timer. With stopRunning, the user's running timer is
+	// stopped first in the same transaction; otherwise it is an error.
+	Start(entry *Entry, stopRunning bool) error
+	Create(entry *Entry) error
+	FindByTask(workspaceID, taskID int64) ([]Entry, error)
+	FindByID(workspaceID, id int64) (*Entry, error)
+	FindRunning(userID int64) (*Entry, error)
+	Stop(workspaceID, userID, taskID int64) (*Entry, error)
+	Update(entry *Entry) error
+	Delete(workspaceID, userID, id int64) error
+	Report(filter ReportFilter) ([]ReportRow, int64, error)
+	// Timezone returns the user's preferred time zone, or "" if they have
+	// not chosen one.
+	Timezone(userID int64) (string, error)
+}
+
+type PostgresEntryRepository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) EntryRepository {
+	return &PostgresEntryRepository{DB: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanEntry(row rowScanner, e *Entry) error {
+	err := row.Scan(&e.ID, &e.WorkspaceID, &e.TaskID, &e.UserID, &e.StartedAt, &e.EndedAt, &e.Note, &e.CreatedAt,
+		&e.DurationSeconds)
+	e.Running = e.EndedAt == nil
+	return err
+}
+
+// isTimerRunning reports a violation of the one-running-timer-per-user
+// index.
+func isTimerRunning(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "time_entries_running_key"
+}
+
+// The timer to stop may be in another workspace, so with stopRunning the
+// transaction runs across tenants.
+func (r *PostgresEntryRepository) Start(entry *Entry, stopRunning bool) error {
+	var err error
+	if stopRunning {
+		err = db.AcrossTenants(r.DB, func(tx *sql.Tx) error {
+			_, err := tx.Exec(`UPDATE time_entries SET ended_at = $2 WHERE user_id = $1 AND ended_at IS NULL;`,
+				entry.UserID, entry.StartedAt)
+			if err != nil {
+				return err
+			}
+			return r.insert(tx, entry)
+		})
+	} else {
+		err = r.Create(entry)
+	}
+	if isTimerRunning(err) {
+		return ErrTimerRunning
+	}
+	return err
+}
+
+func (r *PostgresEntryRepository) Create(entry *Entry) error {
+	return db.WithTenant(r.DB, entry.WorkspaceID, func(tx *sql.Tx) error {
+		return r.insert(tx, entry)
+	})
+}
+
+func (r *PostgresEntryRepository) insert(tx *sql.Tx, entry *Entry) error {
+	query := `INSERT INTO time_entries (workspace_id, task_id, user_id, started_at, ended_at, note)
+            VALUES ($1, $2, $3, $4, $5, $6)
+            RETURNING id, created_at;`
+
+	err := tx.QueryRow(query, entry.WorkspaceID, entry.TaskID, entry.UserID, entry.StartedAt, entry.EndedAt,
+		entry.Note).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return err
+	}
+	entry.Running = entry.EndedAt == nil
+	if entry.EndedAt != nil {
+		entry.DurationSeconds = int64(entry.EndedAt.Sub(entry.StartedAt) / time.Second)
+	}
+	return nil
+}
+
+func (r *PostgresEntryRepository) FindByTask(workspaceID, taskID int64) ([]Entry, error) {
+	query := `SELECT ` + entryColumns + `
+          FROM time_entries e
+          WHERE e.workspace_id = $1 AND e.task_id = $2
+          ORDER BY e.started_at DESC, e.id DESC;`
+
+	entries := []Entry{}
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, workspaceID, taskID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			e := Entry{}
+			if err := scanEntry(rows, &e); err != nil {
+				return err
+			}
+			entries = append(entries, e)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *PostgresEntryRepository) FindByID(workspaceID, id int64) (*Entry, error) {
+	query := `SELECT ` + entryColumns + ` FROM time_entries e WHERE e.workspace_id = $1 AND e.id = $2;`
+
+	var found *Entry
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		e := Entry{}
+		err := scanEntry(tx.QueryRow(query, workspaceID, id), &e)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		found = &e
+		return nil
+	})
+	return found, err
+}
+
+// FindRunning looks in every workspace, since a user has at most one
+// running timer overall.
+func (r *PostgresEntryRepository) FindRunning(userID int64) (*Entry, error) {
+	query := `SELECT ` + entryColumns + ` FROM time_entries e WHERE e.user_id = $1 AND e.ended_at IS NULL;`
+
+	var found *Entry
+	err := db.AcrossTenants(r.DB, func(tx *sql.Tx) error {
+		e := Entry{}
+		err := scanEntry(tx.QueryRow(query, userID), &e)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		found = &e
+		return nil
+	})
+	return found, err
+}
+
+func (r *PostgresEntryRepository) Stop(workspaceID, userID, taskID int64) (*Entry, error) {
+	query := `UPDATE time_entries e SET ended_at = NOW() AT TIME ZONE 'UTC'
+            WHERE e.workspace_id = $1 AND e.user_id = $2 AND e.task_id = $3 AND e.ended_at IS NULL
+            RETURNING ` + entryColumns + `;`
+
+	e := Entry{}
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		err := scanEntry(tx.QueryRow(query, workspaceID, userID, taskID), &e)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoTimer
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *PostgresEntryRepository) Update(entry *Entry) error {
+	query := `UPDATE time_entries e SET started_at = $1, ended_at = $2, note = $3
+            WHERE e.workspace_id = $4 AND e.user_id = $5 AND e.id = $6
+            RETURNING ` + entryColumns + `;`
+
+	return db.WithTenant(r.DB, entry.WorkspaceID, func(tx *sql.Tx) error {
+		err := scanEntry(tx.QueryRow(query, entry.StartedAt, entry.EndedAt, entry.Note, entry.WorkspaceID,
+			entry.UserID, entry.ID), entry)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	})
+}
+
+func (r *PostgresEntryRepository) Delete(workspaceID, userID, id int64) error {
+	query := `DELETE FROM time_entries WHERE workspace_id = $1 AND user_id = $2 AND id = $3;`
+
+	return db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		res, err := tx.Exec(query, workspaceID, userID, id)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+// reportGroups holds, per grouping, the key and name of a row and any join
+// needed to compute them, over the report's entries x.
+var reportGroups = map[GroupBy]struct{ key, name, join string }{
+	GroupByProject: {
+		key:  `COALESCE(x.project_id::text, '')`,
+		name: `COALESCE(p.name, '')`,
+		join: `LEFT JOIN projects p ON p.id = x.project_id`,
+	},
+	GroupByLabel: {
+		key:  `COALESCE(l.label, '')`,
+		name: `COALESCE(l.label, '')`,
+		join: `LEFT JOIN LATERAL unnest(x.labels) AS l(label) ON true`,
+	},
+	GroupByDay: {
+		key:  `to_char(x.local_start, 'YYYY-MM-DD')`,
+		name: `to_char(x.local_start, 'YYYY-MM-DD')`,
+	},
+}
+
+// Report sums the time of entries that started within the filter's dates,
+// on tasks the viewer can see. An entry counts on the local day it started.
+func (r *PostgresEntryRepository) Report(filter ReportFilter) ([]ReportRow, int64, error) {
+	group, ok := reportGroups[filter.GroupBy]
+	if !ok {
+		group = reportGroups[GroupByProject]
+	}
+
+	// The date range is converted to UTC bounds in SQL so that days
+	// shortened or lengthened by DST are handled by Postgres.
+	entries := `
+          WITH x AS (
+            SELECT t.project_id, t.labels,
+                   (e.started_at AT TIME ZONE 'UTC') AT TIME ZONE $3 AS local_start,
+                   EXTRACT(EPOCH FROM COALESCE(e.ended_at, NOW() AT TIME ZONE 'UTC') - e.started_at)::bigint AS seconds
+            FROM time_entries e
+            JOIN tasks t ON t.id = e.task_id
+            WHERE t.workspace_id = $1
+              AND ((t.project_id IS NULL AND t.created_by = $2)
+                OR t.project_id IN (SELECT project_id FROM project_members WHERE user_id = $2))
+              AND ($4::int IS NULL OR e.user_id = $4)
+              AND e.started_at >= ($5::date::timestamp AT TIME ZONE $3) AT TIME ZONE 'UTC'
+              AND e.started_at < (($6::date + 1)::timestamp AT TIME ZONE $3) AT TIME ZONE 'UTC'
+          )`
+	query := entries + `
+          SELECT ` + group.key + `, ` + group.name + `, SUM(x.seconds), COUNT(*)
+          FROM x ` + group.join + `
+          GROUP BY 1, 2
+          ORDER BY 3 DESC, 1;`
+	totalQuery := entries + `SELECT COALESCE(SUM(x.seconds), 0) FROM x;`
+
+	from := filter.From.Format("2006-01-02")
+	to := filter.To.Format("2006-01-02")
+	args := []any{filter.WorkspaceID, filter.ViewerID, filter.Timezone, filter.UserID, from, to}
+
+	rows := []ReportRow{}
+	var total int64
+	err := db.WithTenant(r.DB, filter.WorkspaceID, func(tx *sql.Tx) error {
+		if err := tx.QueryRow(totalQuery, args...).Scan(&total); err != nil {
+			return err
+		}
+
+		result, err := tx.Query(query, args...)
+		if err != nil {
+			return err
+		}
+		defer result.Close()
+
+		for result.Next() {
+			row := ReportRow{}
+			if err := result.Scan(&row.Key, &row.Name, &row.Seconds, &row.Entries); err != nil {
+				return err
+			}
+			rows = append(rows, row)
+		}
+		return result.Err()
+	})
+	if db.IsUnknownTimezone(err) {
+		return nil, 0, ErrInvalidReport
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return rows, total, nil
+}
+
+func (r *PostgresEntryRepository) Timezone(userID int64) (string, error) {
+	var tz string
+	err := r.DB.QueryRow(`SELECT timezone FROM digest_settings WHERE user_id = $1;`, userID).Scan(&tz)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return tz, err
+}
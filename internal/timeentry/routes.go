@@ -0,0 +1,18 @@
+package timeentry
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Route("/tasks/{id}/time-entries", func(r chi.Router) {
+		r.Get("/", h.GetAllEntries)
+		r.Post("/", h.CreateEntry)
+		r.Post("/start", h.StartTimer)
+		r.Post("/stop", h.StopTimer)
+		r.Put("/{entryID}", h.UpdateEntry)
+		r.Delete("/{entryID}", h.DeleteEntry)
+	})
+	r.Get("/time-entries/running", h.GetRunningTimer)
+	r.Get("/time-entries/report", h.GetReport)
+}
@@ -0,0 +1,275 @@
+package timeentry
+
+import (
+	"math"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/authz"
+	"github.com/sudarshanmg/gotask/internal/task"
+	"github.com/sudarshanmg/gotask/pkg/validation"
+)
+
+var validate = validator.New()
+
+const (
+	maxEntryLength = 24 * time.Hour
+	// maxReportDays bounds a report's date range.
+	maxReportDays = 366
+)
+
+type EntryService interface {
+	Start(caller auth.Caller, taskID int64, req StartTimerRequest) (*Entry, error)
+	Stop(caller auth.Caller, taskID int64) (*Entry, error)
+	Running(caller auth.Caller) (*Entry, error)
+	Create(caller auth.Caller, taskID int64, req CreateEntryRequest) (*Entry, error)
+	GetAll(caller auth.Caller, taskID int64) ([]Entry, error)
+	Update(caller auth.Caller, taskID, id int64, req UpdateEntryRequest) (*Entry, error)
+	Delete(caller auth.Caller, taskID, id int64) error
+	Report(caller auth.Caller, filter ReportFilter) (*Report, error)
+}
+
+type entryService struct {
+	repo  EntryRepository
+	tasks task.TaskService
+}
+
+func NewService(repo EntryRepository, tasks task.TaskService) EntryService {
+	return &entryService{repo: repo, tasks: tasks}
+}
+
+// checkTask ensures the caller can see the task and, when write is set,
+// edit it. Logging time counts as editing; anyone who can see the task can
+// see the time logged on it.
+func (s *entryService) checkTask(caller auth.Caller, taskID int64, write bool) error {
+	if taskID <= 0 {
+		return ErrTaskNotFound
+	}
+	actions, err := s.tasks.Actions(authz.Subject{UserID: caller.UserID, WorkspaceID: caller.WorkspaceID}, taskID)
+	if err != nil {
+		return err
+	}
+
+	canRead, canWrite := false, false
+	for _, a := range actions {
+		canRead = canRead || a == authz.ActionRead
+		canWrite = canWrite || a == authz.ActionUpdate
+	}
+	if !canRead {
+		return ErrTaskNotFound
+	}
+	if write && !canWrite {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// Start begins a timer on the task. A user has at most one running timer.
+func (s *entryService) Start(caller auth.Caller, taskID int64, req StartTimerRequest) (*Entry, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+	if err := s.checkTask(caller, taskID, true); err != nil {
+		return nil, err
+	}
+
+	entry := &Entry{
+		WorkspaceID: caller.WorkspaceID,
+		TaskID:      taskID,
+		UserID:      caller.UserID,
+		StartedAt:   time.Now().UTC(),
+		Note:        req.Note,
+	}
+	if err := s.repo.Start(entry, req.Switch); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *entryService) Stop(caller auth.Caller, taskID int64) (*Entry, error) {
+	if err := s.checkTask(caller, taskID, false); err != nil {
+		return nil, err
+	}
+	return s.repo.Stop(caller.WorkspaceID, caller.UserID, taskID)
+}
+
+// Running returns the caller's running timer, which may be in another
+// workspace, or nil.
+func (s *entryService) Running(caller auth.Caller) (*Entry, error) {
+	return s.repo.FindRunning(caller.UserID)
+}
+
+func checkRange(startedAt time.Time, endedAt *time.Time) error {
+	if endedAt == nil {
+		if startedAt.After(time.Now()) {
+			return ErrInFuture
+		}
+		return nil
+	}
+	if !endedAt.After(startedAt) {
+		return ErrInvalidRange
+	}
+	if endedAt.Sub(startedAt) > maxEntryLength {
+		return ErrTooLong
+	}
+	if endedAt.After(time.Now().Add(time.Minute)) {
+		return ErrInFuture
+	}
+	return nil
+}
+
+// Create logs time worked without a timer.
+func (s *entryService) Create(caller auth.Caller, taskID int64, req CreateEntryRequest) (*Entry, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+	if err := checkRange(req.StartedAt, &req.EndedAt); err != nil {
+		return nil, err
+	}
+	if err := s.checkTask(caller, taskID, true); err != nil {
+		return nil, err
+	}
+
+	endedAt := req.EndedAt.UTC()
+	entry := &Entry{
+		WorkspaceID: caller.WorkspaceID,
+		TaskID:      taskID,
+		UserID:      caller.UserID,
+		StartedAt:   req.StartedAt.UTC(),
+		EndedAt:     &endedAt,
+		Note:        req.Note,
+	}
+	if err := s.repo.Create(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *entryService) GetAll(caller auth.Caller, taskID int64) ([]Entry, error) {
+	if err := s.checkTask(caller, taskID, false); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByTask(caller.WorkspaceID, taskID)
+}
+
+// own loads one of the caller's entries on the task.
+func (s *entryService) own(caller auth.Caller, taskID, id int64) (*Entry, error) {
+	if id <= 0 {
+		return nil, ErrInvalidID
+	}
+	if err := s.checkTask(caller, taskID, false); err != nil {
+		return nil, err
+	}
+
+	entry, err := s.repo.FindByID(caller.WorkspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil || entry.TaskID != taskID || entry.UserID != caller.UserID {
+		return nil, ErrNotFound
+	}
+	return entry, nil
+}
+
+// Update corrects one of the caller's entries. A running timer stays
+// running unless ended_at is given.
+func (s *entryService) Update(caller auth.Caller, taskID, id int64, req UpdateEntryRequest) (*Entry, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+
+	entry, err := s.own(caller, taskID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.StartedAt != nil {
+		entry.StartedAt = req.StartedAt.UTC()
+	}
+	if req.EndedAt != nil {
+		endedAt := req.EndedAt.UTC()
+		entry.EndedAt = &endedAt
+	}
+	if req.Note != nil {
+		entry.Note = *req.Note
+	}
+	if err := checkRange(entry.StartedAt, entry.EndedAt); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *entryService) Delete(caller auth.Caller, taskID, id int64) error {
+	if _, err := s.own(caller, taskID, id); err != nil {
+		return err
+	}
+	return s.repo.Delete(caller.WorkspaceID, caller.UserID, id)
+}
+
+func hours(seconds int64) float64 {
+	return math.Round(float64(seconds)/36) / 100
+}
+
+// Report aggregates logged time by project, label or day. Without a time
+// zone it uses the one from the caller's digest settings, then UTC; without
+// dates it covers the last 30 days.
+func (s *entryService) Report(caller auth.Caller, filter ReportFilter) (*Report, error) {
+	if filter.GroupBy == "" {
+		filter.GroupBy = GroupByProject
+	}
+	if _, ok := reportGroups[filter.GroupBy]; !ok {
+		return nil, ErrInvalidReport
+	}
+
+	if filter.Timezone == "" {
+		tz, err := s.repo.Timezone(caller.UserID)
+		if err != nil {
+			return nil, err
+		}
+		filter.Timezone = tz
+	}
+	if filter.Timezone == "" {
+		filter.Timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(filter.Timezone)
+	if err != nil {
+		return nil, ErrInvalidReport
+	}
+
+	if filter.To.IsZero() {
+		filter.To = time.Now().In(loc)
+	}
+	if filter.From.IsZero() {
+		filter.From = filter.To.AddDate(0, 0, -29)
+	}
+	days := filter.To.Sub(filter.From).Hours() / 24
+	if days < 0 || days >= maxReportDays {
+		return nil, ErrInvalidReport
+	}
+
+	filter.WorkspaceID = caller.WorkspaceID
+	filter.ViewerID = caller.UserID
+	rows, total, err := s.repo.Report(filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		rows[i].Hours = hours(rows[i].Seconds)
+	}
+
+	return &Report{
+		From:         filter.From.Format("2006-01-02"),
+		To:           filter.To.Format("2006-01-02"),
+		Timezone:     filter.Timezone,
+		GroupBy:      filter.GroupBy,
+		TotalSeconds: total,
+		TotalHours:   hours(total),
+		Rows:         rows,
+	}, nil
+}
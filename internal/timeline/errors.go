@@ -0,0 +1,12 @@
+package timeline
+
+import "errors"
+
+var (
+	ErrNotFound        = errors.New("project not found")
+	ErrTaskNotFound    = errors.New("task not found")
+	ErrInvalidID       = errors.New("invalid ID")
+	ErrForbidden       = errors.New("you do not have permission to reschedule this task")
+	ErrInvalidRequest  = errors.New("start_at or due_at is required")
+	ErrInvalidSchedule = errors.New("start_at must not be after due_at")
+)
@@ -0,0 +1,76 @@
+package timeline
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/task"
+	"github.com/sudarshanmg/gotask/pkg/response"
+)
+
+type Handler struct {
+	service TimelineService
+}
+
+func NewHandler(service TimelineService) *Handler {
+	return &Handler{service: service}
+}
+
+func writeServiceError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidID), errors.Is(err, ErrInvalidRequest), errors.Is(err, ErrInvalidSchedule),
+		errors.Is(err, task.ErrInvalidSchedule),
+		strings.HasPrefix(err.Error(), "validation failed:"):
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrTaskNotFound), errors.Is(err, task.ErrNotFound):
+		response.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrForbidden), errors.Is(err, task.ErrForbidden):
+		response.WriteError(w, http.StatusForbidden, err.Error())
+	default:
+		response.WriteError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+func (h *Handler) GetTimeline(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	timeline, err := h.service.Get(auth.GetCaller(r), id)
+	if err != nil {
+		writeServiceError(w, err, "failed to build timeline")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, timeline)
+}
+
+func (h *Handler) RescheduleTask(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	var req RescheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	res, err := h.service.Reschedule(auth.GetCaller(r), id, req)
+	if err != nil {
+		writeServiceError(w, err, "failed to reschedule task")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,91 @@
+package timeline
+
+import (
+	"time"
+)
+
+// Task is what scheduling needs to know about a task.
+type Task struct {
+	ID              int64
+	Title           string
+	Completed       bool
+	StatusID        *int64
+	StartAt         *time.Time
+	DueAt           *time.Time
+	EstimateMinutes *int
+	BlockedBy       []int64
+}
+
+// plannedStart is when the task is planned to begin: its start date, or its
+// estimate before its due date, or its due date for a task without either.
+// It must only be called for tasks with a due date.
+func (t *Task) plannedStart() time.Time {
+	if t.StartAt != nil {
+		return *t.StartAt
+	}
+	if t.EstimateMinutes != nil {
+		return t.DueAt.Add(-time.Duration(*t.EstimateMinutes) * time.Minute)
+	}
+	return *t.DueAt
+}
+
+// Bar is a scheduled task: one with a due date. Start and End are as
+// planned; the early dates are the soonest it can happen once its blockers
+// are done, and the late dates the latest it can happen without moving the
+// end of the timeline.
+type Bar struct {
+	TaskID      int64     `json:"task_id"`
+	Title       string    `json:"title"`
+	Completed   bool      `json:"completed"`
+	StatusID    *int64    `json:"status_id"`
+	BlockedBy   []int64   `json:"blocked_by"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	EarlyStart  time.Time `json:"early_start"`
+	EarlyFinish time.Time `json:"early_finish"`
+	LateStart   time.Time `json:"late_start"`
+	LateFinish  time.Time `json:"late_finish"`
+	// SlackMinutes is how long the task can slip without moving the end of
+	// the timeline; tasks without slack are critical.
+	SlackMinutes int64 `json:"slack_minutes"`
+	Critical     bool  `json:"critical"`
+	// Conflict means the task is planned to start before a blocker ends.
+	Conflict bool `json:"conflict"`
+}
+
+type Timeline struct {
+	ProjectID int64 `json:"project_id"`
+	// Start and End span every bar's early dates; nil when nothing is
+	// scheduled.
+	Start *time.Time `json:"start"`
+	End   *time.Time `json:"end"`
+	Bars  []Bar      `json:"bars"`
+	// CriticalPath lists the critical tasks in order of early start.
+	CriticalPath []int64 `json:"critical_path"`
+	// Unscheduled tasks have no due date.
+	Unscheduled []int64 `json:"unscheduled"`
+}
+
+// RescheduleRequest moves a task. Giving only one of the dates shifts the
+// other by the same amount, keeping the task's length.
+type RescheduleRequest struct {
+	StartAt *time.Time `json:"start_at,omitempty"`
+	DueAt   *time.Time `json:"due_at,omitempty"`
+	// DryRun reports the shifts without saving them.
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+type Shift struct {
+	TaskID          int64      `json:"task_id"`
+	PreviousStartAt *time.Time `json:"previous_start_at"`
+	PreviousDueAt   *time.Time `json:"previous_due_at"`
+	StartAt         *time.Time `json:"start_at"`
+	DueAt           *time.Time `json:"due_at"`
+}
+
+type RescheduleResponse struct {
+	DryRun bool `json:"dry_run"`
+	// Shifts has the moved task first, then every dependent that had to
+	// move, in dependency order.
+	Shifts []Shift `json:"shifts"`
+}
@@ -0,0 +1,57 @@
+package timeline
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/sudarshanmg/gotask/pkg/db"
+)
+
+type TimelineRepository interface {
+	// Tasks returns the project's tasks with their blockers, or the user's
+	// personal tasks when projectID is nil.
+	Tasks(workspaceID int64, projectID *int64, userID int64) ([]Task, error)
+}
+
+type PostgresTimelineRepository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) TimelineRepository {
+	return &PostgresTimelineRepository{DB: db}
+}
+
+func (r *PostgresTimelineRepository) Tasks(workspaceID int64, projectID *int64, userID int64) ([]Task, error) {
+	query := `
+          SELECT t.id, t.title, t.completed, t.status_id, t.start_at, t.due_at, t.estimate_minutes,
+                 ARRAY(SELECT d.blocked_by_id FROM task_dependencies d WHERE d.task_id = t.id ORDER BY d.blocked_by_id)
+          FROM tasks t
+          WHERE t.workspace_id = $1
+            AND (($2::int IS NOT NULL AND t.project_id = $2)
+              OR ($2::int IS NULL AND t.project_id IS NULL AND t.created_by = $3))
+          ORDER BY t.id;`
+
+	tasks := []Task{}
+	err := db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, workspaceID, projectID, userID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			t := Task{}
+			err := rows.Scan(&t.ID, &t.Title, &t.Completed, &t.StatusID, &t.StartAt, &t.DueAt,
+				&t.EstimateMinutes, pq.Array(&t.BlockedBy))
+			if err != nil {
+				return err
+			}
+			tasks = append(tasks, t)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
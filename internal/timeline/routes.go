@@ -0,0 +1,10 @@
+package timeline
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Get("/projects/{id}/timeline", h.GetTimeline)
+	r.Post("/tasks/{id}/reschedule", h.RescheduleTask)
+}
@@ -0,0 +1,246 @@
+package timeline
+
+import (
+	"time"
+)
+
+// graph holds the dependencies between scheduled tasks, those with a due
+// date. Blockers without a due date are ignored.
+type graph struct {
+	tasks      map[int64]*Task
+	blockers   map[int64][]int64
+	dependents map[int64][]int64
+	// order lists the tasks with blockers before the tasks they block, by
+	// ID otherwise. Writes reject cycles; should one exist anyway, its tasks
+	// come last.
+	order []int64
+}
+
+func newGraph(tasks []Task) *graph {
+	g := &graph{tasks: map[int64]*Task{}, blockers: map[int64][]int64{}, dependents: map[int64][]int64{}}
+	for i := range tasks {
+		if tasks[i].DueAt != nil {
+			g.tasks[tasks[i].ID] = &tasks[i]
+		}
+	}
+
+	waiting := map[int64]int{}
+	queue := []int64{}
+	for i := range tasks {
+		t := &tasks[i]
+		if g.tasks[t.ID] == nil {
+			continue
+		}
+		for _, b := range t.BlockedBy {
+			if g.tasks[b] == nil {
+				continue
+			}
+			g.blockers[t.ID] = append(g.blockers[t.ID], b)
+			g.dependents[b] = append(g.dependents[b], t.ID)
+		}
+		waiting[t.ID] = len(g.blockers[t.ID])
+		if waiting[t.ID] == 0 {
+			queue = append(queue, t.ID)
+		}
+	}
+
+	done := map[int64]bool{}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		g.order = append(g.order, id)
+		done[id] = true
+		for _, d := range g.dependents[id] {
+			waiting[d]--
+			if waiting[d] == 0 {
+				queue = append(queue, d)
+			}
+		}
+	}
+	for i := range tasks {
+		if id := tasks[i].ID; g.tasks[id] != nil && !done[id] {
+			g.order = append(g.order, id)
+		}
+	}
+	return g
+}
+
+// downstream returns every task that waits on id, directly or through
+// others.
+func (g *graph) downstream(id int64) map[int64]bool {
+	found := map[int64]bool{}
+	stack := []int64{id}
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, d := range g.dependents[cur] {
+			if !found[d] {
+				found[d] = true
+				stack = append(stack, d)
+			}
+		}
+	}
+	return found
+}
+
+// build lays out the timeline with the critical path method. A task starts
+// when planned or, if later, once its last blocker can finish; the latest
+// dates come from walking back from the end of the timeline.
+func build(projectID int64, tasks []Task) *Timeline {
+	g := newGraph(tasks)
+	timeline := &Timeline{ProjectID: projectID, Bars: []Bar{}, CriticalPath: []int64{}, Unscheduled: []int64{}}
+	for _, t := range tasks {
+		if t.DueAt == nil {
+			timeline.Unscheduled = append(timeline.Unscheduled, t.ID)
+		}
+	}
+	if len(g.order) == 0 {
+		return timeline
+	}
+
+	bars := map[int64]*Bar{}
+	var start, end time.Time
+	for i, id := range g.order {
+		t := g.tasks[id]
+		blockedBy := t.BlockedBy
+		if blockedBy == nil {
+			blockedBy = []int64{}
+		}
+		bar := &Bar{
+			TaskID:    id,
+			Title:     t.Title,
+			Completed: t.Completed,
+			StatusID:  t.StatusID,
+			BlockedBy: blockedBy,
+			Start:     t.plannedStart(),
+			End:       *t.DueAt,
+		}
+		bar.EarlyStart = bar.Start
+		for _, b := range g.blockers[id] {
+			if blocker, ok := bars[b]; ok && blocker.EarlyFinish.After(bar.EarlyStart) {
+				bar.EarlyStart = blocker.EarlyFinish
+				bar.Conflict = true
+			}
+		}
+		bar.EarlyFinish = bar.EarlyStart.Add(bar.End.Sub(bar.Start))
+		bars[id] = bar
+
+		if i == 0 || bar.EarlyStart.Before(start) {
+			start = bar.EarlyStart
+		}
+		if bar.EarlyFinish.After(end) {
+			end = bar.EarlyFinish
+		}
+	}
+
+	for i := len(g.order) - 1; i >= 0; i-- {
+		bar := bars[g.order[i]]
+		bar.LateFinish = end
+		for _, d := range g.dependents[bar.TaskID] {
+			if dependent, ok := bars[d]; ok && dependent.LateStart.Before(bar.LateFinish) {
+				bar.LateFinish = dependent.LateStart
+			}
+		}
+		bar.LateStart = bar.LateFinish.Add(-bar.End.Sub(bar.Start))
+		bar.SlackMinutes = int64(bar.LateStart.Sub(bar.EarlyStart) / time.Minute)
+		bar.Critical = bar.SlackMinutes <= 0
+	}
+
+	for _, id := range g.order {
+		timeline.Bars = append(timeline.Bars, *bars[id])
+	}
+	critical := []*Bar{}
+	for i := range timeline.Bars {
+		if timeline.Bars[i].Critical {
+			critical = append(critical, &timeline.Bars[i])
+		}
+	}
+	// Bars are in dependency order, which a stable sort by early start keeps
+	// for ties.
+	for i := 1; i < len(critical); i++ {
+		for j := i; j > 0 && critical[j].EarlyStart.Before(critical[j-1].EarlyStart); j-- {
+			critical[j], critical[j-1] = critical[j-1], critical[j]
+		}
+	}
+	for _, bar := range critical {
+		timeline.CriticalPath = append(timeline.CriticalPath, bar.TaskID)
+	}
+
+	timeline.Start = &start
+	timeline.End = &end
+	return timeline
+}
+
+func shiftTime(t *time.Time, d time.Duration) *time.Time {
+	if t == nil {
+		return nil
+	}
+	shifted := t.Add(d)
+	return &shifted
+}
+
+// reschedule moves the task and then, in dependency order, every open task
+// downstream of it that would start before one of its blockers is due,
+// just far enough to start when the last of them is due. Moving a task
+// earlier leaves its dependents where they are. tasks is updated in place.
+func reschedule(tasks []Task, id int64, req RescheduleRequest) ([]Shift, error) {
+	var moved *Task
+	for i := range tasks {
+		if tasks[i].ID == id {
+			moved = &tasks[i]
+		}
+	}
+	if moved == nil {
+		return nil, ErrTaskNotFound
+	}
+
+	shift := Shift{TaskID: id, PreviousStartAt: moved.StartAt, PreviousDueAt: moved.DueAt}
+	switch {
+	case req.StartAt != nil && req.DueAt != nil:
+		moved.StartAt, moved.DueAt = req.StartAt, req.DueAt
+	case req.DueAt != nil:
+		if moved.DueAt != nil {
+			moved.StartAt = shiftTime(moved.StartAt, req.DueAt.Sub(*moved.DueAt))
+		}
+		moved.DueAt = req.DueAt
+	case req.StartAt != nil:
+		if moved.DueAt != nil {
+			moved.DueAt = shiftTime(moved.DueAt, req.StartAt.Sub(moved.plannedStart()))
+		}
+		moved.StartAt = req.StartAt
+	default:
+		return nil, ErrInvalidRequest
+	}
+	if moved.StartAt != nil && moved.DueAt != nil && moved.StartAt.After(*moved.DueAt) {
+		return nil, ErrInvalidSchedule
+	}
+	shift.StartAt, shift.DueAt = moved.StartAt, moved.DueAt
+	shifts := []Shift{shift}
+
+	g := newGraph(tasks)
+	affected := g.downstream(id)
+	for _, tid := range g.order {
+		t := g.tasks[tid]
+		if !affected[tid] || t.Completed {
+			continue
+		}
+		var ready time.Time
+		for _, b := range g.blockers[tid] {
+			if due := *g.tasks[b].DueAt; due.After(ready) {
+				ready = due
+			}
+		}
+		start := t.plannedStart()
+		if !start.Before(ready) {
+			continue
+		}
+
+		delta := ready.Sub(start)
+		s := Shift{TaskID: tid, PreviousStartAt: t.StartAt, PreviousDueAt: t.DueAt}
+		t.StartAt = shiftTime(t.StartAt, delta)
+		t.DueAt = shiftTime(t.DueAt, delta)
+		s.StartAt, s.DueAt = t.StartAt, t.DueAt
+		shifts = append(shifts, s)
+	}
+	return shifts, nil
+}
@@ -0,0 +1,99 @@
+package timeline
+
+import (
+	"errors"
+
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/authz"
+	"github.com/sudarshanmg/gotask/internal/project"
+	"github.com/sudarshanmg/gotask/internal/task"
+)
+
+type TimelineService interface {
+	Get(caller auth.Caller, projectID int64) (*Timeline, error)
+	Reschedule(caller auth.Caller, taskID int64, req RescheduleRequest) (*RescheduleResponse, error)
+}
+
+type timelineService struct {
+	repo     TimelineRepository
+	projects project.ProjectRepository
+	tasks    task.TaskService
+}
+
+func NewService(repo TimelineRepository, projects project.ProjectRepository, tasks task.TaskService) TimelineService {
+	return &timelineService{repo: repo, projects: projects, tasks: tasks}
+}
+
+// Get lays out the project's tasks. Projects the caller cannot see are
+// reported as not found.
+func (s *timelineService) Get(caller auth.Caller, projectID int64) (*Timeline, error) {
+	if projectID <= 0 {
+		return nil, ErrNotFound
+	}
+	role, err := s.projects.GetMemberRole(caller.WorkspaceID, projectID, caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !role.CanRead() {
+		return nil, ErrNotFound
+	}
+
+	tasks, err := s.repo.Tasks(caller.WorkspaceID, &projectID, caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return build(projectID, tasks), nil
+}
+
+// Reschedule moves the task and pushes back the open tasks waiting on it.
+// The shifts are saved together through the task service, so they are
+// checked, stamped for sync and published like any other edit; if the
+// caller may not edit one of the dependents, nothing is saved.
+func (s *timelineService) Reschedule(caller auth.Caller, taskID int64, req RescheduleRequest) (*RescheduleResponse, error) {
+	if taskID <= 0 {
+		return nil, ErrTaskNotFound
+	}
+	actions, err := s.tasks.Actions(authz.Subject{UserID: caller.UserID, WorkspaceID: caller.WorkspaceID}, taskID)
+	if err != nil {
+		return nil, err
+	}
+	canRead, canWrite := false, false
+	for _, a := range actions {
+		canRead = canRead || a == authz.ActionRead
+		canWrite = canWrite || a == authz.ActionUpdate
+	}
+	if !canRead {
+		return nil, ErrTaskNotFound
+	}
+	if !canWrite {
+		return nil, ErrForbidden
+	}
+
+	t, err := s.tasks.GetById(caller, taskID)
+	if errors.Is(err, task.ErrNotFound) {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := s.repo.Tasks(caller.WorkspaceID, t.ProjectID, caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+	shifts, err := reschedule(tasks, taskID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !req.DryRun {
+		changes := make([]task.ScheduleChange, 0, len(shifts))
+		for _, shift := range shifts {
+			changes = append(changes, task.ScheduleChange{TaskID: shift.TaskID, StartAt: shift.StartAt, DueAt: shift.DueAt})
+		}
+		if err := s.tasks.Reschedule(caller, changes); err != nil {
+			return nil, err
+		}
+	}
+	return &RescheduleResponse{DryRun: req.DryRun, Shifts: shifts}, nil
+}
@@ -0,0 +1,10 @@
+package transfer
+
+import "errors"
+
+var (
+	ErrInvalidFormat  = errors.New("format must be csv, json or ndjson")
+	ErrInvalidFile    = errors.New("invalid import file")
+	ErrInvalidMapping = errors.New("invalid column mapping")
+	ErrTooManyRows    = errors.New("import file has too many rows")
+)
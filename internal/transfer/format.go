@@ -0,0 +1,277 @@
+package transfer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exporter writes records to a file as they come.
+type exporter interface {
+	write(rec *Record) error
+	// close finishes the file.
+	close() error
+}
+
+func newExporter(format Format, w io.Writer, comments bool) exporter {
+	switch format {
+	case FormatCSV:
+		return newCSVExporter(w, comments)
+	case FormatNDJSON:
+		return &ndjsonExporter{enc: json.NewEncoder(w)}
+	default:
+		return &jsonExporter{w: w}
+	}
+}
+
+// csvColumns are named after the task fields, so an export maps onto them
+// when imported again. Lists are separated by semicolons.
+var csvColumns = []string{"id", "external_id", "title", "description", "completed", "project_id", "project",
+	"status", "labels", "assignee_ids", "start_at", "due_at", "completed_at", "estimate_minutes", "story_points",
+	"priority", "created_at", "updated_at"}
+
+type csvExporter struct {
+	cw       *csv.Writer
+	comments bool
+	started  bool
+}
+
+func newCSVExporter(w io.Writer, comments bool) *csvExporter {
+	return &csvExporter{cw: csv.NewWriter(w), comments: comments}
+}
+
+func (e *csvExporter) header() error {
+	e.started = true
+	header := csvColumns
+	if e.comments {
+		header = append(header[:len(header):len(header)], "comments")
+	}
+	return e.cw.Write(header)
+}
+
+func (e *csvExporter) write(rec *Record) error {
+	if !e.started {
+		if err := e.header(); err != nil {
+			return err
+		}
+	}
+
+	optional := func(v *string) string {
+		if v == nil {
+			return ""
+		}
+		return *v
+	}
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+	projectID, estimate, priority := "", "", ""
+	if rec.ProjectID != nil {
+		projectID = strconv.FormatInt(*rec.ProjectID, 10)
+	}
+	if rec.EstimateMinutes != nil {
+		estimate = strconv.Itoa(*rec.EstimateMinutes)
+	}
+	if rec.Priority != nil {
+		priority = strconv.Itoa(*rec.Priority)
+	}
+	assignees := make([]string, len(rec.AssigneeIDs))
+	for i, id := range rec.AssigneeIDs {
+		assignees[i] = strconv.FormatInt(id, 10)
+	}
+
+	row := []string{strconv.FormatInt(rec.ID, 10), rec.ExternalID, rec.Title, rec.Description,
+		strconv.FormatBool(rec.Completed), projectID, optional(rec.Project), optional(rec.Status),
+		strings.Join(rec.Labels, ";"), strings.Join(assignees, ";"), formatTime(rec.StartAt), formatTime(rec.DueAt),
+		formatTime(rec.CompletedAt), estimate, optional(rec.StoryPoints), priority, formatTime(&rec.CreatedAt),
+		formatTime(&rec.UpdatedAt)}
+	if e.comments {
+		// A CSV cell cannot hold a list of objects, so comments are JSON.
+		comments := rec.Comments
+		if comments == nil {
+			comments = []Comment{}
+		}
+		raw, err := json.Marshal(comments)
+		if err != nil {
+			return err
+		}
+		row = append(row, string(raw))
+	}
+	return e.cw.Write(row)
+}
+
+func (e *csvExporter) close() error {
+	if !e.started {
+		if err := e.header(); err != nil {
+			return err
+		}
+	}
+	e.cw.Flush()
+	return e.cw.Error()
+}
+
+// jsonExporter writes a JSON array, one task per line.
+type jsonExporter struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonExporter) write(rec *Record) error {
+	raw, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if e.count == 0 {
+		sep = "[\n"
+	}
+	e.count++
+	if _, err := io.WriteString(e.w, sep); err != nil {
+		return err
+	}
+	_, err = e.w.Write(raw)
+	return err
+}
+
+func (e *jsonExporter) close() error {
+	end := "\n]\n"
+	if e.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+type ndjsonExporter struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonExporter) write(rec *Record) error {
+	return e.enc.Encode(rec)
+}
+
+func (e *ndjsonExporter) close() error {
+	return nil
+}
+
+// recordReader reads an import file one record at a time. CSV values are
+// strings; JSON values keep their types, with numbers as json.Number.
+type recordReader interface {
+	// next returns io.EOF after the last record.
+	next() (map[string]any, error)
+	// columns lists the CSV header or, for JSON, the keys of the records
+	// read so far.
+	columns() []string
+}
+
+func newReader(format Format, r io.Reader) (recordReader, error) {
+	switch format {
+	case FormatCSV:
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		header, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return &csvReader{cr: cr}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Spreadsheets often start UTF-8 files with a byte order mark.
+		if len(header) > 0 {
+			header[0] = strings.TrimPrefix(header[0], "\ufeff")
+		}
+		for i := range header {
+			header[i] = strings.TrimSpace(header[i])
+		}
+		return &csvReader{cr: cr, header: header}, nil
+	case FormatJSON:
+		dec := json.NewDecoder(r)
+		dec.UseNumber()
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+			return nil, errors.New("expected an array of tasks")
+		}
+		return &jsonReader{dec: dec, array: true, keys: map[string]bool{}}, nil
+	case FormatNDJSON:
+		dec := json.NewDecoder(r)
+		dec.UseNumber()
+		return &jsonReader{dec: dec, keys: map[string]bool{}}, nil
+	}
+	return nil, ErrInvalidFormat
+}
+
+type csvReader struct {
+	cr     *csv.Reader
+	header []string
+}
+
+func (r *csvReader) next() (map[string]any, error) {
+	for {
+		row, err := r.cr.Read()
+		if err != nil {
+			return nil, err
+		}
+		// Blank lines are skipped by the csv package; rows of empty cells
+		// are skipped here.
+		if strings.TrimSpace(strings.Join(row, "")) == "" {
+			continue
+		}
+		rec := map[string]any{}
+		for i, column := range r.header {
+			if i < len(row) {
+				rec[column] = row[i]
+			}
+		}
+		return rec, nil
+	}
+}
+
+func (r *csvReader) columns() []string {
+	return r.header
+}
+
+type jsonReader struct {
+	dec   *json.Decoder
+	array bool
+	keys  map[string]bool
+}
+
+func (r *jsonReader) next() (map[string]any, error) {
+	if r.array && !r.dec.More() {
+		if _, err := r.dec.Token(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	var rec map[string]any
+	if err := r.dec.Decode(&rec); err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		return nil, errors.New("expected a task object")
+	}
+	for key := range rec {
+		r.keys[key] = true
+	}
+	return rec, nil
+}
+
+func (r *jsonReader) columns() []string {
+	keys := make([]string, 0, len(r.keys))
+	for key := range r.keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,183 @@
+package transfer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/authz"
+	"github.com/sudarshanmg/gotask/pkg/response"
+)
+
+const (
+	// maxImportBytes bounds an uploaded import file.
+	maxImportBytes = 20 << 20
+	// importMemory is how much of an upload is kept in memory; the rest
+	// goes to a temporary file.
+	importMemory = 4 << 20
+)
+
+type Handler struct {
+	service TransferService
+	policy  authz.Policy
+}
+
+func NewHandler(service TransferService, policy authz.Policy) *Handler {
+	return &Handler{service: service, policy: policy}
+}
+
+var taskResource = authz.Resource{Type: authz.ResourceTask}
+
+func writeServiceError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidFormat), errors.Is(err, ErrInvalidFile), errors.Is(err, ErrInvalidMapping),
+		strings.HasPrefix(err.Error(), "validation failed:"):
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrTooManyRows):
+		response.WriteError(w, http.StatusRequestEntityTooLarge, err.Error())
+	default:
+		response.WriteError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+var contentTypes = map[Format]string{
+	FormatCSV:    "text/csv; charset=utf-8",
+	FormatJSON:   "application/json",
+	FormatNDJSON: "application/x-ndjson",
+}
+
+// exportWriter sends the response headers with the first bytes of the file,
+// so that an error before then can still be reported as such.
+type exportWriter struct {
+	w       http.ResponseWriter
+	format  Format
+	started bool
+}
+
+func (e *exportWriter) Write(p []byte) (int, error) {
+	if !e.started {
+		e.started = true
+		e.w.Header().Set("Content-Type", contentTypes[e.format])
+		e.w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="tasks.%s"`, e.format))
+		e.w.WriteHeader(http.StatusOK)
+	}
+	return e.w.Write(p)
+}
+
+// Export streams every task the caller can read as ?format=csv|json|ndjson
+// (default json). ?include=comments adds each task's comments.
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) {
+	if !authz.Authorize(w, r, h.policy, authz.ActionRead, taskResource) {
+		return
+	}
+	q := r.URL.Query()
+	opts := ExportOptions{Format: Format(q.Get("format"))}
+	if opts.Format == "" {
+		opts.Format = FormatJSON
+	}
+	for _, include := range strings.Split(q.Get("include"), ",") {
+		opts.Comments = opts.Comments || strings.TrimSpace(include) == "comments"
+	}
+
+	out := &exportWriter{w: w, format: opts.Format}
+	if err := h.service.Export(auth.GetCaller(r), opts, out); err != nil {
+		if !out.started {
+			writeServiceError(w, err, "failed to export tasks")
+			return
+		}
+		// The client sees a truncated file.
+		log.Printf("transfer: export for user %d failed: %v", auth.GetCaller(r).UserID, err)
+	}
+}
+
+// upload reads the multipart form of an import: the file in "file" and
+// its format in "format", or else from the file's extension.
+func upload(w http.ResponseWriter, r *http.Request) (multipart.File, Format, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	if err := r.ParseMultipartForm(importMemory); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "expected a multipart form with a file")
+		return nil, "", false
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "file is required")
+		return nil, "", false
+	}
+
+	format := Format(r.FormValue("format"))
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(header.Filename)) {
+		case ".csv":
+			format = FormatCSV
+		case ".json":
+			format = FormatJSON
+		case ".ndjson", ".jsonl":
+			format = FormatNDJSON
+		}
+	}
+	return file, format, true
+}
+
+// Preview reads the first records of an uploaded file and suggests a
+// mapping for it.
+func (h *Handler) Preview(w http.ResponseWriter, r *http.Request) {
+	if !authz.Authorize(w, r, h.policy, authz.ActionCreate, taskResource) {
+		return
+	}
+	file, format, ok := upload(w, r)
+	if !ok {
+		return
+	}
+	defer file.Close()
+	defer r.MultipartForm.RemoveAll()
+
+	preview, err := h.service.Preview(auth.GetCaller(r), format, file)
+	if err != nil {
+		writeServiceError(w, err, "failed to read import file")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, preview)
+}
+
+// Import creates tasks from an uploaded file. "mapping" is a JSON object
+// from task fields to columns, defaulting to the suggested one, and
+// "dry_run=true" only checks the rows.
+func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
+	if !authz.Authorize(w, r, h.policy, authz.ActionCreate, taskResource) {
+		return
+	}
+	file, format, ok := upload(w, r)
+	if !ok {
+		return
+	}
+	defer file.Close()
+	defer r.MultipartForm.RemoveAll()
+
+	var mapping Mapping
+	if raw := r.FormValue("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+			response.WriteError(w, http.StatusBadRequest, "invalid mapping")
+			return
+		}
+	}
+	dryRun := r.FormValue("dry_run") == "true"
+
+	report, err := h.service.Import(auth.GetCaller(r), format, file, mapping, dryRun)
+	if err != nil {
+		writeServiceError(w, err, "failed to import tasks")
+		return
+	}
+
+	status := http.StatusCreated
+	if dryRun {
+		status = http.StatusOK
+	}
+	response.WriteJSON(w, status, report)
+}
@@ -0,0 +1,229 @@
+package transfer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sudarshanmg/gotask/internal/task"
+)
+
+// fields are the task fields an import can set.
+var fields = []string{"external_id", "title", "description", "completed", "project_id", "status_id",
+	"sprint_id", "milestone_id", "story_points", "priority", "assignee_ids", "labels", "start_at", "due_at",
+	"estimate_minutes"}
+
+func normalizeColumn(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+}
+
+// suggest maps each field to the column of the same name, ignoring case,
+// spaces and dashes. Without an external_id column, the external ID is read
+// from an id column.
+func suggest(columns []string) Mapping {
+	byName := map[string]string{}
+	for _, c := range columns {
+		if _, ok := byName[normalizeColumn(c)]; !ok {
+			byName[normalizeColumn(c)] = c
+		}
+	}
+
+	mapping := Mapping{}
+	for _, f := range fields {
+		if c, ok := byName[f]; ok {
+			mapping[f] = c
+		}
+	}
+	if _, ok := mapping["external_id"]; !ok {
+		if c, ok := byName["id"]; ok {
+			mapping["external_id"] = c
+		}
+	}
+	return mapping
+}
+
+// checkMapping makes sure every mapped field exists and a title is mapped.
+func checkMapping(mapping Mapping) error {
+	known := map[string]bool{}
+	for _, f := range fields {
+		known[f] = true
+	}
+	for f, c := range mapping {
+		if !known[f] {
+			return fmt.Errorf("%w: unknown field %q", ErrInvalidMapping, f)
+		}
+		if c == "" {
+			return fmt.Errorf("%w: no column for %q", ErrInvalidMapping, f)
+		}
+	}
+	if mapping["title"] == "" {
+		return fmt.Errorf("%w: title must be mapped", ErrInvalidMapping)
+	}
+	return nil
+}
+
+// text reads a scalar value as a string; "" means the value is missing.
+func text(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return strings.TrimSpace(v), nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", fmt.Errorf("expected a single value")
+}
+
+// list reads a JSON array, or a string separated by semicolons or commas.
+func list(v any) ([]string, error) {
+	items, ok := v.([]any)
+	if !ok {
+		s, err := text(v)
+		if err != nil {
+			return nil, err
+		}
+		items = []any{}
+		for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ';' || r == ',' }) {
+			items = append(items, part)
+		}
+	}
+
+	values := []string{}
+	for _, item := range items {
+		s, err := text(item)
+		if err != nil {
+			return nil, err
+		}
+		if s != "" {
+			values = append(values, s)
+		}
+	}
+	return values, nil
+}
+
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "", "0", "false", "no", "n":
+		return false, nil
+	case "1", "true", "yes", "y", "x", "done":
+		return true, nil
+	}
+	return false, fmt.Errorf("expected true or false")
+}
+
+// Dates and times without a zone are read in UTC.
+var timeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04",
+	"2006-01-02"}
+
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("expected an RFC 3339 time or a YYYY-MM-DD date")
+}
+
+// convert reads a record through the mapping. Missing and empty values
+// leave the field unset; the task service validates the rest.
+func convert(rec map[string]any, mapping Mapping) (task.ImportTaskRequest, error) {
+	req := task.ImportTaskRequest{}
+	for _, field := range fields {
+		column, ok := mapping[field]
+		if !ok {
+			continue
+		}
+		if err := setField(&req, field, rec[column]); err != nil {
+			return req, fmt.Errorf("%s: %v", field, err)
+		}
+	}
+	return req, nil
+}
+
+func setField(req *task.ImportTaskRequest, field string, v any) error {
+	t := &req.Task
+	switch field {
+	case "labels":
+		labels, err := list(v)
+		if err != nil {
+			return err
+		}
+		t.Labels = labels
+		return nil
+	case "assignee_ids":
+		ids, err := list(v)
+		if err != nil {
+			return err
+		}
+		for _, s := range ids {
+			id, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return fmt.Errorf("expected user IDs")
+			}
+			t.AssigneeIDs = append(t.AssigneeIDs, id)
+		}
+		return nil
+	}
+
+	s, err := text(v)
+	if err != nil || s == "" {
+		return err
+	}
+	switch field {
+	case "external_id":
+		req.ExternalID = s
+	case "title":
+		t.Title = s
+	case "description":
+		t.Description = s
+	case "story_points":
+		t.StoryPoints = &s
+	case "completed":
+		req.Completed, err = parseBool(s)
+	case "project_id", "status_id", "sprint_id", "milestone_id":
+		id, perr := strconv.ParseInt(s, 10, 64)
+		if perr != nil {
+			return fmt.Errorf("expected an ID")
+		}
+		switch field {
+		case "project_id":
+			t.ProjectID = &id
+		case "status_id":
+			t.StatusID = &id
+		case "sprint_id":
+			t.SprintID = &id
+		case "milestone_id":
+			t.MilestoneID = &id
+		}
+	case "estimate_minutes":
+		minutes, perr := strconv.Atoi(s)
+		if perr != nil {
+			return fmt.Errorf("expected a number of minutes")
+		}
+		t.EstimateMinutes = &minutes
+	case "priority":
+		// Either 1 to 4 or p1 to p4.
+		priority, perr := strconv.Atoi(strings.TrimPrefix(strings.ToLower(s), "p"))
+		if perr != nil {
+			return fmt.Errorf("expected a priority from 1 to 4")
+		}
+		t.Priority = &priority
+	case "start_at", "due_at":
+		at, perr := parseTime(s)
+		if perr != nil {
+			return perr
+		}
+		if field == "start_at" {
+			t.StartAt = &at
+		} else {
+			t.DueAt = &at
+		}
+	}
+	return err
+}
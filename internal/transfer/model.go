@@ -0,0 +1,102 @@
+package transfer
+
+import (
+	"time"
+)
+
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatJSON   Format = "json"
+	FormatNDJSON Format = "ndjson"
+)
+
+func (f Format) valid() bool {
+	return f == FormatCSV || f == FormatJSON || f == FormatNDJSON
+}
+
+// Record is an exported task. ExternalID is the ID the task was imported
+// with or, for a task created here, its own ID, so that importing an export
+// again does not duplicate tasks.
+type Record struct {
+	ID              int64      `json:"id"`
+	ExternalID      string     `json:"external_id"`
+	Title           string     `json:"title"`
+	Description     string     `json:"description"`
+	Completed       bool       `json:"completed"`
+	ProjectID       *int64     `json:"project_id"`
+	Project         *string    `json:"project"`
+	Status          *string    `json:"status"`
+	Labels          []string   `json:"labels"`
+	AssigneeIDs     []int64    `json:"assignee_ids"`
+	StartAt         *time.Time `json:"start_at"`
+	DueAt           *time.Time `json:"due_at"`
+	CompletedAt     *time.Time `json:"completed_at"`
+	EstimateMinutes *int       `json:"estimate_minutes"`
+	StoryPoints     *string    `json:"story_points"`
+	Priority        *int       `json:"priority"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	// Comments are only exported when asked for.
+	Comments []Comment `json:"comments,omitempty"`
+}
+
+type Comment struct {
+	AuthorID  *int64    `json:"author_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportOptions struct {
+	Format   Format
+	Comments bool
+}
+
+// Mapping names, for each task field, the CSV column or JSON key it is read
+// from.
+type Mapping map[string]string
+
+// Preview describes an import file so that the caller can choose a
+// mapping before importing it.
+type Preview struct {
+	Format  Format   `json:"format"`
+	Columns []string `json:"columns"`
+	// Fields lists the task fields that can be mapped, and Mapping suggests
+	// a column for each field with a column of the same name.
+	Fields  []string         `json:"fields"`
+	Mapping Mapping          `json:"mapping"`
+	Sample  []map[string]any `json:"sample"`
+}
+
+type RowStatus string
+
+const (
+	RowCreated RowStatus = "created"
+	// RowValid is reported instead of RowCreated on a dry run.
+	RowValid RowStatus = "valid"
+	// RowExisting rows were imported before under the same external ID.
+	RowExisting RowStatus = "existing"
+	RowInvalid  RowStatus = "invalid"
+)
+
+// RowResult reports one row of an import; Row counts records from 1,
+// without the CSV header.
+type RowResult struct {
+	Row        int       `json:"row"`
+	ExternalID string    `json:"external_id,omitempty"`
+	Status     RowStatus `json:"status"`
+	TaskID     *int64    `json:"task_id,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun   bool        `json:"dry_run"`
+	Format   Format      `json:"format"`
+	Mapping  Mapping     `json:"mapping"`
+	Total    int         `json:"total"`
+	Created  int         `json:"created"`
+	Existing int         `json:"existing"`
+	Invalid  int         `json:"invalid"`
+	Rows     []RowResult `json:"rows"`
+}
@@ -0,0 +1,81 @@
+package transfer
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/lib/pq"
+	"github.com/sudarshanmg/gotask/pkg/db"
+)
+
+type TransferRepository interface {
+	// Export calls fn with every task the user can read, in ID order, as
+	// the rows arrive from the database.
+	Export(workspaceID, userID int64, comments bool, fn func(*Record) error) error
+}
+
+type PostgresTransferRepository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) TransferRepository {
+	return &PostgresTransferRepository{DB: db}
+}
+
+func (r *PostgresTransferRepository) Export(workspaceID, userID int64, comments bool, fn func(*Record) error) error {
+	// Comment times are stored in UTC.
+	query := `
+          SELECT t.id, COALESCE(t.external_id, t.id::text), t.title, t.description, t.completed,
+                 t.project_id, p.name, s.name, t.labels,
+                 ARRAY(SELECT a.user_id FROM task_assignees a WHERE a.task_id = t.id ORDER BY a.user_id),
+                 t.start_at, t.due_at, t.completed_at, t.estimate_minutes, t.story_points,
+                 t.priority, t.created_at, t.updated_at,
+                 CASE WHEN $3 THEN (
+                   SELECT COALESCE(json_agg(json_build_object(
+                            'author_id', c.author_id, 'body', c.body,
+                            'created_at', to_char(c.created_at, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'))
+                          ORDER BY c.created_at, c.id), '[]')
+                   FROM task_comments c WHERE c.task_id = t.id) END
+          FROM tasks t
+          LEFT JOIN projects p ON p.id = t.project_id
+          LEFT JOIN project_statuses s ON s.id = t.status_id
+          WHERE t.workspace_id = $1
+            AND ((t.project_id IS NULL AND t.created_by = $2)
+              OR t.project_id IN (SELECT project_id FROM project_members WHERE user_id = $2))
+          ORDER BY t.id;`
+
+	return db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, workspaceID, userID, comments)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			rec := Record{}
+			var rawComments []byte
+			err := rows.Scan(&rec.ID, &rec.ExternalID, &rec.Title, &rec.Description, &rec.Completed,
+				&rec.ProjectID, &rec.Project, &rec.Status, pq.Array(&rec.Labels), pq.Array(&rec.AssigneeIDs),
+				&rec.StartAt, &rec.DueAt, &rec.CompletedAt, &rec.EstimateMinutes, &rec.StoryPoints,
+				&rec.Priority, &rec.CreatedAt, &rec.UpdatedAt, &rawComments)
+			if err != nil {
+				return err
+			}
+			if rec.Labels == nil {
+				rec.Labels = []string{}
+			}
+			if rec.AssigneeIDs == nil {
+				rec.AssigneeIDs = []int64{}
+			}
+			if comments {
+				if err := json.Unmarshal(rawComments, &rec.Comments); err != nil {
+					return err
+				}
+			}
+			if err := fn(&rec); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	})
+}
@@ -0,0 +1,11 @@
+package transfer
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Get("/export", h.Export)
+	r.Post("/import", h.Import)
+	r.Post("/import/preview", h.Preview)
+}
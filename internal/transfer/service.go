@@ -0,0 +1,198 @@
+package transfer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/task"
+)
+
+const (
+	// maxImportRows bounds the records of one import.
+	maxImportRows = 5000
+	// previewRows is the number of records shown by a preview.
+	previewRows = 5
+)
+
+type TransferService interface {
+	// Export streams every task the caller can read to w.
+	Export(caller auth.Caller, opts ExportOptions, w io.Writer) error
+	Preview(caller auth.Caller, format Format, file io.Reader) (*Preview, error)
+	// Import creates a task for each record of file through the task
+	// service. A nil mapping uses the suggested one.
+	Import(caller auth.Caller, format Format, file io.Reader, mapping Mapping, dryRun bool) (*ImportReport, error)
+}
+
+type transferService struct {
+	repo  TransferRepository
+	tasks task.TaskService
+}
+
+func NewService(repo TransferRepository, tasks task.TaskService) TransferService {
+	return &transferService{repo: repo, tasks: tasks}
+}
+
+func (s *transferService) Export(caller auth.Caller, opts ExportOptions, w io.Writer) error {
+	if !opts.Format.valid() {
+		return ErrInvalidFormat
+	}
+
+	exp := newExporter(opts.Format, w, opts.Comments)
+	err := s.repo.Export(caller.WorkspaceID, caller.UserID, opts.Comments, exp.write)
+	if err != nil {
+		return err
+	}
+	return exp.close()
+}
+
+func invalidFile(row int, err error) error {
+	if row == 0 {
+		return fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	return fmt.Errorf("%w: row %d: %v", ErrInvalidFile, row, err)
+}
+
+func (s *transferService) Preview(caller auth.Caller, format Format, file io.Reader) (*Preview, error) {
+	if !format.valid() {
+		return nil, ErrInvalidFormat
+	}
+	reader, err := newReader(format, file)
+	if err != nil {
+		return nil, invalidFile(0, err)
+	}
+
+	sample := []map[string]any{}
+	for len(sample) < previewRows {
+		rec, err := reader.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, invalidFile(len(sample)+1, err)
+		}
+		sample = append(sample, rec)
+	}
+
+	columns := reader.columns()
+	if columns == nil {
+		columns = []string{}
+	}
+	return &Preview{
+		Format:  format,
+		Columns: columns,
+		Fields:  fields,
+		Mapping: suggest(columns),
+		Sample:  sample,
+	}, nil
+}
+
+// Import creates tasks as it reads the file. Invalid rows are reported and
+// skipped; a file that cannot be read fails the import after the rows
+// before the problem were created, which importing the fixed file again
+// leaves alone when the rows have external IDs.
+func (s *transferService) Import(caller auth.Caller, format Format, file io.Reader, mapping Mapping,
+	dryRun bool) (*ImportReport, error) {
+	if !format.valid() {
+		return nil, ErrInvalidFormat
+	}
+	if mapping != nil {
+		if err := checkMapping(mapping); err != nil {
+			return nil, err
+		}
+	}
+	reader, err := newReader(format, file)
+	if err != nil {
+		return nil, invalidFile(0, err)
+	}
+
+	report := &ImportReport{DryRun: dryRun, Format: format, Mapping: mapping, Rows: []RowResult{}}
+	// seen catches repeated external IDs within a dry run, where the first
+	// row is not saved.
+	seen := map[string]int64{}
+	for row := 1; ; row++ {
+		rec, err := reader.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, invalidFile(row, err)
+		}
+		if row > maxImportRows {
+			return nil, ErrTooManyRows
+		}
+		if report.Mapping == nil {
+			report.Mapping = suggest(reader.columns())
+			if err := checkMapping(report.Mapping); err != nil {
+				return nil, err
+			}
+		}
+
+		result, err := s.importRow(caller, rec, report.Mapping, dryRun, seen)
+		if err != nil {
+			return nil, err
+		}
+		result.Row = row
+		report.Total++
+		switch result.Status {
+		case RowCreated, RowValid:
+			report.Created++
+		case RowExisting:
+			report.Existing++
+		case RowInvalid:
+			report.Invalid++
+		}
+		report.Rows = append(report.Rows, result)
+	}
+	if report.Mapping == nil {
+		report.Mapping = suggest(reader.columns())
+	}
+	return report, nil
+}
+
+// importRow imports one record. Problems with the record are reported in
+// the result; only unexpected errors are returned.
+func (s *transferService) importRow(caller auth.Caller, rec map[string]any, mapping Mapping, dryRun bool,
+	seen map[string]int64) (RowResult, error) {
+	req, err := convert(rec, mapping)
+	result := RowResult{ExternalID: req.ExternalID}
+	if err != nil {
+		result.Status = RowInvalid
+		result.Error = err.Error()
+		return result, nil
+	}
+	if id, ok := seen[req.ExternalID]; ok && req.ExternalID != "" {
+		result.Status = RowExisting
+		if id != 0 {
+			result.TaskID = &id
+		}
+		return result, nil
+	}
+
+	res, created, err := s.tasks.Import(caller, req, dryRun)
+	if err != nil {
+		if !task.IsClientError(err) {
+			return result, err
+		}
+		result.Status = RowInvalid
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	var id int64
+	if res != nil {
+		id = res.ID
+		result.TaskID = &id
+	}
+	seen[req.ExternalID] = id
+	switch {
+	case !created:
+		result.Status = RowExisting
+	case dryRun:
+		result.Status = RowValid
+	default:
+		result.Status = RowCreated
+	}
+	return result, nil
+}
@@ -0,0 +1,9 @@
+package webhook
+
+import "errors"
+
+var (
+	ErrNotFound         = errors.New("webhook not found")
+	ErrInvalidID        = errors.New("invalid webhook ID")
+	ErrDeliveryNotFound = errors.New("delivery not found")
+)
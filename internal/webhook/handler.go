@@ -0,0 +1,182 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/pkg/response"
+)
+
+type Handler struct {
+	service WebhookService
+}
+
+func NewHandler(service WebhookService) *Handler {
+	return &Handler{service: service}
+}
+
+func webhookID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return 0, false
+	}
+	return id, true
+}
+
+func writeServiceError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidID):
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrDeliveryNotFound):
+		response.WriteError(w, http.StatusNotFound, err.Error())
+	default:
+		response.WriteError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+func (h *Handler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var req CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	webhook, err := h.service.Create(auth.GetCaller(r), req)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, webhook)
+}
+
+func (h *Handler) GetAllWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := h.service.GetAll(auth.GetCaller(r))
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch webhooks")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, webhooks)
+}
+
+func (h *Handler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	webhook, err := h.service.GetById(auth.GetCaller(r), id)
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch webhook")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, webhook)
+}
+
+func (h *Handler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	webhook, err := h.service.Update(auth.GetCaller(r), id, req)
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrInvalidID) {
+		writeServiceError(w, err, "")
+		return
+	}
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, webhook)
+}
+
+func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(auth.GetCaller(r), id); err != nil {
+		writeServiceError(w, err, "failed to delete webhook")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{"message": "webhook deleted successfully"})
+}
+
+func (h *Handler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	filter := DeliveryFilter{}
+	switch status := DeliveryStatus(r.URL.Query().Get("status")); status {
+	case "":
+	case StatusPending, StatusSucceeded, StatusDead:
+		filter.Status = &status
+	default:
+		response.WriteError(w, http.StatusBadRequest, "status must be pending, succeeded or dead")
+		return
+	}
+
+	deliveries, total, totalPages, err := h.service.Deliveries(auth.GetCaller(r), id, page, limit, filter)
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch deliveries")
+		return
+	}
+
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	w.Header().Set("X-Total-Pages", strconv.Itoa(totalPages))
+
+	response.WriteJSON(w, http.StatusOK, deliveries)
+}
+
+func (h *Handler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := strconv.ParseInt(chi.URLParam(r, "deliveryID"), 10, 64)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid delivery ID format")
+		return
+	}
+
+	delivery, err := h.service.Redeliver(auth.GetCaller(r), id, deliveryID)
+	if err != nil {
+		writeServiceError(w, err, "failed to redeliver")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusAccepted, delivery)
+}
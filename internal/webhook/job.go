@@ -0,0 +1,17 @@
+package webhook
+
+// Job sends due webhook deliveries. Every replica may run it: deliveries
+// are claimed with FOR UPDATE SKIP LOCKED, so each attempt is made by a
+// single server.
+type Job struct {
+	Service WebhookService
+	Batch   int
+}
+
+func (j *Job) Name() string {
+	return "webhook deliveries"
+}
+
+func (j *Job) Run() (int, error) {
+	return j.Service.ProcessDue(j.Batch)
+}
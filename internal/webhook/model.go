@@ -0,0 +1,88 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/sudarshanmg/gotask/internal/task"
+)
+
+// Webhook is an endpoint that receives task events. Secret is only filled
+// in, and returned, when the webhook is created.
+type Webhook struct {
+	ID          int64            `json:"id"`
+	WorkspaceID int64            `json:"workspace_id"`
+	CreatedBy   int64            `json:"created_by"`
+	URL         string           `json:"url"`
+	Secret      string           `json:"secret,omitempty"`
+	Events      []task.EventType `json:"events"`
+	Active      bool             `json:"active"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+type DeliveryStatus string
+
+const (
+	StatusPending   DeliveryStatus = "pending"
+	StatusSucceeded DeliveryStatus = "succeeded"
+	StatusDead      DeliveryStatus = "dead"
+)
+
+type Delivery struct {
+	ID             int64          `json:"id"`
+	WebhookID      int64          `json:"webhook_id"`
+	EventID        string         `json:"event_id"`
+	EventType      task.EventType `json:"event_type"`
+	Payload        string         `json:"payload"`
+	Status         DeliveryStatus `json:"status"`
+	Attempts       int            `json:"attempts"`
+	NextAttemptAt  time.Time      `json:"next_attempt_at"`
+	ResponseStatus *int           `json:"response_status"`
+	LastError      string         `json:"last_error"`
+	DeliveredAt    *time.Time     `json:"delivered_at"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+// Target is a claimed delivery together with where and how to send it.
+type Target struct {
+	Delivery Delivery
+	URL      string
+	Secret   string
+}
+
+// Result is the outcome of one delivery attempt.
+type Result struct {
+	StatusCode int
+	Err        error
+}
+
+func (r Result) OK() bool {
+	return r.Err == nil && r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+// errorMessage is what the delivery log keeps of a failed attempt.
+func (r Result) errorMessage() string {
+	if r.Err == nil {
+		return ""
+	}
+	message := []rune(r.Err.Error())
+	if len(message) > maxErrorLength {
+		message = message[:maxErrorLength]
+	}
+	return string(message)
+}
+
+type CreateWebhookRequest struct {
+	URL    string           `json:"url" validate:"required,http_url,max=2000"`
+	Events []task.EventType `json:"events" validate:"required,min=1,dive,oneof=task.created task.updated task.completed task.deleted"`
+}
+
+type UpdateWebhookRequest struct {
+	URL    *string           `json:"url" validate:"omitempty,http_url,max=2000"`
+	Events *[]task.EventType `json:"events" validate:"omitempty,min=1,dive,oneof=task.created task.updated task.completed task.deleted"`
+	Active *bool             `json:"active"`
+}
+
+type DeliveryFilter struct {
+	Status *DeliveryStatus
+}
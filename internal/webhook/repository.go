@@ -0,0 +1,317 @@
+package webhook
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sudarshanmg/gotask/internal/task"
+)
+
+const (
+	// maxAttempts is how many times a delivery is tried before it is
+	// dead-lettered.
+	maxAttempts = 8
+	maxBackoff  = 6 * time.Hour
+
+	webhookColumns = `id, workspace_id, created_by, url, events, active, created_at, updated_at`
+
+	deliveryColumns = `d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+            d.next_attempt_at, d.response_status, d.last_error, d.delivered_at, d.created_at`
+)
+
+// WebhookRepository scopes webhooks to the workspace and the user who
+// created them.
+type WebhookRepository interface {
+	Create(webhook *Webhook) error
+	FindForUser(workspaceID, userID int64) ([]Webhook, error)
+	FindById(workspaceID, userID, id int64) (*Webhook, error)
+	Update(webhook *Webhook) error
+	Delete(workspaceID, userID, id int64) error
+	// Enqueue records a pending delivery of the event for every active
+	// webhook in the event's workspace that subscribes to it and whose
+	// creator can see the task.
+	Enqueue(event task.Event, payload []byte) (int64, error)
+	FindDeliveries(webhookID int64, filter DeliveryFilter, offset, limit int) ([]Delivery, error)
+	CountDeliveries(webhookID int64, filter DeliveryFilter) (int64, error)
+	Redeliver(webhookID, deliveryID int64) (*Delivery, error)
+	// ProcessDue claims up to limit due deliveries and hands each to send.
+	// Claimed rows stay locked until the batch commits, so concurrent
+	// callers on other replicas skip them.
+	ProcessDue(limit int, send func(t Target) Result) (int, error)
+}
+
+type PostgresWebhookRepository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) WebhookRepository {
+	return &PostgresWebhookRepository{DB: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWebhook(row rowScanner, w *Webhook) error {
+	var events []string
+	err := row.Scan(&w.ID, &w.WorkspaceID, &w.CreatedBy, &w.URL, pq.Array(&events), &w.Active, &w.CreatedAt,
+		&w.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	w.Events = make([]task.EventType, len(events))
+	for i, e := range events {
+		w.Events[i] = task.EventType(e)
+	}
+	return nil
+}
+
+func scanDelivery(row rowScanner, d *Delivery) error {
+	return row.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.ResponseStatus, &d.LastError, &d.DeliveredAt, &d.CreatedAt)
+}
+
+func eventNames(events []task.EventType) []string {
+	names := make([]string, len(events))
+	for i, e := range events {
+		names[i] = string(e)
+	}
+	return names
+}
+
+func (r *PostgresWebhookRepository) Create(w *Webhook) error {
+	query := `INSERT INTO webhooks (workspace_id, created_by, url, secret, events)
+            VALUES ($1, $2, $3, $4, $5)
+            RETURNING id, active, created_at, updated_at;`
+
+	return r.DB.QueryRow(query, w.WorkspaceID, w.CreatedBy, w.URL, w.Secret, pq.Array(eventNames(w.Events))).
+		Scan(&w.ID, &w.Active, &w.CreatedAt, &w.UpdatedAt)
+}
+
+func (r *PostgresWebhookRepository) FindForUser(workspaceID, userID int64) ([]Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks
+            WHERE workspace_id = $1 AND created_by = $2
+            ORDER BY id;`
+
+	rows, err := r.DB.Query(query, workspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []Webhook{}
+	for rows.Next() {
+		w := Webhook{}
+		if err := scanWebhook(rows, &w); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, rows.Err()
+}
+
+func (r *PostgresWebhookRepository) FindById(workspaceID, userID, id int64) (*Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks
+            WHERE workspace_id = $1 AND created_by = $2 AND id = $3;`
+
+	w := &Webhook{}
+	err := scanWebhook(r.DB.QueryRow(query, workspaceID, userID, id), w)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (r *PostgresWebhookRepository) Update(w *Webhook) error {
+	query := `UPDATE webhooks SET url = $1, events = $2, active = $3, updated_at = NOW()
+            WHERE workspace_id = $4 AND created_by = $5 AND id = $6
+            RETURNING updated_at;`
+
+	err := r.DB.QueryRow(query, w.URL, pq.Array(eventNames(w.Events)), w.Active, w.WorkspaceID, w.CreatedBy,
+		w.ID).Scan(&w.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (r *PostgresWebhookRepository) Delete(workspaceID, userID, id int64) error {
+	query := `DELETE FROM webhooks WHERE workspace_id = $1 AND created_by = $2 AND id = $3;`
+
+	res, err := r.DB.Exec(query, workspaceID, userID, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresWebhookRepository) Enqueue(event task.Event, payload []byte) (int64, error) {
+	// Mirrors task visibility: personal tasks are visible to their creator,
+	// project tasks to project members. An event with an audience is only
+	// delivered to it, because the memberships may be gone.
+	query := `INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+            SELECT w.id, $3, $2, $6
+            FROM webhooks w
+            WHERE w.workspace_id = $1 AND w.active AND $2 = ANY(w.events)
+              AND (w.created_by = ANY($7)
+                OR ($7 IS NULL AND $4::bigint IS NULL AND w.created_by = $5)
+                OR ($7 IS NULL AND EXISTS (SELECT 1 FROM project_members pm
+                                          WHERE pm.project_id = $4 AND pm.user_id = w.created_by)))
+            ON CONFLICT (webhook_id, event_id) DO NOTHING;`
+
+	res, err := r.DB.Exec(query, event.WorkspaceID, string(event.Type), event.ID, event.Task.ProjectID,
+		event.Task.CreatedBy, string(payload), pq.Array(event.Audience))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (r *PostgresWebhookRepository) FindDeliveries(webhookID int64, filter DeliveryFilter, offset, limit int) ([]Delivery, error) {
+	query := `SELECT ` + deliveryColumns + `
+          FROM webhook_deliveries d
+          WHERE d.webhook_id = $1 AND ($2::text IS NULL OR d.status = $2)
+          ORDER BY d.id DESC
+          LIMIT $3 OFFSET $4;`
+
+	rows, err := r.DB.Query(query, webhookID, filter.Status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		d := Delivery{}
+		if err := scanDelivery(rows, &d); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *PostgresWebhookRepository) CountDeliveries(webhookID int64, filter DeliveryFilter) (int64, error) {
+	query := `SELECT COUNT(*) FROM webhook_deliveries d
+            WHERE d.webhook_id = $1 AND ($2::text IS NULL OR d.status = $2);`
+
+	var count int64
+	err := r.DB.QueryRow(query, webhookID, filter.Status).Scan(&count)
+	return count, err
+}
+
+// Redeliver queues the delivery again with a fresh set of attempts, whatever
+// its current status.
+func (r *PostgresWebhookRepository) Redeliver(webhookID, deliveryID int64) (*Delivery, error) {
+	query := `UPDATE webhook_deliveries d
+            SET status = 'pending', attempts = 0, next_attempt_at = NOW(), last_error = ''
+            WHERE d.webhook_id = $1 AND d.id = $2
+            RETURNING ` + deliveryColumns + `;`
+
+	d := &Delivery{}
+	err := scanDelivery(r.DB.QueryRow(query, webhookID, deliveryID), d)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// backoff is the wait before retry number attempts+1: one minute, doubling
+// each time, capped at maxBackoff.
+func backoff(attempts int) time.Duration {
+	if attempts > 12 {
+		return maxBackoff
+	}
+	return min(time.Duration(1<<attempts)*time.Minute, maxBackoff)
+}
+
+func (r *PostgresWebhookRepository) ProcessDue(limit int, send func(t Target) Result) (int, error) {
+	claim := `SELECT ` + deliveryColumns + `, w.url, w.secret
+          FROM webhook_deliveries d
+          JOIN webhooks w ON w.id = d.webhook_id
+          WHERE d.status = 'pending' AND d.next_attempt_at <= NOW() AND w.active
+          ORDER BY d.next_attempt_at
+          LIMIT $1
+          FOR UPDATE OF d SKIP LOCKED;`
+
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(claim, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	batch := []Target{}
+	for rows.Next() {
+		t := Target{}
+		d := &t.Delivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.ResponseStatus, &d.LastError, &d.DeliveredAt, &d.CreatedAt,
+			&t.URL, &t.Secret); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, t := range batch {
+		result := send(t)
+
+		var statusCode *int
+		if result.StatusCode != 0 {
+			statusCode = &result.StatusCode
+		}
+		lastError := result.errorMessage()
+
+		switch {
+		case result.OK():
+			_, err = tx.Exec(`UPDATE webhook_deliveries
+                    SET status = 'succeeded', attempts = attempts + 1, delivered_at = NOW(),
+                        response_status = $2, last_error = ''
+                    WHERE id = $1;`, t.Delivery.ID, statusCode)
+		case t.Delivery.Attempts+1 >= maxAttempts:
+			_, err = tx.Exec(`UPDATE webhook_deliveries
+                    SET status = 'dead', attempts = attempts + 1,
+                        response_status = $2, last_error = $3
+                    WHERE id = $1;`, t.Delivery.ID, statusCode, lastError)
+		default:
+			_, err = tx.Exec(`UPDATE webhook_deliveries
+                    SET attempts = attempts + 1, next_attempt_at = NOW() + $4 * interval '1 second',
+                        response_status = $2, last_error = $3
+                    WHERE id = $1;`, t.Delivery.ID, statusCode, lastError,
+				int64(backoff(t.Delivery.Attempts)/time.Second))
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(batch), nil
+}
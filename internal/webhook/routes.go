@@ -0,0 +1,17 @@
+package webhook
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Route("/webhooks", func(r chi.Router) {
+		r.Get("/", h.GetAllWebhooks)
+		r.Post("/", h.CreateWebhook)
+		r.Get("/{id}", h.GetWebhook)
+		r.Put("/{id}", h.UpdateWebhook)
+		r.Delete("/{id}", h.DeleteWebhook)
+		r.Get("/{id}/deliveries", h.ListDeliveries)
+		r.Post("/{id}/deliveries/{deliveryID}/redeliver", h.Redeliver)
+	})
+}
@@ -0,0 +1,195 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/internal/task"
+	"github.com/sudarshanmg/gotask/pkg/outbound"
+	"github.com/sudarshanmg/gotask/pkg/validation"
+)
+
+var validate = validator.New()
+
+// maxErrorLength is how much of an attempt's error is kept in the delivery
+// log. Response bodies are not kept at all, so a webhook cannot be used to
+// read what an endpoint returns.
+const maxErrorLength = 500
+
+// WebhookService manages the caller's webhooks. It also implements
+// task.EventPublisher, queueing a delivery for every matching webhook.
+type WebhookService interface {
+	Create(caller auth.Caller, req CreateWebhookRequest) (*Webhook, error)
+	GetAll(caller auth.Caller) ([]Webhook, error)
+	GetById(caller auth.Caller, id int64) (*Webhook, error)
+	Update(caller auth.Caller, id int64, req UpdateWebhookRequest) (*Webhook, error)
+	Delete(caller auth.Caller, id int64) error
+	Deliveries(caller auth.Caller, id int64, page, limit int, filter DeliveryFilter) ([]Delivery, int64, int, error)
+	Redeliver(caller auth.Caller, id, deliveryID int64) (*Delivery, error)
+	Publish(event task.Event) error
+	ProcessDue(limit int) (int, error)
+}
+
+type webhookService struct {
+	repo   WebhookRepository
+	client *http.Client
+}
+
+func NewService(repo WebhookRepository) WebhookService {
+	return &webhookService{repo: repo, client: outbound.NewClient(10 * time.Second)}
+}
+
+func generateSecret() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(bytes), nil
+}
+
+// Create registers a webhook. The signing secret is only returned here.
+func (s *webhookService) Create(caller auth.Caller, req CreateWebhookRequest) (*Webhook, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := &Webhook{
+		WorkspaceID: caller.WorkspaceID,
+		CreatedBy:   caller.UserID,
+		URL:         req.URL,
+		Secret:      secret,
+		Events:      req.Events,
+	}
+	if err := s.repo.Create(webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (s *webhookService) GetAll(caller auth.Caller) ([]Webhook, error) {
+	return s.repo.FindForUser(caller.WorkspaceID, caller.UserID)
+}
+
+func (s *webhookService) GetById(caller auth.Caller, id int64) (*Webhook, error) {
+	if id <= 0 {
+		return nil, ErrInvalidID
+	}
+	return s.repo.FindById(caller.WorkspaceID, caller.UserID, id)
+}
+
+func (s *webhookService) Update(caller auth.Caller, id int64, req UpdateWebhookRequest) (*Webhook, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+
+	webhook, err := s.GetById(caller, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL != nil {
+		webhook.URL = *req.URL
+	}
+	if req.Events != nil {
+		webhook.Events = *req.Events
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+
+	if err := s.repo.Update(webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (s *webhookService) Delete(caller auth.Caller, id int64) error {
+	if id <= 0 {
+		return ErrInvalidID
+	}
+	return s.repo.Delete(caller.WorkspaceID, caller.UserID, id)
+}
+
+func (s *webhookService) Deliveries(caller auth.Caller, id int64, page, limit int, filter DeliveryFilter) ([]Delivery, int64, int, error) {
+	if _, err := s.GetById(caller, id); err != nil {
+		return nil, 0, 0, err
+	}
+
+	offset := (page - 1) * limit
+	deliveries, err := s.repo.FindDeliveries(id, filter, offset, limit)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	total, err := s.repo.CountDeliveries(id, filter)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+	return deliveries, total, totalPages, nil
+}
+
+// Redeliver sends a past delivery again, including dead-lettered ones.
+func (s *webhookService) Redeliver(caller auth.Caller, id, deliveryID int64) (*Delivery, error) {
+	if _, err := s.GetById(caller, id); err != nil {
+		return nil, err
+	}
+	if deliveryID <= 0 {
+		return nil, ErrDeliveryNotFound
+	}
+	return s.repo.Redeliver(id, deliveryID)
+}
+
+func (s *webhookService) Publish(event task.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = s.repo.Enqueue(event, payload)
+	return err
+}
+
+// ProcessDue sends up to limit due deliveries.
+func (s *webhookService) ProcessDue(limit int) (int, error) {
+	return s.repo.ProcessDue(limit, s.send)
+}
+
+// send POSTs a delivery's payload, signed with the webhook secret.
+func (s *webhookService) send(t Target) Result {
+	body := []byte(t.Delivery.Payload)
+
+	req, err := http.NewRequest(http.MethodPost, t.URL, bytes.NewReader(body))
+	if err != nil {
+		return Result{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gotask-webhooks")
+	req.Header.Set("X-Gotask-Event", string(t.Delivery.EventType))
+	req.Header.Set("X-Gotask-Delivery", fmt.Sprint(t.Delivery.ID))
+	req.Header.Set(SignatureHeader, Sign(t.Secret, time.Now(), body))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return Result{Err: err}
+	}
+	res.Body.Close()
+
+	result := Result{StatusCode: res.StatusCode}
+	if !result.OK() {
+		result.Err = fmt.Errorf("endpoint responded with %s", res.Status)
+	}
+	return result
+}
@@ -0,0 +1,12 @@
+package workspace
+
+import "errors"
+
+var (
+	ErrNotFound      = errors.New("workspace not found")
+	ErrInvalidID     = errors.New("invalid workspace ID")
+	ErrForbidden     = errors.New("you do not have permission to perform this action")
+	ErrInviteInvalid = errors.New("invite link is invalid, expired or used up")
+	ErrMemberMissing = errors.New("member not found")
+	ErrOwnerRemoval  = errors.New("the workspace owner cannot be removed")
+)
@@ -0,0 +1,197 @@
+package workspace
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/pkg/response"
+)
+
+type Handler struct {
+	service WorkspaceService
+}
+
+func NewHandler(service WorkspaceService) *Handler {
+	return &Handler{service: service}
+}
+
+func writeServiceError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidID), errors.Is(err, ErrOwnerRemoval):
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrMemberMissing), errors.Is(err, ErrInviteInvalid):
+		response.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrForbidden):
+		response.WriteError(w, http.StatusForbidden, err.Error())
+	default:
+		response.WriteError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+func parseID(r *http.Request, param string) (int64, error) {
+	return strconv.ParseInt(chi.URLParam(r, param), 10, 64)
+}
+
+func (h *Handler) CreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var req CreateWorkspaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	ws, err := h.service.Create(auth.GetCaller(r), req)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, ws)
+}
+
+func (h *Handler) GetAllWorkspaces(w http.ResponseWriter, r *http.Request) {
+	workspaces, err := h.service.GetAll(auth.GetCaller(r))
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch workspaces")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, workspaces)
+}
+
+func (h *Handler) GetCurrentWorkspace(w http.ResponseWriter, r *http.Request) {
+	caller := auth.GetCaller(r)
+	ws, err := h.service.GetById(caller, caller.WorkspaceID)
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch the workspace")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, ws)
+}
+
+func (h *Handler) GetWorkspaceByID(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r, "id")
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	ws, err := h.service.GetById(auth.GetCaller(r), id)
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch the workspace")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, ws)
+}
+
+func (h *Handler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r, "id")
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	members, err := h.service.ListMembers(auth.GetCaller(r), id)
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch members")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, members)
+}
+
+func (h *Handler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r, "id")
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+	userID, err := parseID(r, "userID")
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid user ID format")
+		return
+	}
+
+	if err := h.service.RemoveMember(auth.GetCaller(r), id, userID); err != nil {
+		writeServiceError(w, err, "failed to remove member")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{"message": "member removed successfully"})
+}
+
+func (h *Handler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id, err := parseID(r, "id")
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	var req CreateInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	invite, err := h.service.CreateInvite(auth.GetCaller(r), id, req)
+	if err != nil {
+		writeServiceError(w, err, "failed to create invite")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, invite)
+}
+
+func (h *Handler) ListInvites(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r, "id")
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+
+	invites, err := h.service.ListInvites(auth.GetCaller(r), id)
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch invites")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, invites)
+}
+
+func (h *Handler) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r, "id")
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid ID format")
+		return
+	}
+	inviteID, err := parseID(r, "inviteID")
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid invite ID format")
+		return
+	}
+
+	if err := h.service.RevokeInvite(auth.GetCaller(r), id, inviteID); err != nil {
+		writeServiceError(w, err, "failed to revoke invite")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{"message": "invite revoked successfully"})
+}
+
+func (h *Handler) AcceptInvite(w http.ResponseWriter, r *http.Request) {
+	ws, err := h.service.AcceptInvite(auth.GetCaller(r), chi.URLParam(r, "token"))
+	if err != nil {
+		writeServiceError(w, err, "failed to accept invite")
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, ws)
+}
@@ -0,0 +1,36 @@
+package workspace
+
+import (
+	"net/http"
+
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/pkg/response"
+)
+
+// TenancyMiddleware enforces the workspace carried in the access token. It
+// runs after auth.AuthMiddleware and rejects tokens without a workspace, as
+// well as users who have been removed from the workspace since the token was
+// issued.
+func TenancyMiddleware(repo WorkspaceRepository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			caller := auth.GetCaller(r)
+			if caller.WorkspaceID == 0 {
+				response.WriteError(w, http.StatusUnauthorized, "token is not bound to a workspace, please log in again")
+				return
+			}
+
+			role, err := repo.GetMemberRole(caller.WorkspaceID, caller.UserID)
+			if err != nil {
+				response.WriteError(w, http.StatusInternalServerError, "failed to verify workspace membership")
+				return
+			}
+			if !role.Valid() {
+				response.WriteError(w, http.StatusForbidden, "you are not a member of this workspace")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package workspace
+
+import (
+	"time"
+)
+
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleMember Role = "member"
+)
+
+func (r Role) Valid() bool {
+	return r == RoleOwner || r == RoleMember
+}
+
+// CanManage reports whether the role may invite and remove members.
+func (r Role) CanManage() bool {
+	return r == RoleOwner
+}
+
+type Workspace struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	OwnerID   int64     `json:"owner_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Member struct {
+	WorkspaceID int64     `json:"workspace_id"`
+	UserID      int64     `json:"user_id"`
+	Username    string    `json:"username"`
+	Role        Role      `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type Invite struct {
+	ID          int64      `json:"id"`
+	WorkspaceID int64      `json:"workspace_id"`
+	Token       string     `json:"token"`
+	CreatedBy   int64      `json:"created_by"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	MaxUses     *int       `json:"max_uses"`
+	Uses        int        `json:"uses"`
+	Revoked     bool       `json:"revoked"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type CreateWorkspaceRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// CreateInviteRequest leaves both limits optional; an invite without them
+// stays valid until it is revoked.
+type CreateInviteRequest struct {
+	ExpiresInHours int `json:"expires_in_hours" validate:"omitempty,min=1,max=720"`
+	MaxUses        int `json:"max_uses" validate:"omitempty,min=1,max=1000"`
+}
+
+type WorkspaceResponse struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	OwnerID   int64     `json:"owner_id"`
+	Role      Role      `json:"role"`
+	Current   bool      `json:"current"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type InviteResponse struct {
+	Invite
+	URL string `json:"url"`
+}
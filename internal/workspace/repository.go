@@ -0,0 +1,321 @@
+package workspace
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sudarshanmg/gotask/pkg/db"
+)
+
+type WorkspaceRepository interface {
+	Create(ws *Workspace) (int64, error)
+	FindById(id int64) (*Workspace, error)
+	FindForUser(userID int64) ([]Workspace, []Role, error)
+	GetMemberRole(workspaceID, userID int64) (Role, error)
+	ListMembers(workspaceID int64) ([]Member, error)
+	RemoveMember(workspaceID, userID int64) error
+	CreateInvite(invite *Invite) (int64, error)
+	ListInvites(workspaceID int64) ([]Invite, error)
+	RevokeInvite(workspaceID, inviteID int64) error
+	AcceptInvite(token string, userID int64) (int64, error)
+}
+
+type PostgresWorkspaceRepository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) WorkspaceRepository {
+	return &PostgresWorkspaceRepository{DB: db}
+}
+
+func (r *PostgresWorkspaceRepository) Create(ws *Workspace) (int64, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	ws.CreatedAt = time.Now()
+
+	var id int64
+	err = tx.QueryRow(`INSERT INTO workspaces (name, owner_id, created_at) VALUES ($1, $2, $3) RETURNING id;`,
+		ws.Name, ws.OwnerID, ws.CreatedAt).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`INSERT INTO workspace_members (workspace_id, user_id, role) VALUES ($1, $2, $3);`,
+		id, ws.OwnerID, RoleOwner)
+	if err != nil {
+		return 0, err
+	}
+
+	// The owner administers the workspace, including its members' roles.
+	_, err = tx.Exec(`INSERT INTO user_roles (workspace_id, user_id, role_id)
+            SELECT $1, $2, id FROM roles WHERE name = 'admin';`, id, ws.OwnerID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	ws.ID = id
+	return id, nil
+}
+
+func (r *PostgresWorkspaceRepository) FindById(id int64) (*Workspace, error) {
+	query := `SELECT id, name, COALESCE(owner_id, 0), created_at FROM workspaces WHERE id = $1;`
+
+	ws := Workspace{}
+	err := r.DB.QueryRow(query, id).Scan(&ws.ID, &ws.Name, &ws.OwnerID, &ws.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ws, nil
+}
+
+func (r *PostgresWorkspaceRepository) FindForUser(userID int64) ([]Workspace, []Role, error) {
+	query := `
+          SELECT w.id, w.name, COALESCE(w.owner_id, 0), w.created_at, m.role
+          FROM workspaces w
+          JOIN workspace_members m ON m.workspace_id = w.id
+          WHERE m.user_id = $1
+          ORDER BY w.id;`
+
+	rows, err := r.DB.Query(query, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	workspaces := []Workspace{}
+	roles := []Role{}
+	for rows.Next() {
+		ws := Workspace{}
+		var role Role
+		if err := rows.Scan(&ws.ID, &ws.Name, &ws.OwnerID, &ws.CreatedAt, &role); err != nil {
+			return nil, nil, err
+		}
+		workspaces = append(workspaces, ws)
+		roles = append(roles, role)
+	}
+	return workspaces, roles, rows.Err()
+}
+
+// GetMemberRole returns an empty role when the user is not a member.
+func (r *PostgresWorkspaceRepository) GetMemberRole(workspaceID, userID int64) (Role, error) {
+	query := `SELECT role FROM workspace_members WHERE workspace_id = $1 AND user_id = $2;`
+
+	var role Role
+	err := r.DB.QueryRow(query, workspaceID, userID).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return role, err
+}
+
+func (r *PostgresWorkspaceRepository) ListMembers(workspaceID int64) ([]Member, error) {
+	query := `
+          SELECT m.workspace_id, m.user_id, u.username, m.role, m.created_at
+          FROM workspace_members m
+          JOIN users u ON u.id = m.user_id
+          WHERE m.workspace_id = $1
+          ORDER BY m.created_at;`
+
+	rows, err := r.DB.Query(query, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []Member{}
+	for rows.Next() {
+		m := Member{}
+		if err := rows.Scan(&m.WorkspaceID, &m.UserID, &m.Username, &m.Role, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// RemoveMember takes the user out of the workspace together with their
+// project memberships, task assignments, roles and sessions inside it. Tasks
+// that lose an assignee get a change number for sync clients.
+func (r *PostgresWorkspaceRepository) RemoveMember(workspaceID, userID int64) error {
+	cleanup := []string{
+		`DELETE FROM project_members
+         WHERE user_id = $2 AND project_id IN (SELECT id FROM projects WHERE workspace_id = $1);`,
+		`UPDATE refresh_tokens SET revoked = true WHERE workspace_id = $1 AND user_id = $2;`,
+		`DELETE FROM user_roles WHERE workspace_id = $1 AND user_id = $2;`,
+	}
+
+	return db.WithTenant(r.DB, workspaceID, func(tx *sql.Tx) error {
+		if err := db.LockTaskChanges(tx, workspaceID); err != nil {
+			return err
+		}
+
+		res, err := tx.Exec(`DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2;`,
+			workspaceID, userID)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrMemberMissing
+		}
+
+		for _, query := range cleanup {
+			if _, err := tx.Exec(query, workspaceID, userID); err != nil {
+				return err
+			}
+		}
+
+		rows, err := tx.Query(`DELETE FROM task_assignees
+            WHERE user_id = $2 AND task_id IN (SELECT id FROM tasks WHERE workspace_id = $1)
+            RETURNING task_id;`, workspaceID, userID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		ids := []int64{}
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		rows.Close()
+
+		_, err = tx.Exec(`UPDATE tasks SET change_seq = nextval('task_change_seq')
+                WHERE workspace_id = $1 AND id = ANY($2);`, workspaceID, pq.Array(ids))
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE tasks SET field_seqs = field_seqs || jsonb_build_object('assignee_ids', change_seq)
+                WHERE workspace_id = $1 AND id = ANY($2);`, workspaceID, pq.Array(ids))
+		return err
+	})
+}
+
+func (r *PostgresWorkspaceRepository) CreateInvite(invite *Invite) (int64, error) {
+	query := `INSERT INTO workspace_invites (workspace_id, token, created_by, expires_at, max_uses)
+            VALUES ($1, $2, $3, $4, $5)
+            RETURNING id, created_at;`
+
+	err := r.DB.QueryRow(query, invite.WorkspaceID, invite.Token, invite.CreatedBy, invite.ExpiresAt,
+		invite.MaxUses).Scan(&invite.ID, &invite.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return invite.ID, nil
+}
+
+func (r *PostgresWorkspaceRepository) ListInvites(workspaceID int64) ([]Invite, error) {
+	query := `
+          SELECT id, workspace_id, token, COALESCE(created_by, 0), expires_at, max_uses, uses, revoked, created_at
+          FROM workspace_invites
+          WHERE workspace_id = $1
+          ORDER BY id DESC;`
+
+	rows, err := r.DB.Query(query, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invites := []Invite{}
+	for rows.Next() {
+		inv := Invite{}
+		if err := rows.Scan(&inv.ID, &inv.WorkspaceID, &inv.Token, &inv.CreatedBy, &inv.ExpiresAt,
+			&inv.MaxUses, &inv.Uses, &inv.Revoked, &inv.CreatedAt); err != nil {
+			return nil, err
+		}
+		invites = append(invites, inv)
+	}
+	return invites, rows.Err()
+}
+
+func (r *PostgresWorkspaceRepository) RevokeInvite(workspaceID, inviteID int64) error {
+	res, err := r.DB.Exec(`UPDATE workspace_invites SET revoked = true WHERE workspace_id = $1 AND id = $2;`,
+		workspaceID, inviteID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrInviteInvalid
+	}
+	return nil
+}
+
+// AcceptInvite adds the user to the invite's workspace and returns its ID.
+// The invite row is locked so concurrent accepts cannot exceed max_uses.
+// Accepting an invite to a workspace the user already belongs to is a no-op
+// that does not consume a use.
+func (r *PostgresWorkspaceRepository) AcceptInvite(token string, userID int64) (int64, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var id, workspaceID int64
+	query := `SELECT id, workspace_id FROM workspace_invites
+            WHERE token = $1
+              AND NOT revoked
+              AND (expires_at IS NULL OR expires_at > NOW())
+              AND (max_uses IS NULL OR uses < max_uses)
+            FOR UPDATE;`
+	err = tx.QueryRow(query, token).Scan(&id, &workspaceID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrInviteInvalid
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.Exec(`INSERT INTO workspace_members (workspace_id, user_id, role)
+            VALUES ($1, $2, $3) ON CONFLICT DO NOTHING;`, workspaceID, userID, RoleMember)
+	if err != nil {
+		return 0, err
+	}
+	joined, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if joined > 0 {
+		if _, err := tx.Exec(`UPDATE workspace_invites SET uses = uses + 1 WHERE id = $1;`, id); err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec(`INSERT INTO user_roles (workspace_id, user_id, role_id)
+                SELECT $1, $2, id FROM roles WHERE name = 'member';`, workspaceID, userID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return workspaceID, nil
+}
@@ -0,0 +1,23 @@
+package workspace
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+// RegisterRoutes mounts the workspace routes. They need a valid access token
+// but not TenancyMiddleware, since they are how users find, join and manage
+// workspaces other than their current one.
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Route("/workspaces", func(r chi.Router) {
+		r.Get("/", h.GetAllWorkspaces)
+		r.Post("/", h.CreateWorkspace)
+		r.Get("/current", h.GetCurrentWorkspace)
+		r.Get("/{id}", h.GetWorkspaceByID)
+		r.Get("/{id}/members", h.ListMembers)
+		r.Delete("/{id}/members/{userID}", h.RemoveMember)
+		r.Get("/{id}/invites", h.ListInvites)
+		r.Post("/{id}/invites", h.CreateInvite)
+		r.Delete("/{id}/invites/{inviteID}", h.RevokeInvite)
+	})
+	r.Post("/invites/{token}/accept", h.AcceptInvite)
+}
@@ -0,0 +1,221 @@
+package workspace
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sudarshanmg/gotask/internal/auth"
+	"github.com/sudarshanmg/gotask/pkg/validation"
+)
+
+var validate = validator.New()
+
+type WorkspaceService interface {
+	Create(caller auth.Caller, req CreateWorkspaceRequest) (*WorkspaceResponse, error)
+	GetAll(caller auth.Caller) ([]WorkspaceResponse, error)
+	GetById(caller auth.Caller, id int64) (*WorkspaceResponse, error)
+	ListMembers(caller auth.Caller, id int64) ([]Member, error)
+	RemoveMember(caller auth.Caller, id, memberID int64) error
+	CreateInvite(caller auth.Caller, id int64, req CreateInviteRequest) (*InviteResponse, error)
+	ListInvites(caller auth.Caller, id int64) ([]InviteResponse, error)
+	RevokeInvite(caller auth.Caller, id, inviteID int64) error
+	AcceptInvite(caller auth.Caller, token string) (*WorkspaceResponse, error)
+}
+
+type workspaceService struct {
+	repo WorkspaceRepository
+}
+
+func NewService(repo WorkspaceRepository) WorkspaceService {
+	return &workspaceService{repo: repo}
+}
+
+func generateInviteToken() (string, error) {
+	bytes := make([]byte, 24)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+func mapWorkspaceToResponse(ws *Workspace, role Role, currentID int64) WorkspaceResponse {
+	return WorkspaceResponse{
+		ID:        ws.ID,
+		Name:      ws.Name,
+		OwnerID:   ws.OwnerID,
+		Role:      role,
+		Current:   ws.ID == currentID,
+		CreatedAt: ws.CreatedAt,
+	}
+}
+
+func mapInviteToResponse(inv *Invite) InviteResponse {
+	return InviteResponse{Invite: *inv, URL: "/invites/" + inv.Token + "/accept"}
+}
+
+// load returns the workspace and the caller's role in it. Workspaces the
+// caller does not belong to are reported as not found.
+func (s *workspaceService) load(caller auth.Caller, id int64) (*Workspace, Role, error) {
+	if id <= 0 {
+		return nil, "", ErrInvalidID
+	}
+
+	role, err := s.repo.GetMemberRole(id, caller.UserID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !role.Valid() {
+		return nil, "", ErrNotFound
+	}
+
+	ws, err := s.repo.FindById(id)
+	if err != nil {
+		return nil, "", err
+	}
+	if ws == nil {
+		return nil, "", ErrNotFound
+	}
+	return ws, role, nil
+}
+
+func (s *workspaceService) manage(caller auth.Caller, id int64) (*Workspace, error) {
+	ws, role, err := s.load(caller, id)
+	if err != nil {
+		return nil, err
+	}
+	if !role.CanManage() {
+		return nil, ErrForbidden
+	}
+	return ws, nil
+}
+
+func (s *workspaceService) Create(caller auth.Caller, req CreateWorkspaceRequest) (*WorkspaceResponse, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+
+	ws := Workspace{Name: req.Name, OwnerID: caller.UserID}
+	if _, err := s.repo.Create(&ws); err != nil {
+		return nil, err
+	}
+
+	res := mapWorkspaceToResponse(&ws, RoleOwner, caller.WorkspaceID)
+	return &res, nil
+}
+
+func (s *workspaceService) GetAll(caller auth.Caller) ([]WorkspaceResponse, error) {
+	workspaces, roles, err := s.repo.FindForUser(caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]WorkspaceResponse, 0, len(workspaces))
+	for i := range workspaces {
+		responses = append(responses, mapWorkspaceToResponse(&workspaces[i], roles[i], caller.WorkspaceID))
+	}
+	return responses, nil
+}
+
+func (s *workspaceService) GetById(caller auth.Caller, id int64) (*WorkspaceResponse, error) {
+	ws, role, err := s.load(caller, id)
+	if err != nil {
+		return nil, err
+	}
+	res := mapWorkspaceToResponse(ws, role, caller.WorkspaceID)
+	return &res, nil
+}
+
+func (s *workspaceService) ListMembers(caller auth.Caller, id int64) ([]Member, error) {
+	if _, _, err := s.load(caller, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListMembers(id)
+}
+
+func (s *workspaceService) RemoveMember(caller auth.Caller, id, memberID int64) error {
+	ws, role, err := s.load(caller, id)
+	if err != nil {
+		return err
+	}
+	// Members may always leave a workspace on their own.
+	if !role.CanManage() && memberID != caller.UserID {
+		return ErrForbidden
+	}
+	if memberID == ws.OwnerID {
+		return ErrOwnerRemoval
+	}
+	return s.repo.RemoveMember(id, memberID)
+}
+
+func (s *workspaceService) CreateInvite(caller auth.Caller, id int64, req CreateInviteRequest) (*InviteResponse, error) {
+	if err := validate.Struct(req); err != nil {
+		return nil, validation.FormatValidationError(err)
+	}
+	if _, err := s.manage(caller, id); err != nil {
+		return nil, err
+	}
+
+	token, err := generateInviteToken()
+	if err != nil {
+		return nil, err
+	}
+
+	invite := Invite{
+		WorkspaceID: id,
+		Token:       token,
+		CreatedBy:   caller.UserID,
+	}
+	if req.ExpiresInHours > 0 {
+		expires := time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		invite.ExpiresAt = &expires
+	}
+	if req.MaxUses > 0 {
+		invite.MaxUses = &req.MaxUses
+	}
+
+	if _, err := s.repo.CreateInvite(&invite); err != nil {
+		return nil, err
+	}
+
+	res := mapInviteToResponse(&invite)
+	return &res, nil
+}
+
+func (s *workspaceService) ListInvites(caller auth.Caller, id int64) ([]InviteResponse, error) {
+	if _, err := s.manage(caller, id); err != nil {
+		return nil, err
+	}
+
+	invites, err := s.repo.ListInvites(id)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]InviteResponse, 0, len(invites))
+	for i := range invites {
+		responses = append(responses, mapInviteToResponse(&invites[i]))
+	}
+	return responses, nil
+}
+
+func (s *workspaceService) RevokeInvite(caller auth.Caller, id, inviteID int64) error {
+	if _, err := s.manage(caller, id); err != nil {
+		return err
+	}
+	return s.repo.RevokeInvite(id, inviteID)
+}
+
+func (s *workspaceService) AcceptInvite(caller auth.Caller, token string) (*WorkspaceResponse, error) {
+	if token == "" {
+		return nil, ErrInviteInvalid
+	}
+
+	id, err := s.repo.AcceptInvite(token, caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetById(caller, id)
+}
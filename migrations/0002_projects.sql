@@ -0,0 +1,35 @@
+CREATE TABLE projects (
+  id SERIAL PRIMARY KEY,
+  name TEXT NOT NULL,
+  owner_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at TIMESTAMP DEFAULT NOW(),
+  updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE project_members (
+  project_id INT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  role TEXT NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+  created_at TIMESTAMP DEFAULT NOW(),
+  PRIMARY KEY (project_id, user_id)
+);
+
+CREATE INDEX project_members_user_id_idx ON project_members (user_id);
+
+-- Tasks without a project are personal and only visible to their creator.
+-- Rows that predate this migration have no creator and stay hidden until
+-- they are backfilled.
+ALTER TABLE tasks
+  ADD COLUMN project_id INT REFERENCES projects(id) ON DELETE CASCADE,
+  ADD COLUMN created_by INT REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX tasks_project_id_idx ON tasks (project_id);
+CREATE INDEX tasks_created_by_idx ON tasks (created_by);
+
+CREATE TABLE task_assignees (
+  task_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  PRIMARY KEY (task_id, user_id)
+);
+
+CREATE INDEX task_assignees_user_id_idx ON task_assignees (user_id);
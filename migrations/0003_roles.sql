@@ -0,0 +1,46 @@
+CREATE TABLE roles (
+  id SERIAL PRIMARY KEY,
+  name TEXT UNIQUE NOT NULL,
+  description TEXT NOT NULL DEFAULT ''
+);
+
+-- A '*' in resource_type or action matches any value.
+CREATE TABLE role_permissions (
+  role_id INT NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+  resource_type TEXT NOT NULL,
+  action TEXT NOT NULL,
+  PRIMARY KEY (role_id, resource_type, action)
+);
+
+CREATE TABLE user_roles (
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  role_id INT NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+  PRIMARY KEY (user_id, role_id)
+);
+
+INSERT INTO roles (name, description) VALUES
+  ('admin', 'Full access, including role management'),
+  ('member', 'Create and manage own tasks and projects'),
+  ('guest', 'Read-only access');
+
+INSERT INTO role_permissions (role_id, resource_type, action)
+SELECT r.id, p.resource_type, p.action
+FROM roles r
+JOIN (VALUES
+  ('admin', '*', '*'),
+  ('member', 'task', 'read'),
+  ('member', 'task', 'create'),
+  ('member', 'task', 'update'),
+  ('member', 'task', 'delete'),
+  ('member', 'project', 'read'),
+  ('member', 'project', 'create'),
+  ('member', 'project', 'update'),
+  ('member', 'project', 'delete'),
+  ('member', 'project', 'manage'),
+  ('guest', 'task', 'read'),
+  ('guest', 'project', 'read')
+) AS p(role, resource_type, action) ON p.role = r.name;
+
+-- Existing accounts keep the access they had before roles existed.
+INSERT INTO user_roles (user_id, role_id)
+SELECT u.id, r.id FROM users u, roles r WHERE r.name = 'member';
@@ -0,0 +1,111 @@
+CREATE TABLE workspaces (
+  id SERIAL PRIMARY KEY,
+  name TEXT NOT NULL,
+  owner_id INT REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE workspace_members (
+  workspace_id INT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  role TEXT NOT NULL CHECK (role IN ('owner', 'member')),
+  created_at TIMESTAMP DEFAULT NOW(),
+  last_used_at TIMESTAMP,
+  PRIMARY KEY (workspace_id, user_id)
+);
+
+CREATE INDEX workspace_members_user_id_idx ON workspace_members (user_id);
+
+CREATE TABLE workspace_invites (
+  id SERIAL PRIMARY KEY,
+  workspace_id INT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  token TEXT UNIQUE NOT NULL,
+  created_by INT REFERENCES users(id) ON DELETE SET NULL,
+  expires_at TIMESTAMP,
+  max_uses INT,
+  uses INT NOT NULL DEFAULT 0,
+  revoked BOOLEAN NOT NULL DEFAULT false,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Every existing user gets a personal workspace that takes over their data.
+INSERT INTO workspaces (name, owner_id)
+SELECT username || '''s workspace', id FROM users;
+
+INSERT INTO workspace_members (workspace_id, user_id, role)
+SELECT id, owner_id, 'owner' FROM workspaces;
+
+ALTER TABLE projects ADD COLUMN workspace_id INT REFERENCES workspaces(id) ON DELETE CASCADE;
+
+UPDATE projects p SET workspace_id = w.id
+FROM workspaces w WHERE w.owner_id = p.owner_id;
+
+-- Collaborators on a project join the workspace that now owns it.
+INSERT INTO workspace_members (workspace_id, user_id, role)
+SELECT DISTINCT p.workspace_id, m.user_id, 'member'
+FROM project_members m JOIN projects p ON p.id = m.project_id
+ON CONFLICT DO NOTHING;
+
+-- Roles are granted per workspace. Members keep the roles they had in
+-- every workspace they belong to.
+ALTER TABLE user_roles ADD COLUMN workspace_id INT REFERENCES workspaces(id) ON DELETE CASCADE;
+ALTER TABLE user_roles DROP CONSTRAINT user_roles_pkey;
+
+INSERT INTO user_roles (workspace_id, user_id, role_id)
+SELECT m.workspace_id, ur.user_id, ur.role_id
+FROM user_roles ur JOIN workspace_members m ON m.user_id = ur.user_id;
+
+DELETE FROM user_roles WHERE workspace_id IS NULL;
+ALTER TABLE user_roles ALTER COLUMN workspace_id SET NOT NULL;
+ALTER TABLE user_roles ADD PRIMARY KEY (workspace_id, user_id, role_id);
+
+-- Owners administer their workspaces; without this nobody could ever be
+-- given the admin role.
+INSERT INTO user_roles (workspace_id, user_id, role_id)
+SELECT w.id, w.owner_id, r.id FROM workspaces w, roles r WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE projects ALTER COLUMN workspace_id SET NOT NULL;
+CREATE INDEX projects_workspace_id_idx ON projects (workspace_id);
+
+-- Legacy tasks without a creator have no workspace and stay unreachable.
+ALTER TABLE tasks ADD COLUMN workspace_id INT REFERENCES workspaces(id) ON DELETE CASCADE;
+
+UPDATE tasks t SET workspace_id = p.workspace_id
+FROM projects p WHERE p.id = t.project_id;
+
+UPDATE tasks t SET workspace_id = w.id
+FROM workspaces w WHERE t.workspace_id IS NULL AND w.owner_id = t.created_by;
+
+CREATE INDEX tasks_workspace_id_idx ON tasks (workspace_id);
+
+ALTER TABLE refresh_tokens ADD COLUMN workspace_id INT REFERENCES workspaces(id) ON DELETE CASCADE;
+
+UPDATE refresh_tokens rt SET workspace_id = w.id
+FROM workspaces w WHERE w.owner_id = rt.user_id;
+
+-- Row-level security backs up the explicit workspace_id filters in the
+-- repositories. Request-scoped queries run through db.WithTenant, which sets
+-- app.workspace_id for the transaction; rows of any other workspace are then
+-- invisible even if a WHERE clause is missing. It fails closed: sessions
+-- that set neither app.workspace_id nor app.bypass_rls see no rows at all.
+-- Background jobs that work across workspaces run through db.AcrossTenants,
+-- and migrations that change tenant rows set app.bypass_rls themselves.
+CREATE FUNCTION tenant_visible(row_workspace_id INT) RETURNS BOOLEAN
+  LANGUAGE sql STABLE
+  AS $$
+    SELECT COALESCE(current_setting('app.bypass_rls', true), '') = 'on'
+        OR row_workspace_id = NULLIF(current_setting('app.workspace_id', true), '')::int
+  $$;
+
+ALTER TABLE tasks ENABLE ROW LEVEL SECURITY;
+ALTER TABLE tasks FORCE ROW LEVEL SECURITY;
+CREATE POLICY tasks_tenant_isolation ON tasks
+  USING (tenant_visible(workspace_id))
+  WITH CHECK (tenant_visible(workspace_id));
+
+ALTER TABLE projects ENABLE ROW LEVEL SECURITY;
+ALTER TABLE projects FORCE ROW LEVEL SECURITY;
+CREATE POLICY projects_tenant_isolation ON projects
+  USING (tenant_visible(workspace_id))
+  WITH CHECK (tenant_visible(workspace_id));
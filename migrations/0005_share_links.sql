@@ -0,0 +1,20 @@
+CREATE TABLE share_links (
+  id SERIAL PRIMARY KEY,
+  workspace_id INT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  token TEXT UNIQUE NOT NULL,
+  resource_type TEXT NOT NULL CHECK (resource_type IN ('task', 'project')),
+  resource_id INT NOT NULL,
+  created_by INT REFERENCES users(id) ON DELETE SET NULL,
+  password_hash TEXT,
+  expires_at TIMESTAMP,
+  revoked BOOLEAN NOT NULL DEFAULT false,
+  view_count INT NOT NULL DEFAULT 0,
+  last_viewed_at TIMESTAMP,
+  -- Wrong passwords in a row; every few of them lock the link for a while,
+  -- in UTC.
+  failed_attempts INT NOT NULL DEFAULT 0,
+  locked_until TIMESTAMP,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX share_links_resource_idx ON share_links (workspace_id, resource_type, resource_id);
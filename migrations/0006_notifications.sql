@@ -0,0 +1,38 @@
+ALTER TABLE tasks ADD COLUMN due_at TIMESTAMP;
+CREATE INDEX tasks_due_at_idx ON tasks (due_at) WHERE NOT completed;
+
+CREATE TABLE task_comments (
+  id SERIAL PRIMARY KEY,
+  workspace_id INT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  task_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  author_id INT REFERENCES users(id) ON DELETE SET NULL,
+  body TEXT NOT NULL,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX task_comments_task_id_idx ON task_comments (task_id);
+
+ALTER TABLE task_comments ENABLE ROW LEVEL SECURITY;
+ALTER TABLE task_comments FORCE ROW LEVEL SECURITY;
+CREATE POLICY task_comments_tenant_isolation ON task_comments
+  USING (tenant_visible(workspace_id))
+  WITH CHECK (tenant_visible(workspace_id));
+
+CREATE TABLE notifications (
+  id SERIAL PRIMARY KEY,
+  workspace_id INT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  type TEXT NOT NULL,
+  task_id INT REFERENCES tasks(id) ON DELETE CASCADE,
+  actor_id INT REFERENCES users(id) ON DELETE SET NULL,
+  message TEXT NOT NULL,
+  -- Set for notifications that must be created at most once per user, such
+  -- as "due soon" for a particular due date.
+  dedupe_key TEXT,
+  read_at TIMESTAMP,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX notifications_inbox_idx ON notifications (workspace_id, user_id, id DESC);
+CREATE INDEX notifications_unread_idx ON notifications (workspace_id, user_id) WHERE read_at IS NULL;
+CREATE UNIQUE INDEX notifications_dedupe_idx ON notifications (user_id, dedupe_key) WHERE dedupe_key IS NOT NULL;
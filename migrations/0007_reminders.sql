@@ -0,0 +1,24 @@
+CREATE TABLE task_reminders (
+  id SERIAL PRIMARY KEY,
+  workspace_id INT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  task_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  -- Exactly one of remind_at (absolute) and minutes_before_due (relative).
+  remind_at TIMESTAMP,
+  minutes_before_due INT CHECK (minutes_before_due >= 0),
+  channel TEXT NOT NULL CHECK (channel IN ('in_app', 'email', 'webhook')),
+  webhook_url TEXT NOT NULL DEFAULT '',
+  -- When the scheduler should fire it. NULL for relative reminders on a task
+  -- without a due date; kept in sync when the due date changes.
+  fire_at TIMESTAMP,
+  fired_at TIMESTAMP,
+  failed_at TIMESTAMP,
+  attempts INT NOT NULL DEFAULT 0,
+  last_error TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP DEFAULT NOW(),
+  CHECK ((remind_at IS NULL) <> (minutes_before_due IS NULL))
+);
+
+CREATE INDEX task_reminders_pending_idx ON task_reminders (fire_at)
+  WHERE fired_at IS NULL AND failed_at IS NULL;
+CREATE INDEX task_reminders_task_id_idx ON task_reminders (task_id);
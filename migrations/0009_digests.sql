@@ -0,0 +1,22 @@
+-- The backfills below cover every workspace's rows.
+SET app.bypass_rls = on;
+
+ALTER TABLE tasks ADD COLUMN completed_at TIMESTAMP;
+UPDATE tasks SET completed_at = updated_at WHERE completed;
+
+ALTER TABLE task_assignees ADD COLUMN assigned_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+-- One row per user with a verified email. The digest job creates rows with
+-- the defaults below for users who have never changed their settings.
+CREATE TABLE digest_settings (
+  user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  frequency TEXT NOT NULL DEFAULT 'daily' CHECK (frequency IN ('off', 'daily', 'weekly')),
+  send_time TIME NOT NULL DEFAULT '08:00',
+  timezone TEXT NOT NULL DEFAULT 'UTC',
+  -- ISO day of week (1 = Monday) for weekly digests.
+  weekday INT NOT NULL DEFAULT 1 CHECK (weekday BETWEEN 1 AND 7),
+  -- Local date of the last digest, so each period gets at most one.
+  last_sent_on DATE,
+  last_sent_at TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT NOW()
+);
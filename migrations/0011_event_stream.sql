@@ -0,0 +1,3 @@
+-- Global order of task events streamed to clients; used as the SSE event ID
+-- so a client can resume on any server instance.
+CREATE SEQUENCE task_event_seq;
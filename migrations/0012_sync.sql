@@ -0,0 +1,57 @@
+-- Every write to a task takes the next number from task_change_seq, so sync
+-- clients can ask for everything that changed after the last number they saw.
+-- Writers in a workspace hold an advisory lock from nextval until commit
+-- (db.LockTaskChanges), so numbers become visible in order.
+CREATE SEQUENCE task_change_seq;
+
+ALTER TABLE tasks ADD COLUMN change_seq BIGINT NOT NULL DEFAULT nextval('task_change_seq');
+-- Change number of the last write to each field, e.g. {"title": 42}, for
+-- per-field conflict reporting.
+ALTER TABLE tasks ADD COLUMN field_seqs JSONB NOT NULL DEFAULT '{}';
+-- Client-generated ID of a task created through sync, so a retried create
+-- does not make a second task.
+ALTER TABLE tasks ADD COLUMN client_id TEXT;
+
+CREATE INDEX tasks_workspace_change_seq_idx ON tasks (workspace_id, change_seq);
+CREATE UNIQUE INDEX tasks_client_id_key ON tasks (workspace_id, created_by, client_id)
+  WHERE client_id IS NOT NULL;
+
+-- Deleted tasks leave a tombstone for sync clients. visible_to records who
+-- could see the task when it was deleted, since its project and memberships
+-- may be gone by the time a client asks.
+CREATE TABLE task_tombstones (
+  task_id INT PRIMARY KEY,
+  workspace_id INT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  visible_to INT[] NOT NULL,
+  change_seq BIGINT NOT NULL,
+  deleted_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX task_tombstones_workspace_change_seq_idx ON task_tombstones (workspace_id, change_seq);
+
+ALTER TABLE task_tombstones ENABLE ROW LEVEL SECURITY;
+ALTER TABLE task_tombstones FORCE ROW LEVEL SECURITY;
+CREATE POLICY task_tombstones_tenant_isolation ON task_tombstones
+  USING (tenant_visible(workspace_id))
+  WITH CHECK (tenant_visible(workspace_id));
+
+-- Users gain and lose sight of tasks without the tasks changing when they
+-- join or leave a project. These rows list such tasks in that user's sync:
+-- as updated when they became visible and as deleted when they did not.
+CREATE TABLE task_visibility_changes (
+  task_id INT NOT NULL,
+  workspace_id INT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  user_id INT NOT NULL,
+  visible BOOLEAN NOT NULL,
+  change_seq BIGINT NOT NULL,
+  PRIMARY KEY (task_id, user_id)
+);
+
+CREATE INDEX task_visibility_changes_user_change_seq_idx
+  ON task_visibility_changes (workspace_id, user_id, change_seq);
+
+ALTER TABLE task_visibility_changes ENABLE ROW LEVEL SECURITY;
+ALTER TABLE task_visibility_changes FORCE ROW LEVEL SECURITY;
+CREATE POLICY task_visibility_changes_tenant_isolation ON task_visibility_changes
+  USING (tenant_visible(workspace_id))
+  WITH CHECK (tenant_visible(workspace_id));
@@ -0,0 +1,28 @@
+ALTER TABLE tasks ADD COLUMN labels TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE tasks ADD COLUMN estimate_minutes INT CHECK (estimate_minutes > 0);
+
+CREATE INDEX tasks_labels_idx ON tasks USING GIN (labels);
+
+-- Time worked on a task, in UTC. A running timer has no ended_at.
+CREATE TABLE time_entries (
+  id SERIAL PRIMARY KEY,
+  workspace_id INT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  task_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  started_at TIMESTAMP NOT NULL,
+  ended_at TIMESTAMP CHECK (ended_at >= started_at),
+  note TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX time_entries_task_id_idx ON time_entries (task_id);
+CREATE INDEX time_entries_user_started_at_idx ON time_entries (user_id, started_at);
+
+-- A user has at most one running timer, across all workspaces.
+CREATE UNIQUE INDEX time_entries_running_key ON time_entries (user_id) WHERE ended_at IS NULL;
+
+ALTER TABLE time_entries ENABLE ROW LEVEL SECURITY;
+ALTER TABLE time_entries FORCE ROW LEVEL SECURITY;
+CREATE POLICY time_entries_tenant_isolation ON time_entries
+  USING (tenant_visible(workspace_id))
+  WITH CHECK (tenant_visible(workspace_id));
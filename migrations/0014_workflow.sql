@@ -0,0 +1,66 @@
+-- The backfills below cover every workspace's rows.
+SET app.bypass_rls = on;
+
+-- Workflow columns of a project's board. The category tells what a status
+-- means: tasks in a done status are completed, the others are open.
+CREATE TABLE project_statuses (
+  id SERIAL PRIMARY KEY,
+  project_id INT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  category TEXT NOT NULL CHECK (category IN ('todo', 'in_progress', 'done')),
+  position INT NOT NULL DEFAULT 0,
+  created_at TIMESTAMP DEFAULT NOW(),
+  UNIQUE (project_id, name)
+);
+
+INSERT INTO project_statuses (project_id, name, category, position)
+SELECT p.id, s.name, s.category, s.position
+FROM projects p
+CROSS JOIN (VALUES ('To do', 'todo', 0), ('In progress', 'in_progress', 1), ('Done', 'done', 2))
+  AS s (name, category, position);
+
+-- Personal tasks have no status.
+ALTER TABLE tasks ADD COLUMN status_id INT REFERENCES project_statuses(id) ON DELETE SET NULL;
+
+UPDATE tasks t
+SET status_id = s.id
+FROM project_statuses s
+WHERE s.project_id = t.project_id
+  AND s.category = CASE WHEN t.completed THEN 'done' ELSE 'todo' END;
+
+CREATE INDEX tasks_status_id_idx ON tasks (status_id);
+
+-- Every status a project task has been in, in UTC, for burndown and
+-- cumulative-flow reports. The category is copied so that the history
+-- survives later changes to the status itself; status_id is cleared when the
+-- status is deleted.
+CREATE TABLE task_status_history (
+  id SERIAL PRIMARY KEY,
+  workspace_id INT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  task_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  project_id INT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  status_id INT REFERENCES project_statuses(id) ON DELETE SET NULL,
+  category TEXT NOT NULL CHECK (category IN ('todo', 'in_progress', 'done')),
+  entered_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX task_status_history_task_entered_at_idx ON task_status_history (task_id, entered_at);
+CREATE INDEX task_status_history_project_id_idx ON task_status_history (project_id);
+
+-- Existing tasks start out open when they were created, and completed ones
+-- move to done when they were completed.
+INSERT INTO task_status_history (workspace_id, task_id, project_id, status_id, category, entered_at)
+SELECT t.workspace_id, t.id, t.project_id, s.id, 'todo', COALESCE(t.created_at, NOW())
+FROM tasks t
+JOIN project_statuses s ON s.project_id = t.project_id AND s.category = 'todo';
+
+INSERT INTO task_status_history (workspace_id, task_id, project_id, status_id, category, entered_at)
+SELECT t.workspace_id, t.id, t.project_id, t.status_id, 'done', COALESCE(t.completed_at, t.updated_at, NOW())
+FROM tasks t
+WHERE t.project_id IS NOT NULL AND t.completed;
+
+ALTER TABLE task_status_history ENABLE ROW LEVEL SECURITY;
+ALTER TABLE task_status_history FORCE ROW LEVEL SECURITY;
+CREATE POLICY task_status_history_tenant_isolation ON task_status_history
+  USING (tenant_visible(workspace_id))
+  WITH CHECK (tenant_visible(workspace_id));
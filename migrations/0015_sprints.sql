@@ -0,0 +1,39 @@
+-- Story points are estimated on the project's scale; personal tasks use
+-- Fibonacci.
+ALTER TABLE projects ADD COLUMN point_scale TEXT NOT NULL DEFAULT 'fibonacci'
+  CHECK (point_scale IN ('fibonacci', 'tshirt'));
+
+-- Time-boxed iterations of a project. Dates are local calendar dates, both
+-- inclusive. Closing a sprint records its numbers for velocity history, so
+-- that moving or re-estimating tasks later does not rewrite it; open_tasks
+-- are the ones that were carried over.
+CREATE TABLE sprints (
+  id SERIAL PRIMARY KEY,
+  workspace_id INT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  project_id INT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  goal TEXT NOT NULL DEFAULT '',
+  start_date DATE NOT NULL,
+  end_date DATE NOT NULL CHECK (end_date >= start_date),
+  closed_at TIMESTAMP,
+  committed_points INT,
+  completed_points INT,
+  completed_tasks INT,
+  open_tasks INT,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX sprints_project_start_date_idx ON sprints (project_id, start_date);
+
+-- Tasks without a sprint are in the project's backlog.
+ALTER TABLE tasks
+  ADD COLUMN sprint_id INT REFERENCES sprints(id) ON DELETE SET NULL,
+  ADD COLUMN story_points TEXT;
+
+CREATE INDEX tasks_sprint_id_idx ON tasks (sprint_id);
+
+ALTER TABLE sprints ENABLE ROW LEVEL SECURITY;
+ALTER TABLE sprints FORCE ROW LEVEL SECURITY;
+CREATE POLICY sprints_tenant_isolation ON sprints
+  USING (tenant_visible(workspace_id))
+  WITH CHECK (tenant_visible(workspace_id));
@@ -0,0 +1,15 @@
+-- When work on a task is planned to begin; with due_at it makes the task's
+-- bar on the project timeline.
+ALTER TABLE tasks ADD COLUMN start_at TIMESTAMP;
+
+-- task_id cannot start before blocked_by_id is done. Both tasks are in the
+-- same project, or both personal tasks of the same user, and the graph has
+-- no cycles; the service checks both.
+CREATE TABLE task_dependencies (
+  task_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  blocked_by_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  PRIMARY KEY (task_id, blocked_by_id),
+  CHECK (task_id <> blocked_by_id)
+);
+
+CREATE INDEX task_dependencies_blocked_by_id_idx ON task_dependencies (blocked_by_id);
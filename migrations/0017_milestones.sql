@@ -0,0 +1,25 @@
+-- Goals of a project, such as releases, due by the end of their target date
+-- in the viewer's time zone.
+CREATE TABLE milestones (
+  id SERIAL PRIMARY KEY,
+  workspace_id INT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  project_id INT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  title TEXT NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  target_date DATE NOT NULL,
+  created_by INT REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMP DEFAULT NOW(),
+  updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX milestones_project_target_date_idx ON milestones (project_id, target_date);
+
+ALTER TABLE tasks ADD COLUMN milestone_id INT REFERENCES milestones(id) ON DELETE SET NULL;
+
+CREATE INDEX tasks_milestone_id_idx ON tasks (milestone_id);
+
+ALTER TABLE milestones ENABLE ROW LEVEL SECURITY;
+ALTER TABLE milestones FORCE ROW LEVEL SECURITY;
+CREATE POLICY milestones_tenant_isolation ON milestones
+  USING (tenant_visible(workspace_id))
+  WITH CHECK (tenant_visible(workspace_id));
@@ -0,0 +1,6 @@
+-- ID of an imported task in the source it came from, so importing the same
+-- file again does not make a second task.
+ALTER TABLE tasks ADD COLUMN external_id TEXT;
+
+CREATE UNIQUE INDEX tasks_external_id_key ON tasks (workspace_id, created_by, external_id)
+  WHERE external_id IS NOT NULL;
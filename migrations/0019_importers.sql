@@ -0,0 +1,38 @@
+-- Task priority, from 1 (most urgent) to 4; NULL is no priority.
+ALTER TABLE tasks ADD COLUMN priority SMALLINT CHECK (priority BETWEEN 1 AND 4);
+
+-- Imports from other apps run in the background. The uploaded file is kept
+-- until the job finishes; heartbeat_at lets another server take over a job
+-- whose server went away.
+CREATE TABLE import_jobs (
+  id SERIAL PRIMARY KEY,
+  workspace_id INT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  source TEXT NOT NULL CHECK (source IN ('todoist', 'trello', 'todotxt')),
+  filename TEXT NOT NULL DEFAULT '',
+  file BYTEA,
+  status TEXT NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'running', 'completed', 'failed')),
+  attempts INT NOT NULL DEFAULT 0,
+  total INT NOT NULL DEFAULT 0,
+  processed INT NOT NULL DEFAULT 0,
+  created INT NOT NULL DEFAULT 0,
+  existing INT NOT NULL DEFAULT 0,
+  invalid INT NOT NULL DEFAULT 0,
+  skipped INT NOT NULL DEFAULT 0,
+  projects_created INT NOT NULL DEFAULT 0,
+  results JSONB NOT NULL DEFAULT '[]',
+  error TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  started_at TIMESTAMP,
+  heartbeat_at TIMESTAMP,
+  finished_at TIMESTAMP
+);
+
+CREATE INDEX import_jobs_user_idx ON import_jobs (workspace_id, user_id, id DESC);
+CREATE INDEX import_jobs_pending_idx ON import_jobs (created_at) WHERE status IN ('queued', 'running');
+
+ALTER TABLE import_jobs ENABLE ROW LEVEL SECURITY;
+ALTER TABLE import_jobs FORCE ROW LEVEL SECURITY;
+CREATE POLICY import_jobs_tenant_isolation ON import_jobs
+  USING (tenant_visible(workspace_id))
+  WITH CHECK (tenant_visible(workspace_id));
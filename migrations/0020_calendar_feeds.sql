@@ -0,0 +1,12 @@
+-- Secret calendar feed URLs, one per user and workspace. Like share links,
+-- feeds are looked up by token without a workspace, so there is no
+-- row-level security on this table.
+CREATE TABLE calendar_feeds (
+  id SERIAL PRIMARY KEY,
+  workspace_id INT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  token TEXT UNIQUE NOT NULL,
+  created_at TIMESTAMP DEFAULT NOW(),
+  last_used_at TIMESTAMP,
+  UNIQUE (workspace_id, user_id)
+);
@@ -0,0 +1,31 @@
+-- Passwords for apps that cannot log in with a JWT, such as CalDAV clients.
+-- Each is bound to one workspace. Passwords are random, so a SHA-256 hash
+-- is enough; there is no row-level security because requests are matched
+-- to a password before their workspace is known.
+CREATE TABLE app_passwords (
+  id SERIAL PRIMARY KEY,
+  workspace_id INT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  password_hash TEXT UNIQUE NOT NULL,
+  created_at TIMESTAMP DEFAULT NOW(),
+  last_used_at TIMESTAMP
+);
+
+CREATE INDEX app_passwords_user_idx ON app_passwords (workspace_id, user_id);
+
+-- Resource names and UIDs chosen by CalDAV clients for the tasks they
+-- create. Other tasks are served as task-{id}.ics.
+CREATE TABLE caldav_objects (
+  task_id INT PRIMARY KEY REFERENCES tasks(id) ON DELETE CASCADE,
+  workspace_id INT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  uid TEXT NOT NULL,
+  UNIQUE (workspace_id, name)
+);
+
+ALTER TABLE caldav_objects ENABLE ROW LEVEL SECURITY;
+ALTER TABLE caldav_objects FORCE ROW LEVEL SECURITY;
+CREATE POLICY caldav_objects_tenant_isolation ON caldav_objects
+  USING (tenant_visible(workspace_id))
+  WITH CHECK (tenant_visible(workspace_id));
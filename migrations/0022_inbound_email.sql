@@ -0,0 +1,36 @@
+-- Files attached to tasks, uploaded or received by email. Contents are
+-- kept in the database, like import files, and are limited in size by the
+-- application.
+CREATE TABLE task_attachments (
+  id SERIAL PRIMARY KEY,
+  workspace_id INT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  task_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  uploaded_by INT REFERENCES users(id) ON DELETE SET NULL,
+  filename TEXT NOT NULL,
+  content_type TEXT NOT NULL,
+  size INT NOT NULL,
+  data BYTEA NOT NULL,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX task_attachments_task_id_idx ON task_attachments (task_id);
+
+ALTER TABLE task_attachments ENABLE ROW LEVEL SECURITY;
+ALTER TABLE task_attachments FORCE ROW LEVEL SECURITY;
+CREATE POLICY task_attachments_tenant_isolation ON task_attachments
+  USING (tenant_visible(workspace_id))
+  WITH CHECK (tenant_visible(workspace_id));
+
+-- Inbound email addresses, one per user and workspace. Mail is matched to
+-- an address by its token before the workspace is known, so, like
+-- calendar feeds, there is no row-level security on this table.
+CREATE TABLE inbound_addresses (
+  id SERIAL PRIMARY KEY,
+  workspace_id INT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  token TEXT UNIQUE NOT NULL,
+  project_id INT REFERENCES projects(id) ON DELETE SET NULL,
+  created_at TIMESTAMP DEFAULT NOW(),
+  last_used_at TIMESTAMP,
+  UNIQUE (workspace_id, user_id)
+);
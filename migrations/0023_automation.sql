@@ -0,0 +1,61 @@
+-- Automation rules: when a task is created, changes status, gets a label
+-- or passes its due date, and matches the condition, the actions run as
+-- the rule's creator. Personal rules (no project) apply to their
+-- creator's personal tasks.
+CREATE TABLE automation_rules (
+  id SERIAL PRIMARY KEY,
+  workspace_id INT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  created_by INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  project_id INT REFERENCES projects(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  trigger TEXT NOT NULL CHECK (trigger IN ('created', 'status_changed', 'due_passed', 'label_added')),
+  -- label_added rules for a single label; NULL matches any label.
+  trigger_label TEXT,
+  condition TEXT NOT NULL DEFAULT '',
+  actions JSONB NOT NULL,
+  secret TEXT NOT NULL,
+  active BOOLEAN NOT NULL DEFAULT true,
+  -- In UTC, like task due dates, which due_passed rules compare it with.
+  created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'UTC'),
+  updated_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'UTC')
+);
+
+CREATE INDEX automation_rules_trigger_idx ON automation_rules (workspace_id, trigger) WHERE active;
+
+ALTER TABLE automation_rules ENABLE ROW LEVEL SECURITY;
+ALTER TABLE automation_rules FORCE ROW LEVEL SECURITY;
+CREATE POLICY automation_rules_tenant_isolation ON automation_rules
+  USING (tenant_visible(workspace_id))
+  WITH CHECK (tenant_visible(workspace_id));
+
+-- One run per rule and event. due_passed events are keyed by task and due
+-- date. rule_chain lists the rules that caused the event, which are not
+-- run for it again; task is the task as the event saw it. Runs outlive
+-- their task, so task_id is not a foreign key.
+CREATE TABLE automation_runs (
+  id SERIAL PRIMARY KEY,
+  workspace_id INT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  rule_id INT NOT NULL REFERENCES automation_rules(id) ON DELETE CASCADE,
+  task_id INT NOT NULL,
+  event_id TEXT NOT NULL,
+  trigger TEXT NOT NULL,
+  rule_chain INT[] NOT NULL DEFAULT '{}',
+  task JSONB,
+  status TEXT NOT NULL DEFAULT 'pending'
+    CHECK (status IN ('pending', 'running', 'succeeded', 'skipped', 'failed')),
+  results JSONB NOT NULL DEFAULT '[]',
+  error TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  started_at TIMESTAMP,
+  finished_at TIMESTAMP,
+  UNIQUE (rule_id, event_id)
+);
+
+CREATE INDEX automation_runs_pending_idx ON automation_runs (id) WHERE status IN ('pending', 'running');
+CREATE INDEX automation_runs_rule_id_idx ON automation_runs (rule_id, id DESC);
+
+ALTER TABLE automation_runs ENABLE ROW LEVEL SECURITY;
+ALTER TABLE automation_runs FORCE ROW LEVEL SECURITY;
+CREATE POLICY automation_runs_tenant_isolation ON automation_runs
+  USING (tenant_visible(workspace_id))
+  WITH CHECK (tenant_visible(workspace_id));
@@ -11,6 +11,18 @@ type Config struct {
 	Port      string
 	URL       string
 	JWTSecret string
+	// AppURL is the base URL of the web app, used for links in emails.
+	AppURL string
+	// SMTP settings; mail is written to the log when SMTPHost is empty.
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	MailFrom     string
+	// InboundDomain is the domain of users' inbound email addresses, and
+	// InboundSecret what the mail server sends to deliver to them.
+	InboundDomain string
+	InboundSecret string
 }
 
 func Load() Config {
@@ -25,9 +37,26 @@ func Load() Config {
 	jwt := os.Getenv("JWT_SECRET")
 
 	config := Config{
-		Port:      port,
-		URL:       url,
-		JWTSecret: jwt,
+		Port:          port,
+		URL:           url,
+		JWTSecret:     jwt,
+		AppURL:        os.Getenv("APP_URL"),
+		SMTPHost:      os.Getenv("SMTP_HOST"),
+		SMTPPort:      os.Getenv("SMTP_PORT"),
+		SMTPUsername:  os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:  os.Getenv("SMTP_PASSWORD"),
+		MailFrom:      os.Getenv("MAIL_FROM"),
+		InboundDomain: os.Getenv("INBOUND_DOMAIN"),
+		InboundSecret: os.Getenv("INBOUND_SECRET"),
+	}
+	if config.SMTPPort == "" {
+		config.SMTPPort = "25"
+	}
+	if config.MailFrom == "" {
+		config.MailFrom = "gotask <no-reply@localhost>"
+	}
+	if config.InboundDomain == "" {
+		config.InboundDomain = "localhost"
 	}
 
 	return config
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"database/sql"
+)
+
+// taskChangesLock is the advisory lock class for task writes; the second
+// key is the workspace.
+const taskChangesLock = 7341
+
+// LockTaskChanges must be called in every transaction that takes a number
+// from task_change_seq, before it does. Writers in a workspace then commit
+// in sequence order, so a reader that has seen change N has also seen every
+// earlier change and a sync token never skips one that committed late.
+func LockTaskChanges(tx *sql.Tx, workspaceID int64) error {
+	_, err := tx.Exec(`SELECT pg_advisory_xact_lock($1, $2::int);`, taskChangesLock, workspaceID)
+	return err
+}
+
+// TombstoneTasks records the deletion of the tasks matched by where (over
+// tasks t) for sync clients, along with who could see each of them. It must
+// run before the delete, in a transaction holding LockTaskChanges.
+func TombstoneTasks(tx *sql.Tx, where string, args ...any) error {
+	_, err := tx.Exec(`INSERT INTO task_tombstones (task_id, workspace_id, visible_to, change_seq)
+            SELECT t.id, t.workspace_id,
+                   CASE WHEN t.project_id IS NULL THEN ARRAY[t.created_by]
+                        ELSE ARRAY(SELECT user_id FROM project_members WHERE project_id = t.project_id) END,
+                   nextval('task_change_seq')
+            FROM tasks t
+            WHERE `+where+`
+            ORDER BY t.id
+            ON CONFLICT (task_id) DO NOTHING;`, args...)
+	return err
+}
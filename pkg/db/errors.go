@@ -0,0 +1,16 @@
+package db
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// IsUnknownTimezone reports whether err is Postgres rejecting a time zone
+// name, as AT TIME ZONE does for one missing from the server's tz database.
+// Zones are checked in Go first, but the two databases can differ.
+func IsUnknownTimezone(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "22023" && strings.HasPrefix(pqErr.Message, "time zone")
+}
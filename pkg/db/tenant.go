@@ -0,0 +1,50 @@
+package db
+
+import (
+	"database/sql"
+	"strconv"
+)
+
+// WithTenant runs fn inside a transaction bound to the given workspace. The
+// app.workspace_id setting it applies is what the row-level security
+// policies on tenant tables check, so queries in fn only ever see that
+// workspace's rows. Queries on tenant tables outside WithTenant or
+// AcrossTenants see no rows at all.
+func WithTenant(db *sql.DB, workspaceID int64, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`SELECT set_config('app.workspace_id', $1, true);`, strconv.FormatInt(workspaceID, 10))
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AcrossTenants runs fn inside a transaction that row-level security does
+// not restrict, by setting app.bypass_rls. It is for background jobs that
+// work through every workspace's rows; anything acting for a user belongs
+// in WithTenant.
+func AcrossTenants(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT set_config('app.bypass_rls', 'on', true);`); err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,341 @@
+// Package ical reads and writes iCalendar (RFC 5545) data: components such
+// as VCALENDAR, VTODO and VEVENT holding properties with parameters.
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Property is a content line. Value is as written in the file; use Text
+// and SetText for TEXT values, which are escaped.
+type Property struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// Text returns the property's value unescaped.
+func (p *Property) Text() string {
+	return unescape(p.Value)
+}
+
+type Component struct {
+	Name       string
+	Props      []Property
+	Components []*Component
+}
+
+func NewComponent(name string) *Component {
+	return &Component{Name: name}
+}
+
+// Add appends a property with a value written as is.
+func (c *Component) Add(name, value string, params map[string]string) {
+	c.Props = append(c.Props, Property{Name: name, Params: params, Value: value})
+}
+
+// AddText appends a TEXT property, escaping its value.
+func (c *Component) AddText(name, value string) {
+	c.Add(name, escape(value), nil)
+}
+
+// AddTime appends a DATE-TIME property in UTC.
+func (c *Component) AddTime(name string, t time.Time) {
+	c.Add(name, t.UTC().Format(utcLayout), nil)
+}
+
+// AddDate appends a DATE property, for all-day values.
+func (c *Component) AddDate(name string, t time.Time) {
+	c.Add(name, t.Format(dateLayout), map[string]string{"VALUE": "DATE"})
+}
+
+// Get returns the first property with the name, or nil.
+func (c *Component) Get(name string) *Property {
+	for i := range c.Props {
+		if strings.EqualFold(c.Props[i].Name, name) {
+			return &c.Props[i]
+		}
+	}
+	return nil
+}
+
+// All returns every property with the name.
+func (c *Component) All(name string) []Property {
+	props := []Property{}
+	for _, p := range c.Props {
+		if strings.EqualFold(p.Name, name) {
+			props = append(props, p)
+		}
+	}
+	return props
+}
+
+// Children returns the nested components with the name, such as the
+// VTODOs of a VCALENDAR.
+func (c *Component) Children(name string) []*Component {
+	children := []*Component{}
+	for _, child := range c.Components {
+		if strings.EqualFold(child.Name, name) {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+const (
+	utcLayout      = "20060102T150405Z"
+	floatingLayout = "20060102T150405"
+	dateLayout     = "20060102"
+	// lineLimit is the longest content line, in bytes, before folding.
+	lineLimit = 75
+)
+
+// Time reads a DATE or DATE-TIME value. Times with a TZID in the time zone
+// database are read in that zone; times without a zone, and those whose
+// TZID is unknown, are read in loc. Dates are midnight in loc, with allDay
+// set.
+func (p *Property) Time(loc *time.Location) (t time.Time, allDay bool, err error) {
+	value := strings.TrimSpace(p.Value)
+	if p.Params["VALUE"] == "DATE" || len(value) == len(dateLayout) {
+		t, err = time.ParseInLocation(dateLayout, value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err = time.Parse(utcLayout, value)
+		return t, false, err
+	}
+	if tzid := p.Params["TZID"]; tzid != "" {
+		if zone, zerr := time.LoadLocation(strings.Trim(tzid, "/")); zerr == nil {
+			loc = zone
+		}
+	}
+	t, err = time.ParseInLocation(floatingLayout, value, loc)
+	return t, false, err
+}
+
+// escape writes a TEXT value: backslashes, semicolons, commas and newlines
+// are escaped.
+func escape(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+func unescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// SplitText splits a multi-valued TEXT value such as CATEGORIES on its
+// unescaped commas.
+func SplitText(value string) []string {
+	parts := []string{}
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case ',':
+			parts = append(parts, unescape(value[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, unescape(value[start:]))
+}
+
+// JoinText writes a multi-valued TEXT value.
+func JoinText(values []string) string {
+	escaped := make([]string, len(values))
+	for i, v := range values {
+		escaped[i] = escape(v)
+	}
+	return strings.Join(escaped, ",")
+}
+
+// Encode writes the component and its children with CRLF line endings,
+// folding lines longer than 75 bytes.
+func Encode(w io.Writer, c *Component) error {
+	bw := bufio.NewWriter(w)
+	encode(bw, c)
+	return bw.Flush()
+}
+
+func encode(w *bufio.Writer, c *Component) {
+	writeLine(w, "BEGIN:"+c.Name)
+	for _, p := range c.Props {
+		var line strings.Builder
+		line.WriteString(p.Name)
+		for _, name := range sortedKeys(p.Params) {
+			value := p.Params[name]
+			if strings.ContainsAny(value, ":;,") {
+				value = `"` + strings.ReplaceAll(value, `"`, "") + `"`
+			}
+			line.WriteString(";" + name + "=" + value)
+		}
+		line.WriteString(":" + p.Value)
+		writeLine(w, line.String())
+	}
+	for _, child := range c.Components {
+		encode(w, child)
+	}
+	writeLine(w, "END:"+c.Name)
+}
+
+// writeLine folds the line without splitting a UTF-8 character; each
+// continuation line starts with a space.
+func writeLine(w *bufio.Writer, line string) {
+	limit := lineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// The leading space counts towards the next line.
+		limit = lineLimit - 1
+	}
+	w.WriteString(line + "\r\n")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Decode reads the first component of r, usually a VCALENDAR. Property
+// and parameter names are upper-cased.
+func Decode(r io.Reader) (*Component, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var stack []*Component
+	for n, line := range lines {
+		if line == "" {
+			continue
+		}
+		p, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+		switch p.Name {
+		case "BEGIN":
+			c := NewComponent(strings.ToUpper(p.Value))
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Components = append(parent.Components, c)
+			}
+			stack = append(stack, c)
+		case "END":
+			if len(stack) == 0 || !strings.EqualFold(stack[len(stack)-1].Name, p.Value) {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", n+1, p.Value)
+			}
+			if len(stack) == 1 {
+				return stack[0], nil
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("line %d: property outside a component", n+1)
+			}
+			c := stack[len(stack)-1]
+			c.Props = append(c.Props, p)
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("missing END:%s", stack[0].Name)
+	}
+	return nil, errors.New("no calendar data")
+}
+
+// unfold joins continuation lines, which start with a space or a tab.
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	lines := []string{}
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) == 0 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseLine reads NAME;PARAM=VALUE;...:VALUE, where parameter values may
+// be quoted.
+func parseLine(line string) (Property, error) {
+	p := Property{}
+	i := strings.IndexAny(line, ";:")
+	if i <= 0 {
+		return p, errors.New("invalid content line")
+	}
+	p.Name = strings.ToUpper(line[:i])
+	for line[i] == ';' {
+		line = line[i+1:]
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 {
+			return p, errors.New("invalid parameter")
+		}
+		name := strings.ToUpper(line[:eq])
+		line = line[eq+1:]
+		var value string
+		if strings.HasPrefix(line, `"`) {
+			end := strings.IndexByte(line[1:], '"')
+			if end < 0 {
+				return p, errors.New("unterminated parameter value")
+			}
+			value = line[1 : end+1]
+			line = line[end+2:]
+			i = 0
+		} else {
+			i = strings.IndexAny(line, ";:")
+			if i < 0 {
+				return p, errors.New("invalid content line")
+			}
+			value = line[:i]
+		}
+		if p.Params == nil {
+			p.Params = map[string]string{}
+		}
+		p.Params[name] = value
+		if i >= len(line) {
+			return p, errors.New("invalid content line")
+		}
+		if line[i] != ';' && line[i] != ':' {
+			return p, errors.New("invalid parameter")
+		}
+	}
+	p.Value = line[i+1:]
+	return p, nil
+}
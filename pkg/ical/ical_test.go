@@ -0,0 +1,190 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestEncodeFoldsLongLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"short", "Buy milk"},
+		{"ASCII", strings.Repeat("abcdefghij", 20)},
+		{"multi-byte", strings.Repeat("日本語のタスク", 15)},
+		{"emoji", strings.Repeat("✅🚀", 40)},
+	}
+	for _, tt := range tests {
+		c := NewComponent("VTODO")
+		c.AddText("SUMMARY", tt.value)
+
+		var buf bytes.Buffer
+		if err := Encode(&buf, c); err != nil {
+			t.Fatalf("%s: Encode: %v", tt.name, err)
+		}
+		out := buf.String()
+		if !strings.HasSuffix(out, "\r\n") {
+			t.Errorf("%s: output does not end in CRLF", tt.name)
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+			if len(line) > lineLimit {
+				t.Errorf("%s: line of %d bytes: %q", tt.name, len(line), line)
+			}
+			if !utf8.ValidString(line) {
+				t.Errorf("%s: line splits a character: %q", tt.name, line)
+			}
+		}
+
+		decoded, err := Decode(&buf)
+		if err != nil {
+			t.Fatalf("%s: Decode: %v", tt.name, err)
+		}
+		if got := decoded.Get("SUMMARY").Text(); got != tt.value {
+			t.Errorf("%s: round trip = %q, want %q", tt.name, got, tt.value)
+		}
+	}
+}
+
+func TestTextEscaping(t *testing.T) {
+	tests := []struct {
+		text    string
+		escaped string
+	}{
+		{"plain", "plain"},
+		{"a, b; c", `a\, b\; c`},
+		{`back\slash`, `back\\slash`},
+		{"two\nlines", `two\nlines`},
+		{"crlf\r\nline", `crlf\nline`},
+	}
+	for _, tt := range tests {
+		c := NewComponent("VTODO")
+		c.AddText("DESCRIPTION", tt.text)
+		p := c.Get("DESCRIPTION")
+		if p.Value != tt.escaped {
+			t.Errorf("AddText(%q) wrote %q, want %q", tt.text, p.Value, tt.escaped)
+		}
+		want := strings.ReplaceAll(tt.text, "\r\n", "\n")
+		if got := p.Text(); got != want {
+			t.Errorf("Text() of %q = %q, want %q", p.Value, got, want)
+		}
+	}
+
+	if got := (&Property{Value: `upper\Ncase and trailing\`}).Text(); got != "upper\ncase and trailing\\" {
+		t.Errorf("Text() = %q", got)
+	}
+}
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"work", []string{"work"}},
+		{"work,home", []string{"work", "home"}},
+		{`a\,b,c`, []string{"a,b", "c"}},
+		{`a\\,b`, []string{`a\`, "b"}},
+		{"", []string{""}},
+	}
+	for _, tt := range tests {
+		got := SplitText(tt.value)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+			t.Errorf("SplitText(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+		if joined := JoinText(got); tt.value != "" && joined != tt.value {
+			t.Errorf("JoinText(%q) = %q, want %q", got, joined, tt.value)
+		}
+	}
+}
+
+func TestPropertyTime(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		line   string
+		want   time.Time
+		allDay bool
+	}{
+		{"UTC", "DUE:20240301T120000Z", time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), false},
+		{"floating", "DUE:20240301T120000", time.Date(2024, 3, 1, 12, 0, 0, 0, berlin), false},
+		{"TZID", "DUE;TZID=Asia/Tokyo:20240301T120000", time.Date(2024, 3, 1, 12, 0, 0, 0, tokyo), false},
+		{"quoted TZID", `DUE;TZID="/Asia/Tokyo":20240301T120000`, time.Date(2024, 3, 1, 12, 0, 0, 0, tokyo), false},
+		{"unknown TZID", "DUE;TZID=Custom Zone:20240301T120000", time.Date(2024, 3, 1, 12, 0, 0, 0, berlin), false},
+		{"DATE", "DUE;VALUE=DATE:20240301", time.Date(2024, 3, 1, 0, 0, 0, 0, berlin), true},
+		{"bare date", "DTSTART:20240301", time.Date(2024, 3, 1, 0, 0, 0, 0, berlin), true},
+	}
+	for _, tt := range tests {
+		p, err := parseLine(tt.line)
+		if err != nil {
+			t.Fatalf("%s: parseLine: %v", tt.name, err)
+		}
+		got, allDay, err := p.Time(berlin)
+		if err != nil {
+			t.Errorf("%s: Time: %v", tt.name, err)
+			continue
+		}
+		if !got.Equal(tt.want) || allDay != tt.allDay {
+			t.Errorf("%s: Time = %v, %v, want %v, %v", tt.name, got, allDay, tt.want, tt.allDay)
+		}
+	}
+
+	for _, value := range []string{"tomorrow", "20241301T000000Z", "2024-03-01"} {
+		if _, _, err := (&Property{Name: "DUE", Value: value}).Time(time.UTC); err == nil {
+			t.Errorf("Time(%q) did not fail", value)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	src := "\ufeffBEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"BEGIN:VTODO\r\n" +
+		"UID:1@example.com\r\n" +
+		"summary;LANGUAGE=en:Write the\r\n" +
+		"  report\r\n" +
+		"X-NOTE;X-A=\"a;b:c\";X-B=d:value:with:colons\r\n" +
+		"END:VTODO\r\n" +
+		"END:VCALENDAR\r\n"
+
+	cal, err := Decode(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	todos := cal.Children("VTODO")
+	if cal.Name != "VCALENDAR" || len(todos) != 1 {
+		t.Fatalf("got %s with %d to-dos", cal.Name, len(todos))
+	}
+	summary := todos[0].Get("SUMMARY")
+	if summary == nil || summary.Text() != "Write the report" || summary.Params["LANGUAGE"] != "en" {
+		t.Errorf("SUMMARY = %+v", summary)
+	}
+	note := todos[0].Get("X-NOTE")
+	if note == nil || note.Params["X-A"] != "a;b:c" || note.Params["X-B"] != "d" || note.Value != "value:with:colons" {
+		t.Errorf("X-NOTE = %+v", note)
+	}
+
+	invalid := []string{
+		"",
+		"BEGIN:VCALENDAR\r\n",
+		"BEGIN:VCALENDAR\r\nEND:VTODO\r\n",
+		"SUMMARY:outside\r\n",
+		"BEGIN:VCALENDAR\r\nX;A=\"unterminated:x\r\nEND:VCALENDAR\r\n",
+		"BEGIN:VCALENDAR\r\nno colon\r\nEND:VCALENDAR\r\n",
+	}
+	for _, src := range invalid {
+		if _, err := Decode(strings.NewReader(src)); err == nil {
+			t.Errorf("Decode(%q) did not fail", src)
+		}
+	}
+}
@@ -0,0 +1,537 @@
+// Package markdown renders CommonMark with GFM task list items to HTML and
+// sanitizes HTML for display.
+package markdown
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type blockKind int
+
+const (
+	blockParagraph blockKind = iota
+	blockHeading
+	blockCode
+	blockHTML
+	blockRule
+	blockQuote
+	blockList
+	blockItem
+)
+
+type block struct {
+	kind blockKind
+	// text is the inline content of paragraphs and headings, and the
+	// literal content of code and HTML blocks.
+	text     string
+	level    int
+	info     string
+	children []*block
+	// Lists.
+	ordered bool
+	start   int
+	tight   bool
+	// Items: task is 0 for plain items, 1 for unchecked and 2 for checked
+	// task items.
+	task int
+}
+
+// line is a source line with its number, kept through container prefixes
+// so that task items can be found in the source.
+type line struct {
+	text string
+	no   int
+}
+
+// TaskItem is a "- [ ]" or "- [x]" item of a task list. Index counts task
+// items in document order from 0.
+type TaskItem struct {
+	Index   int    `json:"index"`
+	Text    string `json:"text"`
+	Checked bool   `json:"checked"`
+	line    int
+}
+
+type linkRef struct {
+	dest  string
+	title string
+}
+
+type parser struct {
+	refs  map[string]linkRef
+	tasks []TaskItem
+}
+
+// normalize converts CRLF line endings to \n, expands tabs in indentation
+// to four-column stops, and replaces NUL characters. Lines keep their
+// numbers in the source.
+func normalize(src string) []line {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\x00", "�")
+	raw := strings.Split(src, "\n")
+	lines := make([]line, len(raw))
+	for i, l := range raw {
+		lines[i] = line{text: expandTabs(l), no: i}
+	}
+	return lines
+}
+
+func expandTabs(s string) string {
+	if !strings.Contains(s, "\t") {
+		return s
+	}
+	var b strings.Builder
+	col := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\t' && c != ' ' && c != '>' {
+			// Tabs after the indentation and block quote markers are
+			// left alone.
+			b.WriteString(s[i:])
+			break
+		}
+		if c == '\t' {
+			n := 4 - col%4
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+			continue
+		}
+		b.WriteByte(c)
+		col++
+	}
+	return b.String()
+}
+
+func parse(src string) (*parser, []*block) {
+	p := &parser{refs: map[string]linkRef{}}
+	return p, p.blocks(normalize(src))
+}
+
+func isBlank(s string) bool {
+	return strings.TrimSpace(s) == ""
+}
+
+func indentOf(s string) int {
+	return len(s) - len(strings.TrimLeft(s, " "))
+}
+
+// unindent removes up to n leading spaces.
+func unindent(s string, n int) string {
+	i := 0
+	for i < n && i < len(s) && s[i] == ' ' {
+		i++
+	}
+	return s[i:]
+}
+
+var (
+	atxHeading   = regexp.MustCompile(`^#{1,6}(?:[ \t]|$)`)
+	atxClosing   = regexp.MustCompile(`(?:^|[ \t]+)#+[ \t]*$`)
+	thematic     = regexp.MustCompile(`^(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	setextLine   = regexp.MustCompile(`^(?:=+|-+)[ \t]*$`)
+	fenceOpen    = regexp.MustCompile("^(`{3,}|~{3,})(.*)$")
+	taskMarker   = regexp.MustCompile(`^\[([ xX])\][ \t]+\S`)
+	htmlRawOpen  = regexp.MustCompile(`(?i)^<(script|pre|style|textarea)(?:\s|>|$)`)
+	htmlRawClose = regexp.MustCompile(`(?i)</(script|pre|style|textarea)>`)
+	htmlBlockTag = regexp.MustCompile(`(?i)^</?([a-z][a-z0-9]*)(?:\s|/?>|$)`)
+	htmlAnyTag   = regexp.MustCompile(`^(?:` + openTag + `|` + closeTag + `)[ \t]*$`)
+	linkRefDef   = regexp.MustCompile(`^\[((?:[^\\\[\]]|\\.){1,999})\]:[ \t]*(<[^<>\n]*>|\S+)(?:[ \t]+("(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|\((?:[^()\\]|\\.)*\)))?[ \t]*$`)
+)
+
+var blockTags = map[string]bool{}
+
+func init() {
+	for _, t := range strings.Fields(`address article aside base basefont blockquote body caption center col
+		colgroup dd details dialog dir div dl dt fieldset figcaption figure footer form frame frameset
+		h1 h2 h3 h4 h5 h6 head header hr html iframe legend li link main menu menuitem nav noframes ol
+		optgroup option p param search section summary table tbody td tfoot th thead title tr track ul`) {
+		blockTags[t] = true
+	}
+}
+
+// htmlStart returns the kind of HTML block a line starts: 1 for raw text
+// elements, 2 for comments, 6 for block-level tags and 7 for any other
+// complete tag, which cannot interrupt a paragraph.
+func htmlStart(s string) int {
+	switch {
+	case htmlRawOpen.MatchString(s):
+		return 1
+	case strings.HasPrefix(s, "<!--"):
+		return 2
+	}
+	if m := htmlBlockTag.FindStringSubmatch(s); m != nil && blockTags[strings.ToLower(m[1])] {
+		return 6
+	}
+	if htmlAnyTag.MatchString(s) {
+		return 7
+	}
+	return 0
+}
+
+type listMarker struct {
+	bullet  byte
+	ordered bool
+	start   int
+	// width is the column where the item's content starts.
+	width   int
+	content string
+	empty   bool
+}
+
+func parseListMarker(s string) (listMarker, bool) {
+	m := listMarker{}
+	indent := indentOf(s)
+	if indent > 3 {
+		return m, false
+	}
+	rest := s[indent:]
+	n := 0
+	switch {
+	case rest != "" && strings.IndexByte("-+*", rest[0]) >= 0:
+		m.bullet, n = rest[0], 1
+	default:
+		for n < len(rest) && n < 9 && rest[n] >= '0' && rest[n] <= '9' {
+			n++
+		}
+		if n == 0 || n >= len(rest) || (rest[n] != '.' && rest[n] != ')') {
+			return m, false
+		}
+		m.ordered, m.bullet = true, rest[n]
+		m.start, _ = strconv.Atoi(rest[:n])
+		n++
+	}
+	rest = rest[n:]
+	if rest != "" && rest[0] != ' ' {
+		return m, false
+	}
+	spaces := indentOf(rest)
+	switch {
+	case isBlank(rest):
+		m.empty, m.width = true, indent+n+1
+	case spaces > 4:
+		m.width, m.content = indent+n+1, rest[1:]
+	default:
+		m.width, m.content = indent+n+spaces, rest[spaces:]
+	}
+	return m, true
+}
+
+// startsBlock reports whether a line starts a block other than a
+// paragraph, which ends a paragraph before it. Only lists with content, and
+// ordered ones starting at 1, do so.
+func startsBlock(s string) bool {
+	if indentOf(s) > 3 {
+		return false
+	}
+	t := s[indentOf(s):]
+	if atxHeading.MatchString(t) || thematic.MatchString(t) || fenceOpen.MatchString(t) || strings.HasPrefix(t, ">") {
+		return true
+	}
+	if k := htmlStart(t); k > 0 && k < 7 {
+		return true
+	}
+	if m, ok := parseListMarker(s); ok && !m.empty && (!m.ordered || m.start == 1) {
+		return true
+	}
+	return false
+}
+
+func (p *parser) blocks(lines []line) []*block {
+	var blocks []*block
+	for i := 0; i < len(lines); {
+		l := lines[i]
+		if isBlank(l.text) {
+			i++
+			continue
+		}
+		indent := indentOf(l.text)
+		if indent >= 4 {
+			b, n := indentedCode(lines[i:])
+			blocks, i = append(blocks, b), i+n
+			continue
+		}
+		s := l.text[indent:]
+		switch {
+		case fenceOpen.MatchString(s) && !(s[0] == '`' && strings.Contains(fenceOpen.FindStringSubmatch(s)[2], "`")):
+			b, n := fencedCode(lines[i:], indent)
+			blocks, i = append(blocks, b), i+n
+		case atxHeading.MatchString(s):
+			level := len(s) - len(strings.TrimLeft(s, "#"))
+			text := strings.TrimSpace(atxClosing.ReplaceAllString(strings.TrimSpace(s[level:]), ""))
+			blocks, i = append(blocks, &block{kind: blockHeading, level: level, text: text}), i+1
+		case thematic.MatchString(s):
+			blocks, i = append(blocks, &block{kind: blockRule}), i+1
+		case strings.HasPrefix(s, ">"):
+			b, n := p.quote(lines[i:])
+			blocks, i = append(blocks, b), i+n
+		case htmlStart(s) > 0:
+			b, n := htmlBlock(lines[i:], htmlStart(s))
+			blocks, i = append(blocks, b), i+n
+		default:
+			if _, ok := parseListMarker(l.text); ok {
+				b, n := p.list(lines[i:])
+				blocks, i = append(blocks, b), i+n
+				continue
+			}
+			b, n := p.paragraph(lines[i:])
+			if b != nil {
+				blocks = append(blocks, b)
+			}
+			i += n
+		}
+	}
+	return blocks
+}
+
+func indentedCode(lines []line) (*block, int) {
+	var code []string
+	n := 0
+	for n < len(lines) && (isBlank(lines[n].text) || indentOf(lines[n].text) >= 4) {
+		code = append(code, unindent(lines[n].text, 4))
+		n++
+	}
+	for len(code) > 0 && isBlank(code[len(code)-1]) {
+		code = code[:len(code)-1]
+	}
+	return &block{kind: blockCode, text: strings.Join(code, "\n") + "\n"}, n
+}
+
+func fencedCode(lines []line, indent int) (*block, int) {
+	m := fenceOpen.FindStringSubmatch(lines[0].text[indent:])
+	fence := m[1]
+	b := &block{kind: blockCode, info: unescape(strings.TrimSpace(m[2]))}
+
+	var code []string
+	n := 1
+	for ; n < len(lines); n++ {
+		t := lines[n].text
+		if indentOf(t) < 4 {
+			c := strings.TrimSpace(t)
+			if strings.HasPrefix(c, fence) && strings.Trim(c, fence[:1]) == "" {
+				n++
+				break
+			}
+		}
+		code = append(code, unindent(t, indent))
+	}
+	if len(code) > 0 {
+		b.text = strings.Join(code, "\n") + "\n"
+	}
+	return b, n
+}
+
+func htmlBlock(lines []line, kind int) (*block, int) {
+	var html []string
+	n := 0
+	for ; n < len(lines); n++ {
+		t := lines[n].text
+		if kind >= 6 && isBlank(t) {
+			break
+		}
+		html = append(html, t)
+		if (kind == 1 && htmlRawClose.MatchString(t)) || (kind == 2 && strings.Contains(t, "-->")) {
+			n++
+			break
+		}
+	}
+	return &block{kind: blockHTML, text: strings.Join(html, "\n") + "\n"}, n
+}
+
+func (p *parser) quote(lines []line) (*block, int) {
+	var inner []line
+	n := 0
+	for ; n < len(lines); n++ {
+		t := lines[n].text
+		if indent := indentOf(t); indent < 4 && strings.HasPrefix(t[indent:], ">") {
+			rest := t[indent+1:]
+			if strings.HasPrefix(rest, " ") {
+				rest = rest[1:]
+			}
+			inner = append(inner, line{text: rest, no: lines[n].no})
+			continue
+		}
+		// A lazy continuation line carries on a paragraph.
+		if !isBlank(t) && len(inner) > 0 && !isBlank(inner[len(inner)-1].text) && !startsBlock(t) {
+			inner = append(inner, lines[n])
+			continue
+		}
+		break
+	}
+	return &block{kind: blockQuote, children: p.blocks(inner)}, n
+}
+
+func sameList(a, b listMarker) bool {
+	return a.ordered == b.ordered && a.bullet == b.bullet
+}
+
+func (p *parser) list(lines []line) (*block, int) {
+	first, _ := parseListMarker(lines[0].text)
+	list := &block{kind: blockList, ordered: first.ordered, start: first.start, tight: true}
+
+	n := 0
+	for n < len(lines) {
+		m, ok := parseListMarker(lines[n].text)
+		if !ok || !sameList(m, first) {
+			break
+		}
+		item := &block{kind: blockItem}
+		content := []line{{text: m.content, no: lines[n].no}}
+		if c := taskMarker.FindStringSubmatch(m.content); c != nil {
+			item.task = 1
+			if c[1] != " " {
+				item.task = 2
+			}
+			content[0].text = strings.TrimLeft(m.content[3:], " \t")
+			p.tasks = append(p.tasks, TaskItem{Index: len(p.tasks), Text: strings.TrimSpace(content[0].text),
+				Checked: item.task == 2, line: lines[n].no})
+		}
+
+		j := n + 1
+		for j < len(lines) {
+			t := lines[j].text
+			if isBlank(t) {
+				// Blank lines belong to the item if it goes on after them;
+				// an item starting with a blank line ends at another.
+				k := j
+				for k < len(lines) && isBlank(lines[k].text) {
+					k++
+				}
+				if k == len(lines) || indentOf(lines[k].text) < m.width || (m.empty && j == n+1) {
+					break
+				}
+				for ; j < k; j++ {
+					content = append(content, line{no: lines[j].no})
+				}
+				continue
+			}
+			if indentOf(t) >= m.width {
+				content = append(content, line{text: t[m.width:], no: lines[j].no})
+				j++
+				continue
+			}
+			if !isBlank(content[len(content)-1].text) && !startsBlock(t) {
+				if _, isItem := parseListMarker(t); !isItem {
+					content = append(content, lines[j])
+					j++
+					continue
+				}
+			}
+			break
+		}
+
+		if looseItem(content) {
+			list.tight = false
+		}
+		item.children = p.blocks(content)
+		list.children = append(list.children, item)
+
+		// Items separated by blank lines make the list loose.
+		k := j
+		for k < len(lines) && isBlank(lines[k].text) {
+			k++
+		}
+		if k < len(lines) {
+			if next, ok := parseListMarker(lines[k].text); ok && sameList(next, first) && !thematic.MatchString(strings.TrimSpace(lines[k].text)) {
+				if k > j {
+					list.tight = false
+				}
+				n = k
+				continue
+			}
+		}
+		n = j
+		break
+	}
+	return list, n
+}
+
+// looseItem reports whether blank lines separate blocks directly inside an
+// item, outside of fenced code.
+func looseItem(content []line) bool {
+	fence := ""
+	blank := false
+	for _, l := range content {
+		t := l.text
+		if fence != "" {
+			if c := strings.TrimSpace(t); strings.HasPrefix(c, fence) && strings.Trim(c, fence[:1]) == "" {
+				fence = ""
+			}
+			continue
+		}
+		if isBlank(t) {
+			blank = true
+			continue
+		}
+		if blank && indentOf(t) == 0 {
+			return true
+		}
+		blank = false
+		if m := fenceOpen.FindStringSubmatch(strings.TrimLeft(t, " ")); m != nil && indentOf(t) < 4 {
+			fence = m[1]
+		}
+	}
+	return false
+}
+
+func (p *parser) paragraph(lines []line) (*block, int) {
+	text := []string{strings.TrimLeft(lines[0].text, " ")}
+	n := 1
+	level := 0
+	for ; n < len(lines); n++ {
+		t := lines[n].text
+		if isBlank(t) {
+			break
+		}
+		if indentOf(t) < 4 {
+			if setextLine.MatchString(strings.TrimLeft(t, " ")) {
+				level = 1
+				if strings.TrimLeft(t, " ")[0] == '-' {
+					level = 2
+				}
+				n++
+				break
+			}
+			if startsBlock(t) {
+				break
+			}
+		}
+		text = append(text, strings.TrimLeft(t, " "))
+	}
+
+	// Link reference definitions at the start of a paragraph are taken
+	// out of it.
+	for len(text) > 0 {
+		m := linkRefDef.FindStringSubmatch(text[0])
+		if m == nil {
+			break
+		}
+		label := normalizeLabel(m[1])
+		if _, exists := p.refs[label]; !exists && label != "" {
+			dest := m[2]
+			if strings.HasPrefix(dest, "<") {
+				dest = dest[1 : len(dest)-1]
+			}
+			title := m[3]
+			if title != "" {
+				title = title[1 : len(title)-1]
+			}
+			p.refs[label] = linkRef{dest: unescape(dest), title: unescape(title)}
+		}
+		text = text[1:]
+	}
+	if len(text) == 0 {
+		return nil, n
+	}
+
+	content := strings.TrimRight(strings.Join(text, "\n"), " \t")
+	if level > 0 {
+		return &block{kind: blockHeading, level: level, text: content}, n
+	}
+	return &block{kind: blockParagraph, text: content}, n
+}
+
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.ToUpper(strings.Join(strings.Fields(label), " ")))
+}
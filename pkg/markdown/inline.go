@@ -0,0 +1,585 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type inlineKind int
+
+const (
+	inlineText inlineKind = iota
+	inlineHTML
+	inlineCode
+	inlineSoftBreak
+	inlineHardBreak
+	inlineEmph
+	inlineStrong
+	inlineLink
+	inlineImage
+	inlineRoot
+)
+
+// inline is a node of the inline tree. Siblings are linked so that
+// emphasis and links can wrap a range of them.
+type inline struct {
+	kind        inlineKind
+	text        string
+	dest, title string
+
+	parent, first, last, prev, next *inline
+}
+
+func (n *inline) appendChild(c *inline) {
+	c.unlink()
+	c.parent = n
+	if n.last != nil {
+		n.last.next, c.prev = c, n.last
+	} else {
+		n.first = c
+	}
+	n.last = c
+}
+
+func (n *inline) insertAfter(c *inline) {
+	c.unlink()
+	c.parent, c.prev, c.next = n.parent, n, n.next
+	if n.next != nil {
+		n.next.prev = c
+	} else if n.parent != nil {
+		n.parent.last = c
+	}
+	n.next = c
+}
+
+func (n *inline) unlink() {
+	if n.prev != nil {
+		n.prev.next = n.next
+	} else if n.parent != nil {
+		n.parent.first = n.next
+	}
+	if n.next != nil {
+		n.next.prev = n.prev
+	} else if n.parent != nil {
+		n.parent.last = n.prev
+	}
+	n.parent, n.prev, n.next = nil, nil, nil
+}
+
+// delimiter is a run of * or _ that may open or close emphasis.
+type delimiter struct {
+	node              *inline
+	char              byte
+	count, orig       int
+	canOpen, canClose bool
+	prev, next        *delimiter
+}
+
+// bracket is a [ or ![ that may start a link or image.
+type bracket struct {
+	node         *inline
+	image        bool
+	active       bool
+	bracketAfter bool
+	// index is where the link text starts.
+	index     int
+	prevDelim *delimiter
+	prev      *bracket
+}
+
+type inlineParser struct {
+	src      string
+	pos      int
+	refs     map[string]linkRef
+	root     *inline
+	delims   *delimiter
+	brackets *bracket
+}
+
+const (
+	tagName   = `[A-Za-z][A-Za-z0-9-]*`
+	attribute = `(?:\s+[A-Za-z_:][A-Za-z0-9_.:-]*(?:\s*=\s*(?:[^\s"'=<>` + "`" + `]+|'[^']*'|"[^"]*"))?)`
+	openTag   = `<` + tagName + attribute + `*\s*/?>`
+	closeTag  = `</` + tagName + `\s*>`
+)
+
+var (
+	inlineTag    = regexp.MustCompile(`^(?:` + openTag + `|` + closeTag + `|<!-->|<!--->|(?s:<!--.*?-->))`)
+	autolinkURI  = regexp.MustCompile(`^<([A-Za-z][A-Za-z0-9.+-]{1,31}:[^<>\x00-\x20]*)>`)
+	autolinkMail = regexp.MustCompile(`^<([a-zA-Z0-9.!#$%&'*+/=?^_` + "`" + `{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*)>`)
+	entity       = regexp.MustCompile(`^&(?:#[xX][0-9a-fA-F]{1,6}|#[0-9]{1,7}|[A-Za-z][A-Za-z0-9]{1,31});`)
+	escapable    = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+)
+
+// unescape resolves backslash escapes and entities in link destinations,
+// titles and code block info strings.
+func unescape(s string) string {
+	if !strings.ContainsAny(s, "\\&") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && strings.IndexByte(escapable, s[i+1]) >= 0:
+			b.WriteByte(s[i+1])
+			i++
+		case s[i] == '&':
+			if m := entity.FindString(s[i:]); m != "" {
+				b.WriteString(html.UnescapeString(m))
+				i += len(m) - 1
+				continue
+			}
+			b.WriteByte('&')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func parseInlines(src string, refs map[string]linkRef) *inline {
+	p := &inlineParser{src: src, refs: refs, root: &inline{kind: inlineRoot}}
+	for p.pos < len(p.src) {
+		p.next()
+	}
+	p.processEmphasis(nil)
+	return p.root
+}
+
+func (p *inlineParser) text(s string) *inline {
+	n := &inline{kind: inlineText, text: s}
+	p.root.appendChild(n)
+	return n
+}
+
+func (p *inlineParser) next() {
+	c := p.src[p.pos]
+	switch c {
+	case '\n':
+		p.newline()
+	case '\\':
+		p.pos++
+		switch {
+		case p.pos < len(p.src) && p.src[p.pos] == '\n':
+			p.root.appendChild(&inline{kind: inlineHardBreak})
+			p.pos++
+			p.skipSpaces()
+		case p.pos < len(p.src) && strings.IndexByte(escapable, p.src[p.pos]) >= 0:
+			p.text(p.src[p.pos : p.pos+1])
+			p.pos++
+		default:
+			p.text("\\")
+		}
+	case '`':
+		p.codeSpan()
+	case '*', '_':
+		p.delimiterRun(c)
+	case '[':
+		p.pos++
+		p.pushBracket(p.text("["), false)
+	case '!':
+		if p.pos+1 < len(p.src) && p.src[p.pos+1] == '[' {
+			p.pos += 2
+			p.pushBracket(p.text("!["), true)
+		} else {
+			p.pos++
+			p.text("!")
+		}
+	case ']':
+		p.closeBracket()
+	case '<':
+		p.angle()
+	case '&':
+		if m := entity.FindString(p.src[p.pos:]); m != "" {
+			p.text(html.UnescapeString(m))
+			p.pos += len(m)
+		} else {
+			p.pos++
+			p.text("&")
+		}
+	default:
+		end := p.pos + 1
+		for end < len(p.src) && strings.IndexByte("\n\\`*_[]!<&", p.src[end]) < 0 {
+			end++
+		}
+		p.text(p.src[p.pos:end])
+		p.pos = end
+	}
+}
+
+func (p *inlineParser) skipSpaces() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// newline ends a line with a hard break after two spaces, else a soft one.
+func (p *inlineParser) newline() {
+	kind := inlineSoftBreak
+	if last := p.root.last; last != nil && last.kind == inlineText && strings.HasSuffix(last.text, " ") {
+		if strings.HasSuffix(last.text, "  ") {
+			kind = inlineHardBreak
+		}
+		last.text = strings.TrimRight(last.text, " ")
+	}
+	p.root.appendChild(&inline{kind: kind})
+	p.pos++
+	p.skipSpaces()
+}
+
+func (p *inlineParser) codeSpan() {
+	start := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] == '`' {
+		p.pos++
+	}
+	ticks := p.pos - start
+
+	for i := p.pos; i < len(p.src); {
+		if p.src[i] != '`' {
+			i++
+			continue
+		}
+		j := i
+		for j < len(p.src) && p.src[j] == '`' {
+			j++
+		}
+		if j-i == ticks {
+			code := strings.ReplaceAll(p.src[p.pos:i], "\n", " ")
+			if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+				code = code[1 : len(code)-1]
+			}
+			p.root.appendChild(&inline{kind: inlineCode, text: code})
+			p.pos = j
+			return
+		}
+		i = j
+	}
+	p.text(p.src[start:p.pos])
+}
+
+func (p *inlineParser) angle() {
+	rest := p.src[p.pos:]
+	if m := autolinkURI.FindStringSubmatch(rest); m != nil {
+		link := &inline{kind: inlineLink, dest: m[1]}
+		link.appendChild(&inline{kind: inlineText, text: m[1]})
+		p.root.appendChild(link)
+		p.pos += len(m[0])
+		return
+	}
+	if m := autolinkMail.FindStringSubmatch(rest); m != nil {
+		link := &inline{kind: inlineLink, dest: "mailto:" + m[1]}
+		link.appendChild(&inline{kind: inlineText, text: m[1]})
+		p.root.appendChild(link)
+		p.pos += len(m[0])
+		return
+	}
+	if m := inlineTag.FindString(rest); m != "" {
+		p.root.appendChild(&inline{kind: inlineHTML, text: m})
+		p.pos += len(m)
+		return
+	}
+	p.pos++
+	p.text("<")
+}
+
+func isPunct(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+func (p *inlineParser) delimiterRun(c byte) {
+	start := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] == c {
+		p.pos++
+	}
+
+	before, after := '\n', '\n'
+	if start > 0 {
+		before, _ = utf8.DecodeLastRuneInString(p.src[:start])
+	}
+	if p.pos < len(p.src) {
+		after, _ = utf8.DecodeRuneInString(p.src[p.pos:])
+	}
+	beforeSpace, afterSpace := unicode.IsSpace(before), unicode.IsSpace(after)
+	beforePunct, afterPunct := isPunct(before), isPunct(after)
+	left := !afterSpace && (!afterPunct || beforeSpace || beforePunct)
+	right := !beforeSpace && (!beforePunct || afterSpace || afterPunct)
+
+	canOpen, canClose := left, right
+	if c == '_' {
+		canOpen = left && (!right || beforePunct)
+		canClose = right && (!left || afterPunct)
+	}
+
+	node := p.text(p.src[start:p.pos])
+	if canOpen || canClose {
+		d := &delimiter{node: node, char: c, count: p.pos - start, orig: p.pos - start,
+			canOpen: canOpen, canClose: canClose, prev: p.delims}
+		if p.delims != nil {
+			p.delims.next = d
+		}
+		p.delims = d
+	}
+}
+
+func (p *inlineParser) removeDelimiter(d *delimiter) {
+	if d.prev != nil {
+		d.prev.next = d.next
+	}
+	if d.next != nil {
+		d.next.prev = d.prev
+	} else {
+		p.delims = d.prev
+	}
+}
+
+type openerKey struct {
+	char    byte
+	canOpen bool
+	mod3    int
+}
+
+// processEmphasis matches the delimiters above bottom into emphasis, as in
+// the CommonMark reference implementation.
+func (p *inlineParser) processEmphasis(bottom *delimiter) {
+	openersBottom := map[openerKey]*delimiter{}
+
+	closer := p.delims
+	for closer != nil && closer.prev != bottom {
+		closer = closer.prev
+	}
+	for closer != nil {
+		if !closer.canClose {
+			closer = closer.next
+			continue
+		}
+		key := openerKey{closer.char, closer.canOpen, closer.orig % 3}
+		opener := closer.prev
+		found := false
+		for opener != nil && opener != bottom && opener != openersBottom[key] {
+			oddMatch := (closer.canOpen || opener.canClose) && closer.orig%3 != 0 && (opener.orig+closer.orig)%3 == 0
+			if opener.char == closer.char && opener.canOpen && !oddMatch {
+				found = true
+				break
+			}
+			opener = opener.prev
+		}
+
+		old := closer
+		if found {
+			use := 1
+			kind := inlineEmph
+			if closer.count >= 2 && opener.count >= 2 {
+				use, kind = 2, inlineStrong
+			}
+			opener.count -= use
+			closer.count -= use
+			opener.node.text = opener.node.text[:len(opener.node.text)-use]
+			closer.node.text = closer.node.text[:len(closer.node.text)-use]
+
+			emph := &inline{kind: kind}
+			for n := opener.node.next; n != nil && n != closer.node; {
+				next := n.next
+				emph.appendChild(n)
+				n = next
+			}
+			opener.node.insertAfter(emph)
+
+			for d := closer.prev; d != nil && d != opener; {
+				prev := d.prev
+				p.removeDelimiter(d)
+				d = prev
+			}
+			if opener.count == 0 {
+				opener.node.unlink()
+				p.removeDelimiter(opener)
+			}
+			if closer.count == 0 {
+				next := closer.next
+				closer.node.unlink()
+				p.removeDelimiter(closer)
+				closer = next
+			}
+			continue
+		}
+
+		closer = closer.next
+		openersBottom[key] = old.prev
+		if !old.canOpen {
+			p.removeDelimiter(old)
+		}
+	}
+
+	for p.delims != nil && p.delims != bottom {
+		p.removeDelimiter(p.delims)
+	}
+}
+
+func (p *inlineParser) pushBracket(node *inline, image bool) {
+	if p.brackets != nil {
+		p.brackets.bracketAfter = true
+	}
+	p.brackets = &bracket{node: node, image: image, active: true, index: p.pos, prevDelim: p.delims, prev: p.brackets}
+}
+
+func (p *inlineParser) closeBracket() {
+	p.pos++
+	opener := p.brackets
+	if opener == nil {
+		p.text("]")
+		return
+	}
+	if !opener.active {
+		p.brackets = opener.prev
+		p.text("]")
+		return
+	}
+
+	start := p.pos
+	dest, title, matched := p.inlineLink()
+	if !matched {
+		// A full reference [text][label], or a collapsed [text][] or
+		// shortcut [text] one labelled by the text.
+		p.pos = start
+		label := ""
+		n := p.linkLabel()
+		if n > 2 {
+			label = p.src[start+1 : start+n-1]
+		} else if !opener.bracketAfter {
+			label = p.src[opener.index : start-1]
+		}
+		if ref, ok := p.refs[normalizeLabel(label)]; ok && label != "" {
+			dest, title, matched = ref.dest, ref.title, true
+			p.pos = start + n
+		}
+	}
+
+	if !matched {
+		p.brackets = opener.prev
+		p.text("]")
+		return
+	}
+
+	kind := inlineLink
+	if opener.image {
+		kind = inlineImage
+	}
+	link := &inline{kind: kind, dest: dest, title: title}
+	for n := opener.node.next; n != nil; {
+		next := n.next
+		link.appendChild(n)
+		n = next
+	}
+	p.root.appendChild(link)
+	p.processEmphasis(opener.prevDelim)
+	opener.node.unlink()
+	p.brackets = opener.prev
+
+	// Links may not contain other links.
+	if !opener.image {
+		for b := p.brackets; b != nil; b = b.prev {
+			if !b.image {
+				b.active = false
+			}
+		}
+	}
+}
+
+// linkLabel returns the length of the [label] at the current position, or
+// 0 if there is none.
+func (p *inlineParser) linkLabel() int {
+	if p.pos >= len(p.src) || p.src[p.pos] != '[' {
+		return 0
+	}
+	for i := p.pos + 1; i < len(p.src) && i-p.pos <= 1000; i++ {
+		switch p.src[i] {
+		case '\\':
+			i++
+		case '[':
+			return 0
+		case ']':
+			return i - p.pos + 1
+		}
+	}
+	return 0
+}
+
+// inlineLink parses "(destination "title")" after a closing bracket.
+func (p *inlineParser) inlineLink() (string, string, bool) {
+	s := p.src
+	i := p.pos
+	if i >= len(s) || s[i] != '(' {
+		return "", "", false
+	}
+	i = skipWhitespace(s, i+1)
+
+	var dest string
+	if i < len(s) && s[i] == '<' {
+		j := i + 1
+		for j < len(s) && s[j] != '>' && s[j] != '<' && s[j] != '\n' {
+			if s[j] == '\\' {
+				j++
+			}
+			j++
+		}
+		if j >= len(s) || s[j] != '>' {
+			return "", "", false
+		}
+		dest, i = s[i+1:j], j+1
+	} else {
+		j, depth := i, 0
+		for j < len(s) && s[j] > ' ' {
+			if s[j] == '\\' && j+1 < len(s) && strings.IndexByte(escapable, s[j+1]) >= 0 {
+				j += 2
+				continue
+			}
+			if s[j] == '(' {
+				depth++
+			} else if s[j] == ')' {
+				if depth == 0 {
+					break
+				}
+				depth--
+			}
+			j++
+		}
+		if depth != 0 {
+			return "", "", false
+		}
+		dest, i = s[i:j], j
+	}
+
+	var title string
+	if j := skipWhitespace(s, i); j > i && j < len(s) && strings.IndexByte(`"'(`, s[j]) >= 0 {
+		closing := s[j]
+		if closing == '(' {
+			closing = ')'
+		}
+		k := j + 1
+		for k < len(s) && s[k] != closing {
+			if s[k] == '\\' {
+				k++
+			}
+			k++
+		}
+		if k >= len(s) {
+			return "", "", false
+		}
+		title, i = s[j+1:k], k+1
+	}
+	i = skipWhitespace(s, i)
+	if i >= len(s) || s[i] != ')' {
+		return "", "", false
+	}
+	p.pos = i + 1
+	return unescape(dest), unescape(title), true
+}
+
+func skipWhitespace(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n') {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,125 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+const rel = ` rel="nofollow noopener noreferrer"`
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"javascript URL", `<a href="javascript:alert(1)">x</a>`, `<a` + rel + `>x</a>`},
+		{"decimal entity in scheme", `<a href="&#106;avascript:alert(1)">x</a>`, `<a` + rel + `>x</a>`},
+		{"hex and named entities", `<a href="&#x6A;&#x61;vascript&colon;alert(1)">x</a>`, `<a` + rel + `>x</a>`},
+		{"tab entity in scheme", `<a href="java&#x09;script:alert(1)">x</a>`, `<a` + rel + `>x</a>`},
+		{"control character in scheme", "<a href=\"java\x01script:alert(1)\">x</a>", `<a` + rel + `>x</a>`},
+		{"leading space and upper case", `<a href=" JAVASCRIPT:alert(1)">x</a>`, `<a` + rel + `>x</a>`},
+		{"data URL", `<a href="data:text/html,hi">x</a>`, `<a` + rel + `>x</a>`},
+		{"colon after path", `<a href="/a?q=1:2">x</a>`, `<a href="/a?q=1:2"` + rel + `>x</a>`},
+		{"https URL", `<a href="https://example.com">x</a>`, `<a href="https://example.com"` + rel + `>x</a>`},
+		{"mailto URL", `<a href="mailto:a@example.com">x</a>`, `<a href="mailto:a@example.com"` + rel + `>x</a>`},
+		{"image with handler", `<img src="vbscript:x" onerror="alert(1)">`, `<img />`},
+		{"script", `a<script>alert(1)</script>b`, `ab`},
+		{"script inside svg", `<svg><script>alert(1)</script></svg>after`, `after`},
+		{"math with xlink", `<math><mi xlink:href="javascript:alert(1)">x</mi></math>after`, `after`},
+		{"nested svg", `<svg><svg></svg><a href="javascript:x">y</a></svg>after`, `after`},
+		{"split script tag", `<scr<script>ipt>alert(1)</script>`, `ipt&gt;alert(1)`},
+		{"comment", `<!-- <script>alert(1)</script> -->x`, `x`},
+		{"noscript parsing differential", `<noscript><p title="</noscript><img src=x onerror=alert(1)>"></noscript>`,
+			`<img src="x" />&#34;&gt;`},
+		{"misnested tags", `<b><i>text</b>`, `<b><i>text</i></b>`},
+		{"unclosed tag", `<em>unclosed`, `<em>unclosed</em>`},
+		{"stray end tag", `</div>text`, `text`},
+		{"quote in single-quoted attribute", `<a title='x" onmouseover="alert(1)'>t</a>`,
+			`<a title="x&#34; onmouseover=&#34;alert(1)"` + rel + `>t</a>`},
+		{"unquoted attributes", `<a title=x onmouseover=alert(1)>t</a>`, `<a title="x"` + rel + `>t</a>`},
+		{"style and event handlers", `<p style="color:red" onclick="y">p</p>`, `<p>p</p>`},
+		{"text input", `<input type="text" value="x">`, ``},
+		{"checkbox", `<input type="checkbox" checked>`, `<input type="checkbox" checked="" disabled="" />`},
+		{"unsafe class", `<code class="language-go evil">c</code>`, `<code>c</code>`},
+		{"safe class", `<code class="language-go">c</code>`, `<code class="language-go">c</code>`},
+		{"text is escaped", `1 < 2 & "3"`, `1 &lt; 2 &amp; &#34;3&#34;`},
+	}
+	for _, tt := range tests {
+		if got := Sanitize(tt.in); got != tt.want {
+			t.Errorf("%s: Sanitize(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRenderSafe(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		notWant string
+	}{
+		{"javascript link", "[x](javascript:alert(1))", `<a` + rel + `>x</a>`, "javascript"},
+		{"entity in image source", "![i](&#106;avascript:x)", `<img alt="i" />`, "avascript"},
+		{"raw script", "<script>alert(1)</script>\n\ntext", "<p>text</p>", "script"},
+		{"raw handler", `<b onclick="alert(1)">x</b>`, "<b>x</b>", "onclick"},
+		{"task list", "- [x] done", `<li class="task-list-item"><input type="checkbox" checked="" disabled="" /> done</li>`, ""},
+	}
+	for _, tt := range tests {
+		got := RenderSafe(tt.in)
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("%s: RenderSafe(%q) = %q, want it to contain %q", tt.name, tt.in, got, tt.want)
+		}
+		if tt.notWant != "" && strings.Contains(got, tt.notWant) {
+			t.Errorf("%s: RenderSafe(%q) = %q, want no %q", tt.name, tt.in, got, tt.notWant)
+		}
+	}
+}
+
+func TestTasks(t *testing.T) {
+	src := "- [ ] a\n  - [x] b\n> - [ ] c\n>   1. [ ] d\n\n```\n- [ ] not a task\n```\n- [X] e"
+	want := []struct {
+		text    string
+		checked bool
+	}{{"a", false}, {"b", true}, {"c", false}, {"d", false}, {"e", true}}
+
+	got := Tasks(src)
+	if len(got) != len(want) {
+		t.Fatalf("Tasks found %d items, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i].Index != i || got[i].Text != w.text || got[i].Checked != w.checked {
+			t.Errorf("item %d = %+v, want %q checked=%v", i, got[i], w.text, w.checked)
+		}
+	}
+}
+
+func TestSetTask(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		index   int
+		checked bool
+		want    string
+		ok      bool
+	}{
+		{"nested", "- [ ] a\n  - [ ] b", 1, true, "- [ ] a\n  - [x] b", true},
+		{"nested under a plain item", "- a\n  - [X] b", 0, false, "- a\n  - [ ] b", true},
+		{"quoted", "> - [ ] a\n> > * [x] b", 1, false, "> - [ ] a\n> > * [ ] b", true},
+		{"quoted inside an item", "- [ ] a\n  > - [ ] b", 1, true, "- [ ] a\n  > - [x] b", true},
+		{"ordered", "1. [ ] a\n2) [ ] b", 1, true, "1. [ ] a\n2) [x] b", true},
+		{"CRLF", "- [ ] a\r\n- [ ] b\r\n", 1, true, "- [ ] a\r\n- [x] b\r\n", true},
+		{"fenced code is skipped", "```\n- [ ] no\n```\n- [ ] yes", 0, true, "```\n- [ ] no\n```\n- [x] yes", true},
+		{"indented code is skipped", "\t- [ ] no\n- [ ] yes", 0, true, "\t- [ ] no\n- [x] yes", true},
+		{"code inside an item", "- [ ] a\n\n      - [ ] no", 1, true, "- [ ] a\n\n      - [ ] no", false},
+		{"index past the end", "- [ ] a", 1, true, "- [ ] a", false},
+		{"negative index", "- [ ] a", -1, true, "- [ ] a", false},
+	}
+	for _, tt := range tests {
+		got, ok := SetTask(tt.src, tt.index, tt.checked)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%s: SetTask(%q, %d, %v) = %q, %v, want %q, %v", tt.name, tt.src, tt.index, tt.checked,
+				got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Render converts Markdown to HTML. Raw HTML in the source is passed
+// through, so output shown to users must go through Sanitize, or use
+// RenderSafe.
+func Render(src string) string {
+	p, blocks := parse(src)
+	r := &renderer{refs: p.refs}
+	r.blocks(blocks, false)
+	return r.b.String()
+}
+
+// RenderSafe renders Markdown to sanitized HTML.
+func RenderSafe(src string) string {
+	return Sanitize(Render(src))
+}
+
+type renderer struct {
+	b    strings.Builder
+	refs map[string]linkRef
+}
+
+func (r *renderer) newline() {
+	if s := r.b.String(); s != "" && !strings.HasSuffix(s, "\n") {
+		r.b.WriteByte('\n')
+	}
+}
+
+func (r *renderer) blocks(blocks []*block, tight bool) {
+	for _, b := range blocks {
+		if tight && b.kind == blockParagraph {
+			r.inlines(b.text)
+			continue
+		}
+		r.newline()
+		r.block(b)
+	}
+}
+
+func (r *renderer) block(b *block) {
+	switch b.kind {
+	case blockParagraph:
+		r.b.WriteString("<p>")
+		r.inlines(b.text)
+		r.b.WriteString("</p>\n")
+	case blockHeading:
+		fmt.Fprintf(&r.b, "<h%d>", b.level)
+		r.inlines(b.text)
+		fmt.Fprintf(&r.b, "</h%d>\n", b.level)
+	case blockCode:
+		r.b.WriteString("<pre><code")
+		if lang, _, _ := strings.Cut(b.info, " "); lang != "" {
+			r.b.WriteString(` class="language-` + html.EscapeString(lang) + `"`)
+		}
+		r.b.WriteString(">" + html.EscapeString(b.text) + "</code></pre>\n")
+	case blockHTML:
+		r.b.WriteString(b.text)
+	case blockRule:
+		r.b.WriteString("<hr />\n")
+	case blockQuote:
+		r.b.WriteString("<blockquote>\n")
+		r.blocks(b.children, false)
+		r.newline()
+		r.b.WriteString("</blockquote>\n")
+	case blockList:
+		tag := "ul"
+		if b.ordered {
+			tag = "ol"
+		}
+		r.b.WriteString("<" + tag)
+		if b.ordered && b.start != 1 {
+			r.b.WriteString(` start="` + strconv.Itoa(b.start) + `"`)
+		}
+		for _, item := range b.children {
+			if item.task > 0 {
+				r.b.WriteString(` class="contains-task-list"`)
+				break
+			}
+		}
+		r.b.WriteString(">\n")
+		for _, item := range b.children {
+			r.item(item, b.tight)
+		}
+		r.b.WriteString("</" + tag + ">\n")
+	}
+}
+
+func (r *renderer) item(item *block, tight bool) {
+	if item.task == 0 {
+		r.b.WriteString("<li>")
+	} else {
+		r.b.WriteString(`<li class="task-list-item"><input type="checkbox" disabled=""`)
+		if item.task == 2 {
+			r.b.WriteString(` checked=""`)
+		}
+		r.b.WriteString(" /> ")
+	}
+	r.blocks(item.children, tight)
+	if len(item.children) > 0 && !(tight && item.children[len(item.children)-1].kind == blockParagraph) {
+		r.newline()
+	}
+	r.b.WriteString("</li>\n")
+}
+
+func (r *renderer) inlines(src string) {
+	r.inline(parseInlines(src, r.refs))
+}
+
+func (r *renderer) inline(n *inline) {
+	for c := n.first; c != nil; c = c.next {
+		switch c.kind {
+		case inlineText:
+			r.b.WriteString(html.EscapeString(c.text))
+		case inlineHTML:
+			r.b.WriteString(c.text)
+		case inlineCode:
+			r.b.WriteString("<code>" + html.EscapeString(c.text) + "</code>")
+		case inlineSoftBreak:
+			r.b.WriteString("\n")
+		case inlineHardBreak:
+			r.b.WriteString("<br />\n")
+		case inlineEmph:
+			r.b.WriteString("<em>")
+			r.inline(c)
+			r.b.WriteString("</em>")
+		case inlineStrong:
+			r.b.WriteString("<strong>")
+			r.inline(c)
+			r.b.WriteString("</strong>")
+		case inlineLink:
+			r.b.WriteString(`<a href="` + html.EscapeString(encodeURL(c.dest)) + `"`)
+			if c.title != "" {
+				r.b.WriteString(` title="` + html.EscapeString(c.title) + `"`)
+			}
+			r.b.WriteString(">")
+			r.inline(c)
+			r.b.WriteString("</a>")
+		case inlineImage:
+			r.b.WriteString(`<img src="` + html.EscapeString(encodeURL(c.dest)) + `" alt="` + html.EscapeString(plainText(c)) + `"`)
+			if c.title != "" {
+				r.b.WriteString(` title="` + html.EscapeString(c.title) + `"`)
+			}
+			r.b.WriteString(" />")
+		}
+	}
+}
+
+// plainText is the text of a node without markup, for image descriptions.
+func plainText(n *inline) string {
+	var b strings.Builder
+	for c := n.first; c != nil; c = c.next {
+		switch c.kind {
+		case inlineText, inlineCode:
+			b.WriteString(c.text)
+		case inlineSoftBreak, inlineHardBreak:
+			b.WriteString("\n")
+		default:
+			b.WriteString(plainText(c))
+		}
+	}
+	return b.String()
+}
+
+// encodeURL percent-encodes the characters of a destination that may not
+// appear in a URL, keeping existing escapes.
+func encodeURL(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			b.WriteByte(c)
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9',
+			strings.IndexByte(";/?:@&=+$,-_.!~*'()#", c) >= 0:
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&15])
+		}
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// Tasks lists the task list items of a document.
+func Tasks(src string) []TaskItem {
+	p, _ := parse(src)
+	if p.tasks == nil {
+		return []TaskItem{}
+	}
+	return p.tasks
+}
+
+// taskBox finds the [ ] or [x] of the task item starting a source line,
+// after any block quote and list markers.
+var taskBox = regexp.MustCompile(`^((?:[ \t]*>)*(?:[ \t]*(?:[-+*]|\d{1,9}[.)])[ \t]+)+)\[[ xX]\]`)
+
+// SetTask checks or unchecks the task item at index, leaving the rest of
+// the source as it is. It reports false if there is no such item.
+func SetTask(src string, index int, checked bool) (string, bool) {
+	tasks := Tasks(src)
+	if index < 0 || index >= len(tasks) {
+		return src, false
+	}
+
+	lines := strings.Split(src, "\n")
+	no := tasks[index].line
+	if no >= len(lines) {
+		return src, false
+	}
+	m := taskBox.FindStringSubmatchIndex(lines[no])
+	if m == nil {
+		return src, false
+	}
+	box := "[ ]"
+	if checked {
+		box = "[x]"
+	}
+	lines[no] = lines[no][:m[3]] + box + lines[no][m[1]:]
+	return strings.Join(lines, "\n"), true
+}
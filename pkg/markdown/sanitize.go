@@ -0,0 +1,192 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+
+	nethtml "golang.org/x/net/html"
+)
+
+// allowedAttrs lists the elements kept by Sanitize and their attributes.
+// "title" is allowed on every one of them.
+var allowedAttrs = map[string][]string{
+	"a": {"href"}, "abbr": nil, "b": nil, "blockquote": nil, "br": nil, "code": {"class"}, "dd": nil,
+	"del": nil, "details": nil, "div": nil, "dl": nil, "dt": nil, "em": nil, "h1": nil, "h2": nil,
+	"h3": nil, "h4": nil, "h5": nil, "h6": nil, "hr": nil, "i": nil, "img": {"src", "alt", "width", "height"},
+	"input": {"type", "checked", "disabled"}, "ins": nil, "kbd": nil, "li": {"class"}, "mark": nil,
+	"ol": {"start", "class"}, "p": nil, "pre": nil, "q": nil, "s": nil, "samp": nil, "span": nil,
+	"strong": nil, "sub": nil, "summary": nil, "sup": nil, "table": nil, "tbody": nil,
+	"td": {"align", "colspan", "rowspan"}, "tfoot": nil, "th": {"align", "colspan", "rowspan"},
+	"thead": nil, "tr": nil, "u": nil, "ul": {"class"},
+}
+
+// droppedWithContent are elements removed along with everything inside
+// them; other unknown elements are removed but their text is kept.
+var droppedWithContent = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true, "noscript": true,
+	"template": true, "textarea": true, "select": true, "title": true, "head": true, "svg": true,
+	"math": true, "frame": true, "frameset": true, "noembed": true, "noframes": true, "xmp": true,
+	"applet": true,
+}
+
+var voidElements = map[string]bool{"br": true, "hr": true, "img": true, "input": true}
+
+var (
+	safeClass  = regexp.MustCompile(`^(?:task-list-item|contains-task-list|language-[A-Za-z0-9_+#.-]+)$`)
+	safeNumber = regexp.MustCompile(`^[0-9]{1,4}$`)
+)
+
+// safeURL allows relative URLs and the http, https and mailto schemes.
+// Browsers ignore whitespace and control characters in schemes, so those
+// are removed before checking.
+func safeURL(u string) bool {
+	cleaned := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, u)
+	end := strings.IndexAny(cleaned, "/?#")
+	if end < 0 {
+		end = len(cleaned)
+	}
+	colon := strings.IndexByte(cleaned[:end], ':')
+	if colon < 0 {
+		return true
+	}
+	switch strings.ToLower(cleaned[:colon]) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+func safeAttr(tag, name, value string) bool {
+	switch name {
+	case "href", "src":
+		return safeURL(value)
+	case "class":
+		for _, c := range strings.Fields(value) {
+			if !safeClass.MatchString(c) {
+				return false
+			}
+		}
+		return true
+	case "start", "width", "height", "colspan", "rowspan":
+		return safeNumber.MatchString(value)
+	case "align":
+		switch value {
+		case "left", "right", "center":
+			return true
+		}
+		return false
+	case "type":
+		return tag == "input" && strings.EqualFold(value, "checkbox")
+	}
+	return true
+}
+
+// Sanitize keeps a safe subset of HTML: formatting elements with a few
+// attributes, links and images to http, https and mailto URLs, and disabled
+// checkboxes. Scripts, styles, event handlers and other elements are
+// removed, and elements are balanced so that the output can be inserted
+// into a page as it is.
+func Sanitize(src string) string {
+	var b strings.Builder
+	var open []string
+	skip := 0
+
+	z := nethtml.NewTokenizer(strings.NewReader(src))
+	for {
+		tt := z.Next()
+		if tt == nethtml.ErrorToken {
+			// io.EOF, or input too large for the tokenizer's buffer.
+			break
+		}
+		tok := z.Token()
+		name := tok.Data
+
+		if skip > 0 {
+			switch {
+			case tt == nethtml.StartTagToken && droppedWithContent[name]:
+				skip++
+			case tt == nethtml.EndTagToken && droppedWithContent[name]:
+				skip--
+			}
+			continue
+		}
+
+		switch tt {
+		case nethtml.TextToken:
+			b.WriteString(html.EscapeString(tok.Data))
+		case nethtml.StartTagToken, nethtml.SelfClosingTagToken:
+			if droppedWithContent[name] {
+				if tt == nethtml.StartTagToken {
+					skip = 1
+				}
+				continue
+			}
+			attrs, ok := allowedAttrs[name]
+			if !ok {
+				continue
+			}
+			if name == "input" && !isCheckbox(tok.Attr) {
+				continue
+			}
+			b.WriteString("<" + name)
+			for _, a := range tok.Attr {
+				if a.Namespace != "" || (a.Key != "title" && !slices.Contains(attrs, a.Key)) || !safeAttr(name, a.Key, a.Val) {
+					continue
+				}
+				if name == "input" && a.Key == "disabled" {
+					continue
+				}
+				b.WriteString(" " + a.Key + `="` + html.EscapeString(a.Val) + `"`)
+			}
+			switch name {
+			case "a":
+				b.WriteString(` rel="nofollow noopener noreferrer"`)
+			case "input":
+				b.WriteString(` disabled=""`)
+			}
+			if voidElements[name] {
+				b.WriteString(" />")
+				continue
+			}
+			b.WriteString(">")
+			if tt == nethtml.SelfClosingTagToken {
+				b.WriteString("</" + name + ">")
+			} else {
+				open = append(open, name)
+			}
+		case nethtml.EndTagToken:
+			// Closing an element also closes those opened inside it;
+			// closing one that is not open is ignored.
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] != name {
+					continue
+				}
+				for j := len(open) - 1; j >= i; j-- {
+					b.WriteString("</" + open[j] + ">")
+				}
+				open = open[:i]
+				break
+			}
+		}
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+	return b.String()
+}
+
+func isCheckbox(attrs []nethtml.Attribute) bool {
+	for _, a := range attrs {
+		if a.Key == "type" && strings.EqualFold(a.Val, "checkbox") {
+			return true
+		}
+	}
+	return false
+}